@@ -0,0 +1,102 @@
+// Command reconcile checks the balance of every account against its initial balance plus its completed transfers and prints the discrepancies as JSON
+//
+//	go run ./cmd/reconcile [-correct -reason "..."] [app configuration flags or environment]
+//
+// With -correct, the difference of each balance found to differ is booked as a transaction against the suspense account of its currency, see ADJUSTMENT_SUSPENSE_ACCOUNT_ID, and the correction is recorded with the reason and the OS user running the command
+// The command exits with status 1 when discrepancies remain uncorrected, so that it can run as a scheduled check
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+
+	"account-test/config"
+	"account-test/internal/core/domain"
+	"account-test/internal/core/services"
+	"account-test/internal/logger"
+	"account-test/internal/repositories"
+	db "account-test/postgres"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run will reconcile the balances as configured by args and print the result
+// The function will return the exit status of the command
+func run(args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	correct := flags.Bool("correct", false, "book the difference of each balance found to differ against the suspense account of its currency")
+	reason := flags.String("reason", "", "reason recorded with the corrections, required with -correct")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	// The remaining arguments configure the app as for the server
+	appConfig, err := config.Load(flags.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	if err := logger.Init(os.Stderr, appConfig.Log); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	dbClient, err := db.Init(appConfig.DB)
+	if err != nil {
+		return 1
+	}
+	defer dbClient.Close()
+	accountPort, err := repositories.NewAccountPort(dbClient, db.NewReadPool(dbClient, nil, 0), appConfig.DB)
+	if err != nil {
+		slog.Error("invalid database schema", "error", err)
+		return 2
+	}
+
+	suspenseAccounts, err := services.LoadSuspenseAccounts(context.Background(), accountPort, *appConfig.Adjustment)
+	if err != nil {
+		slog.Error("invalid suspense accounts", "error", err)
+		return 2
+	}
+
+	reconciliation, err := services.NewReconciliationSvc(accountPort, appConfig.Snapshot.Delay, suspenseAccounts).Reconcile(context.Background(), domain.ReconcileCommand{
+		Correct: *correct,
+		Reason:  *reason,
+		Actor:   actor(),
+	})
+	if err != nil {
+		slog.Error("reconciliation failed", "error", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(reconciliation)
+	for _, discrepancy := range reconciliation.Discrepancies {
+		if !discrepancy.Corrected {
+			return 1
+		}
+	}
+	return 0
+}
+
+// actor will identify the OS user running the command, as the actor recorded with the corrections
+func actor() string {
+	current, err := user.Current()
+	if err != nil {
+		return "cli"
+	}
+	return "cli:" + current.Username
+}
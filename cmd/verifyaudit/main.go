@@ -0,0 +1,79 @@
+// Command verifyaudit checks the hash chain of the audit log and prints the result as JSON
+//
+//	go run ./cmd/verifyaudit [-anchor last_hash] [app configuration flags or environment]
+//
+// -anchor is the last_hash printed by an earlier run, kept outside the database, so that entries removed from the end of the log are detected too
+// The command reads the database over a read-only connection without running migrations, and does not need PORT
+// The command exits with status 1 when the chain is broken, so that it can run as a scheduled check
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"account-test/config"
+	"account-test/internal/core/services"
+	"account-test/internal/logger"
+	"account-test/internal/repositories"
+	db "account-test/postgres"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run will verify the audit log as configured by args and print the result
+// The function will return the exit status of the command
+func run(args []string) int {
+	flags := flag.NewFlagSet("verifyaudit", flag.ContinueOnError)
+	anchor := flags.String("anchor", "", "last_hash of an earlier verification that the log must still contain")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	// The remaining arguments configure the app as for the server, without the API ports
+	appConfig, err := config.LoadCommand(flags.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		return 2
+	}
+	if err := logger.Init(os.Stderr, appConfig.Log); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	dbClient, err := db.InitReadOnly(appConfig.DB)
+	if err != nil {
+		return 1
+	}
+	defer dbClient.Close()
+	auditPort, err := repositories.NewAuditPort(dbClient, appConfig.DB)
+	if err != nil {
+		slog.Error("invalid database schema", "error", err)
+		return 2
+	}
+
+	verification, err := services.NewAuditSvc(auditPort).VerifyAuditLog(context.Background(), *anchor)
+	if err != nil {
+		slog.Error("audit log verification failed", "error", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(verification)
+	if !verification.Valid {
+		return 1
+	}
+	return 0
+}
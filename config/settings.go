@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces the value of secret settings when the config is printed
+const redacted = "REDACTED"
+
+// value is a typed configuration field that can be set from its string form
+type value interface {
+	Set(raw string) error
+	String() string
+}
+
+// setting is a single configuration value, read from the config file and the environment under key and from the command line flag named flagName(key)
+// secret settings are printed as REDACTED, unless redact is set to print the value with only its secret parts removed
+type setting struct {
+	key    string
+	usage  string
+	secret bool
+	redact func(raw string) string
+	value  value
+}
+
+// flagName returns the command line flag for a setting key, DB_HOST is set with -db-host
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
+
+// printable returns the value of the setting as it may be printed, with secrets redacted
+func (s setting) printable() string {
+	if !s.secret || s.value.String() == "" {
+		return s.value.String()
+	}
+	if s.redact != nil {
+		return s.redact(s.value.String())
+	}
+	return redacted
+}
+
+// redactURL returns rawURL with the password replaced, or REDACTED when rawURL cannot be parsed
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+	if _, ok := parsed.User.Password(); ok {
+		parsed.User = url.UserPassword(parsed.User.Username(), redacted)
+	}
+	return parsed.String()
+}
+
+type stringValue struct{ p *string }
+
+func (v stringValue) Set(raw string) error {
+	*v.p = raw
+	return nil
+}
+
+func (v stringValue) String() string { return *v.p }
+
+type intValue struct{ p *int }
+
+func (v intValue) Set(raw string) error {
+	parsed, err := strconv.Atoi(raw)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", raw)
+	}
+	*v.p = parsed
+	return nil
+}
+
+func (v intValue) String() string { return strconv.Itoa(*v.p) }
+
+type floatValue struct{ p *float64 }
+
+func (v floatValue) Set(raw string) error {
+	parsed, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", raw)
+	}
+	*v.p = parsed
+	return nil
+}
+
+func (v floatValue) String() string { return strconv.FormatFloat(*v.p, 'f', -1, 64) }
+
+type boolValue struct{ p *bool }
+
+func (v boolValue) Set(raw string) error {
+	parsed, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", raw)
+	}
+	*v.p = parsed
+	return nil
+}
+
+func (v boolValue) String() string { return strconv.FormatBool(*v.p) }
+
+type durationValue struct{ p *time.Duration }
+
+func (v durationValue) Set(raw string) error {
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 5s", raw)
+	}
+	*v.p = parsed
+	return nil
+}
+
+func (v durationValue) String() string { return v.p.String() }
+
+// stringListValue is a comma separated list of strings, with blank items dropped
+type stringListValue struct{ p *[]string }
+
+func (v stringListValue) Set(raw string) error {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v.p = items
+	return nil
+}
+
+func (v stringListValue) String() string { return strings.Join(*v.p, ",") }
+
+// readFile will read a flat YAML file of setting keys to scalar values, such as dev.env
+// Empty values are read as empty strings
+func readFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw := map[string]any{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	values := map[string]string{}
+	for key, value := range raw {
+		switch value := value.(type) {
+		case nil:
+			values[key] = ""
+		case string, int, float64, bool:
+			values[key] = fmt.Sprint(value)
+		default:
+			return nil, fmt.Errorf("%s: %s must be a single value", path, key)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,130 @@
+package accountid
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/static"
+	"fmt"
+)
+
+const (
+	// ModeClient accepts account IDs supplied by the client creating the account
+	ModeClient = "client"
+	// ModeGenerated generates the ID of every new account, clients must not supply one
+	ModeGenerated = "generated"
+
+	FormatText   = "text"
+	FormatULID   = "ulid"
+	FormatUUIDv7 = "uuidv7"
+	FormatMod97  = "mod97"
+)
+
+// Formats are the account ID formats a Policy can validate and generate
+var Formats = []string{FormatText, FormatULID, FormatUUIDv7, FormatMod97}
+
+type AccountIDConfig struct {
+	Mode   string
+	Format string
+	// Prefix starts every mod97 account ID and is covered by its check digits
+	Prefix string
+	// PreviousFormats are formats existing accounts were created in, their IDs are still accepted for every request but creating an account
+	PreviousFormats []string
+}
+
+// format validates and generates account IDs of one format
+type format interface {
+	valid(id string) bool
+	generate() (string, error)
+}
+
+// Policy implements ports.AccountIDPolicy for the mode and formats of an AccountIDConfig
+type Policy struct {
+	generated bool
+	format    format
+	invalid   string
+	previous  []format
+}
+
+// New will return the Policy described by config
+// The function will return an error if the mode or a format is not known or IDs in the text format are to be generated
+func New(config *AccountIDConfig) (*Policy, error) {
+	policy := &Policy{}
+	switch config.Mode {
+	case ModeClient:
+	case ModeGenerated:
+		policy.generated = true
+	default:
+		return nil, fmt.Errorf("unknown account ID mode %q", config.Mode)
+	}
+
+	if policy.generated && config.Format == FormatText {
+		return nil, fmt.Errorf("account IDs in the %s format cannot be generated", FormatText)
+	}
+	var err error
+	if policy.format, policy.invalid, err = newFormat(config.Format, config.Prefix); err != nil {
+		return nil, err
+	}
+	for _, name := range config.PreviousFormats {
+		previous, _, err := newFormat(name, config.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		policy.previous = append(policy.previous, previous)
+	}
+	return policy, nil
+}
+
+// newFormat returns the format called name, with the message of the error rejecting an ID that does not match it
+func newFormat(name string, prefix string) (format, string, error) {
+	switch name {
+	case FormatText:
+		return text{}, static.ErrIDLengthTooLong, nil
+	case FormatULID:
+		return ulid{}, static.ErrIDNotULID, nil
+	case FormatUUIDv7:
+		return uuidv7{}, static.ErrIDNotUUIDv7, nil
+	case FormatMod97:
+		if !validMod97Prefix(prefix) {
+			return nil, "", fmt.Errorf("account ID prefix %q must be 1 to %d upper case letters", prefix, maxMod97PrefixLength)
+		}
+		return mod97{prefix: prefix}, static.ErrIDNotMod97, nil
+	default:
+		return nil, "", fmt.Errorf("unknown account ID format %q", name)
+	}
+}
+
+// Generated reports whether the IDs of new accounts are generated by Generate rather than supplied by clients
+func (p *Policy) Generated() bool {
+	return p.generated
+}
+
+// Validate will check that id is an account ID of the configured format or of one of the previous formats, so that accounts created before the format changed can still be used
+// The function will return a *domain.Error with code domain.ErrCodeInvalidAccountID when it is not
+func (p *Policy) Validate(id string) error {
+	if len(id) == 0 {
+		return domain.NewError(domain.ErrCodeInvalidAccountID, static.ErrIDLengthCannotBeZero)
+	}
+	for _, previous := range p.previous {
+		if previous.valid(id) {
+			return nil
+		}
+	}
+	return p.ValidateNew(id)
+}
+
+// ValidateNew will check that id is an account ID of the configured format, for the ID of a new account
+// The function will return a *domain.Error with code domain.ErrCodeInvalidAccountID when it is not
+func (p *Policy) ValidateNew(id string) error {
+	if len(id) == 0 {
+		return domain.NewError(domain.ErrCodeInvalidAccountID, static.ErrIDLengthCannotBeZero)
+	}
+	if !p.format.valid(id) {
+		return domain.NewError(domain.ErrCodeInvalidAccountID, p.invalid)
+	}
+	return nil
+}
+
+// Generate will return a new random account ID of the configured format
+// The function will return an error if the format cannot be generated or the system random source fails
+func (p *Policy) Generate() (string, error) {
+	return p.format.generate()
+}
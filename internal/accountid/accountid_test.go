@@ -0,0 +1,193 @@
+package accountid
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/static"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AccountIDConfig
+		err    string
+	}{
+		{
+			name:   "Test Case Positive",
+			config: AccountIDConfig{Mode: ModeGenerated, Format: FormatMod97, Prefix: "AC"},
+		},
+		{
+			name:   "Test Case Negative - Unknown mode",
+			config: AccountIDConfig{Mode: "server", Format: FormatULID},
+			err:    `unknown account ID mode "server"`,
+		},
+		{
+			name:   "Test Case Negative - Unknown format",
+			config: AccountIDConfig{Mode: ModeClient, Format: "uuidv4"},
+			err:    `unknown account ID format "uuidv4"`,
+		},
+		{
+			name:   "Test Case Negative - Generated text",
+			config: AccountIDConfig{Mode: ModeGenerated, Format: FormatText},
+			err:    "cannot be generated",
+		},
+		{
+			name:   "Test Case Negative - Lower case prefix",
+			config: AccountIDConfig{Mode: ModeGenerated, Format: FormatMod97, Prefix: "ac"},
+			err:    "must be 1 to 8 upper case letters",
+		},
+		{
+			name:   "Test Case Negative - Unknown previous format",
+			config: AccountIDConfig{Mode: ModeGenerated, Format: FormatULID, PreviousFormats: []string{"uuidv4"}},
+			err:    `unknown account ID format "uuidv4"`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(&tc.config)
+
+			if len(tc.err) > 0 {
+				assert.ErrorContains(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerateValidates(t *testing.T) {
+	for _, config := range []AccountIDConfig{
+		{Mode: ModeGenerated, Format: FormatULID},
+		{Mode: ModeGenerated, Format: FormatUUIDv7},
+		{Mode: ModeGenerated, Format: FormatMod97, Prefix: "AC"},
+	} {
+		t.Run(config.Format, func(t *testing.T) {
+			policy, err := New(&config)
+			assert.NoError(t, err)
+			assert.True(t, policy.Generated())
+
+			seen := map[string]bool{}
+			for i := 0; i < 100; i++ {
+				id, err := policy.Generate()
+				assert.NoError(t, err)
+				assert.NoError(t, policy.Validate(id), id)
+				assert.False(t, seen[id], id)
+				seen[id] = true
+			}
+		})
+	}
+}
+
+func TestValidateNew(t *testing.T) {
+	policy, err := New(&AccountIDConfig{Mode: ModeClient, Format: FormatULID, PreviousFormats: []string{FormatText}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, policy.Validate("123"))
+	assert.NoError(t, policy.ValidateNew("01ARZ3NDEKTSV4RRFFQ69G5FAV"))
+	var domainErr *domain.Error
+	assert.ErrorAs(t, policy.ValidateNew("123"), &domainErr)
+	assert.Equal(t, static.ErrIDNotULID, domainErr.Message)
+	assert.Equal(t, domain.ErrCodeInvalidAccountID, domainErr.Code)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config AccountIDConfig
+		id     string
+		err    string
+	}{
+		{
+			name:   "Test Case Positive - Text",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatText},
+			id:     "123",
+		},
+		{
+			name:   "Test Case Negative - Empty",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatText},
+			id:     "",
+			err:    static.ErrIDLengthCannotBeZero,
+		},
+		{
+			name:   "Test Case Negative - Text longer than 32 characters",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatText},
+			id:     "123456789012345678901234567890123",
+			err:    static.ErrIDLengthTooLong,
+		},
+		{
+			name:   "Test Case Positive - ULID",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatULID},
+			id:     "01ARZ3NDEKTSV4RRFFQ69G5FAV",
+		},
+		{
+			name:   "Test Case Negative - ULID in lower case",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatULID},
+			id:     "01arz3ndektsv4rrffq69g5fav",
+			err:    static.ErrIDNotULID,
+		},
+		{
+			name:   "Test Case Negative - ULID overflowing 128 bits",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatULID},
+			id:     "81ARZ3NDEKTSV4RRFFQ69G5FAV",
+			err:    static.ErrIDNotULID,
+		},
+		{
+			name:   "Test Case Positive - UUIDv7",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatUUIDv7},
+			id:     "017f22e2-79b0-7cc3-98c4-dc0c0c07398f",
+		},
+		{
+			name:   "Test Case Negative - UUIDv4",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatUUIDv7},
+			id:     "9b2e4c1a-3f6d-4e8b-a1c2-7d5e9f0a1b2c",
+			err:    static.ErrIDNotUUIDv7,
+		},
+		{
+			name:   "Test Case Positive - Mod97",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatMod97, Prefix: "GB"},
+			id:     "GB340000000012345678",
+		},
+		{
+			name:   "Test Case Negative - Mod97 with a mistyped digit",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatMod97, Prefix: "GB"},
+			id:     "GB340000000012345679",
+			err:    static.ErrIDNotMod97,
+		},
+		{
+			name:   "Test Case Negative - Mod97 with another prefix",
+			config: AccountIDConfig{Mode: ModeClient, Format: FormatMod97, Prefix: "GB"},
+			id:     "DE340000000012345678",
+			err:    static.ErrIDNotMod97,
+		},
+		{
+			name:   "Test Case Positive - Text of a previous format",
+			config: AccountIDConfig{Mode: ModeGenerated, Format: FormatULID, PreviousFormats: []string{FormatText}},
+			id:     "123",
+		},
+		{
+			name:   "Test Case Negative - Matching no previous format",
+			config: AccountIDConfig{Mode: ModeGenerated, Format: FormatMod97, Prefix: "GB", PreviousFormats: []string{FormatULID}},
+			id:     "017f22e2-79b0-7cc3-98c4-dc0c0c07398f",
+			err:    static.ErrIDNotMod97,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := New(&tc.config)
+			assert.NoError(t, err)
+
+			err = policy.Validate(tc.id)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, domain.ErrCodeInvalidAccountID, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
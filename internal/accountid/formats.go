@@ -0,0 +1,170 @@
+package accountid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// maxTextLength is the upper bound on the length of account IDs in the text format, see README assumption 3
+const maxTextLength = 32
+
+// text accepts free text account IDs supplied by clients
+type text struct{}
+
+func (text) valid(id string) bool {
+	return len(id) <= maxTextLength
+}
+
+func (text) generate() (string, error) {
+	return "", errors.New("account IDs in the text format cannot be generated")
+}
+
+// crockford is the Crockford base32 alphabet ULIDs are encoded with
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulid generates ULIDs, 48 bits of millisecond timestamp followed by 80 random bits in 26 characters of Crockford base32
+// IDs are accepted in the upper case canonical form only, as account IDs are compared exactly
+type ulid struct{}
+
+func (ulid) valid(id string) bool {
+	if len(id) != 26 || id[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if strings.IndexByte(crockford, id[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (ulid) generate() (string, error) {
+	b, err := timestampedRandom()
+	if err != nil {
+		return "", err
+	}
+	// The 128 bits are written as 26 groups of 5 bits from the end, the first character holds the top 3 bits
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out), nil
+}
+
+// uuidv7 generates version 7 UUIDs, 48 bits of millisecond timestamp followed by random bits, in the lower case hyphenated form
+type uuidv7 struct{}
+
+func (uuidv7) valid(id string) bool {
+	if len(id) != 36 || id[14] != '7' || strings.IndexByte("89ab", id[19]) < 0 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if id[i] != '-' {
+				return false
+			}
+		default:
+			if strings.IndexByte("0123456789abcdef", id[i]) < 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (uuidv7) generate() (string, error) {
+	b, err := timestampedRandom()
+	if err != nil {
+		return "", err
+	}
+	b[6] = 0x70 | b[6]&0x0f // version 7
+	b[8] = 0x80 | b[8]&0x3f // RFC 9562 variant
+	h := hex.EncodeToString(b[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:], nil
+}
+
+// timestampedRandom will return 16 bytes starting with the current Unix time in milliseconds as 48 bits, followed by random bytes
+func timestampedRandom() ([16]byte, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return b, err
+	}
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	return b, nil
+}
+
+const (
+	// mod97BodyLength is the number of random digits of a mod97 account ID
+	mod97BodyLength      = 16
+	maxMod97PrefixLength = 8
+)
+
+// mod97 generates account numbers in the style of an IBAN: the prefix, 2 check digits and mod97BodyLength random digits
+// The check digits follow ISO 7064 MOD 97-10, so any single mistyped character or swap of adjacent characters is detected
+type mod97 struct {
+	prefix string
+}
+
+func validMod97Prefix(prefix string) bool {
+	if len(prefix) == 0 || len(prefix) > maxMod97PrefixLength {
+		return false
+	}
+	for i := 0; i < len(prefix); i++ {
+		if prefix[i] < 'A' || prefix[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m mod97) valid(id string) bool {
+	rest, ok := strings.CutPrefix(id, m.prefix)
+	if !ok || len(rest) != 2+mod97BodyLength {
+		return false
+	}
+	for i := 0; i < len(rest); i++ {
+		if rest[i] < '0' || rest[i] > '9' {
+			return false
+		}
+	}
+	// As for an IBAN, the prefix and check digits are moved to the end and the result is 1 modulo 97
+	return mod97Remainder(rest[2:]+m.prefix+rest[:2]) == 1
+}
+
+func (m mod97) generate() (string, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Exp(big.NewInt(10), big.NewInt(mod97BodyLength), nil))
+	if err != nil {
+		return "", err
+	}
+	body := fmt.Sprintf("%0*d", mod97BodyLength, n)
+	check := 98 - mod97Remainder(body+m.prefix+"00")
+	return fmt.Sprintf("%s%02d%s", m.prefix, check, body), nil
+}
+
+// mod97Remainder will return the remainder of s modulo 97, reading s as a number where the letters A to Z stand for 10 to 35
+func mod97Remainder(s string) int {
+	remainder := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' {
+			remainder = (remainder*100 + int(c-'A') + 10) % 97
+		} else {
+			remainder = (remainder*10 + int(c-'0')) % 97
+		}
+	}
+	return remainder
+}
@@ -1,13 +1,132 @@
 package domain
 
+import "time"
+
+// AccountStatusActive is the status of every account when it is created
+const AccountStatusActive = "active"
+
+// AccountStatuses are the statuses an account can be in
+var AccountStatuses = []string{AccountStatusActive}
+
+// DefaultCurrency is the currency of accounts created without one
+const DefaultCurrency = "USD"
+
+// Sort orders for AccountService.ListAccounts, a leading - sorts in descending order
+const (
+	AccountSortID            = "id"
+	AccountSortIDDesc        = "-id"
+	AccountSortCreatedAt     = "created_at"
+	AccountSortCreatedAtDesc = "-created_at"
+	AccountSortBalance       = "balance"
+	AccountSortBalanceDesc   = "-balance"
+)
+
+// AccountSorts are the sort orders accepted by AccountService.ListAccounts
+var AccountSorts = []string{AccountSortID, AccountSortIDDesc, AccountSortCreatedAt, AccountSortCreatedAtDesc, AccountSortBalance, AccountSortBalanceDesc}
+
 // Struct for POST account
 type PostAccount struct {
-	ID      string `json:"account_id"`
-	Balance string `json:"initial_balance"`
+	ID          string            `json:"account_id"`
+	Balance     string            `json:"initial_balance"`
+	Currency    string            `json:"currency,omitempty"`
+	DisplayName string            `json:"display_name,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Labels      []string          `json:"labels,omitempty"`
+}
+
+// Struct for PATCH account
+// Only the fields present in the body are changed, an empty object or list clears the metadata or labels
+type PatchAccount struct {
+	DisplayName *string           `json:"display_name,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Labels      []string          `json:"labels,omitempty"`
 }
 
 // Struct for GET account
+// Metadata and Labels are stored as JSONB and TEXT[] columns and are read by the repository rather than mapped by db tag
 type Account struct {
-	ID      string `json:"account_id" db:"id"`
-	Balance string `json:"balance" db:"balance"`
+	ID          string            `json:"account_id" db:"id"`
+	Balance     string            `json:"balance" db:"balance"`
+	Currency    string            `json:"currency" db:"currency"`
+	Status      string            `json:"status" db:"status"`
+	DisplayName string            `json:"display_name,omitempty" db:"display_name"`
+	Metadata    map[string]string `json:"metadata,omitempty" db:"-"`
+	Labels      []string          `json:"labels,omitempty" db:"-"`
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+}
+
+// Command for creating an account through AccountService.CreateAccount
+// Currency of "" means DefaultCurrency
+type CreateAccountCommand struct {
+	ID             string
+	InitialBalance string
+	Currency       string
+	Details        AccountDetails
+}
+
+// AccountDetails are the descriptive fields of an account, set by its owner and never used by transfers
+type AccountDetails struct {
+	DisplayName string
+	Metadata    map[string]string
+	Labels      []string
+}
+
+// Command for changing the details of an account through AccountService.UpdateAccount
+// A nil field is left unchanged, an empty map or slice clears the metadata or labels
+type UpdateAccountCommand struct {
+	ID          string
+	DisplayName *string
+	Metadata    map[string]string
+	Labels      []string
+}
+
+// Query for listing accounts through AccountService.ListAccounts
+// Empty strings and nil pointers do not filter, Sort of "" means AccountSortID and Limit of 0 means the default page size
+// DisplayName matches display names containing it regardless of case, Labels matches accounts with every label and Metadata matches accounts with every key/value pair
+// CreatedFrom is inclusive and CreatedTo is exclusive, MinBalance and MaxBalance are both inclusive
+// Cursor is the NextCursor of the previous page and must be used with the same Sort
+type ListAccountsQuery struct {
+	Status      string
+	Currency    string
+	IDPrefix    string
+	DisplayName string
+	Labels      []string
+	Metadata    map[string]string
+	MinBalance  *float64
+	MaxBalance  *float64
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        string
+	Limit       int
+	Cursor      string
+}
+
+// AccountCursor is the position of the last account of a page, AccountRepository.ListAccounts continues after it
+// Value is the value of the sorted column of the account as text, ID breaks ties between accounts with the same value
+type AccountCursor struct {
+	Value string
+	ID    string
+}
+
+// Struct for GET accounts
+type AccountList struct {
+	Accounts   []Account `json:"accounts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// Struct for GET account balance
+// Balance is the balance of the account at AsOf, computed from its completed transfers
+type Balance struct {
+	AccountID string    `json:"account_id"`
+	Balance   string    `json:"balance"`
+	Currency  string    `json:"currency"`
+	AsOf      time.Time `json:"as_of"`
+}
+
+// HistoricalBalance is the balance of an account at a point in time as computed by AccountRepository.GetBalanceAsOf
+// Balance is not rounded, CreatedAt is when the account was created as it had no balance before
+type HistoricalBalance struct {
+	Balance   float64   `db:"balance"`
+	Currency  string    `db:"currency"`
+	CreatedAt time.Time `db:"created_at"`
 }
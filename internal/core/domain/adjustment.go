@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// Directions of an adjustment, a credit adds the amount to the balance of the account and a debit takes it away
+const (
+	AdjustmentCredit = "credit"
+	AdjustmentDebit  = "debit"
+)
+
+// Statuses of an adjustment
+// An adjustment waiting for four-eyes approval is pending_approval, an approved adjustment is recorded applied in the DB transaction booking it, or failed
+// approved is only ever returned by an AdjustmentReview, and never recorded
+const (
+	AdjustmentStatusPendingApproval = "pending_approval"
+	AdjustmentStatusApproved        = "approved"
+	AdjustmentStatusRejected        = "rejected"
+	AdjustmentStatusApplied         = "applied"
+	AdjustmentStatusFailed          = "failed"
+)
+
+// AdjustmentReasonCodes are the reasons an adjustment can be made for, one of which is required with every adjustment
+var AdjustmentReasonCodes = []string{"chargeback", "correction", "fee_refund", "goodwill", "write_off"}
+
+// AdjustmentClientID is the client of the transfers booking adjustments, whose reference is unique per adjustment so that an adjustment is never booked twice
+const AdjustmentClientID = "adjustment"
+
+// AdjustmentPolicy configures the adjustments made through AdjustmentService
+// Adjustments are booked against the suspense account of the currency of the account among SuspenseAccountIDs, and are disabled when it is empty
+// With RequireApproval, an adjustment only takes effect once approved by an actor other than the one who requested it
+type AdjustmentPolicy struct {
+	SuspenseAccountIDs []string
+	RequireApproval    bool
+}
+
+// SuspenseAccounts are the ids of the suspense accounts of an AdjustmentPolicy by the currency they hold, at most one per currency
+// Suspense accounts are only booked against by adjustments and balance corrections, never by ordinary transfers
+type SuspenseAccounts map[string]string
+
+// For returns the id of the suspense account holding currency, and false when there is none
+func (s SuspenseAccounts) For(currency string) (string, bool) {
+	id, ok := s[currency]
+	return id, ok
+}
+
+// Contains reports whether id is one of the suspense accounts
+func (s SuspenseAccounts) Contains(id string) bool {
+	for _, suspense := range s {
+		if suspense == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Struct for POST account adjustments
+type PostAdjustment struct {
+	Direction  string `json:"direction"`
+	Amount     string `json:"amount"`
+	ReasonCode string `json:"reason_code"`
+	Note       string `json:"note,omitempty"`
+}
+
+// Command for crediting or debiting an account outside of a transfer through AdjustmentService.Adjust
+// Note is optional, Actor identifies who requested the adjustment
+type AdjustmentCommand struct {
+	AccountID  string
+	Direction  string
+	Amount     string
+	ReasonCode string
+	Note       string
+	Actor      string
+}
+
+// Command for approving or rejecting an adjustment pending approval through AdjustmentService.ReviewAdjustment
+// Actor identifies who reviewed the adjustment and must not be the actor who requested it
+type ReviewAdjustmentCommand struct {
+	AccountID    string
+	AdjustmentID int64
+	Approve      bool
+	Actor        string
+}
+
+// Struct for an adjustment of the balance of an account, booked as a transfer between the account and the suspense account
+// TransactionID is the transfer booking the adjustment once it is applied
+type Adjustment struct {
+	ID                int64      `json:"adjustment_id" db:"id"`
+	AccountID         string     `json:"account_id" db:"account_id"`
+	SuspenseAccountID string     `json:"suspense_account_id" db:"suspense_account_id"`
+	Direction         string     `json:"direction" db:"direction"`
+	Amount            string     `json:"amount" db:"amount"`
+	ReasonCode        string     `json:"reason_code" db:"reason_code"`
+	Note              string     `json:"note,omitempty" db:"note"`
+	Status            string     `json:"status" db:"status"`
+	RequestedBy       string     `json:"requested_by" db:"requested_by"`
+	ReviewedBy        string     `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	TransactionID     int64      `json:"transaction_id,omitempty" db:"transaction_id"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	ReviewedAt        *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+}
+
+// Transaction will return the transfer booking the adjustment between the account and the suspense account
+// A credit is a transfer from the suspense account and a debit is a transfer to it, referenced by the id of the adjustment so that an adjustment is never booked twice
+// The function will return an error object if the metadata of the transfer cannot be encoded
+func (a Adjustment) Transaction() (Transaction, error) {
+	metadata, err := json.Marshal(map[string]any{"adjustment_id": a.ID, "reason_code": a.ReasonCode})
+	if err != nil {
+		return Transaction{}, err
+	}
+	transaction := Transaction{
+		SourceID:      a.SuspenseAccountID,
+		DestinationID: a.AccountID,
+		Amount:        a.Amount,
+		Description:   "Adjustment: " + a.ReasonCode,
+		Reference:     "adjustment-" + strconv.FormatInt(a.ID, 10),
+		Metadata:      metadata,
+		ClientID:      AdjustmentClientID,
+	}
+	if a.Direction == AdjustmentDebit {
+		transaction.SourceID, transaction.DestinationID = transaction.DestinationID, transaction.SourceID
+	}
+	return transaction, nil
+}
+
+// AdjustmentReview decides the outcome of the review of an adjustment pending approval from the adjustment as locked by AdjustmentRepository.ReviewAdjustment
+// It returns the adjustment with its new Status and ReviewedBy, or an error when the adjustment cannot be reviewed
+// It may be called several times for one review, and must decide the same for the same adjustment
+type AdjustmentReview func(adjustment Adjustment) (Adjustment, error)
@@ -0,0 +1,107 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditActionAccountCreate    = "account.create"
+	AuditActionAccountUpdate    = "account.update"
+	AuditActionTransfer         = "transfer.create"
+	AuditActionBalanceCorrect   = "balance.correct"
+	AuditActionAdjustment       = "adjustment.request"
+	AuditActionAdjustmentReview = "adjustment.review"
+	AuditActionTransferRequest  = "transfer.request"
+	AuditActionTransferReview   = "transfer.review"
+)
+
+// Types of the resources changed by the actions recorded in the audit log
+const (
+	AuditResourceAccount     = "account"
+	AuditResourceTransaction = "transaction"
+	AuditResourceAdjustment  = "adjustment"
+	AuditResourceApproval    = "transfer_approval"
+)
+
+// AuditActorSystem is the actor recorded for writes made outside of a request, which have no Principal
+const AuditActorSystem = "system"
+
+// Principal identifies who made a request and from where, to be recorded in the audit log with every write made for the request
+// Actor is the client as identified by middleware.ClientID, so that API keys are never stored
+// Authenticated reports whether Actor was identified by one of the configured API keys, rather than by a key nobody checked or by the client IP
+type Principal struct {
+	Actor         string
+	RequestID     string
+	ClientIP      string
+	Authenticated bool
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the Principal of the request
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the Principal carried by ctx, with the actor AuditActorSystem when there is none
+func PrincipalFrom(ctx context.Context) Principal {
+	principal, _ := ctx.Value(principalKey{}).(Principal)
+	if principal.Actor == "" {
+		principal.Actor = AuditActorSystem
+	}
+	return principal
+}
+
+// AuditEntry is a row of the append-only audit log, recording a write with the values of the resource before and after it
+// Before is null for a resource created by the write, Before and After are stored as nullable JSON columns and are read by the repository rather than mapped by db tag
+// Each entry is chained to the entry before it by PrevHash, the Hash of that entry, so that changing, removing or reordering entries breaks the chain, see ComputeHash
+type AuditEntry struct {
+	ID           int64           `json:"id" db:"id"`
+	CreatedAt    time.Time       `json:"created_at" db:"created_at"`
+	Actor        string          `json:"actor" db:"actor"`
+	Action       string          `json:"action" db:"action"`
+	ResourceType string          `json:"resource_type" db:"resource_type"`
+	ResourceID   string          `json:"resource_id" db:"resource_id"`
+	Before       json.RawMessage `json:"before" db:"-"`
+	After        json.RawMessage `json:"after" db:"-"`
+	RequestID    string          `json:"request_id" db:"request_id"`
+	ClientIP     string          `json:"client_ip" db:"client_ip"`
+	PrevHash     string          `json:"prev_hash" db:"prev_hash"`
+	Hash         string          `json:"hash" db:"hash"`
+}
+
+// ComputeHash will return the hex encoded SHA-256 hash of the entry: every field but ID and Hash, including PrevHash, encoded as JSON in a fixed order
+// The ID is left out as it is assigned by the database once the hash is computed, the order of the entries is kept by PrevHash instead
+func (e AuditEntry) ComputeHash() string {
+	content, _ := json.Marshal(struct {
+		PrevHash     string          `json:"prev_hash"`
+		CreatedAt    string          `json:"created_at"`
+		Actor        string          `json:"actor"`
+		Action       string          `json:"action"`
+		ResourceType string          `json:"resource_type"`
+		ResourceID   string          `json:"resource_id"`
+		Before       json.RawMessage `json:"before"`
+		After        json.RawMessage `json:"after"`
+		RequestID    string          `json:"request_id"`
+		ClientIP     string          `json:"client_ip"`
+	}{e.PrevHash, e.CreatedAt.UTC().Format(time.RFC3339Nano), e.Actor, e.Action, e.ResourceType, e.ResourceID, e.Before, e.After, e.RequestID, e.ClientIP})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditVerification is the result of checking the hash chain of the audit log
+// When the chain is broken, InvalidID is the first entry that does not match and Problem describes how
+// LastHash is the hash of the last entry checked, to be kept outside the database and given as the anchor of the next verification, so that entries removed from the end of the log are detected too
+type AuditVerification struct {
+	Valid     bool   `json:"valid"`
+	Entries   int64  `json:"entries"`
+	LastID    int64  `json:"last_id"`
+	LastHash  string `json:"last_hash"`
+	InvalidID int64  `json:"invalid_id,omitempty"`
+	Problem   string `json:"problem,omitempty"`
+}
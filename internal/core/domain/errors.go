@@ -0,0 +1,65 @@
+package domain
+
+// ErrorCode is a stable, machine-readable identifier for a failure returned to API clients
+type ErrorCode string
+
+const (
+	ErrCodeMalformedRequest     ErrorCode = "MALFORMED_REQUEST"
+	ErrCodeRequestTooLarge      ErrorCode = "REQUEST_TOO_LARGE"
+	ErrCodeInvalidAccountID     ErrorCode = "INVALID_ACCOUNT_ID"
+	ErrCodeInvalidAmount        ErrorCode = "INVALID_AMOUNT"
+	ErrCodeAccountNotFound      ErrorCode = "ACCOUNT_NOT_FOUND"
+	ErrCodeAccountAlreadyExists ErrorCode = "ACCOUNT_ALREADY_EXISTS"
+	ErrCodeSameAccountTransfer  ErrorCode = "SAME_ACCOUNT_TRANSFER"
+	ErrCodeInsufficientFunds    ErrorCode = "INSUFFICIENT_FUNDS"
+	ErrCodeInvalidCurrency      ErrorCode = "INVALID_CURRENCY"
+	ErrCodeCurrencyMismatch     ErrorCode = "CURRENCY_MISMATCH"
+	ErrCodeDuplicateReference   ErrorCode = "DUPLICATE_REFERENCE"
+	ErrCodeForbidden            ErrorCode = "FORBIDDEN"
+	ErrCodeAdjustmentNotFound   ErrorCode = "ADJUSTMENT_NOT_FOUND"
+	ErrCodeNotPendingApproval   ErrorCode = "NOT_PENDING_APPROVAL"
+	ErrCodeApprovalNotFound     ErrorCode = "APPROVAL_NOT_FOUND"
+	ErrCodeApprovalExpired      ErrorCode = "APPROVAL_EXPIRED"
+	ErrCodeRateLimited          ErrorCode = "RATE_LIMITED"
+	ErrCodeTimeout              ErrorCode = "REQUEST_TIMEOUT"
+	ErrCodeInternal             ErrorCode = "INTERNAL_ERROR"
+)
+
+// Error is a failure carrying an ErrorCode and a message safe to return to API clients
+// Err holds the underlying cause, if any, and is never returned to clients
+type Error struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+}
+
+// NewError returns an Error with the given code and client-facing message
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// WrapError returns an Error with the given code and client-facing message wrapping the underlying cause err
+func WrapError(code ErrorCode, message string, err error) *Error {
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Struct for RFC 7807 problem details error responses
+type Problem struct {
+	Type     string    `json:"type"`
+	Title    string    `json:"title"`
+	Status   int       `json:"status"`
+	Detail   string    `json:"detail"`
+	Instance string    `json:"instance,omitempty"`
+	Code     ErrorCode `json:"code"`
+}
@@ -0,0 +1,17 @@
+package domain
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+	HealthStatusDraining    = "draining"
+)
+
+// DependencyHealth only reports the status of a dependency, as /readyz is unauthenticated and the cause of a failure is logged instead
+type DependencyHealth struct {
+	Status string `json:"status"`
+}
+
+type Readiness struct {
+	Status string                      `json:"status"`
+	Checks map[string]DependencyHealth `json:"checks,omitempty"`
+}
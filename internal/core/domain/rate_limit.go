@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// Struct for token bucket rate limit settings
+// Rate is the number of tokens refilled per second and Burst is the bucket capacity
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled reports whether the limit should be enforced
+func (l RateLimit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Struct for the outcome of taking a token from a bucket
+type RateLimitResult struct {
+	Allowed    bool
+	RetryAfter time.Duration
+}
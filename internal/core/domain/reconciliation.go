@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTransfersInFlight is returned by AccountRepository.CorrectBalance when the account has transfers too recent to tell whether they are complete
+var ErrTransfersInFlight = errors.New("account has transfers in flight")
+
+// Struct for POST admin reconciliations
+type PostReconciliation struct {
+	Correct bool   `json:"correct"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Command for reconciling the balances of the accounts through ReconciliationService.Reconcile
+// Correct books the difference of every account found to differ as a transaction against a suspense account, so that its history matches its balance, Reason is required with it and recorded with each correction
+// Actor identifies who asked for the reconciliation and is recorded with each correction
+type ReconcileCommand struct {
+	Correct bool
+	Reason  string
+	Actor   string
+}
+
+// BalanceCheck is an account whose balance may differ from its initial balance plus its completed transfers, as found by AccountRepository.CheckBalances
+// InFlight is set when the account has transfers too recent to tell whether they are complete, its balance cannot be checked until they settle
+type BalanceCheck struct {
+	AccountID       string  `db:"id"`
+	Currency        string  `db:"currency"`
+	Balance         float64 `db:"balance"`
+	ExpectedBalance float64 `db:"expected_balance"`
+	InFlight        bool    `db:"in_flight"`
+}
+
+// ReconciliationClientID is the client id of the transactions booking balance corrections, see BalanceCorrection
+const ReconciliationClientID = "reconciliation"
+
+// BalanceCorrection is a completed transaction between an account and the suspense account of its currency booked by a reconciliation so that the history of the account matches its balance, kept as an audit trail
+// PreviousBalance is the balance computed from the history of the account before the correction, and CorrectedBalance after it, which is the balance of the account
+// The balance of the account is left as it is, the suspense account takes the opposite of the difference
+type BalanceCorrection struct {
+	ID                int64     `db:"id"`
+	AccountID         string    `db:"account_id"`
+	SuspenseAccountID string    `db:"suspense_account_id"`
+	TransactionID     int64     `db:"transaction_id"`
+	PreviousBalance   float64   `db:"previous_balance"`
+	CorrectedBalance  float64   `db:"corrected_balance"`
+	Actor             string    `db:"actor"`
+	Reason            string    `db:"reason"`
+	CreatedAt         time.Time `db:"created_at"`
+}
+
+// Discrepancy is an account whose balance differs from the balance computed from its history
+// Difference is Balance less ExpectedBalance, CorrectionID and TransactionID are the ids of the BalanceCorrection and of its transaction when the difference was booked
+type Discrepancy struct {
+	AccountID       string `json:"account_id"`
+	Currency        string `json:"currency"`
+	Balance         string `json:"balance"`
+	ExpectedBalance string `json:"expected_balance"`
+	Difference      string `json:"difference"`
+	Corrected       bool   `json:"corrected"`
+	CorrectionID    int64  `json:"correction_id,omitempty"`
+	TransactionID   int64  `json:"transaction_id,omitempty"`
+}
+
+// Struct for the result of a reconciliation
+// Skipped are the accounts with transfers in flight, which are checked again by a later reconciliation
+type Reconciliation struct {
+	CheckedAt     time.Time     `json:"checked_at"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+	Skipped       []string      `json:"skipped"`
+}
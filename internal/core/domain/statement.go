@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Formats of TransactionService.Statement
+const (
+	StatementFormatJSON = "json"
+	StatementFormatCSV  = "csv"
+	StatementFormatText = "text"
+)
+
+// StatementFormats are the formats a statement can be exported in
+var StatementFormats = []string{StatementFormatJSON, StatementFormatCSV, StatementFormatText}
+
+// Query for the statement of an account through TransactionService.Statement
+// From of nil means the creation of the account and To of nil means now, the transfers completed after From up to and including To are listed
+type StatementQuery struct {
+	AccountID string
+	From      *time.Time
+	To        *time.Time
+}
+
+// Struct for GET account statement
+// Lines are written one at a time as they are read, ClosingBalance is OpeningBalance plus the Amount of every line
+type Statement struct {
+	AccountID      string          `json:"account_id"`
+	Currency       string          `json:"currency"`
+	From           time.Time       `json:"from"`
+	To             time.Time       `json:"to"`
+	OpeningBalance string          `json:"opening_balance"`
+	Lines          []StatementLine `json:"lines"`
+	ClosingBalance string          `json:"closing_balance"`
+}
+
+// StatementLine is a completed transfer of a Statement
+// Amount is negative when money left the account, Balance is the balance of the account after the transfer
+// CompletedAt is the time the transfer moved the balances, which orders the lines and places them in the period of the statement
+type StatementLine struct {
+	TransactionID  int64     `json:"transaction_id"`
+	CompletedAt    time.Time `json:"completed_at"`
+	CounterpartyID string    `json:"counterparty_account_id"`
+	Description    string    `json:"description,omitempty"`
+	Reference      string    `json:"reference,omitempty"`
+	Amount         string    `json:"amount"`
+	Balance        string    `json:"balance"`
+}
@@ -1,8 +1,70 @@
 package domain
 
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Statuses of a transaction
+// A transaction is recorded pending before the accounts are locked, and is set to completed in the same DB transaction as the balances it moves, or to failed
+const (
+	TransactionStatusPending         = "pending"
+	TransactionStatusCompleted       = "completed"
+	TransactionStatusFailed          = "failed"
+	TransactionStatusPendingApproval = "pending_approval"
+)
+
+// ErrReferenceExists is returned by TransactionRepository.ProcessTransaction when the client already has a transfer with the same Reference that did not fail
+// and by TransferApprovalRepository.InsertTransferApproval when the client already has a transfer with the same Reference pending approval
+var ErrReferenceExists = errors.New("reference already used by a transfer of the client")
+
 // Struct for POST transaction
+// ClientID identifies the API client that made the transfer, References are unique per client, and is never read from or written to JSON
+// Metadata is stored as a nullable JSONB column and is read by the repository rather than mapped by db tag
+// ApprovalID is the TransferApproval holding a transfer with the status pending_approval, which has no ID until it is approved
+// CompletedAt is only read for statements, which list transfers by the time they completed
 type Transaction struct {
-	SourceID      string `json:"source_account_id"`
-	DestinationID string `json:"destination_account_id"`
-	Amount        string `json:"amount"`
+	ID            int64           `json:"transaction_id,omitempty" db:"id"`
+	SourceID      string          `json:"source_account_id" db:"source_account_id"`
+	DestinationID string          `json:"destination_account_id" db:"destination_account_id"`
+	Amount        string          `json:"amount" db:"amount"`
+	Description   string          `json:"description,omitempty" db:"description"`
+	Reference     string          `json:"reference,omitempty" db:"reference"`
+	Metadata      json.RawMessage `json:"metadata,omitempty" db:"-"`
+	ClientID      string          `json:"-" db:"-"`
+	Status        string          `json:"status,omitempty" db:"status"`
+	ApprovalID    int64           `json:"approval_id,omitempty" db:"-"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// Command for moving money between accounts through TransactionService.Transfer
+// Description, Reference and Metadata are optional, Metadata must be a JSON object when set
+type TransferCommand struct {
+	SourceID      string
+	DestinationID string
+	Amount        string
+	Description   string
+	Reference     string
+	Metadata      json.RawMessage
+	ClientID      string
 }
+
+// Query for listing the transactions of an account through TransactionService.ListTransactions
+// Limit of 0 means the default page size, BeforeID of 0 means start from the newest transaction
+type ListTransactionsQuery struct {
+	AccountID string
+	Limit     int
+	BeforeID  int64
+}
+
+// Struct for GET transactions
+type TransactionList struct {
+	Transactions []Transaction `json:"transactions"`
+	NextBeforeID int64         `json:"next_before_id,omitempty"`
+}
+
+// TransferDecision computes the new balances of the source and destination accounts of a transfer from their current balances
+// It returns an error when the transfer must not go ahead, such as when the source account has insufficient funds
+type TransferDecision func(source Account, destination Account) (sourceBalance float64, destinationBalance float64, err error)
@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Statuses of a transfer approval
+// A transfer above the approval threshold is pending_approval until it is reviewed or expires
+// An approved transfer is recorded as completed in the DB transaction executing it, or as failed, so approved is only the outcome of a review and is never stored
+const (
+	TransferApprovalStatusPending   = "pending_approval"
+	TransferApprovalStatusApproved  = "approved"
+	TransferApprovalStatusRejected  = "rejected"
+	TransferApprovalStatusExpired   = "expired"
+	TransferApprovalStatusCompleted = "completed"
+	TransferApprovalStatusFailed    = "failed"
+)
+
+// TransferApprovalPolicy configures the maker-checker approval of large transfers made through TransactionService.Transfer
+// A transfer of more than Threshold is only executed once approved by an actor other than the one who requested it, a Threshold of 0 disables approvals
+// A transfer not reviewed within Expiry of its request expires and can no longer be approved
+type TransferApprovalPolicy struct {
+	Threshold float64
+	Expiry    time.Duration
+}
+
+// Command for approving or rejecting a transfer pending approval through TransactionService.ReviewTransfer
+// Actor identifies who reviewed the transfer and must not be the actor who requested it
+type ReviewTransferCommand struct {
+	ApprovalID int64
+	Approve    bool
+	Actor      string
+}
+
+// Struct for a transfer held for approval, executed as a transfer of the client ClientID once approved
+// InitiatedBy is the actor who requested the transfer, ClientID the client its Reference is unique for, see Transaction, and is never written to JSON
+// Metadata is stored as a nullable JSONB column and is read by the repository rather than mapped by db tag
+// TransactionID is the executed transfer once the approval is completed
+type TransferApproval struct {
+	ID            int64           `json:"approval_id" db:"id"`
+	SourceID      string          `json:"source_account_id" db:"source_account_id"`
+	DestinationID string          `json:"destination_account_id" db:"destination_account_id"`
+	Amount        string          `json:"amount" db:"amount"`
+	Description   string          `json:"description,omitempty" db:"description"`
+	Reference     string          `json:"reference,omitempty" db:"reference"`
+	Metadata      json.RawMessage `json:"metadata,omitempty" db:"-"`
+	ClientID      string          `json:"-" db:"client_id"`
+	Status        string          `json:"status" db:"status"`
+	InitiatedBy   string          `json:"initiated_by" db:"initiated_by"`
+	ReviewedBy    string          `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	TransactionID int64           `json:"transaction_id,omitempty" db:"transaction_id"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	ExpiresAt     time.Time       `json:"expires_at" db:"expires_at"`
+	ReviewedAt    *time.Time      `json:"reviewed_at,omitempty" db:"reviewed_at"`
+}
+
+// Transaction will return the transfer to execute once the approval is approved
+func (a TransferApproval) Transaction() Transaction {
+	return Transaction{
+		SourceID:      a.SourceID,
+		DestinationID: a.DestinationID,
+		Amount:        a.Amount,
+		Description:   a.Description,
+		Reference:     a.Reference,
+		Metadata:      a.Metadata,
+		ClientID:      a.ClientID,
+	}
+}
+
+// TransferApprovalReview decides the outcome of the review of a transfer pending approval from the approval as locked by TransferApprovalRepository.ReviewTransferApproval
+// It returns the approval with its new Status and ReviewedBy, or an error when the transfer cannot be reviewed
+// It may be called several times for one review, and must decide the same for the same approval
+type TransferApprovalReview func(approval TransferApproval) (TransferApproval, error)
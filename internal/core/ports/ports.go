@@ -3,14 +3,98 @@ package ports
 import (
 	"account-test/internal/core/domain"
 	"context"
+	"time"
 )
 
+type AccountService interface {
+	CreateAccount(ctx context.Context, cmd domain.CreateAccountCommand) (domain.Account, error)
+	GetAccount(ctx context.Context, id string) (domain.Account, error)
+	ListAccounts(ctx context.Context, query domain.ListAccountsQuery) (domain.AccountList, error)
+	UpdateAccount(ctx context.Context, cmd domain.UpdateAccountCommand) (domain.Account, error)
+	GetBalance(ctx context.Context, id string, asOf *time.Time) (domain.Balance, error)
+}
+
+type TransactionService interface {
+	Transfer(ctx context.Context, cmd domain.TransferCommand) (domain.Transaction, error)
+	ListTransactions(ctx context.Context, query domain.ListTransactionsQuery) (domain.TransactionList, error)
+	Statement(ctx context.Context, query domain.StatementQuery, w StatementWriter) error
+	ReviewTransfer(ctx context.Context, cmd domain.ReviewTransferCommand) (domain.TransferApproval, error)
+}
+
+// StatementWriter writes a statement as it is read, the header once, every line in order and the footer once
+// The Lines of the statement are never set, ClosingBalance is only set for WriteFooter
+type StatementWriter interface {
+	WriteHeader(statement domain.Statement) error
+	WriteLine(line domain.StatementLine) error
+	WriteFooter(statement domain.Statement) error
+}
+
+type ReconciliationService interface {
+	Reconcile(ctx context.Context, cmd domain.ReconcileCommand) (domain.Reconciliation, error)
+}
+
+type AdjustmentService interface {
+	Adjust(ctx context.Context, cmd domain.AdjustmentCommand) (domain.Adjustment, error)
+	ReviewAdjustment(ctx context.Context, cmd domain.ReviewAdjustmentCommand) (domain.Adjustment, error)
+}
+
+type AuditService interface {
+	VerifyAuditLog(ctx context.Context, anchor string) (domain.AuditVerification, error)
+}
+
 type AccountRepository interface {
-	InsertAccount(ctx context.Context, id string, balance float64) error
+	InsertAccount(ctx context.Context, id string, balance float64, currency string, details domain.AccountDetails) error
+	UpdateAccount(ctx context.Context, cmd domain.UpdateAccountCommand) (*domain.Account, error)
 	GetAccount(ctx context.Context, id string) (*domain.Account, error)
 	CheckAccountExists(ctx context.Context, id string) bool
+	ListAccounts(ctx context.Context, query domain.ListAccountsQuery, after *domain.AccountCursor, limit int) ([]domain.Account, error)
+	GetBalanceAsOf(ctx context.Context, id string, asOf time.Time) (*domain.HistoricalBalance, error)
+	SnapshotBalances(ctx context.Context, asOf time.Time) (int64, error)
+	CheckBalances(ctx context.Context, settledBefore time.Time) ([]domain.BalanceCheck, error)
+	CorrectBalance(ctx context.Context, id string, suspenseID string, settledBefore time.Time, actor string, reason string) (*domain.BalanceCorrection, error)
 }
 
 type TransactionRepository interface {
-	ProcessTransaction(ctx context.Context, transaction domain.Transaction, source_amount float64, destination_amount float64) error
+	ProcessTransaction(ctx context.Context, transaction domain.Transaction, decide domain.TransferDecision) (int64, error)
+	ListTransactions(ctx context.Context, accountID string, beforeID int64, limit int) ([]domain.Transaction, error)
+	StreamTransactions(ctx context.Context, accountID string, from time.Time, to time.Time, each func(domain.Transaction) error) error
+}
+
+type AdjustmentRepository interface {
+	InsertAdjustment(ctx context.Context, adjustment domain.Adjustment, decide domain.TransferDecision) (domain.Adjustment, error)
+	ReviewAdjustment(ctx context.Context, accountID string, id int64, review domain.AdjustmentReview, decide domain.TransferDecision) (domain.Adjustment, error)
+}
+
+type TransferApprovalRepository interface {
+	InsertTransferApproval(ctx context.Context, approval domain.TransferApproval, expiry time.Duration) (domain.TransferApproval, error)
+	ReviewTransferApproval(ctx context.Context, id int64, review domain.TransferApprovalReview, decide domain.TransferDecision) (domain.TransferApproval, error)
+}
+
+type AuditRepository interface {
+	StreamAuditLog(ctx context.Context, each func(domain.AuditEntry) error) error
+}
+
+type BalanceSnapshotter interface {
+	SnapshotBalances(ctx context.Context, asOf time.Time) (int64, error)
+}
+
+type RateLimitRepository interface {
+	Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitResult, error)
+}
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	CheckMigrations(ctx context.Context) error
+}
+
+type AccountIDPolicy interface {
+	Generated() bool
+	Generate() (string, error)
+	Validate(id string) error
+	ValidateNew(id string) error
+}
+
+type TransferMetrics interface {
+	TransferCompleted(amount float64)
+	TransferFailed(reason domain.ErrorCode)
 }
@@ -6,107 +6,366 @@ import (
 	"account-test/internal/core/utils"
 	"account-test/static"
 	"context"
+	"database/sql"
+	"encoding/base64"
 	"encoding/json"
-	"io"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math"
-	"net/http"
+	"regexp"
+	"slices"
 	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultAccountListLimit = 50
+	maxAccountListLimit     = 100
+	// maxIDPrefixLength bounds the id_prefix filter, which is longer than any account ID format
+	maxIDPrefixLength = 64
+)
 
-	"github.com/go-chi/chi"
+// Limits on the details of an account, see validateAccountDetails
+const (
+	maxDisplayNameLength   = 100
+	maxMetadataKeys        = 32
+	maxMetadataKeyLength   = 64
+	maxMetadataValueLength = 512
+	maxLabels              = 16
 )
 
+// currencyPattern matches ISO 4217 alphabetic currency codes
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// labelPattern matches account labels, lower case so that filtering by label does not depend on how it was typed
+var labelPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.:-]{0,62}$`)
+
 type AccountSvcImpl struct {
 	accountRepo ports.AccountRepository
+	ids         ports.AccountIDPolicy
 }
 
-func NewAccountSvc(accountRepo ports.AccountRepository) *AccountSvcImpl {
+func NewAccountSvc(accountRepo ports.AccountRepository, ids ports.AccountIDPolicy) *AccountSvcImpl {
 	return &AccountSvcImpl{
 		accountRepo: accountRepo,
+		ids:         ids,
 	}
 }
 
-// PostAccount will accept a HTTP body containing a domain.PostAccount object
-// The function will check if the inputs from domain.PostAccount object are valid inputs
-// The function will check if the id from domain.PostAccount belongs to an existing account
+// CreateAccount will accept a domain.CreateAccountCommand
+// The function will check if the inputs from domain.CreateAccountCommand are valid inputs
+// When the ports.AccountIDPolicy generates IDs, the function will reject a command with an id and generate one instead
+// Otherwise the function will check if the id from domain.CreateAccountCommand is of the current format and belongs to an existing account
+// The function will default the currency to domain.DefaultCurrency when it is not set
+// The function will check the display name, metadata and labels and remove duplicate labels
 // The function will fix the balance value to a floating point precision of 5
-// The function will create the account with the payload from domain.PostAccount in the account table if all checks are valid
-// The function will return HTTP status OK and no body if the creation is successful
-func (srv *AccountSvcImpl) PostAccount(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	postAccountBody := domain.PostAccount{}
-	body, err := io.ReadAll(r.Body)
+// The function will create the account in the account table if all checks are valid
+// The function will return the created account as a domain.Account object and a *domain.Error if any check fails
+func (srv *AccountSvcImpl) CreateAccount(ctx context.Context, cmd domain.CreateAccountCommand) (domain.Account, error) {
+	if srv.ids.Generated() {
+		if cmd.ID != "" {
+			return domain.Account{}, domain.NewError(domain.ErrCodeInvalidAccountID, static.ErrIDGeneratedByServer)
+		}
+	} else {
+		if err := srv.ids.ValidateNew(cmd.ID); err != nil {
+			return domain.Account{}, err
+		}
+		accountAlreadyExists := srv.accountRepo.CheckAccountExists(ctx, cmd.ID)
+		if accountAlreadyExists {
+			return domain.Account{}, domain.NewError(domain.ErrCodeAccountAlreadyExists, static.ErrAccountAlreadyExist)
+		}
+	}
+	accountBalance, err := strconv.ParseFloat(cmd.InitialBalance, 64)
 	if err != nil {
-		http.Error(w, static.ErrUnableToReadBody, http.StatusBadRequest)
-		return
+		return domain.Account{}, domain.NewError(domain.ErrCodeInvalidAmount, static.ErrBalanceNotValidNumber)
+	}
+	if accountBalance < 0 {
+		return domain.Account{}, domain.NewError(domain.ErrCodeInvalidAmount, static.ErrBalanceCannotBeNegative)
+	}
+	if accountBalance > math.MaxFloat64 {
+		return domain.Account{}, domain.NewError(domain.ErrCodeInvalidAmount, static.ErrBalanceTooLarge)
 	}
-	err = json.Unmarshal(body, &postAccountBody)
+	accountBalance = utils.ToFixed(accountBalance, 5)
+	currency := cmd.Currency
+	if currency == "" {
+		currency = domain.DefaultCurrency
+	}
+	if !currencyPattern.MatchString(currency) {
+		return domain.Account{}, domain.NewError(domain.ErrCodeInvalidCurrency, static.ErrInvalidCurrency)
+	}
+	details := cmd.Details
+	if details.Labels, err = validateAccountDetails(&details.DisplayName, details.Metadata, details.Labels); err != nil {
+		return domain.Account{}, err
+	}
+
+	if srv.ids.Generated() {
+		// Generated IDs are random, a collision is left to the primary key of the account table
+		if cmd.ID, err = srv.ids.Generate(); err != nil {
+			return domain.Account{}, domain.WrapError(domain.ErrCodeInternal, static.ErrGeneratingAccountID, err)
+		}
+	}
+
+	err = srv.accountRepo.InsertAccount(ctx, cmd.ID, accountBalance, currency, details)
 	if err != nil {
-		http.Error(w, static.ErrUnableToReadBody, http.StatusBadRequest)
-		return
+		return domain.Account{}, domain.WrapError(domain.ErrCodeInternal, static.ErrCreatingAccount, err)
 	}
-	if len(postAccountBody.ID) == 0 {
-		http.Error(w, static.ErrIDLengthCannotBeZero, http.StatusBadRequest)
-		return
+	slog.InfoContext(ctx, "account created", "account_id", cmd.ID, "currency", currency)
+	return domain.Account{
+		ID:          cmd.ID,
+		Balance:     utils.FormatAmount(accountBalance),
+		Currency:    currency,
+		Status:      domain.AccountStatusActive,
+		DisplayName: details.DisplayName,
+		Metadata:    details.Metadata,
+		Labels:      details.Labels,
+	}, nil
+}
+
+// UpdateAccount will accept a domain.UpdateAccountCommand
+// The function will check if the id is a valid input and belongs to an existing account
+// The function will check the display name, metadata and labels that are set in the command, remove duplicate labels and leave the others unchanged
+// The function will return the updated account as a domain.Account object and a *domain.Error if any check fails
+func (srv *AccountSvcImpl) UpdateAccount(ctx context.Context, cmd domain.UpdateAccountCommand) (domain.Account, error) {
+	if err := srv.ids.Validate(cmd.ID); err != nil {
+		return domain.Account{}, err
 	}
-	if len(postAccountBody.ID) > 32 {
-		http.Error(w, static.ErrIDLengthTooLong, http.StatusBadRequest)
-		return
+	var err error
+	if cmd.Labels, err = validateAccountDetails(cmd.DisplayName, cmd.Metadata, cmd.Labels); err != nil {
+		return domain.Account{}, err
 	}
-	accountAlreadyExists := srv.accountRepo.CheckAccountExists(ctx, postAccountBody.ID)
-	if accountAlreadyExists {
-		http.Error(w, static.ErrAccountAlreadyExist, http.StatusBadRequest)
-		return
+	accountExists := srv.accountRepo.CheckAccountExists(ctx, cmd.ID)
+	if !accountExists {
+		return domain.Account{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist)
 	}
-	accountBalance, err := strconv.ParseFloat(postAccountBody.Balance, 64)
+	account, err := srv.accountRepo.UpdateAccount(ctx, cmd)
 	if err != nil {
-		http.Error(w, static.ErrBalanceNotValidNumber, http.StatusBadRequest)
-		return
+		return domain.Account{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToUpdateAccount, err)
 	}
-	if accountBalance < 0 {
-		http.Error(w, static.ErrBalanceCannotBeNegative, http.StatusBadRequest)
-		return
+	slog.InfoContext(ctx, "account updated", "account_id", cmd.ID)
+	return *account, nil
+}
+
+// GetAccount will accept an account id
+// the function will check if id is a valid input
+// the function will check if the id belongs to an existing account in the system
+// the function will then retrieve all the account details associated with the id, returned as a domain.Account object
+func (srv *AccountSvcImpl) GetAccount(ctx context.Context, id string) (domain.Account, error) {
+	if err := srv.ids.Validate(id); err != nil {
+		return domain.Account{}, err
 	}
-	if accountBalance > math.MaxFloat64 {
-		http.Error(w, static.ErrBalanceTooLarge, http.StatusBadRequest)
-		return
+	accountAlreadyExists := srv.accountRepo.CheckAccountExists(ctx, id)
+	if !accountAlreadyExists {
+		return domain.Account{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist)
+	}
+	account, err := srv.accountRepo.GetAccount(ctx, id)
+	if err != nil {
+		return domain.Account{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToRetrieveAccount, err)
 	}
+	return *account, nil
+}
 
-	err = srv.accountRepo.InsertAccount(ctx, postAccountBody.ID, utils.ToFixed(accountBalance, 5))
+// GetBalance will accept an account id and the point in time to return the balance at, nil for now
+// The function will check if id is a valid input and asOf is not in the future
+// The function will compute the balance of the account at asOf from its completed transfers, see AccountRepository.GetBalanceAsOf
+// The function will fix the balance value to a floating point precision of 5
+// The function will return the balance as a domain.Balance object and a *domain.Error if the account does not exist or did not exist yet at asOf
+func (srv *AccountSvcImpl) GetBalance(ctx context.Context, id string, asOf *time.Time) (domain.Balance, error) {
+	if err := srv.ids.Validate(id); err != nil {
+		return domain.Balance{}, err
+	}
+	now := time.Now()
+	at := now
+	if asOf != nil {
+		if asOf.After(now) {
+			return domain.Balance{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrAsOfInFuture)
+		}
+		at = *asOf
+	}
+	balance, err := srv.accountRepo.GetBalanceAsOf(ctx, id, at)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Balance{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist)
+	}
 	if err != nil {
-		log.Println("InsertAccount error - ", err.Error())
-		http.Error(w, static.ErrCreatingAccount, http.StatusInternalServerError)
-		return
+		return domain.Balance{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToRetrieveBalance, err)
+	}
+	if at.Before(balance.CreatedAt) {
+		return domain.Balance{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountNotCreatedAsOf)
 	}
-	utils.JSONResponse(w, http.StatusOK, nil)
+	return domain.Balance{
+		AccountID: id,
+		Balance:   utils.FormatAmount(utils.ToFixed(balance.Balance, 5)),
+		Currency:  balance.Currency,
+		AsOf:      at.UTC(),
+	}, nil
 }
 
-// GetAccount will accept a HTTP path parameter of account_id
-// the function will check if account_id is a valid input
-// the function will check if the account_id belongs to an existing account in the system
-// the function will then retrieve all the account details associated with the account_id, returned as a domain.Account object
-func (srv *AccountSvcImpl) GetAccount(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	accountId := chi.URLParam(r, "account_id")
-	if len(accountId) == 0 {
-		http.Error(w, static.ErrIDLengthCannotBeZero, http.StatusBadRequest)
-		return
-	}
-	if len(accountId) > 32 {
-		http.Error(w, static.ErrIDLengthTooLong, http.StatusBadRequest)
-		return
-	}
-	accountAlreadyExists := srv.accountRepo.CheckAccountExists(ctx, accountId)
-	if !accountAlreadyExists {
-		http.Error(w, static.ErrAccountDoesNotExist, http.StatusBadRequest)
-		return
+// SnapshotBalances will accept a point in time to snapshot the balance of every account changed since its latest snapshot, see AccountRepository.SnapshotBalances
+// The function will return the number of snapshots taken and a *domain.Error if the snapshots cannot be taken
+func (srv *AccountSvcImpl) SnapshotBalances(ctx context.Context, asOf time.Time) (int64, error) {
+	taken, err := srv.accountRepo.SnapshotBalances(ctx, asOf)
+	if err != nil {
+		return 0, domain.WrapError(domain.ErrCodeInternal, static.ErrSnapshottingBalances, err)
+	}
+	slog.InfoContext(ctx, "balance snapshots taken", "as_of", asOf, "accounts", taken)
+	return taken, nil
+}
+
+// ListAccounts will accept a domain.ListAccountsQuery
+// The function will check that the filters, sort and cursor of the query are valid
+// The function will default the page size when Limit is not set and cap it to maxAccountListLimit
+// The function will return a page of the accounts matching the filters in the requested order, with the cursor to request the next page
+func (srv *AccountSvcImpl) ListAccounts(ctx context.Context, query domain.ListAccountsQuery) (domain.AccountList, error) {
+	if query.Sort == "" {
+		query.Sort = domain.AccountSortID
+	}
+	if err := validateListAccountsQuery(query); err != nil {
+		return domain.AccountList{}, err
+	}
+	var after *domain.AccountCursor
+	if query.Cursor != "" {
+		cursor, err := decodeAccountCursor(query.Sort, query.Cursor)
+		if err != nil {
+			return domain.AccountList{}, domain.WrapError(domain.ErrCodeMalformedRequest, static.ErrInvalidCursor, err)
+		}
+		after = &cursor
 	}
-	account, err := srv.accountRepo.GetAccount(ctx, accountId)
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultAccountListLimit
+	}
+	if limit > maxAccountListLimit {
+		limit = maxAccountListLimit
+	}
+
+	// One extra row is fetched to tell whether there is a next page
+	accounts, err := srv.accountRepo.ListAccounts(ctx, query, after, limit+1)
 	if err != nil {
-		log.Println("GetAccount error - ", err.Error())
-		http.Error(w, static.ErrUnableToRetrieveAccount, http.StatusInternalServerError)
-		return
+		return domain.AccountList{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToListAccounts, err)
+	}
+	response := domain.AccountList{Accounts: accounts}
+	if len(accounts) > limit {
+		response.Accounts = accounts[:limit]
+		response.NextCursor = encodeAccountCursor(query.Sort, accounts[limit-1])
+	}
+	return response, nil
+}
+
+// validateListAccountsQuery will check the filters and sort of a domain.ListAccountsQuery
+func validateListAccountsQuery(query domain.ListAccountsQuery) error {
+	if query.Status != "" && !slices.Contains(domain.AccountStatuses, query.Status) {
+		return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAccountStatus)
+	}
+	if query.Currency != "" && !currencyPattern.MatchString(query.Currency) {
+		return domain.NewError(domain.ErrCodeInvalidCurrency, static.ErrInvalidCurrency)
+	}
+	if len(query.IDPrefix) > maxIDPrefixLength {
+		return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrIDPrefixTooLong)
+	}
+	if _, err := validateAccountDetails(&query.DisplayName, query.Metadata, query.Labels); err != nil {
+		return err
 	}
-	utils.JSONResponse(w, http.StatusOK, account)
+	if query.MinBalance != nil && query.MaxBalance != nil && *query.MinBalance > *query.MaxBalance {
+		return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidBalanceRange)
+	}
+	if query.CreatedFrom != nil && query.CreatedTo != nil && !query.CreatedFrom.Before(*query.CreatedTo) {
+		return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidCreatedRange)
+	}
+	if !slices.Contains(domain.AccountSorts, query.Sort) {
+		return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAccountSort)
+	}
+	return nil
+}
+
+// accountCursor is the JSON encoded in the opaque cursor returned by ListAccounts
+// Sort is kept so that a cursor cannot be used with a different sort than the page it came from
+type accountCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// encodeAccountCursor will return the cursor continuing a listing in sort order after account
+func encodeAccountCursor(sort string, account domain.Account) string {
+	cursor := accountCursor{Sort: sort, ID: account.ID}
+	switch sort {
+	case domain.AccountSortCreatedAt, domain.AccountSortCreatedAtDesc:
+		cursor.Value = account.CreatedAt.Format(time.RFC3339Nano)
+	case domain.AccountSortBalance, domain.AccountSortBalanceDesc:
+		cursor.Value = account.Balance
+	default:
+		cursor.Value = account.ID
+	}
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
 
+// decodeAccountCursor will decode a cursor returned by encodeAccountCursor for a listing in sort order
+// The function will return an error if the cursor cannot be decoded, was returned for a different sort or holds a value of the wrong type for the sort
+func decodeAccountCursor(sort string, encoded string) (domain.AccountCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return domain.AccountCursor{}, err
+	}
+	var cursor accountCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return domain.AccountCursor{}, err
+	}
+	if cursor.Sort != sort {
+		return domain.AccountCursor{}, fmt.Errorf("cursor was returned for sort %q", cursor.Sort)
+	}
+	switch sort {
+	case domain.AccountSortCreatedAt, domain.AccountSortCreatedAtDesc:
+		_, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	case domain.AccountSortBalance, domain.AccountSortBalanceDesc:
+		_, err = strconv.ParseFloat(cursor.Value, 64)
+	}
+	if err != nil {
+		return domain.AccountCursor{}, err
+	}
+	return domain.AccountCursor{Value: cursor.Value, ID: cursor.ID}, nil
+}
+
+// validateAccountDetails will check the display name, metadata and labels of an account, skipping any that is nil
+// The function will return the labels sorted without duplicates and a *domain.Error if any is not valid
+func validateAccountDetails(displayName *string, metadata map[string]string, labels []string) ([]string, error) {
+	if displayName != nil && utf8.RuneCountInString(*displayName) > maxDisplayNameLength {
+		return nil, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrDisplayNameTooLong)
+	}
+	if len(metadata) > maxMetadataKeys {
+		return nil, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrTooManyMetadataKeys)
+	}
+	for key, value := range metadata {
+		if keyLength := utf8.RuneCountInString(key); keyLength == 0 || keyLength > maxMetadataKeyLength {
+			return nil, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidMetadataKey)
+		}
+		if utf8.RuneCountInString(value) > maxMetadataValueLength {
+			return nil, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrMetadataValueTooLong)
+		}
+	}
+	if labels == nil {
+		return nil, nil
+	}
+	unique := slices.Clone(labels)
+	slices.Sort(unique)
+	unique = slices.Compact(unique)
+	if len(unique) > maxLabels {
+		return nil, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrTooManyLabels)
+	}
+	for _, label := range unique {
+		if !labelPattern.MatchString(label) {
+			return nil, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidLabel)
+		}
+	}
+	return unique, nil
+}
+
+// errorCode will return the code of a *domain.Error, or INTERNAL_ERROR for any other error
+func errorCode(err error) domain.ErrorCode {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return domainErr.Code
+	}
+	return domain.ErrCodeInternal
 }
@@ -1,45 +1,47 @@
 package services
 
 import (
+	"account-test/internal/accountid"
 	"account-test/internal/core/domain"
 	mock_ports "account-test/internal/mocks/ports"
 	"account-test/static"
-	"bytes"
 	"context"
-	"encoding/json"
+	"database/sql"
 	"errors"
-	"io"
-	"net/http"
-	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/go-chi/chi"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
+// newTextIDPolicy will return the default policy of client supplied, free text account IDs
+func newTextIDPolicy(t *testing.T) *accountid.Policy {
+	policy, err := accountid.New(&accountid.AccountIDConfig{Mode: accountid.ModeClient, Format: accountid.FormatText})
+	assert.NoError(t, err)
+	return policy
+}
+
 func TestGetAccount(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	tests := []struct {
 		name       string
-		rec        *httptest.ResponseRecorder
-		req        *http.Request
 		account_id string
 		doMockRepo func(repository *mock_ports.MockAccountRepository)
 		want       domain.Account
 		err        string
-		statusCode int
+		code       domain.ErrorCode
 	}{
 		{
 			name:       "Test Case Positive",
-			rec:        httptest.NewRecorder(),
-			req:        httptest.NewRequest("GET", "/accounts/{account_id}", nil),
 			account_id: "123",
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
-				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(
 					&domain.Account{ID: "123", Balance: "123"},
 					nil,
 				)
@@ -49,42 +51,31 @@ func TestGetAccount(t *testing.T) {
 		},
 		{
 			name:       "Test Case Negative - Empty account passed as parameter",
-			rec:        httptest.NewRecorder(),
-			req:        httptest.NewRequest("GET", "/accounts/{account_id}", nil),
 			account_id: "",
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
-			want:       domain.Account{},
-			err:        static.ErrIDLengthCannotBeZero,
-			statusCode: 400,
+			err:  static.ErrIDLengthCannotBeZero,
+			code: domain.ErrCodeInvalidAccountID,
 		},
 		{
 			name:       "Test Case Negative - Account parameter longer than 32 char",
-			rec:        httptest.NewRecorder(),
-			req:        httptest.NewRequest("GET", "/accounts/{account_id}", nil),
 			account_id: "99999999999999999999999999999999999999999999999999999999999999999999",
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
-			want:       domain.Account{},
-			err:        static.ErrIDLengthTooLong,
-			statusCode: 400,
+			err:  static.ErrIDLengthTooLong,
+			code: domain.ErrCodeInvalidAccountID,
 		},
 		{
 			name:       "Test Case Negative - Account does not exist",
-			rec:        httptest.NewRecorder(),
-			req:        httptest.NewRequest("GET", "/accounts/{account_id}", nil),
 			account_id: "123",
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(false)
 			},
-			want:       domain.Account{},
-			err:        static.ErrAccountDoesNotExist,
-			statusCode: 400,
+			err:  static.ErrAccountDoesNotExist,
+			code: domain.ErrCodeAccountNotFound,
 		},
 		{
 			name:       "Test Case Negative - Repository error",
-			rec:        httptest.NewRecorder(),
-			req:        httptest.NewRequest("GET", "/accounts/{account_id}", nil),
 			account_id: "123",
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
@@ -93,161 +84,546 @@ func TestGetAccount(t *testing.T) {
 					errors.New("random error"),
 				)
 			},
-			want:       domain.Account{},
-			err:        static.ErrUnableToRetrieveAccount,
-			statusCode: 500,
+			err:  static.ErrUnableToRetrieveAccount,
+			code: domain.ErrCodeInternal,
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
 			tc.doMockRepo(mockAccRepo)
-			accSvc := NewAccountSvc(mockAccRepo)
-			handler := http.HandlerFunc(accSvc.GetAccount)
-			rctx := chi.NewRouteContext()
-			rctx.URLParams.Add("account_id", tc.account_id)
-
-			r := tc.req.WithContext(context.WithValue(tc.req.Context(), chi.RouteCtxKey, rctx))
-			handler.ServeHTTP(tc.rec, r)
+			accSvc := NewAccountSvc(mockAccRepo, newTextIDPolicy(t))
+			account, err := accSvc.GetAccount(context.Background(), tc.account_id)
 
 			if len(tc.err) > 0 {
-				bodyBytes, _ := io.ReadAll(tc.rec.Body)
-				assert.Contains(t, string(bodyBytes), tc.err)
-				assert.Equal(t, tc.statusCode, tc.rec.Result().StatusCode)
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
 			} else {
-				var response domain.Account
-				_ = json.NewDecoder(tc.rec.Body).Decode(&response)
-				assert.Equal(t, tc.want, response)
-				assert.Equal(t, 200, tc.rec.Result().StatusCode)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, account)
 			}
 		})
 	}
 }
 
-func TestPostAccount(t *testing.T) {
+func TestCreateAccount(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	tests := []struct {
 		name       string
-		rec        *httptest.ResponseRecorder
-		body       map[string]interface{}
+		cmd        domain.CreateAccountCommand
 		doMockRepo func(repository *mock_ports.MockAccountRepository)
+		want       domain.Account
 		err        string
-		statusCode int
+		code       domain.ErrorCode
 	}{
 		{
 			name: "Test Case Positive",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"account_id":      "123",
-				"initial_balance": "123",
-			},
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "123.123456"},
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
-				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(false)
-				repository.EXPECT().InsertAccount(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
+				repository.EXPECT().InsertAccount(gomock.Any(), "123", 123.12346, domain.DefaultCurrency, domain.AccountDetails{}).Return(
 					nil,
 				)
 			},
-			err: "",
+			want: domain.Account{ID: "123", Balance: "123.12346", Currency: domain.DefaultCurrency, Status: domain.AccountStatusActive},
+			err:  "",
 		},
 		{
-			name: "Test Case Negative - Empty account passed as parameter",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"account_id":      "",
-				"initial_balance": "",
+			name: "Test Case Positive - With currency",
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "10", Currency: "EUR"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
+				repository.EXPECT().InsertAccount(gomock.Any(), "123", 10.0, "EUR", domain.AccountDetails{}).Return(nil)
+			},
+			want: domain.Account{ID: "123", Balance: "10", Currency: "EUR", Status: domain.AccountStatusActive},
+			err:  "",
+		},
+		{
+			name: "Test Case Positive - With details",
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "10", Details: domain.AccountDetails{DisplayName: "Payroll", Metadata: map[string]string{"team": "payments"}, Labels: []string{"vip", "eu", "vip"}}},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
+				repository.EXPECT().InsertAccount(gomock.Any(), "123", 10.0, domain.DefaultCurrency, domain.AccountDetails{DisplayName: "Payroll", Metadata: map[string]string{"team": "payments"}, Labels: []string{"eu", "vip"}}).Return(nil)
 			},
+			want: domain.Account{ID: "123", Balance: "10", Currency: domain.DefaultCurrency, Status: domain.AccountStatusActive, DisplayName: "Payroll", Metadata: map[string]string{"team": "payments"}, Labels: []string{"eu", "vip"}},
+			err:  "",
+		},
+		{
+			name: "Test Case Negative - Invalid label",
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "10", Details: domain.AccountDetails{Labels: []string{"VIP"}}},
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
 			},
-			err:        static.ErrIDLengthCannotBeZero,
-			statusCode: 400,
+			err:  static.ErrInvalidLabel,
+			code: domain.ErrCodeMalformedRequest,
 		},
 		{
-			name: "Test Case Negative - Account parameter longer than 32 char",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"account_id":      "99999999999999999999999999999999999999999999999999999999999999999999",
-				"initial_balance": "123",
+			name: "Test Case Negative - Invalid currency",
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "10", Currency: "eur"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
 			},
+			err:  static.ErrInvalidCurrency,
+			code: domain.ErrCodeInvalidCurrency,
+		},
+		{
+			name: "Test Case Negative - Empty account passed as parameter",
+			cmd:  domain.CreateAccountCommand{ID: "", InitialBalance: ""},
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
-			err:        static.ErrIDLengthTooLong,
-			statusCode: 400,
+			err:  static.ErrIDLengthCannotBeZero,
+			code: domain.ErrCodeInvalidAccountID,
 		},
 		{
-			name: "Test Case Negative - Account already exist",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"account_id":      "123",
-				"initial_balance": "123",
+			name: "Test Case Negative - Account parameter longer than 32 char",
+			cmd:  domain.CreateAccountCommand{ID: "99999999999999999999999999999999999999999999999999999999999999999999", InitialBalance: "123"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
+			err:  static.ErrIDLengthTooLong,
+			code: domain.ErrCodeInvalidAccountID,
+		},
+		{
+			name: "Test Case Negative - Account already exist",
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "123"},
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 			},
-			err:        static.ErrAccountAlreadyExist,
-			statusCode: 400,
+			err:  static.ErrAccountAlreadyExist,
+			code: domain.ErrCodeAccountAlreadyExists,
 		},
 		{
 			name: "Test Case Negative - initial_balance not a number",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"account_id":      "123",
-				"initial_balance": "abc",
-			},
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "abc"},
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(false)
 			},
-			err:        static.ErrBalanceNotValidNumber,
-			statusCode: 400,
+			err:  static.ErrBalanceNotValidNumber,
+			code: domain.ErrCodeInvalidAmount,
 		},
 		{
 			name: "Test Case Negative - negative initial_balance",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"account_id":      "123",
-				"initial_balance": "-123",
-			},
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "-123"},
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(false)
 			},
-			err:        static.ErrBalanceCannotBeNegative,
-			statusCode: 400,
+			err:  static.ErrBalanceCannotBeNegative,
+			code: domain.ErrCodeInvalidAmount,
 		},
 		{
 			name: "Test Case Negative - repository error",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"account_id":      "123",
-				"initial_balance": "123",
-			},
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "123"},
 			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(false)
-				repository.EXPECT().InsertAccount(gomock.Any(), gomock.Any(), gomock.Any()).Return(
+				repository.EXPECT().InsertAccount(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(
 					errors.New("random error"),
 				)
 			},
-			err:        static.ErrCreatingAccount,
-			statusCode: 500,
+			err:  static.ErrCreatingAccount,
+			code: domain.ErrCodeInternal,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			tc.doMockRepo(mockAccRepo)
+			accSvc := NewAccountSvc(mockAccRepo, newTextIDPolicy(t))
+			account, err := accSvc.CreateAccount(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, account)
+			}
+		})
+	}
+}
+
+func TestCreateAccountGeneratedID(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	ids, err := accountid.New(&accountid.AccountIDConfig{Mode: accountid.ModeGenerated, Format: accountid.FormatULID})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		cmd        domain.CreateAccountCommand
+		doMockRepo func(repository *mock_ports.MockAccountRepository)
+		err        string
+		code       domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive",
+			cmd:  domain.CreateAccountCommand{InitialBalance: "10"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().InsertAccount(gomock.Any(), gomock.Any(), 10.0, domain.DefaultCurrency, domain.AccountDetails{}).Return(nil)
+			},
+		},
+		{
+			name: "Test Case Negative - Client supplied ID",
+			cmd:  domain.CreateAccountCommand{ID: "123", InitialBalance: "10"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+			},
+			err:  static.ErrIDGeneratedByServer,
+			code: domain.ErrCodeInvalidAccountID,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			tc.doMockRepo(mockAccRepo)
+			accSvc := NewAccountSvc(mockAccRepo, ids)
+			account, err := accSvc.CreateAccount(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, ids.Validate(account.ID))
+				assert.Equal(t, "10", account.Balance)
+			}
+		})
+	}
+}
+
+func TestUpdateAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	name := "Payroll"
+	longName := strings.Repeat("a", maxDisplayNameLength+1)
+	tooManyKeys := map[string]string{}
+	for i := 0; i <= maxMetadataKeys; i++ {
+		tooManyKeys[strconv.Itoa(i)] = ""
+	}
+	tests := []struct {
+		name       string
+		cmd        domain.UpdateAccountCommand
+		doMockRepo func(repository *mock_ports.MockAccountRepository)
+		want       domain.Account
+		err        string
+		code       domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive",
+			cmd:  domain.UpdateAccountCommand{ID: "123", DisplayName: &name, Labels: []string{"vip", "vip"}},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+				repository.EXPECT().UpdateAccount(gomock.Any(), domain.UpdateAccountCommand{ID: "123", DisplayName: &name, Labels: []string{"vip"}}).
+					Return(&domain.Account{ID: "123", Balance: "10", DisplayName: name, Labels: []string{"vip"}}, nil)
+			},
+			want: domain.Account{ID: "123", Balance: "10", DisplayName: name, Labels: []string{"vip"}},
+		},
+		{
+			name:       "Test Case Negative - Display name too long",
+			cmd:        domain.UpdateAccountCommand{ID: "123", DisplayName: &longName},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrDisplayNameTooLong,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Too many metadata keys",
+			cmd:        domain.UpdateAccountCommand{ID: "123", Metadata: tooManyKeys},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrTooManyMetadataKeys,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Empty metadata key",
+			cmd:        domain.UpdateAccountCommand{ID: "123", Metadata: map[string]string{"": "x"}},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidMetadataKey,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name: "Test Case Negative - Account does not exist",
+			cmd:  domain.UpdateAccountCommand{ID: "123", DisplayName: &name},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
+			},
+			err:  static.ErrAccountDoesNotExist,
+			code: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name: "Test Case Negative - Repository error",
+			cmd:  domain.UpdateAccountCommand{ID: "123", DisplayName: &name},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+				repository.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Return(nil, errors.New("random error"))
+			},
+			err:  static.ErrUnableToUpdateAccount,
+			code: domain.ErrCodeInternal,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			tc.doMockRepo(mockAccRepo)
+			accSvc := NewAccountSvc(mockAccRepo, newTextIDPolicy(t))
+			account, err := accSvc.UpdateAccount(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, account)
+			}
+		})
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	asOf := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	future := time.Now().Add(time.Hour)
+	tests := []struct {
+		name       string
+		account_id string
+		asOf       *time.Time
+		doMockRepo func(repository *mock_ports.MockAccountRepository)
+		want       domain.Balance
+		err        string
+		code       domain.ErrorCode
+	}{
+		{
+			name:       "Test Case Positive",
+			account_id: "123",
+			asOf:       &asOf,
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetBalanceAsOf(gomock.Any(), "123", asOf).Return(
+					&domain.HistoricalBalance{Balance: 100.30000000000001, Currency: "EUR", CreatedAt: asOf.Add(-time.Hour)},
+					nil,
+				)
+			},
+			want: domain.Balance{AccountID: "123", Balance: "100.3", Currency: "EUR", AsOf: asOf},
+		},
+		{
+			name:       "Test Case Negative - as_of in the future",
+			account_id: "123",
+			asOf:       &future,
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+			},
+			err:  static.ErrAsOfInFuture,
+			code: domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Empty account passed as parameter",
+			account_id: "",
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+			},
+			err:  static.ErrIDLengthCannotBeZero,
+			code: domain.ErrCodeInvalidAccountID,
+		},
+		{
+			name:       "Test Case Negative - Account does not exist",
+			account_id: "123",
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetBalanceAsOf(gomock.Any(), "123", gomock.Any()).Return(nil, sql.ErrNoRows)
+			},
+			err:  static.ErrAccountDoesNotExist,
+			code: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:       "Test Case Negative - Account created after as_of",
+			account_id: "123",
+			asOf:       &asOf,
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetBalanceAsOf(gomock.Any(), "123", asOf).Return(
+					&domain.HistoricalBalance{Balance: 100, Currency: "USD", CreatedAt: asOf.Add(time.Minute)},
+					nil,
+				)
+			},
+			err:  static.ErrAccountNotCreatedAsOf,
+			code: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:       "Test Case Negative - Repository error",
+			account_id: "123",
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetBalanceAsOf(gomock.Any(), "123", gomock.Any()).Return(nil, errors.New("random error"))
+			},
+			err:  static.ErrUnableToRetrieveBalance,
+			code: domain.ErrCodeInternal,
 		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
 			tc.doMockRepo(mockAccRepo)
-			accSvc := NewAccountSvc(mockAccRepo)
-			handler := http.HandlerFunc(accSvc.PostAccount)
-			body, _ := json.Marshal(tc.body)
-			req := httptest.NewRequest("POST", "/accounts", bytes.NewReader(body))
-			handler.ServeHTTP(tc.rec, req)
+			accSvc := NewAccountSvc(mockAccRepo, newTextIDPolicy(t))
+			balance, err := accSvc.GetBalance(context.Background(), tc.account_id, tc.asOf)
 
 			if len(tc.err) > 0 {
-				bodyBytes, _ := io.ReadAll(tc.rec.Body)
-				assert.Contains(t, string(bodyBytes), tc.err)
-				assert.Equal(t, tc.statusCode, tc.rec.Result().StatusCode)
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
 			} else {
-				assert.Equal(t, 200, tc.rec.Result().StatusCode)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, balance)
+			}
+		})
+	}
+}
+
+func TestListAccounts(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	createdAt := time.Date(2024, 3, 31, 12, 0, 0, 123456000, time.UTC)
+	accounts := []domain.Account{
+		{ID: "1", Balance: "10", CreatedAt: createdAt},
+		{ID: "2", Balance: "20.5", CreatedAt: createdAt.Add(time.Second)},
+		{ID: "3", Balance: "30", CreatedAt: createdAt.Add(2 * time.Second)},
+	}
+	minBalance, maxBalance := 50.0, 10.0
+
+	tests := []struct {
+		name       string
+		query      domain.ListAccountsQuery
+		doMockRepo func(repository *mock_ports.MockAccountRepository)
+		want       []domain.Account
+		wantCursor domain.AccountCursor
+		err        string
+		code       domain.ErrorCode
+	}{
+		{
+			name:  "Test Case Positive - Next page",
+			query: domain.ListAccountsQuery{Currency: "USD", Limit: 2},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().ListAccounts(gomock.Any(), domain.ListAccountsQuery{Currency: "USD", Limit: 2, Sort: domain.AccountSortID}, nil, 3).Return(accounts, nil)
+			},
+			want:       accounts[:2],
+			wantCursor: domain.AccountCursor{Value: "2", ID: "2"},
+		},
+		{
+			name:  "Test Case Positive - Next page sorted by created_at",
+			query: domain.ListAccountsQuery{Sort: domain.AccountSortCreatedAtDesc, Limit: 2},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().ListAccounts(gomock.Any(), gomock.Any(), nil, 3).Return(accounts, nil)
+			},
+			want:       accounts[:2],
+			wantCursor: domain.AccountCursor{Value: "2024-03-31T12:00:01.123456Z", ID: "2"},
+		},
+		{
+			name:  "Test Case Positive - Next page sorted by balance",
+			query: domain.ListAccountsQuery{Sort: domain.AccountSortBalance, Limit: 2},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().ListAccounts(gomock.Any(), gomock.Any(), nil, 3).Return(accounts, nil)
+			},
+			want:       accounts[:2],
+			wantCursor: domain.AccountCursor{Value: "20.5", ID: "2"},
+		},
+		{
+			name:  "Test Case Positive - Last page with default limit",
+			query: domain.ListAccountsQuery{},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().ListAccounts(gomock.Any(), gomock.Any(), nil, defaultAccountListLimit+1).Return(accounts, nil)
+			},
+			want: accounts,
+		},
+		{
+			name:       "Test Case Negative - Invalid status",
+			query:      domain.ListAccountsQuery{Status: "deleted"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidAccountStatus,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Invalid currency",
+			query:      domain.ListAccountsQuery{Currency: "dollar"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidCurrency,
+			code:       domain.ErrCodeInvalidCurrency,
+		},
+		{
+			name:       "Test Case Negative - Invalid sort",
+			query:      domain.ListAccountsQuery{Sort: "updated_at"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidAccountSort,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Balance range",
+			query:      domain.ListAccountsQuery{MinBalance: &minBalance, MaxBalance: &maxBalance},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidBalanceRange,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Created range",
+			query:      domain.ListAccountsQuery{CreatedFrom: &createdAt, CreatedTo: &createdAt},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidCreatedRange,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Cursor for a different sort",
+			query:      domain.ListAccountsQuery{Sort: domain.AccountSortBalance, Cursor: encodeAccountCursor(domain.AccountSortID, accounts[0])},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidCursor,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:       "Test Case Negative - Malformed cursor",
+			query:      domain.ListAccountsQuery{Cursor: "not a cursor"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {},
+			err:        static.ErrInvalidCursor,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:  "Test Case Negative - Repository error",
+			query: domain.ListAccountsQuery{},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().ListAccounts(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("random error"))
+			},
+			err:  static.ErrUnableToListAccounts,
+			code: domain.ErrCodeInternal,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			tc.doMockRepo(mockAccRepo)
+			accSvc := NewAccountSvc(mockAccRepo, newTextIDPolicy(t))
+			list, err := accSvc.ListAccounts(context.Background(), tc.query)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, list.Accounts)
+			if tc.wantCursor == (domain.AccountCursor{}) {
+				assert.Empty(t, list.NextCursor)
+				return
+			}
+			sort := tc.query.Sort
+			if sort == "" {
+				sort = domain.AccountSortID
 			}
+			cursor, err := decodeAccountCursor(sort, list.NextCursor)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantCursor, cursor)
 		})
 	}
 }
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/static"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type AdjustmentSvcImpl struct {
+	accountRepo    ports.AccountRepository
+	adjustmentRepo ports.AdjustmentRepository
+	ids            ports.AccountIDPolicy
+	policy         domain.AdjustmentPolicy
+	suspense       domain.SuspenseAccounts
+}
+
+// NewAdjustmentSvc returns an AdjustmentService booking adjustments as transfers through adjustmentRepo, against the suspense account of the currency of each account, see LoadSuspenseAccounts
+func NewAdjustmentSvc(accountRepo ports.AccountRepository, adjustmentRepo ports.AdjustmentRepository, ids ports.AccountIDPolicy, policy domain.AdjustmentPolicy, suspense domain.SuspenseAccounts) *AdjustmentSvcImpl {
+	return &AdjustmentSvcImpl{
+		accountRepo:    accountRepo,
+		adjustmentRepo: adjustmentRepo,
+		ids:            ids,
+		policy:         policy,
+		suspense:       suspense,
+	}
+}
+
+// LoadSuspenseAccounts will read the suspense accounts of policy and index them by the currency they hold, so that a misconfigured suspense account is found at startup rather than by every adjustment
+// The function will return the domain.SuspenseAccounts, empty when adjustments are disabled, and a *domain.Error if an account does not exist, cannot be read or holds the same currency as another
+func LoadSuspenseAccounts(ctx context.Context, accountRepo ports.AccountRepository, policy domain.AdjustmentPolicy) (domain.SuspenseAccounts, error) {
+	suspense := domain.SuspenseAccounts{}
+	for _, id := range policy.SuspenseAccountIDs {
+		account, err := accountRepo.GetAccount(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.NewError(domain.ErrCodeAccountNotFound, fmt.Sprintf(static.ErrSuspenseAccountDoesNotExist, id))
+		}
+		if err != nil {
+			return nil, domain.WrapError(domain.ErrCodeInternal, static.ErrGetSuspenseAccount, err)
+		}
+		if other, ok := suspense.For(account.Currency); ok {
+			return nil, domain.NewError(domain.ErrCodeCurrencyMismatch, fmt.Sprintf(static.ErrSuspenseAccountCurrency, other, id, account.Currency))
+		}
+		suspense[account.Currency] = id
+	}
+	return suspense, nil
+}
+
+// Adjust will accept a domain.AdjustmentCommand
+// The function will check that adjustments are enabled and that the inputs from domain.AdjustmentCommand are valid, with a reason code from domain.AdjustmentReasonCodes
+// The function will check if the account, denoted by AccountID, is a valid account within the system other than a suspense account, and that a suspense account holds its currency
+// The function will record the adjustment through ports.AdjustmentRepository, pending approval when the policy requires it, and otherwise book it at once in the DB transaction recording it as applied, see decide
+// The function will return the adjustment as a domain.Adjustment object and a *domain.Error if any check fails or the adjustment cannot be booked
+func (srv *AdjustmentSvcImpl) Adjust(ctx context.Context, cmd domain.AdjustmentCommand) (domain.Adjustment, error) {
+	if len(srv.suspense) == 0 {
+		return domain.Adjustment{}, domain.NewError(domain.ErrCodeForbidden, static.ErrAdjustmentsDisabled)
+	}
+	if err := srv.ids.Validate(cmd.AccountID); err != nil {
+		return domain.Adjustment{}, err
+	}
+	if srv.suspense.Contains(cmd.AccountID) {
+		return domain.Adjustment{}, domain.NewError(domain.ErrCodeSameAccountTransfer, static.ErrAdjustSuspenseAccount)
+	}
+	if cmd.Direction != domain.AdjustmentCredit && cmd.Direction != domain.AdjustmentDebit {
+		return domain.Adjustment{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAdjustmentDirection)
+	}
+	amount, err := parseTransferAmount(cmd.Amount)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	if !slices.Contains(domain.AdjustmentReasonCodes, cmd.ReasonCode) {
+		return domain.Adjustment{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAdjustmentReasonCode)
+	}
+	cmd.Note = strings.TrimSpace(cmd.Note)
+	if utf8.RuneCountInString(cmd.Note) > maxDescriptionLength {
+		return domain.Adjustment{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrNoteTooLong)
+	}
+	account, err := srv.accountRepo.GetAccount(ctx, cmd.AccountID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.Adjustment{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist)
+	}
+	if err != nil {
+		return domain.Adjustment{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToRetrieveAccount, err)
+	}
+	// Checked before the adjustment is recorded, as it could never be booked
+	suspenseID, ok := srv.suspense.For(account.Currency)
+	if !ok {
+		return domain.Adjustment{}, domain.NewError(domain.ErrCodeCurrencyMismatch, static.ErrNoSuspenseAccount)
+	}
+
+	adjustment := domain.Adjustment{
+		AccountID:         cmd.AccountID,
+		SuspenseAccountID: suspenseID,
+		Direction:         cmd.Direction,
+		Amount:            utils.FormatAmount(amount),
+		ReasonCode:        cmd.ReasonCode,
+		Note:              cmd.Note,
+		Status:            domain.AdjustmentStatusApproved,
+		RequestedBy:       cmd.Actor,
+	}
+	if srv.policy.RequireApproval {
+		adjustment.Status = domain.AdjustmentStatusPendingApproval
+	}
+	adjustment, err = srv.adjustmentRepo.InsertAdjustment(ctx, adjustment, srv.decide(amount))
+	if err != nil {
+		slog.InfoContext(ctx, "adjustment failed", "account_id", cmd.AccountID, "code", errorCode(err), "actor", cmd.Actor)
+		return domain.Adjustment{}, adjustmentError(err, static.ErrUnableToAdjust)
+	}
+	slog.InfoContext(ctx, "adjustment requested", "adjustment_id", adjustment.ID, "account_id", adjustment.AccountID, "direction", adjustment.Direction,
+		"amount", adjustment.Amount, "reason_code", adjustment.ReasonCode, "status", adjustment.Status, "transaction_id", adjustment.TransactionID, "actor", cmd.Actor)
+	return adjustment, nil
+}
+
+// ReviewAdjustment will accept a domain.ReviewAdjustmentCommand
+// The function will check that the adjustment belongs to the account, is pending approval and was requested by another actor than the reviewer, through ports.AdjustmentRepository which locks it while it is reviewed
+// The function will book an approved adjustment in the DB transaction recording it as applied, see decide, or record it as failed
+// The function will return the reviewed adjustment as a domain.Adjustment object and a *domain.Error if any check fails or the adjustment cannot be booked
+func (srv *AdjustmentSvcImpl) ReviewAdjustment(ctx context.Context, cmd domain.ReviewAdjustmentCommand) (domain.Adjustment, error) {
+	if err := srv.ids.Validate(cmd.AccountID); err != nil {
+		return domain.Adjustment{}, err
+	}
+	// found tells an adjustment that does not exist from an approved adjustment whose transfer finds no account
+	found := false
+	var amount float64
+	adjustment, err := srv.adjustmentRepo.ReviewAdjustment(ctx, cmd.AccountID, cmd.AdjustmentID, func(adjustment domain.Adjustment) (domain.Adjustment, error) {
+		found = true
+		if adjustment.Status != domain.AdjustmentStatusPendingApproval {
+			return domain.Adjustment{}, domain.NewError(domain.ErrCodeNotPendingApproval, static.ErrAdjustmentNotPending)
+		}
+		if adjustment.RequestedBy == cmd.Actor {
+			return domain.Adjustment{}, domain.NewError(domain.ErrCodeForbidden, static.ErrSelfApproval)
+		}
+		adjustment.Status = domain.AdjustmentStatusRejected
+		if cmd.Approve {
+			adjustment.Status = domain.AdjustmentStatusApproved
+		}
+		adjustment.ReviewedBy = cmd.Actor
+		var err error
+		amount, err = strconv.ParseFloat(adjustment.Amount, 64)
+		return adjustment, err
+	}, func(source domain.Account, destination domain.Account) (float64, float64, error) {
+		return srv.decide(amount)(source, destination)
+	})
+	if err != nil {
+		if !found && errors.Is(err, sql.ErrNoRows) {
+			return domain.Adjustment{}, domain.NewError(domain.ErrCodeAdjustmentNotFound, static.ErrAdjustmentDoesNotExist)
+		}
+		slog.InfoContext(ctx, "adjustment review failed", "adjustment_id", cmd.AdjustmentID, "account_id", cmd.AccountID, "code", errorCode(err), "actor", cmd.Actor)
+		return domain.Adjustment{}, adjustmentError(err, static.ErrUnableToReviewAdjustment)
+	}
+	slog.InfoContext(ctx, "adjustment reviewed", "adjustment_id", adjustment.ID, "account_id", adjustment.AccountID, "status", adjustment.Status,
+		"transaction_id", adjustment.TransactionID, "actor", cmd.Actor)
+	return adjustment, nil
+}
+
+// decide will return the domain.TransferDecision of the transfer booking an approved adjustment of amount, see domain.Adjustment.Transaction
+// A credit is a transfer from a suspense account, which may be overdrawn, and a debit is a transfer to it, which the account must have the balance for
+func (srv *AdjustmentSvcImpl) decide(amount float64) domain.TransferDecision {
+	return func(source domain.Account, destination domain.Account) (float64, float64, error) {
+		return applyTransfer(source, destination, amount, srv.suspense.Contains(source.ID))
+	}
+}
+
+// adjustmentError will return err if it is a *domain.Error, such as the error a transfer booking an adjustment failed with, and wrap it as an internal error with message otherwise
+func adjustmentError(err error, message string) error {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	return domain.WrapError(domain.ErrCodeInternal, message, err)
+}
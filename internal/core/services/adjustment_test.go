@@ -0,0 +1,440 @@
+package services
+
+import (
+	"account-test/internal/core/domain"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAdjustmentPolicy = domain.AdjustmentPolicy{SuspenseAccountIDs: []string{"SUSPENSE", "SUSPENSE-EUR"}}
+
+var testSuspenseAccounts = domain.SuspenseAccounts{"USD": "SUSPENSE", "EUR": "SUSPENSE-EUR"}
+
+func TestAdjust(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	requested := domain.Adjustment{
+		AccountID:         "123",
+		SuspenseAccountID: "SUSPENSE",
+		Direction:         domain.AdjustmentCredit,
+		Amount:            "19.5",
+		ReasonCode:        "chargeback",
+		Note:              "INC-42",
+		Status:            domain.AdjustmentStatusApproved,
+		RequestedBy:       "key:abc",
+	}
+	inserted := func(adjustment domain.Adjustment) domain.Adjustment {
+		adjustment.ID = 7
+		adjustment.CreatedAt = createdAt
+		return adjustment
+	}
+	tests := []struct {
+		name             string
+		cmd              domain.AdjustmentCommand
+		policy           domain.AdjustmentPolicy
+		doMockAccRepo    func(repository *mock_ports.MockAccountRepository)
+		doMockAdjustRepo func(repository *mock_ports.MockAdjustmentRepository)
+		want             domain.Adjustment
+		err              string
+		code             domain.ErrorCode
+	}{
+		{
+			name:   "Test Case Positive - Credit",
+			cmd:    domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback", Note: " INC-42 ", Actor: "key:abc"},
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(&domain.Account{ID: "123", Balance: "100", Currency: "USD"}, nil)
+			},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				repository.EXPECT().InsertAdjustment(gomock.Any(), requested, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, adjustment domain.Adjustment, decide domain.TransferDecision) (domain.Adjustment, error) {
+						transaction, err := inserted(adjustment).Transaction()
+						assert.NoError(t, err)
+						assert.Equal(t, "SUSPENSE", transaction.SourceID)
+						assert.Equal(t, "123", transaction.DestinationID)
+						assert.Equal(t, "adjustment-7", transaction.Reference)
+						assert.Equal(t, domain.AdjustmentClientID, transaction.ClientID)
+						assert.JSONEq(t, `{"adjustment_id":7,"reason_code":"chargeback"}`, string(transaction.Metadata))
+						// The suspense account may be overdrawn
+						sourceBalance, destinationBalance, err := decide(domain.Account{ID: "SUSPENSE", Balance: "0"}, domain.Account{ID: "123", Balance: "100"})
+						assert.NoError(t, err)
+						assert.Equal(t, -19.5, sourceBalance)
+						assert.Equal(t, 119.5, destinationBalance)
+						applied := inserted(adjustment)
+						applied.Status = domain.AdjustmentStatusApplied
+						applied.TransactionID = 11
+						return applied, nil
+					},
+				)
+			},
+			want: func() domain.Adjustment {
+				adjustment := inserted(requested)
+				adjustment.Status = domain.AdjustmentStatusApplied
+				adjustment.TransactionID = 11
+				return adjustment
+			}(),
+		},
+		{
+			name:   "Test Case Positive - Pending approval",
+			cmd:    domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback", Note: "INC-42", Actor: "key:abc"},
+			policy: domain.AdjustmentPolicy{SuspenseAccountIDs: []string{"SUSPENSE", "SUSPENSE-EUR"}, RequireApproval: true},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(&domain.Account{ID: "123", Balance: "100", Currency: "USD"}, nil)
+			},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				pending := requested
+				pending.Status = domain.AdjustmentStatusPendingApproval
+				repository.EXPECT().InsertAdjustment(gomock.Any(), pending, gomock.Any()).Return(inserted(pending), nil)
+			},
+			want: func() domain.Adjustment {
+				adjustment := inserted(requested)
+				adjustment.Status = domain.AdjustmentStatusPendingApproval
+				return adjustment
+			}(),
+		},
+		{
+			name:   "Test Case Negative - Debit larger than balance",
+			cmd:    domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentDebit, Amount: "500", ReasonCode: "write_off", Actor: "key:abc"},
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(&domain.Account{ID: "123", Balance: "100", Currency: "USD"}, nil)
+			},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				repository.EXPECT().InsertAdjustment(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, adjustment domain.Adjustment, decide domain.TransferDecision) (domain.Adjustment, error) {
+						transaction, err := inserted(adjustment).Transaction()
+						assert.NoError(t, err)
+						assert.Equal(t, "123", transaction.SourceID)
+						assert.Equal(t, "SUSPENSE", transaction.DestinationID)
+						_, _, err = decide(domain.Account{ID: "123", Balance: "100"}, domain.Account{ID: "SUSPENSE", Balance: "0"})
+						return domain.Adjustment{}, err
+					},
+				)
+			},
+			err:  static.ErrTransferAmountLargerThanAccount,
+			code: domain.ErrCodeInsufficientFunds,
+		},
+		{
+			name:             "Test Case Negative - Adjustments disabled",
+			cmd:              domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback"},
+			doMockAccRepo:    func(repository *mock_ports.MockAccountRepository) {},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrAdjustmentsDisabled,
+			code:             domain.ErrCodeForbidden,
+		},
+		{
+			name:             "Test Case Negative - Suspense account",
+			cmd:              domain.AdjustmentCommand{AccountID: "SUSPENSE-EUR", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback"},
+			policy:           testAdjustmentPolicy,
+			doMockAccRepo:    func(repository *mock_ports.MockAccountRepository) {},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrAdjustSuspenseAccount,
+			code:             domain.ErrCodeSameAccountTransfer,
+		},
+		{
+			name:             "Test Case Negative - Invalid direction",
+			cmd:              domain.AdjustmentCommand{AccountID: "123", Direction: "refund", Amount: "19.5", ReasonCode: "chargeback"},
+			policy:           testAdjustmentPolicy,
+			doMockAccRepo:    func(repository *mock_ports.MockAccountRepository) {},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrInvalidAdjustmentDirection,
+			code:             domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:             "Test Case Negative - Invalid amount",
+			cmd:              domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "-1", ReasonCode: "chargeback"},
+			policy:           testAdjustmentPolicy,
+			doMockAccRepo:    func(repository *mock_ports.MockAccountRepository) {},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrAmountCannotBeNegative,
+			code:             domain.ErrCodeInvalidAmount,
+		},
+		{
+			name:             "Test Case Negative - Reason code missing",
+			cmd:              domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5"},
+			policy:           testAdjustmentPolicy,
+			doMockAccRepo:    func(repository *mock_ports.MockAccountRepository) {},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrInvalidAdjustmentReasonCode,
+			code:             domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:   "Test Case Negative - Account does not exist",
+			cmd:    domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback"},
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(nil, sql.ErrNoRows)
+			},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrAccountDoesNotExist,
+			code:             domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:   "Test Case Negative - Account cannot be read",
+			cmd:    domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback"},
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(nil, errors.New("random error"))
+			},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrUnableToRetrieveAccount,
+			code:             domain.ErrCodeInternal,
+		},
+		{
+			name:   "Test Case Negative - No suspense account for the currency",
+			cmd:    domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback"},
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(&domain.Account{ID: "123", Balance: "100", Currency: "GBP"}, nil)
+			},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {},
+			err:              static.ErrNoSuspenseAccount,
+			code:             domain.ErrCodeCurrencyMismatch,
+		},
+		{
+			name:   "Test Case Negative - Repository error",
+			cmd:    domain.AdjustmentCommand{AccountID: "123", Direction: domain.AdjustmentCredit, Amount: "19.5", ReasonCode: "chargeback"},
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "123").Return(&domain.Account{ID: "123", Balance: "100", Currency: "USD"}, nil)
+			},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				repository.EXPECT().InsertAdjustment(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.Adjustment{}, errors.New("random error"))
+			},
+			err:  static.ErrUnableToAdjust,
+			code: domain.ErrCodeInternal,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			mockAdjustRepo := mock_ports.NewMockAdjustmentRepository(mockCtrl)
+			tc.doMockAccRepo(mockAccRepo)
+			tc.doMockAdjustRepo(mockAdjustRepo)
+			suspense := domain.SuspenseAccounts{}
+			if len(tc.policy.SuspenseAccountIDs) > 0 {
+				suspense = testSuspenseAccounts
+			}
+			adjustSvc := NewAdjustmentSvc(mockAccRepo, mockAdjustRepo, newTextIDPolicy(t), tc.policy, suspense)
+			adjustment, err := adjustSvc.Adjust(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, adjustment)
+			}
+		})
+	}
+}
+
+func TestReviewAdjustment(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	pending := domain.Adjustment{
+		ID:                7,
+		AccountID:         "123",
+		SuspenseAccountID: "SUSPENSE",
+		Direction:         domain.AdjustmentDebit,
+		Amount:            "19.5",
+		ReasonCode:        "correction",
+		Status:            domain.AdjustmentStatusPendingApproval,
+		RequestedBy:       "key:abc",
+	}
+	// reviewWith returns a ReviewAdjustment implementation running the review against the adjustment, and booking an approved adjustment between source and destination as transaction 1
+	reviewWith := func(adjustment domain.Adjustment, source domain.Account, destination domain.Account) func(context.Context, string, int64, domain.AdjustmentReview, domain.TransferDecision) (domain.Adjustment, error) {
+		return func(ctx context.Context, accountID string, id int64, review domain.AdjustmentReview, decide domain.TransferDecision) (domain.Adjustment, error) {
+			reviewed, err := review(adjustment)
+			if err != nil || reviewed.Status != domain.AdjustmentStatusApproved {
+				return reviewed, err
+			}
+			_, _, err = decide(source, destination)
+			if err != nil {
+				return domain.Adjustment{}, err
+			}
+			reviewed.Status = domain.AdjustmentStatusApplied
+			reviewed.TransactionID = 1
+			return reviewed, nil
+		}
+	}
+	account := domain.Account{ID: "123", Balance: "100"}
+	suspense := domain.Account{ID: "SUSPENSE", Balance: "0"}
+	tests := []struct {
+		name             string
+		cmd              domain.ReviewAdjustmentCommand
+		doMockAdjustRepo func(repository *mock_ports.MockAdjustmentRepository)
+		want             domain.Adjustment
+		err              string
+		code             domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive - Approved",
+			cmd:  domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 7, Approve: true, Actor: "key:def"},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				repository.EXPECT().ReviewAdjustment(gomock.Any(), "123", int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(pending, account, suspense))
+			},
+			want: func() domain.Adjustment {
+				adjustment := pending
+				adjustment.Status = domain.AdjustmentStatusApplied
+				adjustment.ReviewedBy = "key:def"
+				adjustment.TransactionID = 1
+				return adjustment
+			}(),
+		},
+		{
+			name: "Test Case Positive - Rejected",
+			cmd:  domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 7, Actor: "key:def"},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				repository.EXPECT().ReviewAdjustment(gomock.Any(), "123", int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(pending, account, suspense))
+			},
+			want: func() domain.Adjustment {
+				adjustment := pending
+				adjustment.Status = domain.AdjustmentStatusRejected
+				adjustment.ReviewedBy = "key:def"
+				return adjustment
+			}(),
+		},
+		{
+			name: "Test Case Negative - Approved debit larger than balance",
+			cmd:  domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 7, Approve: true, Actor: "key:def"},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				large := pending
+				large.Amount = "500"
+				repository.EXPECT().ReviewAdjustment(gomock.Any(), "123", int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(large, account, suspense))
+			},
+			err:  static.ErrTransferAmountLargerThanAccount,
+			code: domain.ErrCodeInsufficientFunds,
+		},
+		{
+			name: "Test Case Negative - Approved by requester",
+			cmd:  domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 7, Approve: true, Actor: "key:abc"},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				repository.EXPECT().ReviewAdjustment(gomock.Any(), "123", int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(pending, account, suspense))
+			},
+			err:  static.ErrSelfApproval,
+			code: domain.ErrCodeForbidden,
+		},
+		{
+			name: "Test Case Negative - Already reviewed",
+			cmd:  domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 7, Approve: true, Actor: "key:def"},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				applied := pending
+				applied.Status = domain.AdjustmentStatusApplied
+				repository.EXPECT().ReviewAdjustment(gomock.Any(), "123", int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(applied, account, suspense))
+			},
+			err:  static.ErrAdjustmentNotPending,
+			code: domain.ErrCodeNotPendingApproval,
+		},
+		{
+			name: "Test Case Negative - Adjustment does not exist",
+			cmd:  domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 8, Approve: true, Actor: "key:def"},
+			doMockAdjustRepo: func(repository *mock_ports.MockAdjustmentRepository) {
+				repository.EXPECT().ReviewAdjustment(gomock.Any(), "123", int64(8), gomock.Any(), gomock.Any()).Return(domain.Adjustment{}, sql.ErrNoRows)
+			},
+			err:  static.ErrAdjustmentDoesNotExist,
+			code: domain.ErrCodeAdjustmentNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			mockAdjustRepo := mock_ports.NewMockAdjustmentRepository(mockCtrl)
+			tc.doMockAdjustRepo(mockAdjustRepo)
+			adjustSvc := NewAdjustmentSvc(mockAccRepo, mockAdjustRepo, newTextIDPolicy(t), testAdjustmentPolicy, testSuspenseAccounts)
+			adjustment, err := adjustSvc.ReviewAdjustment(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, adjustment)
+			}
+		})
+	}
+}
+
+func TestLoadSuspenseAccounts(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name          string
+		policy        domain.AdjustmentPolicy
+		doMockAccRepo func(repository *mock_ports.MockAccountRepository)
+		want          domain.SuspenseAccounts
+		code          domain.ErrorCode
+	}{
+		{
+			name:   "Test Case Positive",
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "SUSPENSE").Return(&domain.Account{ID: "SUSPENSE", Balance: "0", Currency: "USD"}, nil)
+				repository.EXPECT().GetAccount(gomock.Any(), "SUSPENSE-EUR").Return(&domain.Account{ID: "SUSPENSE-EUR", Balance: "0", Currency: "EUR"}, nil)
+			},
+			want: testSuspenseAccounts,
+		},
+		{
+			name:          "Test Case Positive - Adjustments disabled",
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {},
+			want:          domain.SuspenseAccounts{},
+		},
+		{
+			name:   "Test Case Negative - Suspense account does not exist",
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "SUSPENSE").Return(nil, sql.ErrNoRows)
+			},
+			code: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:   "Test Case Negative - Two suspense accounts hold the same currency",
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "SUSPENSE").Return(&domain.Account{ID: "SUSPENSE", Balance: "0", Currency: "USD"}, nil)
+				repository.EXPECT().GetAccount(gomock.Any(), "SUSPENSE-EUR").Return(&domain.Account{ID: "SUSPENSE-EUR", Balance: "0", Currency: "USD"}, nil)
+			},
+			code: domain.ErrCodeCurrencyMismatch,
+		},
+		{
+			name:   "Test Case Negative - Repository error",
+			policy: testAdjustmentPolicy,
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().GetAccount(gomock.Any(), "SUSPENSE").Return(nil, errors.New("random error"))
+			},
+			code: domain.ErrCodeInternal,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			tc.doMockAccRepo(mockAccRepo)
+			suspense, err := LoadSuspenseAccounts(context.Background(), mockAccRepo, tc.policy)
+
+			if tc.code != "" {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, suspense)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/static"
+	"context"
+	"errors"
+)
+
+// errAuditChainBroken stops reading the audit log at the first entry breaking the hash chain
+var errAuditChainBroken = errors.New("audit log hash chain broken")
+
+type AuditSvcImpl struct {
+	auditRepo ports.AuditRepository
+}
+
+func NewAuditSvc(auditRepo ports.AuditRepository) *AuditSvcImpl {
+	return &AuditSvcImpl{
+		auditRepo: auditRepo,
+	}
+}
+
+// VerifyAuditLog will read the audit log in order and check that each entry is chained to the entry before it and that its hash matches its content, see domain.AuditEntry.ComputeHash
+// The function will stop at the first entry breaking the chain and report it with the problem found
+// When anchor, the last hash of an earlier verification, is not empty, the chain must still contain an entry with that hash
+// The function will return the domain.AuditVerification and a *domain.Error if the audit log cannot be read
+func (srv *AuditSvcImpl) VerifyAuditLog(ctx context.Context, anchor string) (domain.AuditVerification, error) {
+	verification := domain.AuditVerification{Valid: true}
+	anchored := anchor == ""
+	err := srv.auditRepo.StreamAuditLog(ctx, func(entry domain.AuditEntry) error {
+		switch {
+		case entry.PrevHash != verification.LastHash:
+			verification.Problem = static.ErrAuditPrevHashMismatch
+		case entry.ComputeHash() != entry.Hash:
+			verification.Problem = static.ErrAuditHashMismatch
+		default:
+			verification.Entries++
+			verification.LastID = entry.ID
+			verification.LastHash = entry.Hash
+			anchored = anchored || entry.Hash == anchor
+			return nil
+		}
+		verification.Valid = false
+		verification.InvalidID = entry.ID
+		return errAuditChainBroken
+	})
+	if err != nil && !errors.Is(err, errAuditChainBroken) {
+		return domain.AuditVerification{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToVerifyAuditLog, err)
+	}
+	if verification.Valid && !anchored {
+		verification.Valid = false
+		verification.Problem = static.ErrAuditAnchorMissing
+	}
+	return verification, nil
+}
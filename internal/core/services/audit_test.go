@@ -0,0 +1,124 @@
+package services
+
+import (
+	"account-test/internal/core/domain"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// chainAuditEntries will return entries chained to each other as they are appended to the audit log, with ids from 1
+func chainAuditEntries(entries ...domain.AuditEntry) []domain.AuditEntry {
+	prevHash := ""
+	for i := range entries {
+		entries[i].ID = int64(i + 1)
+		entries[i].PrevHash = prevHash
+		entries[i].Hash = entries[i].ComputeHash()
+		prevHash = entries[i].Hash
+	}
+	return entries
+}
+
+func TestVerifyAuditLog(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	newEntries := func() []domain.AuditEntry {
+		return chainAuditEntries(
+			domain.AuditEntry{CreatedAt: createdAt, Actor: "key:abc", Action: domain.AuditActionAccountCreate, ResourceType: domain.AuditResourceAccount, ResourceID: "123", After: json.RawMessage(`{"balance":"100"}`)},
+			domain.AuditEntry{CreatedAt: createdAt, Actor: "key:abc", Action: domain.AuditActionAccountCreate, ResourceType: domain.AuditResourceAccount, ResourceID: "456", After: json.RawMessage(`{"balance":"0"}`)},
+			domain.AuditEntry{CreatedAt: createdAt, Actor: "key:abc", Action: domain.AuditActionTransfer, ResourceType: domain.AuditResourceTransaction, ResourceID: "1", After: json.RawMessage(`{"amount":"10"}`)},
+		)
+	}
+	tests := []struct {
+		name    string
+		entries func() []domain.AuditEntry
+		anchor  string
+		repoErr error
+		want    domain.AuditVerification
+		err     string
+	}{
+		{
+			name:    "Test Case Positive",
+			entries: newEntries,
+			want:    domain.AuditVerification{Valid: true, Entries: 3, LastID: 3, LastHash: newEntries()[2].Hash},
+		},
+		{
+			name:    "Test Case Positive - Empty log",
+			entries: func() []domain.AuditEntry { return nil },
+			want:    domain.AuditVerification{Valid: true},
+		},
+		{
+			name: "Test Case Negative - Entry changed",
+			entries: func() []domain.AuditEntry {
+				entries := newEntries()
+				entries[1].After = json.RawMessage(`{"balance":"1000000"}`)
+				return entries
+			},
+			want: domain.AuditVerification{Entries: 1, LastID: 1, LastHash: newEntries()[0].Hash, InvalidID: 2, Problem: static.ErrAuditHashMismatch},
+		},
+		{
+			name: "Test Case Negative - Entry removed",
+			entries: func() []domain.AuditEntry {
+				entries := newEntries()
+				return append(entries[:1], entries[2:]...)
+			},
+			want: domain.AuditVerification{Entries: 1, LastID: 1, LastHash: newEntries()[0].Hash, InvalidID: 3, Problem: static.ErrAuditPrevHashMismatch},
+		},
+		{
+			name:    "Test Case Positive - Anchored",
+			entries: newEntries,
+			anchor:  newEntries()[1].Hash,
+			want:    domain.AuditVerification{Valid: true, Entries: 3, LastID: 3, LastHash: newEntries()[2].Hash},
+		},
+		{
+			name: "Test Case Negative - Entries removed from the end",
+			entries: func() []domain.AuditEntry {
+				return newEntries()[:1]
+			},
+			anchor: newEntries()[2].Hash,
+			want:   domain.AuditVerification{Entries: 1, LastID: 1, LastHash: newEntries()[0].Hash, Problem: static.ErrAuditAnchorMissing},
+		},
+		{
+			name:    "Test Case Negative - Repository error",
+			entries: newEntries,
+			repoErr: errors.New("random error"),
+			err:     static.ErrUnableToVerifyAuditLog,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAuditRepo := mock_ports.NewMockAuditRepository(mockCtrl)
+			mockAuditRepo.EXPECT().StreamAuditLog(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, each func(domain.AuditEntry) error) error {
+				if tc.repoErr != nil {
+					return tc.repoErr
+				}
+				for _, entry := range tc.entries() {
+					if err := each(entry); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+			verification, err := NewAuditSvc(mockAuditRepo).VerifyAuditLog(context.Background(), tc.anchor)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, verification)
+			}
+		})
+	}
+}
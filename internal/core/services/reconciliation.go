@@ -0,0 +1,114 @@
+package services
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/static"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxReasonLength bounds the reason recorded with a balance correction
+const maxReasonLength = 255
+
+type ReconciliationSvcImpl struct {
+	accountRepo ports.AccountRepository
+	settleDelay time.Duration
+	suspense    domain.SuspenseAccounts
+}
+
+// NewReconciliationSvc returns a ReconciliationService checking the balances of the accounts in accountRepo and booking their differences against the suspense account of their currency, see LoadSuspenseAccounts
+// Transfers recorded less than settleDelay ago may still be in flight, the accounts with such transfers are skipped
+func NewReconciliationSvc(accountRepo ports.AccountRepository, settleDelay time.Duration, suspense domain.SuspenseAccounts) *ReconciliationSvcImpl {
+	return &ReconciliationSvcImpl{
+		accountRepo: accountRepo,
+		settleDelay: settleDelay,
+		suspense:    suspense,
+	}
+}
+
+// Reconcile will accept a domain.ReconcileCommand
+// The function will compare the balance of every account with its initial balance plus its completed transfers and report the accounts whose balance differs
+// When Correct is set, the function will check that a reason is given and book the difference of each of those accounts as a transaction against the suspense account of its currency, recording the correction with the actor and reason
+// The function will check that a suspense account holds the currency of every account to correct before correcting any, and leaves the differences of the suspense accounts themselves uncorrected
+// Accounts with transfers recorded less than the settle delay ago are reported as skipped, as their balance may not be updated yet
+// The function will return the domain.Reconciliation and a *domain.Error if the command is invalid or the balances cannot be checked or corrected
+func (srv *ReconciliationSvcImpl) Reconcile(ctx context.Context, cmd domain.ReconcileCommand) (domain.Reconciliation, error) {
+	cmd.Reason = strings.TrimSpace(cmd.Reason)
+	if cmd.Correct && cmd.Reason == "" {
+		return domain.Reconciliation{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrReasonRequired)
+	}
+	if utf8.RuneCountInString(cmd.Reason) > maxReasonLength {
+		return domain.Reconciliation{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrReasonTooLong)
+	}
+
+	checkedAt := time.Now().UTC()
+	settledBefore := checkedAt.Add(-srv.settleDelay)
+	checks, err := srv.accountRepo.CheckBalances(ctx, settledBefore)
+	if err != nil {
+		return domain.Reconciliation{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToReconcile, err)
+	}
+
+	if cmd.Correct {
+		for _, check := range checks {
+			if check.InFlight || srv.suspense.Contains(check.AccountID) {
+				continue
+			}
+			if _, ok := srv.suspense.For(check.Currency); !ok {
+				return domain.Reconciliation{}, domain.NewError(domain.ErrCodeCurrencyMismatch, fmt.Sprintf(static.ErrNoSuspenseAccountToCorrect, check.Currency, check.AccountID))
+			}
+		}
+	}
+
+	reconciliation := domain.Reconciliation{
+		CheckedAt:     checkedAt,
+		Discrepancies: []domain.Discrepancy{},
+		Skipped:       []string{},
+	}
+	for _, check := range checks {
+		if check.InFlight {
+			reconciliation.Skipped = append(reconciliation.Skipped, check.AccountID)
+			continue
+		}
+		slog.WarnContext(ctx, "balance discrepancy", "account_id", check.AccountID, "balance", check.Balance, "expected_balance", check.ExpectedBalance)
+		discrepancy := domain.Discrepancy{
+			AccountID:       check.AccountID,
+			Currency:        check.Currency,
+			Balance:         utils.FormatAmount(utils.ToFixed(check.Balance, 5)),
+			ExpectedBalance: utils.FormatAmount(check.ExpectedBalance),
+			Difference:      utils.FormatAmount(utils.ToFixed(check.Balance-check.ExpectedBalance, 5)),
+		}
+		if cmd.Correct && !srv.suspense.Contains(check.AccountID) {
+			suspenseID, _ := srv.suspense.For(check.Currency)
+			correction, err := srv.accountRepo.CorrectBalance(ctx, check.AccountID, suspenseID, settledBefore, cmd.Actor, cmd.Reason)
+			if errors.Is(err, domain.ErrTransfersInFlight) {
+				// A transfer was recorded since the balances were checked
+				reconciliation.Skipped = append(reconciliation.Skipped, check.AccountID)
+				continue
+			}
+			if err != nil {
+				return domain.Reconciliation{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToCorrectBalance, err)
+			}
+			if correction == nil {
+				// The balance was corrected since the balances were checked
+				continue
+			}
+			slog.InfoContext(ctx, "balance corrected", "account_id", check.AccountID, "correction_id", correction.ID, "transaction_id", correction.TransactionID,
+				"suspense_account_id", correction.SuspenseAccountID, "previous_balance", correction.PreviousBalance, "corrected_balance", correction.CorrectedBalance, "actor", cmd.Actor)
+			discrepancy.Balance = utils.FormatAmount(utils.ToFixed(correction.CorrectedBalance, 5))
+			discrepancy.ExpectedBalance = utils.FormatAmount(correction.PreviousBalance)
+			discrepancy.Difference = utils.FormatAmount(utils.ToFixed(correction.CorrectedBalance-correction.PreviousBalance, 5))
+			discrepancy.Corrected = true
+			discrepancy.CorrectionID = correction.ID
+			discrepancy.TransactionID = correction.TransactionID
+		}
+		reconciliation.Discrepancies = append(reconciliation.Discrepancies, discrepancy)
+	}
+	return reconciliation, nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"account-test/internal/core/domain"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcile(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	checks := []domain.BalanceCheck{
+		{AccountID: "123", Currency: "USD", Balance: 100.30000000000001, ExpectedBalance: 100},
+		{AccountID: "456", Currency: "EUR", Balance: 20, ExpectedBalance: 20, InFlight: true},
+		{AccountID: "SUSPENSE", Currency: "USD", Balance: -5, ExpectedBalance: 0},
+	}
+	suspenseDiscrepancy := domain.Discrepancy{AccountID: "SUSPENSE", Currency: "USD", Balance: "-5", ExpectedBalance: "0", Difference: "-5"}
+	tests := []struct {
+		name       string
+		cmd        domain.ReconcileCommand
+		doMockRepo func(repository *mock_ports.MockAccountRepository)
+		want       domain.Reconciliation
+		err        string
+		code       domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive - Report only",
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckBalances(gomock.Any(), gomock.Any()).Return(checks, nil)
+			},
+			want: domain.Reconciliation{
+				Discrepancies: []domain.Discrepancy{{AccountID: "123", Currency: "USD", Balance: "100.3", ExpectedBalance: "100", Difference: "0.3"}, suspenseDiscrepancy},
+				Skipped:       []string{"456"},
+			},
+		},
+		{
+			name: "Test Case Positive - Correct",
+			cmd:  domain.ReconcileCommand{Correct: true, Reason: " drift after incident ", Actor: "key:abc"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckBalances(gomock.Any(), gomock.Any()).Return(checks, nil)
+				// The suspense account takes the differences of the other accounts, so its own is left uncorrected
+				repository.EXPECT().CorrectBalance(gomock.Any(), "123", "SUSPENSE", gomock.Any(), "key:abc", "drift after incident").Return(
+					&domain.BalanceCorrection{ID: 9, AccountID: "123", SuspenseAccountID: "SUSPENSE", TransactionID: 42, PreviousBalance: 100, CorrectedBalance: 100.30000000000001},
+					nil,
+				)
+			},
+			want: domain.Reconciliation{
+				Discrepancies: []domain.Discrepancy{
+					{AccountID: "123", Currency: "USD", Balance: "100.3", ExpectedBalance: "100", Difference: "0.3", Corrected: true, CorrectionID: 9, TransactionID: 42},
+					suspenseDiscrepancy,
+				},
+				Skipped: []string{"456"},
+			},
+		},
+		{
+			name: "Test Case Positive - Transfer recorded before the correction",
+			cmd:  domain.ReconcileCommand{Correct: true, Reason: "drift", Actor: "key:abc"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckBalances(gomock.Any(), gomock.Any()).Return(checks[:1], nil)
+				repository.EXPECT().CorrectBalance(gomock.Any(), "123", "SUSPENSE", gomock.Any(), "key:abc", "drift").Return(nil, domain.ErrTransfersInFlight)
+			},
+			want: domain.Reconciliation{Discrepancies: []domain.Discrepancy{}, Skipped: []string{"123"}},
+		},
+		{
+			name: "Test Case Negative - Correct without reason",
+			cmd:  domain.ReconcileCommand{Correct: true, Reason: "  "},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+			},
+			err:  static.ErrReasonRequired,
+			code: domain.ErrCodeMalformedRequest,
+		},
+		{
+			name: "Test Case Negative - Reason too long",
+			cmd:  domain.ReconcileCommand{Correct: true, Reason: strings.Repeat("a", 256)},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+			},
+			err:  static.ErrReasonTooLong,
+			code: domain.ErrCodeMalformedRequest,
+		},
+		{
+			name: "Test Case Negative - Repository error",
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckBalances(gomock.Any(), gomock.Any()).Return(nil, errors.New("random error"))
+			},
+			err:  static.ErrUnableToReconcile,
+			code: domain.ErrCodeInternal,
+		},
+		{
+			name: "Test Case Negative - Correction error",
+			cmd:  domain.ReconcileCommand{Correct: true, Reason: "drift"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckBalances(gomock.Any(), gomock.Any()).Return(checks, nil)
+				repository.EXPECT().CorrectBalance(gomock.Any(), "123", "SUSPENSE", gomock.Any(), "", "drift").Return(nil, errors.New("random error"))
+			},
+			err:  static.ErrUnableToCorrectBalance,
+			code: domain.ErrCodeInternal,
+		},
+		{
+			name: "Test Case Negative - No suspense account for the currency",
+			cmd:  domain.ReconcileCommand{Correct: true, Reason: "drift"},
+			doMockRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckBalances(gomock.Any(), gomock.Any()).Return(
+					append([]domain.BalanceCheck{{AccountID: "012", Currency: "GBP", Balance: 1, ExpectedBalance: 0}}, checks...),
+					nil,
+				)
+			},
+			err:  fmt.Sprintf(static.ErrNoSuspenseAccountToCorrect, "GBP", "012"),
+			code: domain.ErrCodeCurrencyMismatch,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			tc.doMockRepo(mockAccRepo)
+			reconciliationSvc := NewReconciliationSvc(mockAccRepo, 5*time.Minute, domain.SuspenseAccounts{"USD": "SUSPENSE"})
+			reconciliation, err := reconciliationSvc.Reconcile(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.WithinDuration(t, time.Now(), reconciliation.CheckedAt, time.Minute)
+				reconciliation.CheckedAt = time.Time{}
+				assert.Equal(t, tc.want, reconciliation)
+			}
+		})
+	}
+}
+
+func TestReconcileSettleDelay(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+	mockAccRepo.EXPECT().CheckBalances(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, settledBefore time.Time) ([]domain.BalanceCheck, error) {
+		assert.WithinDuration(t, time.Now().Add(-5*time.Minute), settledBefore, time.Minute)
+		return nil, nil
+	})
+
+	_, err := NewReconciliationSvc(mockAccRepo, 5*time.Minute, domain.SuspenseAccounts{}).Reconcile(context.Background(), domain.ReconcileCommand{})
+
+	assert.NoError(t, err)
+}
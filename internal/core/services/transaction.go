@@ -6,116 +6,426 @@ import (
 	"account-test/internal/core/utils"
 	"account-test/static"
 	"context"
+	"database/sql"
 	"encoding/json"
-	"io"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"math"
-	"net/http"
 	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultTransactionListLimit = 50
+	maxTransactionListLimit     = 100
+)
+
+// Limits on the details of a transfer, see validateTransferDetails
+const (
+	maxDescriptionLength     = 255
+	maxReferenceLength       = 64
+	maxTransferMetadataBytes = 4096
 )
 
 type TransactionSvcImpl struct {
 	accountRepo     ports.AccountRepository
 	transactionRepo ports.TransactionRepository
+	approvalRepo    ports.TransferApprovalRepository
+	metrics         ports.TransferMetrics
+	ids             ports.AccountIDPolicy
+	approvals       domain.TransferApprovalPolicy
+	suspense        domain.SuspenseAccounts
 }
 
-func NewTransactionSvc(accountRepo ports.AccountRepository, transactionRepo ports.TransactionRepository) *TransactionSvcImpl {
+// NewTransactionSvc returns a TransactionService holding the transfers above the threshold of approvals for approval through approvalRepo
+// Transfers are rejected when they touch one of the suspense accounts, which only adjustments book against
+func NewTransactionSvc(accountRepo ports.AccountRepository, transactionRepo ports.TransactionRepository, approvalRepo ports.TransferApprovalRepository, metrics ports.TransferMetrics, ids ports.AccountIDPolicy, approvals domain.TransferApprovalPolicy, suspense domain.SuspenseAccounts) *TransactionSvcImpl {
 	return &TransactionSvcImpl{
 		accountRepo:     accountRepo,
 		transactionRepo: transactionRepo,
+		approvalRepo:    approvalRepo,
+		metrics:         metrics,
+		ids:             ids,
+		approvals:       approvals,
+		suspense:        suspense,
 	}
 }
 
-// PostTransaction will accept a HTTP body containing a domain.Transaction object
-// The function will check if the inputs from domain.Transaction object are valid inputs
-// The function will check if the source account and destination account, denoted by SourceID and DestinationID, is a valid account within the system
-// The function will calculate the amount to be transferred from the balance of source account to destination account
-// The function will process the transaction according to the calculated balance of the source and destination account
+// Transfer will accept a domain.TransferCommand
+// The function will check if the inputs from domain.TransferCommand are valid inputs
+// The function will return a DUPLICATE_REFERENCE error if the client already made a transfer with the same reference that did not fail
+// The function will check if the source account and destination account, denoted by SourceID and DestinationID, is a valid account within the system other than a suspense account
+// The function will hold a transfer of more than the approval threshold for approval through ports.TransferApprovalRepository rather than process it, see requestApproval
+// The function will process the transaction through ports.TransactionRepository, which locks both accounts while applyTransfer calculates their new balances
 // The function will fix all calculated values to a floating point precision of 5
-// The function will return HTTP status OK and no body if the creation is successful
-func (srv *TransactionSvcImpl) PostTransaction(w http.ResponseWriter, r *http.Request) {
-	ctx := context.Background()
-	postTransactionBody := domain.Transaction{}
-	body, err := io.ReadAll(r.Body)
+// The function will return the processed transaction, or the transaction pending approval, as a domain.Transaction object and a *domain.Error if any check fails
+// The function will record the outcome of the transfer in ports.TransferMetrics once it is processed
+func (srv *TransactionSvcImpl) Transfer(ctx context.Context, cmd domain.TransferCommand) (domain.Transaction, error) {
+	transaction, transferAmount, err := srv.transfer(ctx, cmd)
 	if err != nil {
-		http.Error(w, static.ErrUnableToReadBody, http.StatusBadRequest)
-		return
+		slog.InfoContext(ctx, "transfer rejected", "source_account_id", cmd.SourceID, "destination_account_id", cmd.DestinationID, "amount", cmd.Amount, "code", errorCode(err))
+		srv.metrics.TransferFailed(errorCode(err))
+		return domain.Transaction{}, err
 	}
-	err = json.Unmarshal(body, &postTransactionBody)
-	if err != nil {
-		http.Error(w, static.ErrUnableToReadBody, http.StatusBadRequest)
-		return
+	if transaction.Status == domain.TransactionStatusPendingApproval {
+		slog.InfoContext(ctx, "transfer pending approval", "approval_id", transaction.ApprovalID, "source_account_id", transaction.SourceID, "destination_account_id", transaction.DestinationID, "amount", transaction.Amount)
+		return transaction, nil
 	}
-	if len(postTransactionBody.SourceID) == 0 || len(postTransactionBody.DestinationID) == 0 {
-		http.Error(w, static.ErrIDLengthCannotBeZero, http.StatusBadRequest)
-		return
+	slog.InfoContext(ctx, "transfer completed", "transaction_id", transaction.ID, "source_account_id", transaction.SourceID, "destination_account_id", transaction.DestinationID, "amount", transaction.Amount)
+	srv.metrics.TransferCompleted(transferAmount)
+	return transaction, nil
+}
+
+// transfer will validate and process a transfer for Transfer, or hold it for approval, returning the transaction and the amount moved
+func (srv *TransactionSvcImpl) transfer(ctx context.Context, cmd domain.TransferCommand) (domain.Transaction, float64, error) {
+	if err := srv.ids.Validate(cmd.SourceID); err != nil {
+		return domain.Transaction{}, 0, err
+	}
+	if err := srv.ids.Validate(cmd.DestinationID); err != nil {
+		return domain.Transaction{}, 0, err
 	}
-	if len(postTransactionBody.SourceID) > 32 || len(postTransactionBody.DestinationID) > 32 {
-		http.Error(w, static.ErrIDLengthTooLong, http.StatusBadRequest)
-		return
+	if cmd.SourceID == cmd.DestinationID {
+		return domain.Transaction{}, 0, domain.NewError(domain.ErrCodeSameAccountTransfer, static.ErrSourceDestinationSame)
 	}
-	if postTransactionBody.SourceID == postTransactionBody.DestinationID {
-		http.Error(w, static.ErrSourceDestinationSame, http.StatusBadRequest)
-		return
+	// A suspense account may be overdrawn by an adjustment, so an ordinary transfer from it would create money
+	if srv.suspense.Contains(cmd.SourceID) || srv.suspense.Contains(cmd.DestinationID) {
+		return domain.Transaction{}, 0, domain.NewError(domain.ErrCodeForbidden, static.ErrTransferSuspenseAccount)
 	}
-	sourceAccountExists := srv.accountRepo.CheckAccountExists(ctx, postTransactionBody.SourceID)
+	sourceAccountExists := srv.accountRepo.CheckAccountExists(ctx, cmd.SourceID)
 	if !sourceAccountExists {
-		http.Error(w, static.ErrSourceAccountDoesNotExist, http.StatusBadRequest)
-		return
+		return domain.Transaction{}, 0, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrSourceAccountDoesNotExist)
 	}
-	destinationAccountExists := srv.accountRepo.CheckAccountExists(ctx, postTransactionBody.SourceID)
+	destinationAccountExists := srv.accountRepo.CheckAccountExists(ctx, cmd.DestinationID)
 	if !destinationAccountExists {
-		http.Error(w, static.ErrDestinationAccountDoesNotExist, http.StatusBadRequest)
-		return
+		return domain.Transaction{}, 0, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrDestinationAccountDoesNotExist)
 	}
-	transferAmount, err := strconv.ParseFloat(postTransactionBody.Amount, 64)
+	transferAmount, err := parseTransferAmount(cmd.Amount)
 	if err != nil {
-		http.Error(w, static.ErrAmountNotValidNumber, http.StatusBadRequest)
-		return
+		return domain.Transaction{}, 0, err
 	}
-	if transferAmount <= 0 {
-		http.Error(w, static.ErrAmountCannotBeNegative, http.StatusBadRequest)
-		return
+	if string(cmd.Metadata) == "null" {
+		cmd.Metadata = nil
 	}
-	if transferAmount > math.MaxFloat64 {
-		http.Error(w, static.ErrAmountTooLarge, http.StatusBadRequest)
-		return
+	if err := validateTransferDetails(cmd); err != nil {
+		return domain.Transaction{}, 0, err
+	}
+
+	transaction := domain.Transaction{
+		SourceID:      cmd.SourceID,
+		DestinationID: cmd.DestinationID,
+		Amount:        utils.FormatAmount(transferAmount),
+		Description:   cmd.Description,
+		Reference:     cmd.Reference,
+		Metadata:      cmd.Metadata,
+		ClientID:      cmd.ClientID,
+	}
+	if srv.approvals.Threshold > 0 && transferAmount > srv.approvals.Threshold {
+		// The initiator must be authenticated, otherwise the reviewer could initiate the transfer themselves under another identity
+		if !domain.PrincipalFrom(ctx).Authenticated {
+			return domain.Transaction{}, 0, domain.NewError(domain.ErrCodeForbidden, static.ErrTransferApprovalAPIKeyRequired)
+		}
+		transaction, err = srv.requestApproval(ctx, transaction)
+		if err != nil {
+			return domain.Transaction{}, 0, err
+		}
+		return transaction, transferAmount, nil
+	}
+	transaction.ID, err = srv.process(ctx, transaction, transferAmount)
+	if err != nil {
+		return domain.Transaction{}, 0, err
+	}
+	transaction.Status = domain.TransactionStatusCompleted
+	return transaction, transferAmount, nil
+}
+
+// process will move the amount of a validated transaction from its source account to its destination account through ports.TransactionRepository
+// The function will return the id of the processed transaction and a *domain.Error if the transfer fails
+func (srv *TransactionSvcImpl) process(ctx context.Context, transaction domain.Transaction, transferAmount float64) (int64, error) {
+	id, err := srv.transactionRepo.ProcessTransaction(ctx, transaction, func(source domain.Account, destination domain.Account) (float64, float64, error) {
+		return applyTransfer(source, destination, transferAmount, false)
+	})
+	if err != nil {
+		return 0, transferError(err)
 	}
-	transferAmount = utils.ToFixed(transferAmount, 5)
+	return id, nil
+}
+
+// transferError will return the *domain.Error of an error a transfer failed with in ports.TransactionRepository
+func transferError(err error) error {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	if errors.Is(err, domain.ErrReferenceExists) {
+		return domain.WrapError(domain.ErrCodeDuplicateReference, static.ErrDuplicateReference, err)
+	}
+	return domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToCompleteTransaction, err)
+}
 
-	sourceAccount, err := srv.accountRepo.GetAccount(ctx, postTransactionBody.SourceID)
+// requestApproval will hold a validated transaction for approval through ports.TransferApprovalRepository, expiring after the expiry of the approval policy by the clock of the database
+// The actor of the domain.Principal carried by ctx is recorded as the initiator, who cannot approve the transfer, see ReviewTransfer
+// The balance of the source account is only checked once the transfer is approved
+// The function will return the transaction with the status pending_approval and the id of its approval, and a *domain.Error if it cannot be held
+func (srv *TransactionSvcImpl) requestApproval(ctx context.Context, transaction domain.Transaction) (domain.Transaction, error) {
+	approval, err := srv.approvalRepo.InsertTransferApproval(ctx, domain.TransferApproval{
+		SourceID:      transaction.SourceID,
+		DestinationID: transaction.DestinationID,
+		Amount:        transaction.Amount,
+		Description:   transaction.Description,
+		Reference:     transaction.Reference,
+		Metadata:      transaction.Metadata,
+		ClientID:      transaction.ClientID,
+		Status:        domain.TransferApprovalStatusPending,
+		InitiatedBy:   domain.PrincipalFrom(ctx).Actor,
+	}, srv.approvals.Expiry)
+	if errors.Is(err, domain.ErrReferenceExists) {
+		return domain.Transaction{}, domain.WrapError(domain.ErrCodeDuplicateReference, static.ErrDuplicateReference, err)
+	}
 	if err != nil {
-		log.Println("GetAccount - Source - error - ", err.Error())
-		http.Error(w, static.ErrGetSourceAccount, http.StatusInternalServerError)
-		return
+		return domain.Transaction{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToRequestApproval, err)
 	}
-	destinationAccount, err := srv.accountRepo.GetAccount(ctx, postTransactionBody.DestinationID)
+	transaction.Status = domain.TransactionStatusPendingApproval
+	transaction.ApprovalID = approval.ID
+	transaction.CreatedAt = approval.CreatedAt
+	return transaction, nil
+}
+
+// ReviewTransfer will accept a domain.ReviewTransferCommand
+// The function will check that the transfer is pending approval, has not expired and was requested by another actor than the reviewer, through ports.TransferApprovalRepository which locks it while it is reviewed
+// The function will execute an approved transfer as Transfer does, as a transfer of the client who requested it, in the DB transaction recording the approval as completed, or record the approval as failed
+// The function will return the reviewed approval as a domain.TransferApproval object and a *domain.Error if any check fails or the transfer fails
+// The function will record the outcome of an approved transfer in ports.TransferMetrics
+func (srv *TransactionSvcImpl) ReviewTransfer(ctx context.Context, cmd domain.ReviewTransferCommand) (domain.TransferApproval, error) {
+	// found and reviewErr tell an approval that does not exist or fails its checks from an approved transfer that fails
+	found := false
+	var reviewErr error
+	var transferAmount float64
+	approval, err := srv.approvalRepo.ReviewTransferApproval(ctx, cmd.ApprovalID, func(approval domain.TransferApproval) (domain.TransferApproval, error) {
+		found = true
+		approval, reviewErr = reviewTransfer(approval, cmd)
+		if reviewErr == nil && approval.Status == domain.TransferApprovalStatusApproved {
+			transferAmount, reviewErr = strconv.ParseFloat(approval.Amount, 64)
+		}
+		return approval, reviewErr
+	}, func(source domain.Account, destination domain.Account) (float64, float64, error) {
+		return applyTransfer(source, destination, transferAmount, false)
+	})
+	if err != nil && cmd.Approve && found && reviewErr == nil {
+		err = transferError(err)
+		slog.InfoContext(ctx, "approved transfer rejected", "approval_id", cmd.ApprovalID, "code", errorCode(err))
+		srv.metrics.TransferFailed(errorCode(err))
+		return domain.TransferApproval{}, err
+	}
+	if err != nil {
+		var domainErr *domain.Error
+		if errors.As(err, &domainErr) {
+			return domain.TransferApproval{}, err
+		}
+		if !found && errors.Is(err, sql.ErrNoRows) {
+			return domain.TransferApproval{}, domain.NewError(domain.ErrCodeApprovalNotFound, static.ErrApprovalDoesNotExist)
+		}
+		return domain.TransferApproval{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToReviewTransfer, err)
+	}
+	slog.InfoContext(ctx, "transfer reviewed", "approval_id", approval.ID, "status", approval.Status, "actor", cmd.Actor)
+	if approval.Status == domain.TransferApprovalStatusCompleted {
+		slog.InfoContext(ctx, "transfer completed", "transaction_id", approval.TransactionID, "approval_id", approval.ID, "source_account_id", approval.SourceID, "destination_account_id", approval.DestinationID, "amount", approval.Amount)
+		srv.metrics.TransferCompleted(transferAmount)
+	}
+	return approval, nil
+}
+
+// reviewTransfer will check that a transfer approval can be reviewed by cmd and return it approved or rejected by the actor of cmd
+// Expiry is decided by ports.TransferApprovalRepository, which reads an approval past its expiry as expired
+// The function will return a *domain.Error if the approval has expired, is not pending approval or was requested by the actor of cmd
+func reviewTransfer(approval domain.TransferApproval, cmd domain.ReviewTransferCommand) (domain.TransferApproval, error) {
+	if approval.Status == domain.TransferApprovalStatusExpired {
+		return domain.TransferApproval{}, domain.NewError(domain.ErrCodeApprovalExpired, static.ErrTransferApprovalExpired)
+	}
+	if approval.Status != domain.TransferApprovalStatusPending {
+		return domain.TransferApproval{}, domain.NewError(domain.ErrCodeNotPendingApproval, static.ErrTransferNotPending)
+	}
+	if approval.InitiatedBy == cmd.Actor {
+		return domain.TransferApproval{}, domain.NewError(domain.ErrCodeForbidden, static.ErrTransferSelfApproval)
+	}
+	approval.Status = domain.TransferApprovalStatusRejected
+	if cmd.Approve {
+		approval.Status = domain.TransferApprovalStatusApproved
+	}
+	approval.ReviewedBy = cmd.Actor
+	return approval, nil
+}
+
+// parseTransferAmount will parse the amount of a transfer, which must be a positive number
+// The function will return the amount fixed to a floating point precision of 5 and a *domain.Error if it is not valid
+func parseTransferAmount(amount string) (float64, error) {
+	transferAmount, err := strconv.ParseFloat(amount, 64)
 	if err != nil {
-		log.Println("GetAccount - Destination - error - ", err.Error())
-		http.Error(w, static.ErrGetDestinationAccount, http.StatusInternalServerError)
-		return
+		return 0, domain.NewError(domain.ErrCodeInvalidAmount, static.ErrAmountNotValidNumber)
+	}
+	if transferAmount <= 0 {
+		return 0, domain.NewError(domain.ErrCodeInvalidAmount, static.ErrAmountCannotBeNegative)
+	}
+	if transferAmount > math.MaxFloat64 {
+		return 0, domain.NewError(domain.ErrCodeInvalidAmount, static.ErrAmountTooLarge)
 	}
-	sourceAccountAmount, err := strconv.ParseFloat(sourceAccount.Balance, 64)
-	if sourceAccountAmount < transferAmount {
-		http.Error(w, static.ErrTransferAmountLargerThanAccount, http.StatusBadRequest)
-		return
+	return utils.ToFixed(transferAmount, 5), nil
+}
+
+// validateTransferDetails will check the optional description, reference and metadata of a domain.TransferCommand
+// Metadata must be a JSON object so that it can be queried as JSONB
+func validateTransferDetails(cmd domain.TransferCommand) error {
+	if utf8.RuneCountInString(cmd.Description) > maxDescriptionLength {
+		return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrDescriptionTooLong)
+	}
+	if utf8.RuneCountInString(cmd.Reference) > maxReferenceLength {
+		return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrReferenceTooLong)
 	}
+	if cmd.Metadata != nil {
+		var object map[string]json.RawMessage
+		if len(cmd.Metadata) > maxTransferMetadataBytes || json.Unmarshal(cmd.Metadata, &object) != nil || object == nil {
+			return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidTransferMetadata)
+		}
+	}
+	return nil
+}
+
+// applyTransfer will accept the source and destination accounts of a transfer, as locked by ports.TransactionRepository, and the amount to transfer
+// The function will check that both accounts hold the same currency and, unless overdraft is allowed as for the suspense account of an adjustment, that the source account has enough balance for the transfer
+// The function will return the new balances of the source and destination account fixed to a floating point precision of 5 and a *domain.Error if the check fails
+func applyTransfer(source domain.Account, destination domain.Account, transferAmount float64, overdraft bool) (float64, float64, error) {
+	if source.Currency != destination.Currency {
+		return 0, 0, domain.NewError(domain.ErrCodeCurrencyMismatch, static.ErrCurrencyMismatch)
+	}
+	sourceAccountAmount, err := strconv.ParseFloat(source.Balance, 64)
 	if err != nil {
-		http.Error(w, static.ErrAmountNotValidNumber, http.StatusBadRequest)
-		return
+		return 0, 0, domain.WrapError(domain.ErrCodeInternal, static.ErrGetSourceAccount, err)
+	}
+	if !overdraft && sourceAccountAmount < transferAmount {
+		return 0, 0, domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount)
 	}
-	destinationAccountAmount, err := strconv.ParseFloat(destinationAccount.Balance, 64)
+	destinationAccountAmount, err := strconv.ParseFloat(destination.Balance, 64)
 	if err != nil {
-		http.Error(w, static.ErrAmountNotValidNumber, http.StatusBadRequest)
-		return
+		return 0, 0, domain.WrapError(domain.ErrCodeInternal, static.ErrGetDestinationAccount, err)
 	}
 	sourceAccountAmount -= transferAmount
 	destinationAccountAmount += transferAmount
-	err = srv.transactionRepo.ProcessTransaction(ctx, postTransactionBody, utils.ToFixed(sourceAccountAmount, 5), utils.ToFixed(destinationAccountAmount, 5))
+	return utils.ToFixed(sourceAccountAmount, 5), utils.ToFixed(destinationAccountAmount, 5), nil
+}
+
+// ListTransactions will accept a domain.ListTransactionsQuery
+// The function will check if the account id is valid and belongs to an existing account
+// The function will default the page size when Limit is not set and cap it to maxTransactionListLimit
+// The function will return a page of the account's transactions, newest first, with the BeforeID to request for the next page
+func (srv *TransactionSvcImpl) ListTransactions(ctx context.Context, query domain.ListTransactionsQuery) (domain.TransactionList, error) {
+	if err := srv.ids.Validate(query.AccountID); err != nil {
+		return domain.TransactionList{}, err
+	}
+	accountExists := srv.accountRepo.CheckAccountExists(ctx, query.AccountID)
+	if !accountExists {
+		return domain.TransactionList{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist)
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultTransactionListLimit
+	}
+	if limit > maxTransactionListLimit {
+		limit = maxTransactionListLimit
+	}
+
+	// One extra row is fetched to tell whether there is a next page
+	transactions, err := srv.transactionRepo.ListTransactions(ctx, query.AccountID, query.BeforeID, limit+1)
+	if err != nil {
+		return domain.TransactionList{}, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToListTransactions, err)
+	}
+	response := domain.TransactionList{Transactions: transactions}
+	if len(transactions) > limit {
+		response.Transactions = transactions[:limit]
+		response.NextBeforeID = transactions[limit-1].ID
+	}
+	return response, nil
+}
+
+// Statement will accept a domain.StatementQuery and a ports.StatementWriter to write the statement of the account to
+// The function will check if the account id is valid and the period is valid and not in the future
+// The function will start the period at the creation of the account when From is not set or is before it and end it now when To is not set
+// The function will write the opening balance at From, see AccountRepository.GetBalanceAsOf, then every completed transfer in the period with the balance after it as it is read, and the closing balance
+// The function will fix all calculated values to a floating point precision of 5
+// The function will return a *domain.Error if any check fails before the header is written, and an error object if the statement cannot be read or written after
+func (srv *TransactionSvcImpl) Statement(ctx context.Context, query domain.StatementQuery, w ports.StatementWriter) error {
+	if err := srv.ids.Validate(query.AccountID); err != nil {
+		return err
+	}
+	to := time.Now()
+	if query.To != nil {
+		if query.To.After(to) {
+			return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrStatementToInFuture)
+		}
+		to = *query.To
+	}
+	var from time.Time
+	if query.From != nil {
+		if !query.From.Before(to) {
+			return domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidStatementPeriod)
+		}
+		from = *query.From
+	}
+
+	opening, err := srv.accountRepo.GetBalanceAsOf(ctx, query.AccountID, from)
+	if errors.Is(err, sql.ErrNoRows) {
+		return domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist)
+	}
+	if err != nil {
+		return domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToProduceStatement, err)
+	}
+	if to.Before(opening.CreatedAt) {
+		return domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountNotCreatedInPeriod)
+	}
+	if from.Before(opening.CreatedAt) {
+		// No transfer can predate the account, so its opening balance is the same at its creation
+		from = opening.CreatedAt
+	}
+
+	balance := utils.ToFixed(opening.Balance, 5)
+	statement := domain.Statement{
+		AccountID:      query.AccountID,
+		Currency:       opening.Currency,
+		From:           from.UTC(),
+		To:             to.UTC(),
+		OpeningBalance: utils.FormatAmount(balance),
+	}
+	if err := w.WriteHeader(statement); err != nil {
+		return err
+	}
+	err = srv.transactionRepo.StreamTransactions(ctx, query.AccountID, from, to, func(transaction domain.Transaction) error {
+		amount, err := strconv.ParseFloat(transaction.Amount, 64)
+		if err != nil {
+			return fmt.Errorf("transaction %d amount: %w", transaction.ID, err)
+		}
+		if transaction.CompletedAt == nil {
+			return fmt.Errorf("transaction %d has no completion time", transaction.ID)
+		}
+		counterparty := transaction.DestinationID
+		if transaction.DestinationID == query.AccountID {
+			counterparty = transaction.SourceID
+		} else {
+			amount = -amount
+		}
+		balance = utils.ToFixed(balance+amount, 5)
+		return w.WriteLine(domain.StatementLine{
+			TransactionID:  transaction.ID,
+			CompletedAt:    transaction.CompletedAt.UTC(),
+			CounterpartyID: counterparty,
+			Description:    transaction.Description,
+			Reference:      transaction.Reference,
+			Amount:         utils.FormatAmount(amount),
+			Balance:        utils.FormatAmount(balance),
+		})
+	})
 	if err != nil {
-		log.Println("UpdateTransaction error - ", err.Error())
-		http.Error(w, static.ErrUnableToCompleteTransaction, http.StatusInternalServerError)
-		return
+		return domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToProduceStatement, err)
 	}
-	utils.JSONResponse(w, http.StatusOK, nil)
+	statement.ClosingBalance = utils.FormatAmount(balance)
+	return w.WriteFooter(statement)
 }
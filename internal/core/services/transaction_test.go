@@ -2,241 +2,616 @@ package services
 
 import (
 	"account-test/internal/core/domain"
+	"account-test/internal/metrics"
 	mock_ports "account-test/internal/mocks/ports"
 	"account-test/static"
-	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
-	"io"
-	"net/http"
-	"net/http/httptest"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestPostTransaction(t *testing.T) {
+// decideWith returns a ProcessTransaction implementation running the transfer decision against the source and destination accounts
+func decideWith(source domain.Account, destination domain.Account) func(context.Context, domain.Transaction, domain.TransferDecision) (int64, error) {
+	return func(ctx context.Context, transaction domain.Transaction, decide domain.TransferDecision) (int64, error) {
+		if _, _, err := decide(source, destination); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+}
+
+func TestTransfer(t *testing.T) {
 	mockCtrl := gomock.NewController(t)
 	defer mockCtrl.Finish()
 
 	tests := []struct {
 		name            string
-		rec             *httptest.ResponseRecorder
-		body            map[string]interface{}
+		cmd             domain.TransferCommand
 		doMockAccRepo   func(repository *mock_ports.MockAccountRepository)
 		doMockTransRepo func(repository *mock_ports.MockTransactionRepository)
+		want            domain.Transaction
 		err             string
-		statusCode      int
+		code            domain.ErrorCode
 	}{
 		{
 			name: "Test Case Positive",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "19",
-			},
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
-				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
-				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
-					&domain.Account{ID: "123", Balance: "123"},
-					nil,
-				)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
-					&domain.Account{ID: "1234", Balance: "123"},
-					nil,
-				)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "1234").Return(true)
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
-				repository.EXPECT().ProcessTransaction(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+				repository.EXPECT().ProcessTransaction(gomock.Any(), domain.Transaction{SourceID: "123", DestinationID: "1234", Amount: "19"}, gomock.Any()).DoAndReturn(
+					func(ctx context.Context, transaction domain.Transaction, decide domain.TransferDecision) (int64, error) {
+						sourceBalance, destinationBalance, err := decide(domain.Account{ID: "123", Balance: "123"}, domain.Account{ID: "1234", Balance: "123"})
+						assert.NoError(t, err)
+						assert.Equal(t, float64(104), sourceBalance)
+						assert.Equal(t, float64(142), destinationBalance)
+						return 1, nil
+					},
+				)
 			},
-			err: "",
+			want: domain.Transaction{ID: 1, SourceID: "123", DestinationID: "1234", Amount: "19", Status: domain.TransactionStatusCompleted},
+			err:  "",
 		},
 		{
 			name: "Test Case Negative - Empty account ID",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "",
-				"destination_account_id": "1234",
-				"amount":                 "19",
-			},
+			cmd:  domain.TransferCommand{SourceID: "", DestinationID: "1234", Amount: "19"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
-			err:        static.ErrIDLengthCannotBeZero,
-			statusCode: 400,
+			err:  static.ErrIDLengthCannotBeZero,
+			code: domain.ErrCodeInvalidAccountID,
 		},
 		{
 			name: "Test Case Negative - Account ID too long",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "12341239172491274912749124912894129847129471294912748492184",
-				"amount":                 "19",
-			},
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "12341239172491274912749124912894129847129471294912748492184", Amount: "19"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
-			err:        static.ErrIDLengthTooLong,
-			statusCode: 400,
+			err:  static.ErrIDLengthTooLong,
+			code: domain.ErrCodeInvalidAccountID,
 		},
 		{
 			name: "Test Case Negative - Source and Destination account the same",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "123",
-				"amount":                 "19",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "123", Amount: "19"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+			},
+			err:  static.ErrSourceDestinationSame,
+			code: domain.ErrCodeSameAccountTransfer,
+		},
+		{
+			name: "Test Case Negative - Source is a suspense account",
+			cmd:  domain.TransferCommand{SourceID: "SUSPENSE", DestinationID: "1234", Amount: "19"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
-			err:        static.ErrSourceDestinationSame,
-			statusCode: 400,
+			err:  static.ErrTransferSuspenseAccount,
+			code: domain.ErrCodeForbidden,
 		},
 		{
-			name: "Test Case Negative - Source account does not exist",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "19",
+			name: "Test Case Negative - Destination is a suspense account",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "SUSPENSE-EUR", Amount: "19"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
+			err:  static.ErrTransferSuspenseAccount,
+			code: domain.ErrCodeForbidden,
+		},
+		{
+			name: "Test Case Negative - Source account does not exist",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
-				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(false)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
-			err:        static.ErrSourceAccountDoesNotExist,
-			statusCode: 400,
+			err:  static.ErrSourceAccountDoesNotExist,
+			code: domain.ErrCodeAccountNotFound,
 		},
 		{
 			name: "Test Case Negative - Destination account does not exist",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "19",
-			},
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
-				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
-				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(false)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "1234").Return(false)
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
-			err:        static.ErrDestinationAccountDoesNotExist,
-			statusCode: 400,
+			err:  static.ErrDestinationAccountDoesNotExist,
+			code: domain.ErrCodeAccountNotFound,
 		},
 		{
 			name: "Test Case Negative - Invalid amount",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "abc",
-			},
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "abc"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
-			err:        static.ErrAmountNotValidNumber,
-			statusCode: 400,
+			err:  static.ErrAmountNotValidNumber,
+			code: domain.ErrCodeInvalidAmount,
 		},
 		{
 			name: "Test Case Negative - Negative amount",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "-10",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "-10"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
+			err:  static.ErrAmountCannotBeNegative,
+			code: domain.ErrCodeInvalidAmount,
+		},
+		{
+			name: "Test Case Negative - Invalid source balance",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "100"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ProcessTransaction(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					decideWith(domain.Account{ID: "123", Balance: "abc"}, domain.Account{ID: "1234", Balance: "123"}),
+				)
 			},
-			err:        static.ErrAmountCannotBeNegative,
-			statusCode: 400,
+			err:  static.ErrGetSourceAccount,
+			code: domain.ErrCodeInternal,
 		},
 		{
-			name: "Test Case Negative - GetAccount error",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "100",
+			name: "Test Case Negative - Amount greater than source account balance",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "500"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ProcessTransaction(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					decideWith(domain.Account{ID: "123", Balance: "123"}, domain.Account{ID: "1234", Balance: "123"}),
+				)
+			},
+			err:  static.ErrTransferAmountLargerThanAccount,
+			code: domain.ErrCodeInsufficientFunds,
+		},
+		{
+			name: "Test Case Negative - Currency mismatch",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "10"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
-					nil,
-					errors.New("random error"),
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ProcessTransaction(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					decideWith(domain.Account{ID: "123", Balance: "100", Currency: "USD"}, domain.Account{ID: "1234", Balance: "100", Currency: "EUR"}),
 				)
 			},
+			err:  static.ErrCurrencyMismatch,
+			code: domain.ErrCodeCurrencyMismatch,
+		},
+		{
+			name: "Test Case Negative - ProcessTransaction error",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "100"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
+			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ProcessTransaction(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), errors.New("random error"))
 			},
-			err:        static.ErrGetSourceAccount,
-			statusCode: 500,
+			err:  static.ErrUnableToCompleteTransaction,
+			code: domain.ErrCodeInternal,
 		},
 		{
-			name: "Test Case Negative - Amount greater than source account balance",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "500",
+			name: "Test Case Positive - With details",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19", Description: "March salary", Reference: "payroll-2024-03", Metadata: json.RawMessage(`{"employee":42}`), ClientID: "ip:192.0.2.1"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ProcessTransaction(gomock.Any(), domain.Transaction{
+					SourceID:      "123",
+					DestinationID: "1234",
+					Amount:        "19",
+					Description:   "March salary",
+					Reference:     "payroll-2024-03",
+					Metadata:      json.RawMessage(`{"employee":42}`),
+					ClientID:      "ip:192.0.2.1",
+				}, gomock.Any()).Return(int64(1), nil)
+			},
+			want: domain.Transaction{ID: 1, SourceID: "123", DestinationID: "1234", Amount: "19", Description: "March salary", Reference: "payroll-2024-03", Metadata: json.RawMessage(`{"employee":42}`), ClientID: "ip:192.0.2.1", Status: domain.TransactionStatusCompleted},
+		},
+		{
+			name: "Test Case Negative - Metadata not an object",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19", Metadata: json.RawMessage(`["salary"]`)},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
-					&domain.Account{ID: "123", Balance: "123"},
-					nil,
-				)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
-					&domain.Account{ID: "1234", Balance: "123"},
-					nil,
-				)
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
 			},
-			statusCode: 400,
-			err:        static.ErrTransferAmountLargerThanAccount,
+			err:  static.ErrInvalidTransferMetadata,
+			code: domain.ErrCodeMalformedRequest,
 		},
 		{
-			name: "Test Case Negative - ProcessTransaction error",
-			rec:  httptest.NewRecorder(),
-			body: map[string]interface{}{
-				"source_account_id":      "123",
-				"destination_account_id": "1234",
-				"amount":                 "100",
+			name: "Test Case Negative - Reference too long",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19", Reference: strings.Repeat("r", maxReferenceLength+1)},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
+				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+			},
+			err:  static.ErrReferenceTooLong,
+			code: domain.ErrCodeMalformedRequest,
+		},
+		{
+			name: "Test Case Negative - Duplicate reference",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19", Reference: "payroll-2024-03"},
 			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
 				repository.EXPECT().CheckAccountExists(gomock.Any(), gomock.Any()).Return(true)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
-					&domain.Account{ID: "123", Balance: "123"},
-					nil,
-				)
-				repository.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Return(
-					&domain.Account{ID: "1234", Balance: "123"},
-					nil,
-				)
 			},
 			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
-				repository.EXPECT().ProcessTransaction(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("random error"))
+				repository.EXPECT().ProcessTransaction(gomock.Any(), gomock.Any(), gomock.Any()).Return(int64(0), fmt.Errorf("%w: payroll-2024-03", domain.ErrReferenceExists))
+			},
+			err:  static.ErrDuplicateReference,
+			code: domain.ErrCodeDuplicateReference,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			mockTransRepo := mock_ports.NewMockTransactionRepository(mockCtrl)
+			tc.doMockAccRepo(mockAccRepo)
+			tc.doMockTransRepo(mockTransRepo)
+			transferMetrics := metrics.New()
+			transSvc := NewTransactionSvc(mockAccRepo, mockTransRepo, nil, transferMetrics, newTextIDPolicy(t), domain.TransferApprovalPolicy{}, testSuspenseAccounts)
+			transaction, err := transSvc.Transfer(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+				assert.Equal(t, float64(1), testutil.ToFloat64(transferMetrics.TransfersFailed(tc.code)))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, transaction)
+				assert.Equal(t, float64(1), testutil.ToFloat64(transferMetrics.TransfersCompleted()))
+			}
+		})
+	}
+}
+
+var testTransferApprovalPolicy = domain.TransferApprovalPolicy{Threshold: 1000, Expiry: time.Hour}
+
+func TestTransferPendingApproval(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name               string
+		cmd                domain.TransferCommand
+		principal          *domain.Principal
+		doMockApprovalRepo func(repository *mock_ports.MockTransferApprovalRepository)
+		want               domain.Transaction
+		err                string
+		code               domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "1000.5", Reference: "payroll-2024-03", ClientID: "key:abc"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().InsertTransferApproval(gomock.Any(), gomock.Any(), time.Hour).DoAndReturn(
+					func(ctx context.Context, approval domain.TransferApproval, expiry time.Duration) (domain.TransferApproval, error) {
+						assert.Equal(t, "1000.5", approval.Amount)
+						assert.Equal(t, "key:abc", approval.ClientID)
+						assert.Equal(t, "key:abc", approval.InitiatedBy)
+						assert.Equal(t, domain.TransferApprovalStatusPending, approval.Status)
+						approval.ID = 7
+						approval.CreatedAt = createdAt
+						return approval, nil
+					})
+			},
+			want: domain.Transaction{
+				SourceID:      "123",
+				DestinationID: "1234",
+				Amount:        "1000.5",
+				Reference:     "payroll-2024-03",
+				ClientID:      "key:abc",
+				Status:        domain.TransactionStatusPendingApproval,
+				ApprovalID:    7,
+				CreatedAt:     createdAt,
+			},
+		},
+		{
+			name: "Test Case Negative - Duplicate pending reference",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "5000", Reference: "payroll-2024-03", ClientID: "key:abc"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().InsertTransferApproval(gomock.Any(), gomock.Any(), time.Hour).Return(domain.TransferApproval{}, fmt.Errorf("%w: payroll-2024-03", domain.ErrReferenceExists))
+			},
+			err:  static.ErrDuplicateReference,
+			code: domain.ErrCodeDuplicateReference,
+		},
+		{
+			name: "Test Case Negative - Repository error",
+			cmd:  domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "5000", ClientID: "key:abc"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().InsertTransferApproval(gomock.Any(), gomock.Any(), time.Hour).Return(domain.TransferApproval{}, errors.New("connection refused"))
+			},
+			err:  static.ErrUnableToRequestApproval,
+			code: domain.ErrCodeInternal,
+		},
+		{
+			name:               "Test Case Negative - Initiator without an admin API key",
+			cmd:                domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "5000", ClientID: "key:made-up"},
+			principal:          &domain.Principal{Actor: "key:made-up"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {},
+			err:                static.ErrTransferApprovalAPIKeyRequired,
+			code:               domain.ErrCodeForbidden,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			mockTransRepo := mock_ports.NewMockTransactionRepository(mockCtrl)
+			mockApprovalRepo := mock_ports.NewMockTransferApprovalRepository(mockCtrl)
+			mockAccRepo.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+			mockAccRepo.EXPECT().CheckAccountExists(gomock.Any(), "1234").Return(true)
+			tc.doMockApprovalRepo(mockApprovalRepo)
+			transferMetrics := metrics.New()
+			transSvc := NewTransactionSvc(mockAccRepo, mockTransRepo, mockApprovalRepo, transferMetrics, newTextIDPolicy(t), testTransferApprovalPolicy, testSuspenseAccounts)
+			principal := domain.Principal{Actor: "key:abc", Authenticated: true}
+			if tc.principal != nil {
+				principal = *tc.principal
+			}
+			transaction, err := transSvc.Transfer(domain.WithPrincipal(context.Background(), principal), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, transaction)
+				assert.Equal(t, float64(0), testutil.ToFloat64(transferMetrics.TransfersCompleted()))
+			}
+		})
+	}
+}
+
+func TestReviewTransfer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	pending := domain.TransferApproval{
+		ID:            7,
+		SourceID:      "123",
+		DestinationID: "1234",
+		Amount:        "5000",
+		Reference:     "payroll-2024-03",
+		ClientID:      "key:abc",
+		Status:        domain.TransferApprovalStatusPending,
+		InitiatedBy:   "key:abc",
+		ExpiresAt:     time.Now().Add(time.Hour),
+	}
+	// reviewWith returns a ReviewTransferApproval implementation running the review against the approval
+	// An approved transfer is decided from a source account holding sourceBalance and completed as transaction 1
+	reviewWith := func(approval domain.TransferApproval, sourceBalance string) func(context.Context, int64, domain.TransferApprovalReview, domain.TransferDecision) (domain.TransferApproval, error) {
+		return func(ctx context.Context, id int64, review domain.TransferApprovalReview, decide domain.TransferDecision) (domain.TransferApproval, error) {
+			reviewed, err := review(approval)
+			if err != nil || reviewed.Status != domain.TransferApprovalStatusApproved {
+				return reviewed, err
+			}
+			if _, _, err := decide(domain.Account{ID: approval.SourceID, Balance: sourceBalance}, domain.Account{ID: approval.DestinationID, Balance: "0"}); err != nil {
+				return domain.TransferApproval{}, err
+			}
+			reviewed.Status = domain.TransferApprovalStatusCompleted
+			reviewed.TransactionID = 1
+			return reviewed, nil
+		}
+	}
+	tests := []struct {
+		name               string
+		cmd                domain.ReviewTransferCommand
+		doMockApprovalRepo func(repository *mock_ports.MockTransferApprovalRepository)
+		want               domain.TransferApproval
+		err                string
+		code               domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive - Approved",
+			cmd:  domain.ReviewTransferCommand{ApprovalID: 7, Approve: true, Actor: "key:def"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().ReviewTransferApproval(gomock.Any(), int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(pending, "6000"))
+			},
+			want: func() domain.TransferApproval {
+				approval := pending
+				approval.Status = domain.TransferApprovalStatusCompleted
+				approval.ReviewedBy = "key:def"
+				approval.TransactionID = 1
+				return approval
+			}(),
+		},
+		{
+			name: "Test Case Positive - Rejected",
+			cmd:  domain.ReviewTransferCommand{ApprovalID: 7, Actor: "key:def"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().ReviewTransferApproval(gomock.Any(), int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(pending, "6000"))
+			},
+			want: func() domain.TransferApproval {
+				approval := pending
+				approval.Status = domain.TransferApprovalStatusRejected
+				approval.ReviewedBy = "key:def"
+				return approval
+			}(),
+		},
+		{
+			name: "Test Case Negative - Insufficient funds once approved",
+			cmd:  domain.ReviewTransferCommand{ApprovalID: 7, Approve: true, Actor: "key:def"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().ReviewTransferApproval(gomock.Any(), int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(pending, "100"))
+			},
+			err:  static.ErrTransferAmountLargerThanAccount,
+			code: domain.ErrCodeInsufficientFunds,
+		},
+		{
+			name: "Test Case Negative - Approved by initiator",
+			cmd:  domain.ReviewTransferCommand{ApprovalID: 7, Approve: true, Actor: "key:abc"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().ReviewTransferApproval(gomock.Any(), int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(pending, "6000"))
+			},
+			err:  static.ErrTransferSelfApproval,
+			code: domain.ErrCodeForbidden,
+		},
+		{
+			name: "Test Case Negative - Expired",
+			cmd:  domain.ReviewTransferCommand{ApprovalID: 7, Approve: true, Actor: "key:def"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				stale := pending
+				stale.Status = domain.TransferApprovalStatusExpired
+				repository.EXPECT().ReviewTransferApproval(gomock.Any(), int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(stale, "6000"))
+			},
+			err:  static.ErrTransferApprovalExpired,
+			code: domain.ErrCodeApprovalExpired,
+		},
+		{
+			name: "Test Case Negative - Already reviewed",
+			cmd:  domain.ReviewTransferCommand{ApprovalID: 7, Approve: true, Actor: "key:def"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				rejected := pending
+				rejected.Status = domain.TransferApprovalStatusRejected
+				repository.EXPECT().ReviewTransferApproval(gomock.Any(), int64(7), gomock.Any(), gomock.Any()).DoAndReturn(reviewWith(rejected, "6000"))
+			},
+			err:  static.ErrTransferNotPending,
+			code: domain.ErrCodeNotPendingApproval,
+		},
+		{
+			name: "Test Case Negative - Approval does not exist",
+			cmd:  domain.ReviewTransferCommand{ApprovalID: 8, Approve: true, Actor: "key:def"},
+			doMockApprovalRepo: func(repository *mock_ports.MockTransferApprovalRepository) {
+				repository.EXPECT().ReviewTransferApproval(gomock.Any(), int64(8), gomock.Any(), gomock.Any()).Return(domain.TransferApproval{}, sql.ErrNoRows)
+			},
+			err:  static.ErrApprovalDoesNotExist,
+			code: domain.ErrCodeApprovalNotFound,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			mockTransRepo := mock_ports.NewMockTransactionRepository(mockCtrl)
+			mockApprovalRepo := mock_ports.NewMockTransferApprovalRepository(mockCtrl)
+			tc.doMockApprovalRepo(mockApprovalRepo)
+			transSvc := NewTransactionSvc(mockAccRepo, mockTransRepo, mockApprovalRepo, metrics.New(), newTextIDPolicy(t), testTransferApprovalPolicy, testSuspenseAccounts)
+			approval, err := transSvc.ReviewTransfer(context.Background(), tc.cmd)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, approval)
+			}
+		})
+	}
+}
+
+func TestListTransactions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	transactions := []domain.Transaction{
+		{ID: 3, SourceID: "123", DestinationID: "1234", Amount: "1", Status: domain.TransactionStatusCompleted},
+		{ID: 2, SourceID: "1234", DestinationID: "123", Amount: "2", Status: domain.TransactionStatusFailed},
+		{ID: 1, SourceID: "123", DestinationID: "1234", Amount: "3", Status: domain.TransactionStatusCompleted},
+	}
+
+	tests := []struct {
+		name            string
+		query           domain.ListTransactionsQuery
+		doMockAccRepo   func(repository *mock_ports.MockAccountRepository)
+		doMockTransRepo func(repository *mock_ports.MockTransactionRepository)
+		want            domain.TransactionList
+		err             string
+		code            domain.ErrorCode
+	}{
+		{
+			name:  "Test Case Positive - Default limit",
+			query: domain.ListTransactionsQuery{AccountID: "123"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ListTransactions(gomock.Any(), "123", int64(0), 51).Return(transactions, nil)
 			},
-			statusCode: 500,
-			err:        static.ErrUnableToCompleteTransaction,
+			want: domain.TransactionList{Transactions: transactions},
+		},
+		{
+			name:  "Test Case Positive - Next page",
+			query: domain.ListTransactionsQuery{AccountID: "123", Limit: 2, BeforeID: 10},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ListTransactions(gomock.Any(), "123", int64(10), 3).Return(transactions, nil)
+			},
+			want: domain.TransactionList{Transactions: transactions[:2], NextBeforeID: 2},
+		},
+		{
+			name:  "Test Case Positive - Limit capped",
+			query: domain.ListTransactionsQuery{AccountID: "123", Limit: 1000},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ListTransactions(gomock.Any(), "123", int64(0), 101).Return([]domain.Transaction{}, nil)
+			},
+			want: domain.TransactionList{Transactions: []domain.Transaction{}},
+		},
+		{
+			name:  "Test Case Negative - Account does not exist",
+			query: domain.ListTransactionsQuery{AccountID: "123"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(false)
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+			},
+			err:  static.ErrAccountDoesNotExist,
+			code: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:  "Test Case Negative - Repository error",
+			query: domain.ListTransactionsQuery{AccountID: "123"},
+			doMockAccRepo: func(repository *mock_ports.MockAccountRepository) {
+				repository.EXPECT().CheckAccountExists(gomock.Any(), "123").Return(true)
+			},
+			doMockTransRepo: func(repository *mock_ports.MockTransactionRepository) {
+				repository.EXPECT().ListTransactions(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("random error"))
+			},
+			err:  static.ErrUnableToListTransactions,
+			code: domain.ErrCodeInternal,
 		},
 	}
 	for _, tc := range tests {
@@ -245,18 +620,158 @@ func TestPostTransaction(t *testing.T) {
 			mockTransRepo := mock_ports.NewMockTransactionRepository(mockCtrl)
 			tc.doMockAccRepo(mockAccRepo)
 			tc.doMockTransRepo(mockTransRepo)
-			transSvc := NewTransactionSvc(mockAccRepo, mockTransRepo)
-			handler := http.HandlerFunc(transSvc.PostTransaction)
-			body, _ := json.Marshal(tc.body)
-			req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(body))
-			handler.ServeHTTP(tc.rec, req)
+			transSvc := NewTransactionSvc(mockAccRepo, mockTransRepo, nil, metrics.New(), newTextIDPolicy(t), domain.TransferApprovalPolicy{}, testSuspenseAccounts)
+			response, err := transSvc.ListTransactions(context.Background(), tc.query)
+
+			if len(tc.err) > 0 {
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
+
+// recordingStatementWriter is a ports.StatementWriter keeping what is written to it
+type recordingStatementWriter struct {
+	header *domain.Statement
+	lines  []domain.StatementLine
+	footer *domain.Statement
+}
+
+func (w *recordingStatementWriter) WriteHeader(statement domain.Statement) error {
+	w.header = &statement
+	return nil
+}
+
+func (w *recordingStatementWriter) WriteLine(line domain.StatementLine) error {
+	w.lines = append(w.lines, line)
+	return nil
+}
+
+func (w *recordingStatementWriter) WriteFooter(statement domain.Statement) error {
+	w.footer = &statement
+	return nil
+}
+
+func TestStatement(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	createdAt := time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	lineAt := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	// Transaction 7 was requested before the period and completed in it, so its line is dated by its completion
+	completedAfterFrom := from.Add(time.Second)
+	transactions := []domain.Transaction{
+		{ID: 7, SourceID: "456", DestinationID: "123", Amount: "0.1", CreatedAt: from.Add(-time.Second), CompletedAt: &completedAfterFrom},
+		{ID: 8, SourceID: "123", DestinationID: "789", Amount: "25.2", Description: "Rent", Reference: "rent-03", CreatedAt: lineAt, CompletedAt: &lineAt},
+	}
+	stream := func(ctx context.Context, accountID string, from time.Time, to time.Time, each func(domain.Transaction) error) error {
+		for _, transaction := range transactions {
+			if err := each(transaction); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tests := []struct {
+		name        string
+		query       domain.StatementQuery
+		doMockRepo  func(accounts *mock_ports.MockAccountRepository, transactions *mock_ports.MockTransactionRepository)
+		wantHeader  *domain.Statement
+		wantLines   []domain.StatementLine
+		wantClosing string
+		err         string
+		code        domain.ErrorCode
+	}{
+		{
+			name:  "Test Case Positive",
+			query: domain.StatementQuery{AccountID: "123", From: &from, To: &to},
+			doMockRepo: func(accounts *mock_ports.MockAccountRepository, transactionRepo *mock_ports.MockTransactionRepository) {
+				accounts.EXPECT().GetBalanceAsOf(gomock.Any(), "123", from).Return(&domain.HistoricalBalance{Balance: 100.2, Currency: "EUR", CreatedAt: createdAt}, nil)
+				transactionRepo.EXPECT().StreamTransactions(gomock.Any(), "123", from, to, gomock.Any()).DoAndReturn(stream)
+			},
+			wantHeader: &domain.Statement{AccountID: "123", Currency: "EUR", From: from, To: to, OpeningBalance: "100.2"},
+			wantLines: []domain.StatementLine{
+				{TransactionID: 7, CompletedAt: completedAfterFrom, CounterpartyID: "456", Amount: "0.1", Balance: "100.3"},
+				{TransactionID: 8, CompletedAt: lineAt, CounterpartyID: "789", Description: "Rent", Reference: "rent-03", Amount: "-25.2", Balance: "75.1"},
+			},
+			wantClosing: "75.1",
+		},
+		{
+			name:  "Test Case Positive - Period starts at the creation of the account",
+			query: domain.StatementQuery{AccountID: "123", To: &to},
+			doMockRepo: func(accounts *mock_ports.MockAccountRepository, transactionRepo *mock_ports.MockTransactionRepository) {
+				accounts.EXPECT().GetBalanceAsOf(gomock.Any(), "123", time.Time{}).Return(&domain.HistoricalBalance{Balance: 50, Currency: "USD", CreatedAt: createdAt}, nil)
+				transactionRepo.EXPECT().StreamTransactions(gomock.Any(), "123", createdAt, to, gomock.Any()).Return(nil)
+			},
+			wantHeader:  &domain.Statement{AccountID: "123", Currency: "USD", From: createdAt, To: to, OpeningBalance: "50"},
+			wantClosing: "50",
+		},
+		{
+			name:  "Test Case Negative - from after to",
+			query: domain.StatementQuery{AccountID: "123", From: &to, To: &from},
+			doMockRepo: func(accounts *mock_ports.MockAccountRepository, transactionRepo *mock_ports.MockTransactionRepository) {
+			},
+			err:  static.ErrInvalidStatementPeriod,
+			code: domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:  "Test Case Negative - Account does not exist",
+			query: domain.StatementQuery{AccountID: "123"},
+			doMockRepo: func(accounts *mock_ports.MockAccountRepository, transactionRepo *mock_ports.MockTransactionRepository) {
+				accounts.EXPECT().GetBalanceAsOf(gomock.Any(), "123", time.Time{}).Return(nil, sql.ErrNoRows)
+			},
+			err:  static.ErrAccountDoesNotExist,
+			code: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:  "Test Case Negative - Account created after the period",
+			query: domain.StatementQuery{AccountID: "123", To: &from},
+			doMockRepo: func(accounts *mock_ports.MockAccountRepository, transactionRepo *mock_ports.MockTransactionRepository) {
+				accounts.EXPECT().GetBalanceAsOf(gomock.Any(), "123", time.Time{}).Return(&domain.HistoricalBalance{Balance: 50, Currency: "USD", CreatedAt: to}, nil)
+			},
+			err:  static.ErrAccountNotCreatedInPeriod,
+			code: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:  "Test Case Negative - Stream error after the header",
+			query: domain.StatementQuery{AccountID: "123", From: &from, To: &to},
+			doMockRepo: func(accounts *mock_ports.MockAccountRepository, transactionRepo *mock_ports.MockTransactionRepository) {
+				accounts.EXPECT().GetBalanceAsOf(gomock.Any(), "123", from).Return(&domain.HistoricalBalance{Balance: 100, Currency: "USD", CreatedAt: createdAt}, nil)
+				transactionRepo.EXPECT().StreamTransactions(gomock.Any(), "123", from, to, gomock.Any()).Return(errors.New("random error"))
+			},
+			wantHeader: &domain.Statement{AccountID: "123", Currency: "USD", From: from, To: to, OpeningBalance: "100"},
+			err:        static.ErrUnableToProduceStatement,
+			code:       domain.ErrCodeInternal,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccRepo := mock_ports.NewMockAccountRepository(mockCtrl)
+			mockTransRepo := mock_ports.NewMockTransactionRepository(mockCtrl)
+			tc.doMockRepo(mockAccRepo, mockTransRepo)
+			transSvc := NewTransactionSvc(mockAccRepo, mockTransRepo, nil, metrics.New(), newTextIDPolicy(t), domain.TransferApprovalPolicy{}, testSuspenseAccounts)
+			writer := &recordingStatementWriter{}
+			err := transSvc.Statement(context.Background(), tc.query, writer)
 
+			assert.Equal(t, tc.wantHeader, writer.header)
+			assert.Equal(t, tc.wantLines, writer.lines)
 			if len(tc.err) > 0 {
-				bodyBytes, _ := io.ReadAll(tc.rec.Body)
-				assert.Contains(t, string(bodyBytes), tc.err)
-				assert.Equal(t, tc.statusCode, tc.rec.Result().StatusCode)
+				var domainErr *domain.Error
+				assert.ErrorAs(t, err, &domainErr)
+				assert.Equal(t, tc.err, domainErr.Message)
+				assert.Equal(t, tc.code, domainErr.Code)
+				assert.Nil(t, writer.footer)
 			} else {
-				assert.Equal(t, 200, tc.rec.Result().StatusCode)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantClosing, writer.footer.ClosingBalance)
 			}
 		})
 	}
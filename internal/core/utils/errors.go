@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/static"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+)
+
+const ContentTypeProblemJSON = "application/problem+json"
+
+var errorCodeStatus = map[domain.ErrorCode]int{
+	domain.ErrCodeMalformedRequest:     http.StatusBadRequest,
+	domain.ErrCodeRequestTooLarge:      http.StatusRequestEntityTooLarge,
+	domain.ErrCodeInvalidAccountID:     http.StatusBadRequest,
+	domain.ErrCodeInvalidAmount:        http.StatusUnprocessableEntity,
+	domain.ErrCodeAccountNotFound:      http.StatusNotFound,
+	domain.ErrCodeAccountAlreadyExists: http.StatusConflict,
+	domain.ErrCodeSameAccountTransfer:  http.StatusUnprocessableEntity,
+	domain.ErrCodeInsufficientFunds:    http.StatusUnprocessableEntity,
+	domain.ErrCodeInvalidCurrency:      http.StatusBadRequest,
+	domain.ErrCodeCurrencyMismatch:     http.StatusUnprocessableEntity,
+	domain.ErrCodeDuplicateReference:   http.StatusConflict,
+	domain.ErrCodeForbidden:            http.StatusForbidden,
+	domain.ErrCodeAdjustmentNotFound:   http.StatusNotFound,
+	domain.ErrCodeNotPendingApproval:   http.StatusConflict,
+	domain.ErrCodeApprovalNotFound:     http.StatusNotFound,
+	domain.ErrCodeApprovalExpired:      http.StatusConflict,
+	domain.ErrCodeRateLimited:          http.StatusTooManyRequests,
+	domain.ErrCodeTimeout:              http.StatusGatewayTimeout,
+	domain.ErrCodeInternal:             http.StatusInternalServerError,
+}
+
+// ErrorStatus will return the HTTP status code for an ErrorCode, defaulting to HTTP status 500 for unknown codes
+func ErrorStatus(code domain.ErrorCode) int {
+	if status, ok := errorCodeStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// ToDomainError will accept the context of a request and an error returned while serving it
+// the function will return the *domain.Error found in err, wrapping any other error as INTERNAL_ERROR with a generic message
+// the function will report an INTERNAL_ERROR as REQUEST_TIMEOUT when the request deadline has passed, as the failure was caused by the deadline
+func ToDomainError(ctx context.Context, err error) *domain.Error {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) {
+		domainErr = domain.WrapError(domain.ErrCodeInternal, static.ErrInternal, err)
+	}
+	if domainErr.Code == domain.ErrCodeInternal && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		domainErr = domain.WrapError(domain.ErrCodeTimeout, static.ErrRequestTimeout, err)
+	}
+	return domainErr
+}
+
+// ErrorResponse will accept an error and write it to the response writer as an RFC 7807 problem+json body
+// the function will use the code and message of a domain.Error to build the body and pick the HTTP status code
+// the function will respond with INTERNAL_ERROR and a generic message for any other error so internal details are not leaked, see ToDomainError
+func ErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	domainErr := ToDomainError(r.Context(), err)
+	if domainErr.Err != nil {
+		slog.ErrorContext(r.Context(), "request failed", "method", r.Method, "path", r.URL.Path, "code", domainErr.Code, "error", domainErr.Error())
+	} else {
+		slog.DebugContext(r.Context(), "request rejected", "method", r.Method, "path", r.URL.Path, "code", domainErr.Code)
+	}
+
+	status := ErrorStatus(domainErr.Code)
+	problem := domain.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   domainErr.Message,
+		Instance: r.URL.Path,
+		Code:     domainErr.Code,
+	}
+	response, _ := json.Marshal(problem)
+	w.Header().Set("Content-Type", ContentTypeProblemJSON)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(response)
+}
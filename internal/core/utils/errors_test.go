@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/static"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want domain.Problem
+	}{
+		{
+			name: "Test Case Positive - Not found",
+			err:  domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist),
+			want: domain.Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: static.ErrAccountDoesNotExist, Instance: "/accounts/123", Code: domain.ErrCodeAccountNotFound},
+		},
+		{
+			name: "Test Case Positive - Wrapped domain error",
+			err:  fmt.Errorf("outer: %w", domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount)),
+			want: domain.Problem{Type: "about:blank", Title: "Unprocessable Entity", Status: 422, Detail: static.ErrTransferAmountLargerThanAccount, Instance: "/accounts/123", Code: domain.ErrCodeInsufficientFunds},
+		},
+		{
+			name: "Test Case Positive - Internal cause is not leaked",
+			err:  domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToRetrieveAccount, errors.New("pq: connection refused")),
+			want: domain.Problem{Type: "about:blank", Title: "Internal Server Error", Status: 500, Detail: static.ErrUnableToRetrieveAccount, Instance: "/accounts/123", Code: domain.ErrCodeInternal},
+		},
+		{
+			name: "Test Case Positive - Plain error",
+			err:  errors.New("random error"),
+			want: domain.Problem{Type: "about:blank", Title: "Internal Server Error", Status: 500, Detail: static.ErrInternal, Instance: "/accounts/123", Code: domain.ErrCodeInternal},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/accounts/123", nil)
+			ErrorResponse(rec, req, tc.err)
+
+			var response domain.Problem
+			_ = json.NewDecoder(rec.Body).Decode(&response)
+			assert.Equal(t, tc.want, response)
+			assert.Equal(t, tc.want.Status, rec.Result().StatusCode)
+			assert.Equal(t, ContentTypeProblemJSON, rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestErrorResponseDeadlineExceeded(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/transactions", nil).WithContext(ctx)
+	ErrorResponse(rec, req, domain.WrapError(domain.ErrCodeInternal, static.ErrUnableToCompleteTransaction, errors.New("pq: canceling statement due to user request")))
+
+	var response domain.Problem
+	_ = json.NewDecoder(rec.Body).Decode(&response)
+	assert.Equal(t, domain.Problem{Type: "about:blank", Title: "Gateway Timeout", Status: 504, Detail: static.ErrRequestTimeout, Instance: "/transactions", Code: domain.ErrCodeTimeout}, response)
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"math"
 	"net/http"
+	"strconv"
 )
 
 // JSONResponse will accept a payload object and status code
@@ -25,3 +26,8 @@ func ToFixed(num float64, precision int) float64 {
 func round(num float64) int {
 	return int(num + math.Copysign(0.5, num))
 }
+
+// FormatAmount will format an amount as the decimal string returned to clients
+func FormatAmount(num float64) string {
+	return strconv.FormatFloat(num, 'f', -1, 64)
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	accountv1 "account-test/api/proto/account/v1"
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/logger"
+	"account-test/internal/middleware"
+	"account-test/static"
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDInterceptor will inject a request ID into the context of every unary call and return it in the x-request-id response header
+// The request ID supplied by the client in the x-request-id metadata is reused when present, otherwise a new one is generated
+func RequestIDInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	key := strings.ToLower(middleware.HeaderRequestID)
+	id := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(key)) > 0 {
+		id = md.Get(key)[0]
+	}
+	if !middleware.IsValidRequestID(id) {
+		id = middleware.NewRequestID()
+	}
+	ctx = logger.WithRequestID(ctx, id)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(key, id))
+
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	slog.DebugContext(ctx, "gRPC request", "method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start).String())
+	return resp, err
+}
+
+// PrincipalInterceptor returns an interceptor injecting the domain.Principal of every unary call into its context, identifying the client by the x-api-key metadata or its peer address as middleware.ClientID does
+// The principal is authenticated when the x-api-key metadata holds one of the admin API keys of config, as middleware.Principal does
+// It must run after RequestIDInterceptor so that the request ID is recorded in the audit log too
+func PrincipalInterceptor(config *middleware.AdminConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		apiKey, remoteAddr := apiKeyOf(ctx), remoteAddrOf(ctx)
+		ctx = domain.WithPrincipal(ctx, domain.Principal{
+			Actor:         middleware.ClientIDOf(apiKey, remoteAddr),
+			RequestID:     logger.RequestID(ctx),
+			ClientIP:      middleware.ClientIP(remoteAddr),
+			Authenticated: middleware.IsAdmin(config, apiKey),
+		})
+		return handler(ctx, req)
+	}
+}
+
+// KeyFunc returns the bucket key a unary call is limited by, as middleware.KeyFunc does for HTTP requests
+// An empty key means the call is not subject to the limiter
+type KeyFunc func(ctx context.Context, req any) string
+
+// RateLimitInterceptor returns an interceptor taking a token from the bucket named by keyFunc for every unary call, as middleware.RateLimit does for HTTP requests
+// Calls are rejected with codes.ResourceExhausted and a retry-after header once the bucket is empty
+// Calls are let through when the store returns an error so that an unavailable store does not take the API down
+func RateLimitInterceptor(store ports.RateLimitRepository, limit domain.RateLimit, keyFunc KeyFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !limit.Enabled() {
+			return handler(ctx, req)
+		}
+		key := keyFunc(ctx, req)
+		if key == "" {
+			return handler(ctx, req)
+		}
+		result, err := store.Take(ctx, key, limit)
+		if err != nil {
+			slog.ErrorContext(ctx, "rate limit store unavailable, letting request through", "error", err)
+			return handler(ctx, req)
+		}
+		if !result.Allowed {
+			slog.InfoContext(ctx, "request rate limited", "method", info.FullMethod, "retry_after", result.RetryAfter.String())
+			_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(middleware.HeaderRetryAfter), strconv.Itoa(middleware.RetryAfterSeconds(result))))
+			return nil, ErrorStatus(ctx, domain.NewError(domain.ErrCodeRateLimited, static.ErrTooManyRequests))
+		}
+		return handler(ctx, req)
+	}
+}
+
+// ClientKey returns a KeyFunc keying a call by the x-api-key metadata when it is one of the admin API keys of config, or by the peer IP otherwise, as middleware.ClientKey does
+func ClientKey(config *middleware.AdminConfig) KeyFunc {
+	return func(ctx context.Context, req any) string {
+		return middleware.ClientKeyOf(config, apiKeyOf(ctx), remoteAddrOf(ctx))
+	}
+}
+
+// TransferSourceKey will key a Transfer call by its source account, as middleware.TransferSourceKey does
+// Other calls, and transfers without a source account left for the service to reject, are not limited here
+func TransferSourceKey(ctx context.Context, req any) string {
+	transfer, ok := req.(*accountv1.TransferRequest)
+	if !ok || transfer.GetSourceAccountId() == "" {
+		return ""
+	}
+	return "transfer:" + transfer.GetSourceAccountId()
+}
+
+// TimeoutInterceptor returns an interceptor setting the deadline of every unary call from config, as middleware.Timeout does for the matching REST routes
+// Transfer is given config.Transfer and every other method config.Default, a timeout of 0 or less leaves the context unchanged
+func TimeoutInterceptor(config *middleware.TimeoutConfig) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		timeout := config.Default
+		if info.FullMethod == accountv1.AccountService_Transfer_FullMethodName {
+			timeout = config.Transfer
+		}
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
+
+// apiKeyOf returns the API key in the x-api-key metadata of a call, or an empty string when there is none
+func apiKeyOf(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(strings.ToLower(middleware.HeaderAPIKey))) > 0 {
+		return md.Get(strings.ToLower(middleware.HeaderAPIKey))[0]
+	}
+	return ""
+}
+
+// remoteAddrOf returns the address of the peer making a call, or an empty string when it is not known
+func remoteAddrOf(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
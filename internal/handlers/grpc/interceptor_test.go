@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	accountv1 "account-test/api/proto/account/v1"
+	"account-test/internal/core/domain"
+	"account-test/internal/middleware"
+	mock_ports "account-test/internal/mocks/ports"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTransferRateLimited(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	limit := domain.RateLimit{Rate: 1, Burst: 1}
+	tests := []struct {
+		name         string
+		doMockLimits func(store *mock_ports.MockRateLimitRepository)
+		doMockSvc    func(service *mock_ports.MockTransactionService)
+		code         codes.Code
+	}{
+		{
+			name: "Test Case Positive",
+			doMockLimits: func(store *mock_ports.MockRateLimitRepository) {
+				store.EXPECT().Take(gomock.Any(), testClientID, limit).Return(domain.RateLimitResult{Allowed: true}, nil)
+				store.EXPECT().Take(gomock.Any(), "transfer:123", limit).Return(domain.RateLimitResult{Allowed: true}, nil)
+			},
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(domain.Transaction{ID: 1, Status: domain.TransactionStatusCompleted}, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name: "Test Case Negative - Client rate limited",
+			doMockLimits: func(store *mock_ports.MockRateLimitRepository) {
+				store.EXPECT().Take(gomock.Any(), testClientID, limit).Return(domain.RateLimitResult{RetryAfter: 1500 * time.Millisecond}, nil)
+			},
+			doMockSvc: func(service *mock_ports.MockTransactionService) {},
+			code:      codes.ResourceExhausted,
+		},
+		{
+			name: "Test Case Negative - Source account rate limited",
+			doMockLimits: func(store *mock_ports.MockRateLimitRepository) {
+				store.EXPECT().Take(gomock.Any(), testClientID, limit).Return(domain.RateLimitResult{Allowed: true}, nil)
+				store.EXPECT().Take(gomock.Any(), "transfer:123", limit).Return(domain.RateLimitResult{RetryAfter: 1500 * time.Millisecond}, nil)
+			},
+			doMockSvc: func(service *mock_ports.MockTransactionService) {},
+			code:      codes.ResourceExhausted,
+		},
+		{
+			name: "Test Case Positive - Store unavailable",
+			doMockLimits: func(store *mock_ports.MockRateLimitRepository) {
+				store.EXPECT().Take(gomock.Any(), gomock.Any(), limit).Return(domain.RateLimitResult{}, errors.New("connection refused")).Times(2)
+			},
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(domain.Transaction{ID: 1, Status: domain.TransactionStatusCompleted}, nil)
+			},
+			code: codes.OK,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mock_ports.NewMockRateLimitRepository(mockCtrl)
+			tc.doMockLimits(store)
+			mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+			tc.doMockSvc(mockTransSvc)
+			client := newTestClient(t, mock_ports.NewMockAccountService(mockCtrl), mockTransSvc,
+				RateLimitInterceptor(store, limit, ClientKey(testAdmin)),
+				RateLimitInterceptor(store, limit, TransferSourceKey),
+			)
+
+			var header metadata.MD
+			_, err := client.Transfer(withAPIKey(context.Background()), &accountv1.TransferRequest{SourceAccountId: "123", DestinationAccountId: "1234", Amount: "19"}, grpc.Header(&header))
+
+			st := status.Convert(err)
+			assert.Equal(t, tc.code, st.Code())
+			if tc.code != codes.OK {
+				assert.Equal(t, string(domain.ErrCodeRateLimited), errorReason(st))
+				assert.Equal(t, []string{"2"}, header.Get(strings.ToLower(middleware.HeaderRetryAfter)))
+			}
+		})
+	}
+}
+
+func TestTimeoutInterceptor(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockAccountSvc := mock_ports.NewMockAccountService(mockCtrl)
+	mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+	client := newTestClient(t, mockAccountSvc, mockTransSvc, TimeoutInterceptor(&middleware.TimeoutConfig{Default: time.Minute, Transfer: 20 * time.Millisecond}))
+
+	t.Run("Test Case Negative - Transfer past its deadline", func(t *testing.T) {
+		mockTransSvc.EXPECT().Transfer(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, cmd domain.TransferCommand) (domain.Transaction, error) {
+			<-ctx.Done()
+			return domain.Transaction{}, ctx.Err()
+		})
+
+		_, err := client.Transfer(context.Background(), &accountv1.TransferRequest{SourceAccountId: "123", DestinationAccountId: "1234", Amount: "19"})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.DeadlineExceeded, st.Code())
+		assert.Equal(t, string(domain.ErrCodeTimeout), errorReason(st))
+	})
+
+	t.Run("Test Case Positive - Default deadline", func(t *testing.T) {
+		mockAccountSvc.EXPECT().GetAccount(gomock.Any(), "123").DoAndReturn(func(ctx context.Context, id string) (domain.Account, error) {
+			deadline, ok := ctx.Deadline()
+			assert.True(t, ok)
+			assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, 5*time.Second)
+			return domain.Account{ID: id, Balance: "0"}, nil
+		})
+
+		_, err := client.GetAccount(context.Background(), &accountv1.GetAccountRequest{AccountId: "123"})
+
+		assert.NoError(t, err)
+	})
+}
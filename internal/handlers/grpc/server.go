@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	accountv1 "account-test/api/proto/account/v1"
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain reported in the google.rpc.ErrorInfo detail attached to every error status
+const ErrorDomain = "account-test"
+
+var errorCodeStatus = map[domain.ErrorCode]codes.Code{
+	domain.ErrCodeMalformedRequest:     codes.InvalidArgument,
+	domain.ErrCodeRequestTooLarge:      codes.ResourceExhausted,
+	domain.ErrCodeInvalidAccountID:     codes.InvalidArgument,
+	domain.ErrCodeInvalidAmount:        codes.InvalidArgument,
+	domain.ErrCodeAccountNotFound:      codes.NotFound,
+	domain.ErrCodeAccountAlreadyExists: codes.AlreadyExists,
+	domain.ErrCodeSameAccountTransfer:  codes.InvalidArgument,
+	domain.ErrCodeInsufficientFunds:    codes.FailedPrecondition,
+	domain.ErrCodeInvalidCurrency:      codes.InvalidArgument,
+	domain.ErrCodeCurrencyMismatch:     codes.FailedPrecondition,
+	domain.ErrCodeDuplicateReference:   codes.AlreadyExists,
+	domain.ErrCodeForbidden:            codes.PermissionDenied,
+	domain.ErrCodeAdjustmentNotFound:   codes.NotFound,
+	domain.ErrCodeNotPendingApproval:   codes.FailedPrecondition,
+	domain.ErrCodeApprovalNotFound:     codes.NotFound,
+	domain.ErrCodeApprovalExpired:      codes.FailedPrecondition,
+	domain.ErrCodeRateLimited:          codes.ResourceExhausted,
+	domain.ErrCodeTimeout:              codes.DeadlineExceeded,
+	domain.ErrCodeInternal:             codes.Internal,
+}
+
+type AccountServer struct {
+	accountv1.UnimplementedAccountServiceServer
+	accountSvc     ports.AccountService
+	transactionSvc ports.TransactionService
+}
+
+func NewAccountServer(accountSvc ports.AccountService, transactionSvc ports.TransactionService) *AccountServer {
+	return &AccountServer{
+		accountSvc:     accountSvc,
+		transactionSvc: transactionSvc,
+	}
+}
+
+// CreateAccount will create the account described by accountv1.CreateAccountRequest, with its currency and details, through ports.AccountService
+func (s *AccountServer) CreateAccount(ctx context.Context, req *accountv1.CreateAccountRequest) (*accountv1.Account, error) {
+	account, err := s.accountSvc.CreateAccount(ctx, domain.CreateAccountCommand{
+		ID:             req.GetAccountId(),
+		InitialBalance: req.GetInitialBalance(),
+		Currency:       req.GetCurrency(),
+		Details: domain.AccountDetails{
+			DisplayName: req.GetDisplayName(),
+			Metadata:    req.GetMetadata(),
+			Labels:      req.GetLabels(),
+		},
+	})
+	if err != nil {
+		return nil, ErrorStatus(ctx, err)
+	}
+	return toAccount(account), nil
+}
+
+// GetAccount will retrieve the account with the id in accountv1.GetAccountRequest through ports.AccountService
+func (s *AccountServer) GetAccount(ctx context.Context, req *accountv1.GetAccountRequest) (*accountv1.Account, error) {
+	account, err := s.accountSvc.GetAccount(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, ErrorStatus(ctx, err)
+	}
+	return toAccount(account), nil
+}
+
+// Transfer will move the amount in accountv1.TransferRequest between accounts through ports.TransactionService
+// The client is identified by the domain.Principal set by PrincipalInterceptor, as over HTTP, so that the reference of the transfer is unique per API key
+// A transfer held for approval is returned with the status pending_approval, no transaction id and the id of its approval, which is reviewed through the REST API
+func (s *AccountServer) Transfer(ctx context.Context, req *accountv1.TransferRequest) (*accountv1.Transaction, error) {
+	var metadata json.RawMessage
+	if req.GetMetadata() != "" {
+		metadata = json.RawMessage(req.GetMetadata())
+	}
+	transaction, err := s.transactionSvc.Transfer(ctx, domain.TransferCommand{
+		SourceID:      req.GetSourceAccountId(),
+		DestinationID: req.GetDestinationAccountId(),
+		Amount:        req.GetAmount(),
+		Description:   req.GetDescription(),
+		Reference:     req.GetReference(),
+		Metadata:      metadata,
+		ClientID:      domain.PrincipalFrom(ctx).Actor,
+	})
+	if err != nil {
+		return nil, ErrorStatus(ctx, err)
+	}
+	return toTransaction(transaction), nil
+}
+
+// ListTransactions will retrieve a page of an account's transactions through ports.TransactionService
+func (s *AccountServer) ListTransactions(ctx context.Context, req *accountv1.ListTransactionsRequest) (*accountv1.ListTransactionsResponse, error) {
+	transactions, err := s.transactionSvc.ListTransactions(ctx, domain.ListTransactionsQuery{
+		AccountID: req.GetAccountId(),
+		Limit:     int(req.GetLimit()),
+		BeforeID:  req.GetBeforeId(),
+	})
+	if err != nil {
+		return nil, ErrorStatus(ctx, err)
+	}
+	response := &accountv1.ListTransactionsResponse{NextBeforeId: transactions.NextBeforeID}
+	for _, transaction := range transactions.Transactions {
+		response.Transactions = append(response.Transactions, toTransaction(transaction))
+	}
+	return response, nil
+}
+
+// ErrorStatus will convert an error returned by a service into a gRPC status error
+// the status code is picked from the code of a domain.Error and the code is attached as the reason of a google.rpc.ErrorInfo detail
+// any other error is reported as codes.Internal with a generic message so internal details are not leaked
+func ErrorStatus(ctx context.Context, err error) error {
+	domainErr := utils.ToDomainError(ctx, err)
+	if domainErr.Err != nil {
+		slog.ErrorContext(ctx, "gRPC request failed", "code", domainErr.Code, "error", domainErr.Error())
+	}
+
+	code, ok := errorCodeStatus[domainErr.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, domainErr.Message)
+	withDetails, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: string(domainErr.Code),
+		Domain: ErrorDomain,
+	})
+	if detailErr != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+func toAccount(account domain.Account) *accountv1.Account {
+	response := &accountv1.Account{
+		AccountId:   account.ID,
+		Balance:     account.Balance,
+		Currency:    account.Currency,
+		Status:      account.Status,
+		DisplayName: account.DisplayName,
+		Metadata:    account.Metadata,
+		Labels:      account.Labels,
+	}
+	if !account.CreatedAt.IsZero() {
+		response.CreatedAt = account.CreatedAt.Format(time.RFC3339)
+	}
+	return response
+}
+
+func toTransaction(transaction domain.Transaction) *accountv1.Transaction {
+	response := &accountv1.Transaction{
+		TransactionId:        transaction.ID,
+		SourceAccountId:      transaction.SourceID,
+		DestinationAccountId: transaction.DestinationID,
+		Amount:               transaction.Amount,
+		Status:               transaction.Status,
+		ApprovalId:           transaction.ApprovalID,
+		Description:          transaction.Description,
+		Reference:            transaction.Reference,
+		Metadata:             string(transaction.Metadata),
+	}
+	if !transaction.CreatedAt.IsZero() {
+		response.CreatedAt = transaction.CreatedAt.Format(time.RFC3339)
+	}
+	return response
+}
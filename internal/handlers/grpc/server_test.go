@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	accountv1 "account-test/api/proto/account/v1"
+	"account-test/internal/core/domain"
+	"account-test/internal/middleware"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// testClientID is the client identified by the API key sent by withAPIKey
+var testClientID = middleware.ClientIDOf("abc", "")
+
+// testAdmin holds the API key sent by withAPIKey as an admin API key, so that its calls are authenticated
+var testAdmin = &middleware.AdminConfig{APIKeys: []string{"abc"}}
+
+// withAPIKey returns ctx sending the API key "abc" with a call
+func withAPIKey(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, strings.ToLower(middleware.HeaderAPIKey), "abc")
+}
+
+// newTestClient starts an AccountServer on an in-memory listener and returns a client connected to it
+// The interceptors run after RequestIDInterceptor and PrincipalInterceptor
+func newTestClient(t *testing.T, accountSvc *mock_ports.MockAccountService, transactionSvc *mock_ports.MockTransactionService, interceptors ...grpc.UnaryServerInterceptor) accountv1.AccountServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(append([]grpc.UnaryServerInterceptor{RequestIDInterceptor, PrincipalInterceptor(testAdmin)}, interceptors...)...))
+	accountv1.RegisterAccountServiceServer(server, NewAccountServer(accountSvc, transactionSvc))
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return accountv1.NewAccountServiceClient(conn)
+}
+
+func TestGetAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name      string
+		doMockSvc func(service *mock_ports.MockAccountService)
+		want      *accountv1.Account
+		code      codes.Code
+		reason    domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetAccount(gomock.Any(), "123").Return(domain.Account{ID: "123", Balance: "123"}, nil)
+			},
+			want: &accountv1.Account{AccountId: "123", Balance: "123"},
+			code: codes.OK,
+		},
+		{
+			name: "Test Case Negative - Account does not exist",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetAccount(gomock.Any(), "123").Return(domain.Account{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist))
+			},
+			code:   codes.NotFound,
+			reason: domain.ErrCodeAccountNotFound,
+		},
+		{
+			name: "Test Case Negative - Unexpected error",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetAccount(gomock.Any(), "123").Return(domain.Account{}, errors.New("random error"))
+			},
+			code:   codes.Internal,
+			reason: domain.ErrCodeInternal,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccSvc := mock_ports.NewMockAccountService(mockCtrl)
+			tc.doMockSvc(mockAccSvc)
+			client := newTestClient(t, mockAccSvc, mock_ports.NewMockTransactionService(mockCtrl))
+			account, err := client.GetAccount(context.Background(), &accountv1.GetAccountRequest{AccountId: "123"})
+
+			st := status.Convert(err)
+			assert.Equal(t, tc.code, st.Code())
+			if tc.code == codes.OK {
+				assert.Equal(t, tc.want.AccountId, account.AccountId)
+				assert.Equal(t, tc.want.Balance, account.Balance)
+			} else {
+				assert.Equal(t, string(tc.reason), errorReason(st))
+			}
+		})
+	}
+}
+
+func TestAccountDetailsRoundTrip(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	account := domain.Account{
+		ID:          "123",
+		Balance:     "100",
+		Currency:    "EUR",
+		Status:      domain.AccountStatusActive,
+		DisplayName: "Payroll",
+		Metadata:    map[string]string{"team": "payments"},
+		Labels:      []string{"region:eu", "vip"},
+		CreatedAt:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	mockAccSvc := mock_ports.NewMockAccountService(mockCtrl)
+	mockAccSvc.EXPECT().CreateAccount(gomock.Any(), domain.CreateAccountCommand{
+		ID:             "123",
+		InitialBalance: "100",
+		Currency:       "EUR",
+		Details: domain.AccountDetails{
+			DisplayName: "Payroll",
+			Metadata:    map[string]string{"team": "payments"},
+			Labels:      []string{"vip", "region:eu"},
+		},
+	}).Return(account, nil)
+	mockAccSvc.EXPECT().GetAccount(gomock.Any(), "123").Return(account, nil)
+	client := newTestClient(t, mockAccSvc, mock_ports.NewMockTransactionService(mockCtrl))
+	want := &accountv1.Account{
+		AccountId:   "123",
+		Balance:     "100",
+		Currency:    "EUR",
+		Status:      domain.AccountStatusActive,
+		DisplayName: "Payroll",
+		Metadata:    map[string]string{"team": "payments"},
+		Labels:      []string{"region:eu", "vip"},
+		CreatedAt:   "2024-03-01T12:00:00Z",
+	}
+
+	created, err := client.CreateAccount(context.Background(), &accountv1.CreateAccountRequest{
+		AccountId:      "123",
+		InitialBalance: "100",
+		Currency:       "EUR",
+		DisplayName:    "Payroll",
+		Metadata:       map[string]string{"team": "payments"},
+		Labels:         []string{"vip", "region:eu"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(want, created))
+
+	got, err := client.GetAccount(context.Background(), &accountv1.GetAccountRequest{AccountId: "123"})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(want, got))
+}
+
+func TestTransfer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	createdAt := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		doMockSvc func(service *mock_ports.MockTransactionService)
+		want      *accountv1.Transaction
+		code      codes.Code
+		reason    domain.ErrorCode
+	}{
+		{
+			name: "Test Case Positive",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19", ClientID: testClientID}).Return(
+					domain.Transaction{ID: 1, SourceID: "123", DestinationID: "1234", Amount: "19", Status: domain.TransactionStatusCompleted, CreatedAt: createdAt},
+					nil,
+				)
+			},
+			want: &accountv1.Transaction{TransactionId: 1, SourceAccountId: "123", DestinationAccountId: "1234", Amount: "19", Status: domain.TransactionStatusCompleted, CreatedAt: "2024-03-31T12:00:00Z"},
+			code: codes.OK,
+		},
+		{
+			name: "Test Case Positive - Held for approval",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19", ClientID: testClientID}).Return(
+					domain.Transaction{SourceID: "123", DestinationID: "1234", Amount: "19", Status: domain.TransactionStatusPendingApproval, ApprovalID: 5, CreatedAt: createdAt},
+					nil,
+				)
+			},
+			want: &accountv1.Transaction{SourceAccountId: "123", DestinationAccountId: "1234", Amount: "19", Status: domain.TransactionStatusPendingApproval, CreatedAt: "2024-03-31T12:00:00Z", ApprovalId: 5},
+			code: codes.OK,
+		},
+		{
+			name: "Test Case Negative - Insufficient funds",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(
+					domain.Transaction{},
+					domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount),
+				)
+			},
+			code:   codes.FailedPrecondition,
+			reason: domain.ErrCodeInsufficientFunds,
+		},
+		{
+			name: "Test Case Negative - Invalid amount",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(
+					domain.Transaction{},
+					domain.NewError(domain.ErrCodeInvalidAmount, static.ErrAmountNotValidNumber),
+				)
+			},
+			code:   codes.InvalidArgument,
+			reason: domain.ErrCodeInvalidAmount,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+			tc.doMockSvc(mockTransSvc)
+			client := newTestClient(t, mock_ports.NewMockAccountService(mockCtrl), mockTransSvc)
+			transaction, err := client.Transfer(withAPIKey(context.Background()), &accountv1.TransferRequest{SourceAccountId: "123", DestinationAccountId: "1234", Amount: "19"})
+
+			st := status.Convert(err)
+			assert.Equal(t, tc.code, st.Code())
+			if tc.code == codes.OK {
+				assert.Equal(t, tc.want.TransactionId, transaction.TransactionId)
+				assert.Equal(t, tc.want.Status, transaction.Status)
+				assert.Equal(t, tc.want.CreatedAt, transaction.CreatedAt)
+				assert.Equal(t, tc.want.ApprovalId, transaction.ApprovalId)
+			} else {
+				assert.Equal(t, string(tc.reason), errorReason(st))
+			}
+		})
+	}
+}
+
+func TestListTransactions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+	mockTransSvc.EXPECT().ListTransactions(gomock.Any(), domain.ListTransactionsQuery{AccountID: "123", Limit: 1, BeforeID: 10}).Return(
+		domain.TransactionList{Transactions: []domain.Transaction{{ID: 9, SourceID: "123", DestinationID: "1234", Amount: "19"}}, NextBeforeID: 9},
+		nil,
+	)
+	client := newTestClient(t, mock_ports.NewMockAccountService(mockCtrl), mockTransSvc)
+	response, err := client.ListTransactions(context.Background(), &accountv1.ListTransactionsRequest{AccountId: "123", Limit: 1, BeforeId: 10})
+
+	assert.NoError(t, err)
+	assert.Len(t, response.Transactions, 1)
+	assert.Equal(t, int64(9), response.Transactions[0].TransactionId)
+	assert.Equal(t, int64(9), response.NextBeforeId)
+}
+
+func TestTransferDetailsRoundTrip(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	createdAt := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	transaction := domain.Transaction{
+		ID:            1,
+		SourceID:      "123",
+		DestinationID: "1234",
+		Amount:        "19",
+		Description:   "March rent",
+		Reference:     "rent-2024-03",
+		Metadata:      json.RawMessage(`{"invoice":"INV-42"}`),
+		Status:        domain.TransactionStatusCompleted,
+		CreatedAt:     createdAt,
+	}
+	mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+	mockTransSvc.EXPECT().Transfer(gomock.Any(), domain.TransferCommand{
+		SourceID:      "123",
+		DestinationID: "1234",
+		Amount:        "19",
+		Description:   "March rent",
+		Reference:     "rent-2024-03",
+		Metadata:      json.RawMessage(`{"invoice":"INV-42"}`),
+		ClientID:      testClientID,
+	}).Return(transaction, nil)
+	mockTransSvc.EXPECT().ListTransactions(gomock.Any(), domain.ListTransactionsQuery{AccountID: "123"}).Return(
+		domain.TransactionList{Transactions: []domain.Transaction{transaction}},
+		nil,
+	)
+	client := newTestClient(t, mock_ports.NewMockAccountService(mockCtrl), mockTransSvc)
+	want := &accountv1.Transaction{
+		TransactionId:        1,
+		SourceAccountId:      "123",
+		DestinationAccountId: "1234",
+		Amount:               "19",
+		Status:               domain.TransactionStatusCompleted,
+		CreatedAt:            "2024-03-31T12:00:00Z",
+		Description:          "March rent",
+		Reference:            "rent-2024-03",
+		Metadata:             `{"invoice":"INV-42"}`,
+	}
+
+	transferred, err := client.Transfer(withAPIKey(context.Background()), &accountv1.TransferRequest{
+		SourceAccountId:      "123",
+		DestinationAccountId: "1234",
+		Amount:               "19",
+		Description:          "March rent",
+		Reference:            "rent-2024-03",
+		Metadata:             `{"invoice":"INV-42"}`,
+	})
+	assert.NoError(t, err)
+	assert.True(t, proto.Equal(want, transferred))
+
+	listed, err := client.ListTransactions(context.Background(), &accountv1.ListTransactionsRequest{AccountId: "123"})
+	assert.NoError(t, err)
+	assert.Len(t, listed.Transactions, 1)
+	assert.True(t, proto.Equal(want, listed.Transactions[0]))
+}
+
+func errorReason(st *status.Status) string {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	return ""
+}
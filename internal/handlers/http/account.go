@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/static"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+type AccountHandler struct {
+	accountSvc ports.AccountService
+}
+
+func NewAccountHandler(accountSvc ports.AccountService) *AccountHandler {
+	return &AccountHandler{
+		accountSvc: accountSvc,
+	}
+}
+
+// PostAccount will accept a HTTP body containing a domain.PostAccount object
+// The function will create the account through ports.AccountService
+// The function will return HTTP status OK and the created account as a domain.Account object, including its ID when the ID is generated by the server
+func (h *AccountHandler) PostAccount(w http.ResponseWriter, r *http.Request) {
+	postAccountBody := domain.PostAccount{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+	err = json.Unmarshal(body, &postAccountBody)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+
+	account, err := h.accountSvc.CreateAccount(r.Context(), domain.CreateAccountCommand{
+		ID:             postAccountBody.ID,
+		InitialBalance: postAccountBody.Balance,
+		Currency:       postAccountBody.Currency,
+		Details: domain.AccountDetails{
+			DisplayName: postAccountBody.DisplayName,
+			Metadata:    postAccountBody.Metadata,
+			Labels:      postAccountBody.Labels,
+		},
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, account)
+}
+
+// PatchAccount will accept a HTTP path parameter of account_id and a HTTP body containing a domain.PatchAccount object
+// The function will update the display name, metadata and labels present in the body through ports.AccountService
+// The function will return HTTP status OK and the updated account as a domain.Account object if the update is successful
+func (h *AccountHandler) PatchAccount(w http.ResponseWriter, r *http.Request) {
+	patchAccountBody := domain.PatchAccount{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+	err = json.Unmarshal(body, &patchAccountBody)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+
+	account, err := h.accountSvc.UpdateAccount(r.Context(), domain.UpdateAccountCommand{
+		ID:          chi.URLParam(r, "account_id"),
+		DisplayName: patchAccountBody.DisplayName,
+		Metadata:    patchAccountBody.Metadata,
+		Labels:      patchAccountBody.Labels,
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, account)
+}
+
+// GetAccount will accept a HTTP path parameter of account_id
+// the function will retrieve the account through ports.AccountService, returned as a domain.Account object
+func (h *AccountHandler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	accountId := chi.URLParam(r, "account_id")
+	account, err := h.accountSvc.GetAccount(r.Context(), accountId)
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, account)
+}
+
+// GetBalance will accept a HTTP path parameter of account_id and an optional query parameter as_of, an RFC 3339 timestamp defaulting to now
+// the function will compute the balance of the account at as_of through ports.AccountService, returned as a domain.Balance object
+func (h *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	accountId := chi.URLParam(r, "account_id")
+	asOf, err := timeParam(r.URL.Query(), "as_of")
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAsOf))
+		return
+	}
+	balance, err := h.accountSvc.GetBalance(r.Context(), accountId, asOf)
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, balance)
+}
+
+// ListAccounts will accept HTTP query parameters status, currency, id_prefix, display_name, min_balance, max_balance, created_from, created_to, sort, limit and cursor
+// label and metadata may be repeated, metadata filters are given as key:value
+// the function will retrieve a page of the accounts matching the filters through ports.AccountService, returned as a domain.AccountList object
+func (h *AccountHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := domain.ListAccountsQuery{
+		Status:      params.Get("status"),
+		Currency:    params.Get("currency"),
+		IDPrefix:    params.Get("id_prefix"),
+		DisplayName: params.Get("display_name"),
+		Labels:      params["label"],
+		Sort:        params.Get("sort"),
+		Cursor:      params.Get("cursor"),
+	}
+	for _, filter := range params["metadata"] {
+		key, value, ok := strings.Cut(filter, ":")
+		if !ok {
+			utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidMetadataFilter))
+			return
+		}
+		if query.Metadata == nil {
+			query.Metadata = map[string]string{}
+		}
+		query.Metadata[key] = value
+	}
+	var err error
+	if limit := params.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+	}
+	if err == nil {
+		query.MinBalance, err = floatParam(params, "min_balance")
+	}
+	if err == nil {
+		query.MaxBalance, err = floatParam(params, "max_balance")
+	}
+	if err == nil {
+		query.CreatedFrom, err = timeParam(params, "created_from")
+	}
+	if err == nil {
+		query.CreatedTo, err = timeParam(params, "created_to")
+	}
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAccountListParam))
+		return
+	}
+
+	accounts, err := h.accountSvc.ListAccounts(r.Context(), query)
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, accounts)
+}
+
+// floatParam will parse the query parameter name as a number, returning nil when it is not set
+func floatParam(params url.Values, name string) (*float64, error) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// timeParam will parse the query parameter name as an RFC 3339 timestamp, returning nil when it is not set
+func timeParam(params url.Values, name string) (*time.Time, error) {
+	value := params.Get(name)
+	if value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
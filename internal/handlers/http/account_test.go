@@ -0,0 +1,406 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/utils"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name       string
+		account_id string
+		doMockSvc  func(service *mock_ports.MockAccountService)
+		want       domain.Account
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name:       "Test Case Positive",
+			account_id: "123",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetAccount(gomock.Any(), "123").Return(domain.Account{ID: "123", Balance: "123"}, nil)
+			},
+			want:       domain.Account{ID: "123", Balance: "123"},
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Negative - Account does not exist",
+			account_id: "123",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetAccount(gomock.Any(), "123").Return(domain.Account{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist))
+			},
+			code:       domain.ErrCodeAccountNotFound,
+			statusCode: 404,
+		},
+		{
+			name:       "Test Case Negative - Service error",
+			account_id: "123",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetAccount(gomock.Any(), "123").Return(domain.Account{}, errors.New("random error"))
+			},
+			code:       domain.ErrCodeInternal,
+			statusCode: 500,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccSvc := mock_ports.NewMockAccountService(mockCtrl)
+			tc.doMockSvc(mockAccSvc)
+			handler := http.HandlerFunc(NewAccountHandler(mockAccSvc).GetAccount)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("account_id", tc.account_id)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/accounts/"+tc.account_id, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+				assert.Equal(t, utils.ContentTypeProblemJSON, rec.Header().Get("Content-Type"))
+			} else {
+				var response domain.Account
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
+
+func TestPostAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name       string
+		body       []byte
+		doMockSvc  func(service *mock_ports.MockAccountService)
+		code       domain.ErrorCode
+		statusCode int
+		response   domain.Account
+	}{
+		{
+			name: "Test Case Positive",
+			body: []byte(`{"account_id":"123","initial_balance":"123"}`),
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().CreateAccount(gomock.Any(), domain.CreateAccountCommand{ID: "123", InitialBalance: "123"}).Return(domain.Account{ID: "123", Balance: "123"}, nil)
+			},
+			statusCode: 200,
+		},
+		{
+			name: "Test Case Positive - With currency",
+			body: []byte(`{"account_id":"123","initial_balance":"123","currency":"EUR"}`),
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().CreateAccount(gomock.Any(), domain.CreateAccountCommand{ID: "123", InitialBalance: "123", Currency: "EUR"}).Return(domain.Account{ID: "123", Balance: "123", Currency: "EUR"}, nil)
+			},
+			statusCode: 200,
+		},
+		{
+			name: "Test Case Positive - Generated ID",
+			body: []byte(`{"initial_balance":"123"}`),
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().CreateAccount(gomock.Any(), domain.CreateAccountCommand{InitialBalance: "123"}).Return(domain.Account{ID: "01J8ZQ4X2V9K3M5N7P8R0S1T2V", Balance: "123", Currency: "USD", Status: "active"}, nil)
+			},
+			statusCode: 200,
+			response:   domain.Account{ID: "01J8ZQ4X2V9K3M5N7P8R0S1T2V", Balance: "123", Currency: "USD", Status: "active"},
+		},
+		{
+			name: "Test Case Negative - Malformed body",
+			body: []byte(`{"account_id":`),
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+			},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name: "Test Case Negative - Account already exist",
+			body: []byte(`{"account_id":"123","initial_balance":"123"}`),
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).Return(domain.Account{}, domain.NewError(domain.ErrCodeAccountAlreadyExists, static.ErrAccountAlreadyExist))
+			},
+			code:       domain.ErrCodeAccountAlreadyExists,
+			statusCode: 409,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccSvc := mock_ports.NewMockAccountService(mockCtrl)
+			tc.doMockSvc(mockAccSvc)
+			handler := http.HandlerFunc(NewAccountHandler(mockAccSvc).PostAccount)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/accounts", bytes.NewReader(tc.body))
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			}
+			if tc.response.ID != "" {
+				var response domain.Account
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.response, response)
+			}
+		})
+	}
+}
+
+func TestListAccounts(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	minBalance := 10.5
+	createdFrom := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		url        string
+		doMockSvc  func(service *mock_ports.MockAccountService)
+		want       domain.AccountList
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name: "Test Case Positive",
+			url:  "/accounts?status=active&currency=USD&id_prefix=acc&min_balance=10.5&created_from=2024-03-01T00:00:00Z&sort=-balance&limit=2&cursor=abc",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().ListAccounts(gomock.Any(), domain.ListAccountsQuery{
+					Status:      "active",
+					Currency:    "USD",
+					IDPrefix:    "acc",
+					MinBalance:  &minBalance,
+					CreatedFrom: &createdFrom,
+					Sort:        "-balance",
+					Limit:       2,
+					Cursor:      "abc",
+				}).Return(domain.AccountList{Accounts: []domain.Account{{ID: "acc1", Balance: "20"}}, NextCursor: "next"}, nil)
+			},
+			want:       domain.AccountList{Accounts: []domain.Account{{ID: "acc1", Balance: "20"}}, NextCursor: "next"},
+			statusCode: 200,
+		},
+		{
+			name: "Test Case Positive - Details filters",
+			url:  "/accounts?display_name=pay&label=vip&label=eu&metadata=team:payments&metadata=url:https://example.com",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().ListAccounts(gomock.Any(), domain.ListAccountsQuery{
+					DisplayName: "pay",
+					Labels:      []string{"vip", "eu"},
+					Metadata:    map[string]string{"team": "payments", "url": "https://example.com"},
+				}).Return(domain.AccountList{Accounts: []domain.Account{}}, nil)
+			},
+			want:       domain.AccountList{Accounts: []domain.Account{}},
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Negative - Metadata filter without value",
+			url:        "/accounts?metadata=team",
+			doMockSvc:  func(service *mock_ports.MockAccountService) {},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name:       "Test Case Negative - Balance not a number",
+			url:        "/accounts?max_balance=lots",
+			doMockSvc:  func(service *mock_ports.MockAccountService) {},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name:       "Test Case Negative - Timestamp not RFC 3339",
+			url:        "/accounts?created_to=31/03/2024",
+			doMockSvc:  func(service *mock_ports.MockAccountService) {},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name: "Test Case Negative - Invalid sort",
+			url:  "/accounts?sort=name",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().ListAccounts(gomock.Any(), gomock.Any()).Return(domain.AccountList{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAccountSort))
+			},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccSvc := mock_ports.NewMockAccountService(mockCtrl)
+			tc.doMockSvc(mockAccSvc)
+			handler := http.HandlerFunc(NewAccountHandler(mockAccSvc).ListAccounts)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.url, nil)
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.AccountList
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
+
+func TestPatchAccount(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	name := "Payroll"
+	tests := []struct {
+		name       string
+		body       []byte
+		doMockSvc  func(service *mock_ports.MockAccountService)
+		want       domain.Account
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name: "Test Case Positive",
+			body: []byte(`{"display_name":"Payroll","labels":[]}`),
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().UpdateAccount(gomock.Any(), domain.UpdateAccountCommand{ID: "123", DisplayName: &name, Labels: []string{}}).
+					Return(domain.Account{ID: "123", Balance: "10", DisplayName: "Payroll"}, nil)
+			},
+			want:       domain.Account{ID: "123", Balance: "10", DisplayName: "Payroll"},
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Negative - Malformed body",
+			body:       []byte(`{"labels":"vip"}`),
+			doMockSvc:  func(service *mock_ports.MockAccountService) {},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name: "Test Case Negative - Account does not exist",
+			body: []byte(`{"metadata":{"team":"payments"}}`),
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().UpdateAccount(gomock.Any(), gomock.Any()).Return(domain.Account{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist))
+			},
+			code:       domain.ErrCodeAccountNotFound,
+			statusCode: 404,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccSvc := mock_ports.NewMockAccountService(mockCtrl)
+			tc.doMockSvc(mockAccSvc)
+			handler := http.HandlerFunc(NewAccountHandler(mockAccSvc).PatchAccount)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("account_id", "123")
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("PATCH", "/accounts/123", bytes.NewReader(tc.body))
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.Account
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
+
+func TestGetBalance(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	asOf := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		query      string
+		doMockSvc  func(service *mock_ports.MockAccountService)
+		want       domain.Balance
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name:  "Test Case Positive",
+			query: "?as_of=2024-03-01T12:00:00Z",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetBalance(gomock.Any(), "123", &asOf).Return(domain.Balance{AccountID: "123", Balance: "50", Currency: "USD", AsOf: asOf}, nil)
+			},
+			want:       domain.Balance{AccountID: "123", Balance: "50", Currency: "USD", AsOf: asOf},
+			statusCode: 200,
+		},
+		{
+			name:  "Test Case Positive - Current balance",
+			query: "",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetBalance(gomock.Any(), "123", nil).Return(domain.Balance{AccountID: "123", Balance: "75", Currency: "USD", AsOf: asOf}, nil)
+			},
+			want:       domain.Balance{AccountID: "123", Balance: "75", Currency: "USD", AsOf: asOf},
+			statusCode: 200,
+		},
+		{
+			name:  "Test Case Negative - Invalid as_of",
+			query: "?as_of=yesterday",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+			},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name:  "Test Case Negative - Account did not exist",
+			query: "?as_of=2024-03-01T12:00:00Z",
+			doMockSvc: func(service *mock_ports.MockAccountService) {
+				service.EXPECT().GetBalance(gomock.Any(), "123", &asOf).Return(domain.Balance{}, domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountNotCreatedAsOf))
+			},
+			code:       domain.ErrCodeAccountNotFound,
+			statusCode: 404,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAccSvc := mock_ports.NewMockAccountService(mockCtrl)
+			tc.doMockSvc(mockAccSvc)
+			handler := http.HandlerFunc(NewAccountHandler(mockAccSvc).GetBalance)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("account_id", "123")
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/accounts/123/balance"+tc.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.Balance
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/internal/middleware"
+	"account-test/static"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type AdjustmentHandler struct {
+	adjustmentSvc ports.AdjustmentService
+}
+
+func NewAdjustmentHandler(adjustmentSvc ports.AdjustmentService) *AdjustmentHandler {
+	return &AdjustmentHandler{
+		adjustmentSvc: adjustmentSvc,
+	}
+}
+
+// PostAdjustment will accept a HTTP path parameter of account_id and a HTTP body containing a domain.PostAdjustment object
+// The function will credit or debit the account against the suspense account through ports.AdjustmentService, with the client of the request as the actor requesting it
+// The function will return HTTP status Created and the applied adjustment, or HTTP status Accepted and the adjustment when it is pending approval
+func (h *AdjustmentHandler) PostAdjustment(w http.ResponseWriter, r *http.Request) {
+	postAdjustmentBody := domain.PostAdjustment{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+	err = json.Unmarshal(body, &postAdjustmentBody)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+
+	adjustment, err := h.adjustmentSvc.Adjust(r.Context(), domain.AdjustmentCommand{
+		AccountID:  chi.URLParam(r, "account_id"),
+		Direction:  postAdjustmentBody.Direction,
+		Amount:     postAdjustmentBody.Amount,
+		ReasonCode: postAdjustmentBody.ReasonCode,
+		Note:       postAdjustmentBody.Note,
+		Actor:      middleware.ClientID(r),
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	if adjustment.Status == domain.AdjustmentStatusPendingApproval {
+		utils.JSONResponse(w, http.StatusAccepted, adjustment)
+		return
+	}
+	utils.JSONResponse(w, http.StatusCreated, adjustment)
+}
+
+// ApproveAdjustment will accept HTTP path parameters of account_id and adjustment_id
+// The function will approve the adjustment pending approval and apply it through ports.AdjustmentService, with the client of the request as the reviewer
+// The function will return HTTP status OK and the applied adjustment
+func (h *AdjustmentHandler) ApproveAdjustment(w http.ResponseWriter, r *http.Request) {
+	h.reviewAdjustment(w, r, true)
+}
+
+// RejectAdjustment will accept HTTP path parameters of account_id and adjustment_id
+// The function will reject the adjustment pending approval through ports.AdjustmentService, with the client of the request as the reviewer
+// The function will return HTTP status OK and the rejected adjustment
+func (h *AdjustmentHandler) RejectAdjustment(w http.ResponseWriter, r *http.Request) {
+	h.reviewAdjustment(w, r, false)
+}
+
+func (h *AdjustmentHandler) reviewAdjustment(w http.ResponseWriter, r *http.Request, approve bool) {
+	adjustmentID, err := strconv.ParseInt(chi.URLParam(r, "adjustment_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAdjustmentID))
+		return
+	}
+
+	adjustment, err := h.adjustmentSvc.ReviewAdjustment(r.Context(), domain.ReviewAdjustmentCommand{
+		AccountID:    chi.URLParam(r, "account_id"),
+		AdjustmentID: adjustmentID,
+		Approve:      approve,
+		Actor:        middleware.ClientID(r),
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, adjustment)
+}
@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostAdjustment(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	adjustment := domain.Adjustment{
+		ID:                7,
+		AccountID:         "123",
+		SuspenseAccountID: "SUSPENSE",
+		Direction:         domain.AdjustmentCredit,
+		Amount:            "19.5",
+		ReasonCode:        "chargeback",
+		Status:            domain.AdjustmentStatusApplied,
+		RequestedBy:       "ip:192.0.2.1",
+		TransactionID:     11,
+		CreatedAt:         time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	pending := adjustment
+	pending.Status = domain.AdjustmentStatusPendingApproval
+	pending.TransactionID = 0
+	tests := []struct {
+		name       string
+		body       []byte
+		doMockSvc  func(service *mock_ports.MockAdjustmentService)
+		want       domain.Adjustment
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name: "Test Case Positive",
+			body: []byte(`{"direction":"credit","amount":"19.5","reason_code":"chargeback"}`),
+			doMockSvc: func(service *mock_ports.MockAdjustmentService) {
+				service.EXPECT().Adjust(gomock.Any(), domain.AdjustmentCommand{AccountID: "123", Direction: "credit", Amount: "19.5", ReasonCode: "chargeback", Actor: "ip:192.0.2.1"}).Return(adjustment, nil)
+			},
+			want:       adjustment,
+			statusCode: 201,
+		},
+		{
+			name: "Test Case Positive - Pending approval",
+			body: []byte(`{"direction":"credit","amount":"19.5","reason_code":"chargeback"}`),
+			doMockSvc: func(service *mock_ports.MockAdjustmentService) {
+				service.EXPECT().Adjust(gomock.Any(), gomock.Any()).Return(pending, nil)
+			},
+			want:       pending,
+			statusCode: 202,
+		},
+		{
+			name:       "Test Case Negative - Malformed body",
+			body:       []byte(`{"amount":19.5}`),
+			doMockSvc:  func(service *mock_ports.MockAdjustmentService) {},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name: "Test Case Negative - Reason code missing",
+			body: []byte(`{"direction":"credit","amount":"19.5"}`),
+			doMockSvc: func(service *mock_ports.MockAdjustmentService) {
+				service.EXPECT().Adjust(gomock.Any(), gomock.Any()).Return(domain.Adjustment{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidAdjustmentReasonCode))
+			},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAdjustmentSvc := mock_ports.NewMockAdjustmentService(mockCtrl)
+			tc.doMockSvc(mockAdjustmentSvc)
+			handler := http.HandlerFunc(NewAdjustmentHandler(mockAdjustmentSvc).PostAdjustment)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("account_id", "123")
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/accounts/123/adjustments", bytes.NewReader(tc.body))
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req.RemoteAddr = "192.0.2.1:1234"
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.Adjustment
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
+
+func TestReviewAdjustment(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	rejected := domain.Adjustment{
+		ID:          7,
+		AccountID:   "123",
+		Direction:   domain.AdjustmentDebit,
+		Amount:      "19.5",
+		ReasonCode:  "correction",
+		Status:      domain.AdjustmentStatusRejected,
+		RequestedBy: "key:abc",
+		ReviewedBy:  "ip:192.0.2.1",
+		CreatedAt:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name         string
+		adjustmentID string
+		review       func(h *AdjustmentHandler) http.HandlerFunc
+		doMockSvc    func(service *mock_ports.MockAdjustmentService)
+		want         domain.Adjustment
+		code         domain.ErrorCode
+		statusCode   int
+	}{
+		{
+			name:         "Test Case Positive",
+			adjustmentID: "7",
+			review:       func(h *AdjustmentHandler) http.HandlerFunc { return h.RejectAdjustment },
+			doMockSvc: func(service *mock_ports.MockAdjustmentService) {
+				service.EXPECT().ReviewAdjustment(gomock.Any(), domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 7, Actor: "ip:192.0.2.1"}).Return(rejected, nil)
+			},
+			want:       rejected,
+			statusCode: 200,
+		},
+		{
+			name:         "Test Case Negative - Approved by requester",
+			adjustmentID: "7",
+			review:       func(h *AdjustmentHandler) http.HandlerFunc { return h.ApproveAdjustment },
+			doMockSvc: func(service *mock_ports.MockAdjustmentService) {
+				service.EXPECT().ReviewAdjustment(gomock.Any(), domain.ReviewAdjustmentCommand{AccountID: "123", AdjustmentID: 7, Approve: true, Actor: "ip:192.0.2.1"}).
+					Return(domain.Adjustment{}, domain.NewError(domain.ErrCodeForbidden, static.ErrSelfApproval))
+			},
+			code:       domain.ErrCodeForbidden,
+			statusCode: 403,
+		},
+		{
+			name:         "Test Case Negative - Not pending approval",
+			adjustmentID: "7",
+			review:       func(h *AdjustmentHandler) http.HandlerFunc { return h.ApproveAdjustment },
+			doMockSvc: func(service *mock_ports.MockAdjustmentService) {
+				service.EXPECT().ReviewAdjustment(gomock.Any(), gomock.Any()).Return(domain.Adjustment{}, domain.NewError(domain.ErrCodeNotPendingApproval, static.ErrAdjustmentNotPending))
+			},
+			code:       domain.ErrCodeNotPendingApproval,
+			statusCode: 409,
+		},
+		{
+			name:         "Test Case Negative - Invalid adjustment ID",
+			adjustmentID: "abc",
+			review:       func(h *AdjustmentHandler) http.HandlerFunc { return h.ApproveAdjustment },
+			doMockSvc:    func(service *mock_ports.MockAdjustmentService) {},
+			code:         domain.ErrCodeMalformedRequest,
+			statusCode:   400,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAdjustmentSvc := mock_ports.NewMockAdjustmentService(mockCtrl)
+			tc.doMockSvc(mockAdjustmentSvc)
+			handler := tc.review(NewAdjustmentHandler(mockAdjustmentSvc))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("account_id", "123")
+			rctx.URLParams.Add("adjustment_id", tc.adjustmentID)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/accounts/123/adjustments/"+tc.adjustmentID+"/approve", nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req.RemoteAddr = "192.0.2.1:1234"
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.Adjustment
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
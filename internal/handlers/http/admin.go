@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/internal/middleware"
+	"account-test/static"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+type AdminHandler struct {
+	reconciliationSvc ports.ReconciliationService
+}
+
+func NewAdminHandler(reconciliationSvc ports.ReconciliationService) *AdminHandler {
+	return &AdminHandler{
+		reconciliationSvc: reconciliationSvc,
+	}
+}
+
+// PostReconciliation will accept a HTTP body containing a domain.PostReconciliation object, an empty body only reporting discrepancies
+// The function will reconcile the balances of the accounts through ports.ReconciliationService, correcting them when correct is set, with the client of the request as the actor of the corrections
+// the write deadline of the response is extended to the request deadline, as checking every account may take longer than HTTP_WRITE_TIMEOUT
+// The function will return HTTP status OK and the result as a domain.Reconciliation object
+func (h *AdminHandler) PostReconciliation(w http.ResponseWriter, r *http.Request) {
+	postReconciliationBody := domain.PostReconciliation{}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+	if len(body) > 0 {
+		err = json.Unmarshal(body, &postReconciliationBody)
+		if err != nil {
+			utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+			return
+		}
+	}
+	extendWriteDeadline(http.NewResponseController(w), r)
+
+	reconciliation, err := h.reconciliationSvc.Reconcile(r.Context(), domain.ReconcileCommand{
+		Correct: postReconciliationBody.Correct,
+		Reason:  postReconciliationBody.Reason,
+		Actor:   middleware.ClientID(r),
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, reconciliation)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostReconciliation(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	checkedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	reconciliation := domain.Reconciliation{
+		CheckedAt:     checkedAt,
+		Discrepancies: []domain.Discrepancy{{AccountID: "123", Currency: "USD", Balance: "100.3", ExpectedBalance: "100", Difference: "0.3", Corrected: true, CorrectionID: 9}},
+		Skipped:       []string{},
+	}
+	tests := []struct {
+		name       string
+		body       []byte
+		doMockSvc  func(service *mock_ports.MockReconciliationService)
+		want       domain.Reconciliation
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name: "Test Case Positive",
+			body: []byte(`{"correct":true,"reason":"drift"}`),
+			doMockSvc: func(service *mock_ports.MockReconciliationService) {
+				service.EXPECT().Reconcile(gomock.Any(), domain.ReconcileCommand{Correct: true, Reason: "drift", Actor: "ip:192.0.2.1"}).Return(reconciliation, nil)
+			},
+			want:       reconciliation,
+			statusCode: 200,
+		},
+		{
+			name: "Test Case Positive - Empty body",
+			doMockSvc: func(service *mock_ports.MockReconciliationService) {
+				service.EXPECT().Reconcile(gomock.Any(), domain.ReconcileCommand{Actor: "ip:192.0.2.1"}).Return(reconciliation, nil)
+			},
+			want:       reconciliation,
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Negative - Malformed body",
+			body:       []byte(`{"correct":"yes"}`),
+			doMockSvc:  func(service *mock_ports.MockReconciliationService) {},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name: "Test Case Negative - Reason missing",
+			body: []byte(`{"correct":true}`),
+			doMockSvc: func(service *mock_ports.MockReconciliationService) {
+				service.EXPECT().Reconcile(gomock.Any(), gomock.Any()).Return(domain.Reconciliation{}, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrReasonRequired))
+			},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockReconciliationSvc := mock_ports.NewMockReconciliationService(mockCtrl)
+			tc.doMockSvc(mockReconciliationSvc)
+			handler := http.HandlerFunc(NewAdminHandler(mockReconciliationSvc).PostReconciliation)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/admin/reconciliations", bytes.NewReader(tc.body))
+			req.RemoteAddr = "192.0.2.1:1234"
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.Reconciliation
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
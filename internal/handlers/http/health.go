@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+type HealthHandler struct {
+	healthRepo ports.HealthRepository
+	draining   atomic.Bool
+}
+
+func NewHealthHandler(healthRepo ports.HealthRepository) *HealthHandler {
+	return &HealthHandler{
+		healthRepo: healthRepo,
+	}
+}
+
+// SetDraining will make Readyz report the app as not ready, to be called when shutdown starts so that load balancers stop sending requests
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Livez will respond with HTTP status OK as long as the app is able to serve requests, without checking its dependencies
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// Readyz will check every dependency of the app within the deadline of the request and respond with a domain.Readiness object
+// The function will return HTTP status OK when every dependency is available and HTTP status 503 when any is not or the app is draining
+// The cause of a failed check is logged rather than returned, so that connection and schema details are not exposed to callers
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if h.draining.Load() {
+		utils.JSONResponse(w, http.StatusServiceUnavailable, domain.Readiness{Status: domain.HealthStatusDraining})
+		return
+	}
+
+	checks := map[string]func(context.Context) error{
+		"database":   h.healthRepo.Ping,
+		"migrations": h.healthRepo.CheckMigrations,
+	}
+	readiness := domain.Readiness{Status: domain.HealthStatusOK, Checks: map[string]domain.DependencyHealth{}}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+			health := domain.DependencyHealth{Status: domain.HealthStatusOK}
+			if err := check(r.Context()); err != nil {
+				slog.WarnContext(r.Context(), "readiness check failed", "check", name, "error", err)
+				health = domain.DependencyHealth{Status: domain.HealthStatusUnavailable}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			readiness.Checks[name] = health
+			if health.Status != domain.HealthStatusOK {
+				readiness.Status = domain.HealthStatusUnavailable
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	if readiness.Status != domain.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	utils.JSONResponse(w, status, readiness)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	mock_ports "account-test/internal/mocks/ports"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadyz(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name       string
+		draining   bool
+		doMockRepo func(repo *mock_ports.MockHealthRepository)
+		want       domain.Readiness
+		statusCode int
+	}{
+		{
+			name: "Test Case Positive",
+			doMockRepo: func(repo *mock_ports.MockHealthRepository) {
+				repo.EXPECT().Ping(gomock.Any()).Return(nil)
+				repo.EXPECT().CheckMigrations(gomock.Any()).Return(nil)
+			},
+			want: domain.Readiness{Status: domain.HealthStatusOK, Checks: map[string]domain.DependencyHealth{
+				"database":   {Status: domain.HealthStatusOK},
+				"migrations": {Status: domain.HealthStatusOK},
+			}},
+			statusCode: 200,
+		},
+		{
+			name: "Test Case Negative - Database unreachable",
+			doMockRepo: func(repo *mock_ports.MockHealthRepository) {
+				repo.EXPECT().Ping(gomock.Any()).Return(errors.New("connection refused"))
+				repo.EXPECT().CheckMigrations(gomock.Any()).Return(errors.New("connection refused"))
+			},
+			want: domain.Readiness{Status: domain.HealthStatusUnavailable, Checks: map[string]domain.DependencyHealth{
+				"database":   {Status: domain.HealthStatusUnavailable},
+				"migrations": {Status: domain.HealthStatusUnavailable},
+			}},
+			statusCode: 503,
+		},
+		{
+			name: "Test Case Negative - Migrations behind",
+			doMockRepo: func(repo *mock_ports.MockHealthRepository) {
+				repo.EXPECT().Ping(gomock.Any()).Return(nil)
+				repo.EXPECT().CheckMigrations(gomock.Any()).Return(errors.New("schema is at migration 2, expected 3"))
+			},
+			want: domain.Readiness{Status: domain.HealthStatusUnavailable, Checks: map[string]domain.DependencyHealth{
+				"database":   {Status: domain.HealthStatusOK},
+				"migrations": {Status: domain.HealthStatusUnavailable},
+			}},
+			statusCode: 503,
+		},
+		{
+			name:       "Test Case Negative - Draining",
+			draining:   true,
+			doMockRepo: func(repo *mock_ports.MockHealthRepository) {},
+			want:       domain.Readiness{Status: domain.HealthStatusDraining},
+			statusCode: 503,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := mock_ports.NewMockHealthRepository(mockCtrl)
+			tc.doMockRepo(repo)
+			handler := NewHealthHandler(repo)
+			if tc.draining {
+				handler.SetDraining()
+			}
+
+			rec := httptest.NewRecorder()
+			handler.Readyz(rec, httptest.NewRequest("GET", "/readyz", nil))
+
+			body := rec.Body.String()
+			var response domain.Readiness
+			assert.NoError(t, json.Unmarshal([]byte(body), &response))
+			assert.Equal(t, tc.want, response)
+			assert.NotContains(t, body, "error")
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/utils"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const openAPIVersion = "3.0.3"
+
+var (
+	openAPIOnce     sync.Once
+	openAPIDocument map[string]any
+)
+
+// ServeOpenAPI will respond with the OpenAPI document describing the REST API
+func ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	utils.JSONResponse(w, http.StatusOK, OpenAPI())
+}
+
+// OpenAPI will return the OpenAPI 3 document describing every REST API route
+// Component schemas are generated from the domain structs so request and response bodies stay in sync with the json tags
+func OpenAPI() map[string]any {
+	openAPIOnce.Do(func() {
+		openAPIDocument = buildOpenAPI()
+	})
+	return openAPIDocument
+}
+
+func buildOpenAPI() map[string]any {
+	schemas := map[string]any{}
+	for name, value := range map[string]any{
+		"PostAccount":        domain.PostAccount{},
+		"PatchAccount":       domain.PatchAccount{},
+		"Account":            domain.Account{},
+		"AccountList":        domain.AccountList{},
+		"Balance":            domain.Balance{},
+		"Statement":          domain.Statement{},
+		"Transaction":        domain.Transaction{},
+		"TransactionList":    domain.TransactionList{},
+		"Problem":            domain.Problem{},
+		"Readiness":          domain.Readiness{},
+		"PostReconciliation": domain.PostReconciliation{},
+		"Reconciliation":     domain.Reconciliation{},
+		"PostAdjustment":     domain.PostAdjustment{},
+		"Adjustment":         domain.Adjustment{},
+		"TransferApproval":   domain.TransferApproval{},
+	} {
+		schemas[name] = schemaOf(reflect.TypeOf(value))
+	}
+
+	accountIDParam := pathParam("account_id", "ID of the account")
+	adjustmentIDParam := pathParam("adjustment_id", "ID of the adjustment")
+	approvalIDParam := pathParam("approval_id", "ID of the transfer approval")
+
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "account-test",
+			"version": "1.0.0",
+		},
+		"paths": map[string]any{
+			"/accounts": map[string]any{
+				"get": operation("List accounts matching the filters, one page at a time", "accounts", []any{
+					queryParam("status", "Only return accounts with this status: "+strings.Join(domain.AccountStatuses, ", "), "string", false),
+					queryParam("currency", "Only return accounts in this ISO 4217 currency", "string", false),
+					queryParam("id_prefix", "Only return accounts whose ID starts with this prefix", "string", false),
+					queryParam("display_name", "Only return accounts whose display name contains this text, ignoring case", "string", false),
+					arrayQueryParam("label", "Only return accounts with this label, repeat to require several labels"),
+					arrayQueryParam("metadata", "Only return accounts with this metadata given as key:value, repeat to require several pairs"),
+					queryParam("min_balance", "Only return accounts with at least this balance", "number", false),
+					queryParam("max_balance", "Only return accounts with at most this balance", "number", false),
+					queryParam("created_from", "Only return accounts created at or after this RFC 3339 timestamp", "string", false),
+					queryParam("created_to", "Only return accounts created before this RFC 3339 timestamp", "string", false),
+					queryParam("sort", "Sort order, one of "+strings.Join(domain.AccountSorts, ", ")+", defaults to id. A leading - sorts in descending order", "string", false),
+					queryParam("limit", "Maximum number of accounts to return, defaults to 50 and is capped at 100", "integer", false),
+					queryParam("cursor", "next_cursor of the previous page, requested with the same sort", "string", false),
+				}, "",
+					response(http.StatusOK, "A page of accounts", "AccountList"),
+					http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError),
+				"post": operation("Create an account with an initial balance. account_id must be omitted when the server generates account IDs", "accounts", nil, "PostAccount",
+					response(http.StatusOK, "The created account", "Account"),
+					http.StatusBadRequest, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/accounts/{account_id}": map[string]any{
+				"get": operation("Get the balance of an account", "accounts", []any{accountIDParam}, "",
+					response(http.StatusOK, "The account", "Account"),
+					http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError),
+				"patch": operation("Change the display name, metadata or labels of an account. Fields missing from the body are left unchanged", "accounts", []any{accountIDParam}, "PatchAccount",
+					response(http.StatusOK, "The updated account", "Account"),
+					http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/accounts/{account_id}/balance": map[string]any{
+				"get": operation("Get the balance of an account at a point in time, computed from its completed transfers", "accounts", []any{
+					accountIDParam,
+					queryParam("as_of", "RFC 3339 timestamp to get the balance at, defaults to now", "string", false),
+				}, "",
+					response(http.StatusOK, "The balance at as_of", "Balance"),
+					http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/accounts/{account_id}/statement": map[string]any{
+				"get": operation("Export the statement of an account for a period: the opening balance, every completed transfer with the balance after it and the closing balance. The statement is streamed, a response ending before the closing balance is incomplete", "accounts", []any{
+					accountIDParam,
+					queryParam("from", "RFC 3339 start of the period, exclusive, defaults to the creation of the account", "string", false),
+					queryParam("to", "RFC 3339 end of the period, inclusive, defaults to now", "string", false),
+					queryParam("format", "json (default), csv or text", "string", false),
+				}, "",
+					statementResponse(),
+					http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/accounts/{account_id}/adjustments": map[string]any{
+				"post": operation("Credit or debit an account outside of a transfer, booked as a transfer against the suspense account. reason_code is one of "+strings.Join(domain.AdjustmentReasonCodes, ", ")+". When four-eyes approval is required, the adjustment is pending until approved by another admin API key. Requires an admin API key", "adjustments", []any{accountIDParam}, "PostAdjustment",
+					mergeResponses(
+						response(http.StatusCreated, "The applied adjustment", "Adjustment"),
+						response(http.StatusAccepted, "The adjustment, pending approval", "Adjustment"),
+					),
+					http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/accounts/{account_id}/adjustments/{adjustment_id}/approve": map[string]any{
+				"post": operation("Approve an adjustment pending approval and apply it. The approver must use another admin API key than the one that requested the adjustment", "adjustments", []any{accountIDParam, adjustmentIDParam}, "",
+					response(http.StatusOK, "The applied adjustment", "Adjustment"),
+					http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/accounts/{account_id}/adjustments/{adjustment_id}/reject": map[string]any{
+				"post": operation("Reject an adjustment pending approval. The reviewer must use another admin API key than the one that requested the adjustment", "adjustments", []any{accountIDParam, adjustmentIDParam}, "",
+					response(http.StatusOK, "The rejected adjustment", "Adjustment"),
+					http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/transactions": map[string]any{
+				"get": operation("List the transactions of an account, newest first", "transactions", []any{
+					queryParam("account_id", "ID of the account", "string", true),
+					queryParam("limit", "Maximum number of transactions to return, defaults to 50 and is capped at 100", "integer", false),
+					queryParam("before_id", "Only return transactions with a lower transaction_id, use next_before_id of the previous page", "integer", false),
+				}, "",
+					response(http.StatusOK, "A page of transactions", "TransactionList"),
+					http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError),
+				"post": operation("Transfer an amount from a source account to a destination account. A reference must be unique among the transfers of the API key that did not fail. A transfer above the approval threshold is held pending until approved by an admin API key of another client", "transactions", nil, "Transaction",
+					mergeResponses(
+						response(http.StatusOK, "Transfer completed", ""),
+						response(http.StatusAccepted, "The transfer with its approval_id, pending approval", "Transaction"),
+					),
+					http.StatusBadRequest, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/transactions/approvals/{approval_id}/approve": map[string]any{
+				"post": operation("Approve a transfer pending approval and process it. The approver must use another API key than the one that requested the transfer. Requires an admin API key", "transactions", []any{approvalIDParam}, "",
+					response(http.StatusOK, "The completed approval with the transaction_id of the transfer", "TransferApproval"),
+					http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusUnprocessableEntity, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/transactions/approvals/{approval_id}/reject": map[string]any{
+				"post": operation("Reject a transfer pending approval. The reviewer must use another API key than the one that requested the transfer. Requires an admin API key", "transactions", []any{approvalIDParam}, "",
+					response(http.StatusOK, "The rejected approval", "TransferApproval"),
+					http.StatusBadRequest, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/admin/reconciliations": map[string]any{
+				"post": operation("Check the balance of every account against its initial balance plus its completed transfers and report the discrepancies. With correct, book the difference of each balance found to differ as a transfer against the suspense account of its currency, recording the reason. Accounts with transfers still settling are skipped. Requires an admin API key", "admin", nil, "PostReconciliation",
+					response(http.StatusOK, "The discrepancies found and the accounts skipped", "Reconciliation"),
+					http.StatusBadRequest, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError),
+			},
+			"/health": map[string]any{
+				"get": map[string]any{
+					"summary":   "Liveness probe, same as /livez",
+					"tags":      []any{"operations"},
+					"responses": map[string]any{"200": textResponse("OK")},
+				},
+			},
+			"/livez": map[string]any{
+				"get": map[string]any{
+					"summary":   "Liveness probe",
+					"tags":      []any{"operations"},
+					"responses": map[string]any{"200": textResponse("OK")},
+				},
+			},
+			"/readyz": map[string]any{
+				"get": map[string]any{
+					"summary": "Readiness probe checking the database is reachable and migrated",
+					"tags":    []any{"operations"},
+					"responses": mergeResponses(
+						response(http.StatusOK, "Ready to serve requests", "Readiness"),
+						response(http.StatusServiceUnavailable, "A dependency is unavailable or the app is shutting down", "Readiness"),
+					),
+				},
+			},
+			"/metrics": map[string]any{
+				"get": map[string]any{
+					"summary":   "Prometheus metrics",
+					"tags":      []any{"operations"},
+					"responses": map[string]any{"200": textResponse("Metrics in the Prometheus text exposition format")},
+				},
+			},
+			"/openapi.json": map[string]any{
+				"get": map[string]any{
+					"summary":   "This OpenAPI document",
+					"tags":      []any{"operations"},
+					"responses": map[string]any{"200": map[string]any{"description": "OpenAPI document", "content": map[string]any{"application/json": map[string]any{"schema": map[string]any{"type": "object"}}}}},
+				},
+			},
+		},
+		"components": map[string]any{
+			"schemas": schemas,
+		},
+	}
+}
+
+// operation will build an OpenAPI operation object
+// requestSchema names the component schema of the JSON request body, empty when the operation has no body
+// every status in errorStatuses is documented as a problem+json response
+func operation(summary string, tag string, parameters []any, requestSchema string, success map[string]any, errorStatuses ...int) map[string]any {
+	responses := map[string]any{}
+	for status, value := range success {
+		responses[status] = value
+	}
+	for _, status := range errorStatuses {
+		responses[statusKey(status)] = map[string]any{
+			"description": http.StatusText(status),
+			"content": map[string]any{
+				utils.ContentTypeProblemJSON: map[string]any{"schema": schemaRef("Problem")},
+			},
+		}
+	}
+
+	op := map[string]any{
+		"summary":   summary,
+		"tags":      []any{tag},
+		"responses": responses,
+	}
+	if len(parameters) > 0 {
+		op["parameters"] = parameters
+	}
+	if requestSchema != "" {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemaRef(requestSchema)},
+			},
+		}
+	}
+	return op
+}
+
+// response will build a JSON response keyed by status, schema is empty for responses without a body
+func response(status int, description string, schema string) map[string]any {
+	value := map[string]any{"description": description}
+	if schema != "" {
+		value["content"] = map[string]any{
+			"application/json": map[string]any{"schema": schemaRef(schema)},
+		}
+	}
+	return map[string]any{statusKey(status): value}
+}
+
+// mergeResponses will combine responses built by response into a single responses object
+func mergeResponses(responses ...map[string]any) map[string]any {
+	merged := map[string]any{}
+	for _, r := range responses {
+		for status, value := range r {
+			merged[status] = value
+		}
+	}
+	return merged
+}
+
+func textResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"text/plain": map[string]any{"schema": map[string]any{"type": "string"}},
+		},
+	}
+}
+
+// statementResponse will build the response of a statement, in JSON, CSV or text depending on its format parameter
+func statementResponse() map[string]any {
+	text := map[string]any{"schema": map[string]any{"type": "string"}}
+	return map[string]any{
+		statusKey(http.StatusOK): map[string]any{
+			"description": "The statement",
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": schemaRef("Statement")},
+				"text/csv":         text,
+				"text/plain":       text,
+			},
+		},
+	}
+}
+
+func pathParam(name string, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "path",
+		"required":    true,
+		"description": description,
+		"schema":      map[string]any{"type": "string"},
+	}
+}
+
+func queryParam(name string, description string, paramType string, required bool) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"required":    required,
+		"description": description,
+		"schema":      map[string]any{"type": paramType},
+	}
+}
+
+// arrayQueryParam will build an optional query parameter that may be repeated, with a string value each time
+func arrayQueryParam(name string, description string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"required":    false,
+		"description": description,
+		"schema":      map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+		"explode":     true,
+	}
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// schemaOf will generate an OpenAPI schema object for a Go type using its json tags for property names
+func schemaOf(t reflect.Type) map[string]any {
+	if t.Kind() == reflect.Pointer {
+		return schemaOf(t.Elem())
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t == rawMessageType {
+		return map[string]any{"type": "object"}
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]any{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !field.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			properties[name] = schemaOf(field.Type)
+		}
+		return map[string]any{"type": "object", "properties": properties}
+	}
+	return map[string]any{}
+}
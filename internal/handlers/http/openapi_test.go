@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"account-test/internal/metrics"
+	"account-test/internal/middleware"
+	mock_ports "account-test/internal/mocks/ports"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter(t *testing.T) chi.Router {
+	mockCtrl := gomock.NewController(t)
+	return NewRouter(RouterDeps{
+		AccountHandler:     NewAccountHandler(mock_ports.NewMockAccountService(mockCtrl)),
+		TransactionHandler: NewTransactionHandler(mock_ports.NewMockTransactionService(mockCtrl)),
+		HealthHandler:      NewHealthHandler(mock_ports.NewMockHealthRepository(mockCtrl)),
+		AdminHandler:       NewAdminHandler(mock_ports.NewMockReconciliationService(mockCtrl)),
+		AdjustmentHandler:  NewAdjustmentHandler(mock_ports.NewMockAdjustmentService(mockCtrl)),
+		RateLimitPort:      mock_ports.NewMockRateLimitRepository(mockCtrl),
+		RateLimit:          &middleware.RateLimitConfig{},
+		Timeout:            &middleware.TimeoutConfig{},
+		Admin:              &middleware.AdminConfig{},
+		Metrics:            metrics.New(),
+	})
+}
+
+// routeKey normalises a chi route pattern to the OpenAPI path it is documented under
+func routeKey(method string, route string) string {
+	if len(route) > 1 {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return strings.ToLower(method) + " " + route
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	registered := map[string]bool{}
+	err := chi.Walk(newTestRouter(t), func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		registered[routeKey(method, route)] = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, registered)
+
+	documented := map[string]bool{}
+	for path, item := range OpenAPI()["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			documented[routeKey(method, path)] = true
+		}
+	}
+
+	for route := range registered {
+		assert.True(t, documented[route], "route %q is registered but missing from the OpenAPI document", route)
+	}
+	for route := range documented {
+		assert.True(t, registered[route], "route %q is documented but not registered", route)
+	}
+}
+
+func TestServeOpenAPI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	newTestRouter(t).ServeHTTP(rec, req)
+
+	assert.Equal(t, 200, rec.Result().StatusCode)
+	var document struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&document))
+	assert.Equal(t, openAPIVersion, document.OpenAPI)
+	assert.Contains(t, document.Components.Schemas["PostAccount"].Properties, "initial_balance")
+	assert.Contains(t, document.Components.Schemas["Account"].Properties, "balance")
+	assert.Contains(t, document.Components.Schemas["Transaction"].Properties, "destination_account_id")
+	assert.Equal(t, map[string]any{"type": "object"}, document.Components.Schemas["Transaction"].Properties["metadata"])
+	assert.NotContains(t, document.Components.Schemas["Transaction"].Properties, "ClientID")
+	assert.Contains(t, document.Components.Schemas["Problem"].Properties, "code")
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"account-test/internal/core/ports"
+	"account-test/internal/metrics"
+	"account-test/internal/middleware"
+	"account-test/internal/tracing"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+)
+
+// Struct for the dependencies of the REST API routes
+type RouterDeps struct {
+	AccountHandler     *AccountHandler
+	TransactionHandler *TransactionHandler
+	HealthHandler      *HealthHandler
+	AdminHandler       *AdminHandler
+	AdjustmentHandler  *AdjustmentHandler
+	RateLimitPort      ports.RateLimitRepository
+	RateLimit          *middleware.RateLimitConfig
+	Timeout            *middleware.TimeoutConfig
+	Admin              *middleware.AdminConfig
+	Metrics            *metrics.Metrics
+}
+
+// writeDeadlineMargin is added to the request deadline for the write deadline of a response that may outlast HTTP_WRITE_TIMEOUT, so that a response cut short by the request deadline is still sent
+const writeDeadlineMargin = 5 * time.Second
+
+// NewRouter will register every REST API route on a new chi router
+// Every route registered here must be described in the document returned by OpenAPI
+func NewRouter(deps RouterDeps) chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(middleware.Principal(deps.Admin))
+	r.Use(tracing.Middleware)
+	r.Use(deps.Metrics.Middleware)
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.RateLimit(deps.RateLimitPort, deps.RateLimit.Client, middleware.ClientKey(deps.Admin)))
+		r.Route("/accounts", func(route chi.Router) {
+			route.Group(func(route chi.Router) {
+				route.Use(middleware.Timeout(deps.Timeout.Default))
+				route.Get("/{account_id}", deps.AccountHandler.GetAccount)
+				route.Patch("/{account_id}", deps.AccountHandler.PatchAccount)
+				route.Get("/{account_id}/balance", deps.AccountHandler.GetBalance)
+				route.Get("/", deps.AccountHandler.ListAccounts)
+				route.Post("/", deps.AccountHandler.PostAccount)
+			})
+			route.With(middleware.Timeout(deps.Timeout.Statement)).Get("/{account_id}/statement", deps.TransactionHandler.GetStatement)
+			route.Group(func(route chi.Router) {
+				route.Use(middleware.Admin(deps.Admin))
+				route.Use(middleware.Timeout(deps.Timeout.Transfer))
+				route.Post("/{account_id}/adjustments", deps.AdjustmentHandler.PostAdjustment)
+				route.Post("/{account_id}/adjustments/{adjustment_id}/approve", deps.AdjustmentHandler.ApproveAdjustment)
+				route.Post("/{account_id}/adjustments/{adjustment_id}/reject", deps.AdjustmentHandler.RejectAdjustment)
+			})
+		})
+		r.Route("/transactions", func(route chi.Router) {
+			route.With(middleware.Timeout(deps.Timeout.Default)).Get("/", deps.TransactionHandler.ListTransactions)
+			route.With(
+				middleware.Timeout(deps.Timeout.Transfer),
+				middleware.RateLimit(deps.RateLimitPort, deps.RateLimit.Transfer, middleware.TransferSourceKey),
+			).Post("/", deps.TransactionHandler.PostTransaction)
+			route.Group(func(route chi.Router) {
+				route.Use(middleware.Admin(deps.Admin))
+				route.Use(middleware.Timeout(deps.Timeout.Transfer))
+				route.Post("/approvals/{approval_id}/approve", deps.TransactionHandler.ApproveTransfer)
+				route.Post("/approvals/{approval_id}/reject", deps.TransactionHandler.RejectTransfer)
+			})
+		})
+		r.Route("/admin", func(route chi.Router) {
+			route.Use(middleware.Admin(deps.Admin))
+			route.Use(middleware.Timeout(deps.Timeout.Admin))
+			route.Post("/reconciliations", deps.AdminHandler.PostReconciliation)
+		})
+	})
+
+	// Health Endpoints for Liveness and Readiness Probes, /health is kept for existing liveness probes
+	r.Get("/health", deps.HealthHandler.Livez)
+	r.Get("/livez", deps.HealthHandler.Livez)
+	r.With(middleware.Timeout(deps.Timeout.Readiness)).Get("/readyz", deps.HealthHandler.Readyz)
+
+	r.Get("/openapi.json", ServeOpenAPI)
+	r.Method(http.MethodGet, "/metrics", deps.Metrics.Handler())
+
+	return r
+}
+
+// extendWriteDeadline will set the write deadline of the response to the deadline of the request plus writeDeadlineMargin, for routes whose timeout may be longer than HTTP_WRITE_TIMEOUT
+// Without a request deadline the response may be written for as long as it takes
+func extendWriteDeadline(rc *http.ResponseController, r *http.Request) {
+	deadline, _ := r.Context().Deadline()
+	if !deadline.IsZero() {
+		deadline = deadline.Add(writeDeadlineMargin)
+	}
+	// Not every ResponseWriter supports write deadlines, the server write timeout applies then
+	_ = rc.SetWriteDeadline(deadline)
+}
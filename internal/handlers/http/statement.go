@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/static"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi"
+)
+
+const (
+	// statementFlushLines is how many lines of a statement are written between flushes to the client
+	statementFlushLines = 100
+	// statementTextTime is the layout of times in a text statement
+	statementTextTime = "2006-01-02 15:04:05"
+)
+
+// GetStatement will accept a HTTP path parameter of account_id and optional query parameters from and to, RFC 3339 timestamps, and format, one of domain.StatementFormats defaulting to json
+// the function will write the statement of the account for the period through ports.TransactionService as it is read, so that a large statement is never held in memory
+// the write deadline of the response is extended to the request deadline, as a statement may take longer to send than HTTP_WRITE_TIMEOUT
+// once the statement has started, a failure can no longer change the response and ends it before the closing balance
+func (h *TransactionHandler) GetStatement(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := domain.StatementQuery{AccountID: chi.URLParam(r, "account_id")}
+	var err error
+	query.From, err = timeParam(params, "from")
+	if err == nil {
+		query.To, err = timeParam(params, "to")
+	}
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidStatementParam))
+		return
+	}
+	format := params.Get("format")
+	if format == "" {
+		format = domain.StatementFormatJSON
+	}
+	stream := &statementStream{w: w, rc: http.NewResponseController(w), accountID: query.AccountID}
+	var writer ports.StatementWriter
+	switch format {
+	case domain.StatementFormatJSON:
+		writer = &jsonStatementWriter{stream}
+	case domain.StatementFormatCSV:
+		writer = &csvStatementWriter{statementStream: stream, csv: csv.NewWriter(w)}
+	case domain.StatementFormatText:
+		writer = &textStatementWriter{stream}
+	default:
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidStatementFormat))
+		return
+	}
+
+	extendWriteDeadline(stream.rc, r)
+
+	err = h.transactionSvc.Statement(r.Context(), query, writer)
+	if err != nil && !stream.started {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "statement failed after it started", "account_id", query.AccountID, "lines", stream.lines, "error", err)
+	}
+}
+
+// statementStream writes a statement to the response, flushing it to the client every statementFlushLines lines
+type statementStream struct {
+	w         http.ResponseWriter
+	rc        *http.ResponseController
+	accountID string
+	started   bool
+	lines     int
+}
+
+// start will send the headers of the response, with a filename when the statement is meant to be saved
+func (s *statementStream) start(contentType string, extension string) {
+	s.started = true
+	s.w.Header().Set("Content-Type", contentType)
+	if extension != "" {
+		s.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "statement-"+s.accountID+extension))
+	}
+	s.w.WriteHeader(http.StatusOK)
+}
+
+// line will count a line written and flush the response every statementFlushLines lines
+func (s *statementStream) line() {
+	s.lines++
+	if s.lines%statementFlushLines == 0 {
+		// Not every ResponseWriter supports flushing, the response is then sent as its buffer fills
+		_ = s.rc.Flush()
+	}
+}
+
+// jsonStatementWriter writes a statement as the JSON of domain.Statement, with the lines written one at a time
+type jsonStatementWriter struct {
+	*statementStream
+}
+
+func (j *jsonStatementWriter) WriteHeader(statement domain.Statement) error {
+	header, err := json.Marshal(struct {
+		AccountID      string    `json:"account_id"`
+		Currency       string    `json:"currency"`
+		From           time.Time `json:"from"`
+		To             time.Time `json:"to"`
+		OpeningBalance string    `json:"opening_balance"`
+	}{statement.AccountID, statement.Currency, statement.From, statement.To, statement.OpeningBalance})
+	if err != nil {
+		return err
+	}
+	j.start("application/json", "")
+	// The header object is left open for the lines
+	_, err = fmt.Fprintf(j.w, `%s,"lines":[`, header[:len(header)-1])
+	return err
+}
+
+func (j *jsonStatementWriter) WriteLine(line domain.StatementLine) error {
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+	if j.lines > 0 {
+		if _, err := j.w.Write([]byte(",")); err != nil {
+			return err
+		}
+	}
+	if _, err := j.w.Write(encoded); err != nil {
+		return err
+	}
+	j.line()
+	return nil
+}
+
+func (j *jsonStatementWriter) WriteFooter(statement domain.Statement) error {
+	closing, err := json.Marshal(statement.ClosingBalance)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, "],\"closing_balance\":%s}\n", closing)
+	return err
+}
+
+// csvStatementWriter writes a statement as CSV, one row per line between an opening balance row and a closing balance row
+type csvStatementWriter struct {
+	*statementStream
+	csv *csv.Writer
+}
+
+func (c *csvStatementWriter) WriteHeader(statement domain.Statement) error {
+	c.start("text/csv; charset=utf-8", ".csv")
+	c.csv.Write([]string{"date", "transaction_id", "counterparty_account_id", "description", "reference", "amount", "balance"})
+	c.csv.Write([]string{statement.From.Format(time.RFC3339), "", "", "Opening balance", "", "", statement.OpeningBalance})
+	return c.flush()
+}
+
+func (c *csvStatementWriter) WriteLine(line domain.StatementLine) error {
+	c.csv.Write([]string{
+		line.CompletedAt.Format(time.RFC3339Nano),
+		strconv.FormatInt(line.TransactionID, 10),
+		line.CounterpartyID,
+		csvText(line.Description),
+		csvText(line.Reference),
+		line.Amount,
+		line.Balance,
+	})
+	if (c.lines+1)%statementFlushLines == 0 {
+		// The rows buffered by the csv.Writer are flushed to the response before it is flushed to the client
+		c.csv.Flush()
+	}
+	c.line()
+	return c.csv.Error()
+}
+
+func (c *csvStatementWriter) WriteFooter(statement domain.Statement) error {
+	c.csv.Write([]string{statement.To.Format(time.RFC3339), "", "", "Closing balance", "", "", statement.ClosingBalance})
+	return c.flush()
+}
+
+// csvText will prefix a free text value starting with =, +, -, @, a tab or a carriage return with a single quote, so that a spreadsheet opening the statement does not evaluate it as a formula
+// Amounts and balances are written as is, as they are numbers starting with - when negative
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// flush will write the rows buffered by the csv.Writer to the response
+func (c *csvStatementWriter) flush() error {
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// textStatementWriter writes a statement as plain text laid out for printing, with fixed width columns
+type textStatementWriter struct {
+	*statementStream
+}
+
+// textStatementRow is the layout of a row of a text statement: date, transaction id, counterparty, description, amount and balance
+const textStatementRow = "%-19s  %10s  %-32s  %-30s  %16s  %16s\n"
+
+func (t *textStatementWriter) WriteHeader(statement domain.Statement) error {
+	t.start("text/plain; charset=utf-8", ".txt")
+	_, err := fmt.Fprintf(t.w, "STATEMENT OF ACCOUNT %s\nCurrency: %s\nPeriod:   %s to %s (UTC)\n\n"+textStatementRow+"%s\n"+textStatementRow,
+		statement.AccountID, statement.Currency, statement.From.Format(statementTextTime), statement.To.Format(statementTextTime),
+		"DATE", "ID", "COUNTERPARTY", "DESCRIPTION", "AMOUNT", "BALANCE",
+		textRule(),
+		statement.From.Format(statementTextTime), "", "", "Opening balance", "", statement.OpeningBalance,
+	)
+	return err
+}
+
+func (t *textStatementWriter) WriteLine(line domain.StatementLine) error {
+	description := line.Description
+	if description == "" {
+		description = line.Reference
+	}
+	_, err := fmt.Fprintf(t.w, textStatementRow,
+		line.CompletedAt.Format(statementTextTime), strconv.FormatInt(line.TransactionID, 10), truncate(line.CounterpartyID, 32), truncate(description, 30), line.Amount, line.Balance)
+	if err != nil {
+		return err
+	}
+	t.line()
+	return nil
+}
+
+func (t *textStatementWriter) WriteFooter(statement domain.Statement) error {
+	_, err := fmt.Fprintf(t.w, textStatementRow+"%s\n",
+		statement.To.Format(statementTextTime), "", "", "Closing balance", "", statement.ClosingBalance, textRule())
+	return err
+}
+
+// textRule will return a rule as wide as a row of a text statement
+func textRule() string {
+	return strings.Repeat("-", len(fmt.Sprintf(textStatementRow, "", "", "", "", "", ""))-1)
+}
+
+// truncate will shorten text to at most width characters, ending it with ~ when it is cut
+func truncate(text string, width int) string {
+	if utf8.RuneCountInString(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:width-1]) + "~"
+}
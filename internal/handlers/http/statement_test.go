@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStatement(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	lineAt := time.Date(2024, 3, 10, 9, 30, 0, 0, time.UTC)
+	header := domain.Statement{AccountID: "123", Currency: "USD", From: from, To: to, OpeningBalance: "100"}
+	lines := []domain.StatementLine{
+		{TransactionID: 7, CompletedAt: lineAt, CounterpartyID: "456", Amount: "0.5", Balance: "100.5"},
+		{TransactionID: 8, CompletedAt: lineAt, CounterpartyID: "789", Description: "Rent, March", Reference: "rent-03", Amount: "-25", Balance: "75.5"},
+	}
+	// writeStatement writes header and lines to the writer of the statement, then the footer unless err is set
+	writeStatement := func(err error) func(context.Context, domain.StatementQuery, ports.StatementWriter) error {
+		return func(ctx context.Context, query domain.StatementQuery, w ports.StatementWriter) error {
+			assert.NoError(t, w.WriteHeader(header))
+			for _, line := range lines {
+				assert.NoError(t, w.WriteLine(line))
+			}
+			if err != nil {
+				return err
+			}
+			footer := header
+			footer.ClosingBalance = "75.5"
+			return w.WriteFooter(footer)
+		}
+	}
+	tests := []struct {
+		name        string
+		query       string
+		doMockSvc   func(service *mock_ports.MockTransactionService)
+		statusCode  int
+		contentType string
+		code        domain.ErrorCode
+		check       func(t *testing.T, body string)
+	}{
+		{
+			name:  "Test Case Positive - JSON",
+			query: "?from=2024-03-01T00:00:00Z&to=2024-04-01T00:00:00Z",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Statement(gomock.Any(), domain.StatementQuery{AccountID: "123", From: &from, To: &to}, gomock.Any()).DoAndReturn(writeStatement(nil))
+			},
+			statusCode:  200,
+			contentType: "application/json",
+			check: func(t *testing.T, body string) {
+				var statement domain.Statement
+				assert.NoError(t, json.Unmarshal([]byte(body), &statement))
+				want := header
+				want.Lines = lines
+				want.ClosingBalance = "75.5"
+				assert.Equal(t, want, statement)
+			},
+		},
+		{
+			name:  "Test Case Positive - CSV",
+			query: "?format=csv",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Statement(gomock.Any(), domain.StatementQuery{AccountID: "123"}, gomock.Any()).DoAndReturn(writeStatement(nil))
+			},
+			statusCode:  200,
+			contentType: "text/csv; charset=utf-8",
+			check: func(t *testing.T, body string) {
+				assert.Equal(t, "date,transaction_id,counterparty_account_id,description,reference,amount,balance\n"+
+					"2024-03-01T00:00:00Z,,,Opening balance,,,100\n"+
+					"2024-03-10T09:30:00Z,7,456,,,0.5,100.5\n"+
+					"2024-03-10T09:30:00Z,8,789,\"Rent, March\",rent-03,-25,75.5\n"+
+					"2024-04-01T00:00:00Z,,,Closing balance,,,75.5\n", body)
+			},
+		},
+		{
+			name:  "Test Case Positive - Text",
+			query: "?format=text",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Statement(gomock.Any(), domain.StatementQuery{AccountID: "123"}, gomock.Any()).DoAndReturn(writeStatement(nil))
+			},
+			statusCode:  200,
+			contentType: "text/plain; charset=utf-8",
+			check: func(t *testing.T, body string) {
+				assert.Contains(t, body, "STATEMENT OF ACCOUNT 123\n")
+				assert.Contains(t, body, "Period:   2024-03-01 00:00:00 to 2024-04-01 00:00:00 (UTC)\n")
+				assert.Regexp(t, `2024-03-10 09:30:00\s+8\s+789\s+Rent, March\s+-25\s+75.5\n`, body)
+				assert.Regexp(t, `Closing balance\s+75.5\n-+\n$`, body)
+			},
+		},
+		{
+			name:  "Test Case Negative - Invalid format",
+			query: "?format=pdf",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+			},
+			statusCode: 400,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:  "Test Case Negative - Invalid from",
+			query: "?from=yesterday",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+			},
+			statusCode: 400,
+			code:       domain.ErrCodeMalformedRequest,
+		},
+		{
+			name:  "Test Case Negative - Account does not exist",
+			query: "",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Statement(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist))
+			},
+			statusCode: 404,
+			code:       domain.ErrCodeAccountNotFound,
+		},
+		{
+			name:  "Test Case Negative - Failure after the statement started",
+			query: "",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Statement(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(writeStatement(errors.New("random error")))
+			},
+			statusCode:  200,
+			contentType: "application/json",
+			check: func(t *testing.T, body string) {
+				assert.NotContains(t, body, "closing_balance")
+				assert.Error(t, json.Unmarshal([]byte(body), &domain.Statement{}))
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+			tc.doMockSvc(mockTransSvc)
+			handler := http.HandlerFunc(NewTransactionHandler(mockTransSvc).GetStatement)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("account_id", "123")
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/accounts/123/statement"+tc.query, nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				assert.Equal(t, tc.contentType, rec.Header().Get("Content-Type"))
+				tc.check(t, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "March salary", truncate("March salary", 12))
+	assert.Equal(t, "March sa~", truncate("March salary", 9))
+	assert.Equal(t, strings.Repeat("é", 4)+"~", truncate(strings.Repeat("é", 10), 5))
+}
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "Test Case Positive - Plain text", value: "Rent, March", want: "Rent, March"},
+		{name: "Test Case Positive - Empty", value: "", want: ""},
+		{name: "Test Case Positive - Formula", value: "=HYPERLINK(\"http://example.com\")", want: "'=HYPERLINK(\"http://example.com\")"},
+		{name: "Test Case Positive - Plus", value: "+1-555-0100", want: "'+1-555-0100"},
+		{name: "Test Case Positive - Minus", value: "-2+3", want: "'-2+3"},
+		{name: "Test Case Positive - At", value: "@SUM(A1:A2)", want: "'@SUM(A1:A2)"},
+		{name: "Test Case Positive - Tab", value: "\t=1", want: "'\t=1"},
+		{name: "Test Case Positive - Carriage return", value: "\r=1", want: "'\r=1"},
+		{name: "Test Case Positive - Formula character inside text", value: "INV-42 = paid", want: "INV-42 = paid"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, csvText(tc.value))
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/internal/middleware"
+	"account-test/static"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+)
+
+type TransactionHandler struct {
+	transactionSvc ports.TransactionService
+}
+
+func NewTransactionHandler(transactionSvc ports.TransactionService) *TransactionHandler {
+	return &TransactionHandler{
+		transactionSvc: transactionSvc,
+	}
+}
+
+// PostTransaction will accept a HTTP body containing a domain.Transaction object
+// The client is identified by middleware.ClientID, so that the reference of the transfer is unique per API key
+// The function will move the amount from the source account to the destination account through ports.TransactionService
+// The body is read up to middleware.MaxTransferBodyBytes, a larger body is rejected with HTTP status Request Entity Too Large
+// The function will return HTTP status OK and no body if the transfer is successful, or HTTP status Accepted and the transaction with its approval_id when it is held for approval
+func (h *TransactionHandler) PostTransaction(w http.ResponseWriter, r *http.Request) {
+	postTransactionBody := domain.Transaction{}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, middleware.MaxTransferBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeRequestTooLarge, static.ErrTransferBodyTooLarge))
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+	err = json.Unmarshal(body, &postTransactionBody)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrUnableToReadBody))
+		return
+	}
+
+	transaction, err := h.transactionSvc.Transfer(r.Context(), domain.TransferCommand{
+		SourceID:      postTransactionBody.SourceID,
+		DestinationID: postTransactionBody.DestinationID,
+		Amount:        postTransactionBody.Amount,
+		Description:   postTransactionBody.Description,
+		Reference:     postTransactionBody.Reference,
+		Metadata:      postTransactionBody.Metadata,
+		ClientID:      middleware.ClientID(r),
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	if transaction.Status == domain.TransactionStatusPendingApproval {
+		utils.JSONResponse(w, http.StatusAccepted, transaction)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, nil)
+}
+
+// ApproveTransfer will accept a HTTP path parameter of approval_id
+// The function will approve the transfer pending approval and process it through ports.TransactionService, with the client of the request as the reviewer
+// The function will return HTTP status OK and the completed approval
+func (h *TransactionHandler) ApproveTransfer(w http.ResponseWriter, r *http.Request) {
+	h.reviewTransfer(w, r, true)
+}
+
+// RejectTransfer will accept a HTTP path parameter of approval_id
+// The function will reject the transfer pending approval through ports.TransactionService, with the client of the request as the reviewer
+// The function will return HTTP status OK and the rejected approval
+func (h *TransactionHandler) RejectTransfer(w http.ResponseWriter, r *http.Request) {
+	h.reviewTransfer(w, r, false)
+}
+
+func (h *TransactionHandler) reviewTransfer(w http.ResponseWriter, r *http.Request, approve bool) {
+	approvalID, err := strconv.ParseInt(chi.URLParam(r, "approval_id"), 10, 64)
+	if err != nil {
+		utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidApprovalID))
+		return
+	}
+
+	approval, err := h.transactionSvc.ReviewTransfer(r.Context(), domain.ReviewTransferCommand{
+		ApprovalID: approvalID,
+		Approve:    approve,
+		Actor:      middleware.ClientID(r),
+	})
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, approval)
+}
+
+// ListTransactions will accept HTTP query parameters account_id, limit and before_id
+// the function will retrieve a page of the account's transactions through ports.TransactionService, returned as a domain.TransactionList object
+func (h *TransactionHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	query := domain.ListTransactionsQuery{AccountID: r.URL.Query().Get("account_id")}
+	var err error
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil {
+			utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidPaginationParameter))
+			return
+		}
+	}
+	if beforeID := r.URL.Query().Get("before_id"); beforeID != "" {
+		query.BeforeID, err = strconv.ParseInt(beforeID, 10, 64)
+		if err != nil {
+			utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeMalformedRequest, static.ErrInvalidPaginationParameter))
+			return
+		}
+	}
+
+	transactions, err := h.transactionSvc.ListTransactions(r.Context(), query)
+	if err != nil {
+		utils.ErrorResponse(w, r, err)
+		return
+	}
+	utils.JSONResponse(w, http.StatusOK, transactions)
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/middleware"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/static"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostTransaction(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name       string
+		body       []byte
+		apiKey     string
+		doMockSvc  func(service *mock_ports.MockTransactionService)
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name: "Test Case Positive",
+			body: []byte(`{"source_account_id":"123","destination_account_id":"1234","amount":"19"}`),
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), domain.TransferCommand{SourceID: "123", DestinationID: "1234", Amount: "19", ClientID: "ip:192.0.2.1"}).Return(
+					domain.Transaction{SourceID: "123", DestinationID: "1234", Amount: "19"},
+					nil,
+				)
+			},
+			statusCode: 200,
+		},
+		{
+			name:   "Test Case Positive - With details",
+			body:   []byte(`{"source_account_id":"123","destination_account_id":"1234","amount":"19","description":"March salary","reference":"payroll-2024-03","metadata":{"employee":42}}`),
+			apiKey: "secret",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), domain.TransferCommand{
+					SourceID:      "123",
+					DestinationID: "1234",
+					Amount:        "19",
+					Description:   "March salary",
+					Reference:     "payroll-2024-03",
+					Metadata:      json.RawMessage(`{"employee":42}`),
+					ClientID:      "key:2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b",
+				}).Return(
+					domain.Transaction{SourceID: "123", DestinationID: "1234", Amount: "19"},
+					nil,
+				)
+			},
+			statusCode: 200,
+		},
+		{
+			name: "Test Case Positive - Pending approval",
+			body: []byte(`{"source_account_id":"123","destination_account_id":"1234","amount":"5000"}`),
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(
+					domain.Transaction{SourceID: "123", DestinationID: "1234", Amount: "5000", Status: domain.TransactionStatusPendingApproval, ApprovalID: 7},
+					nil,
+				)
+			},
+			statusCode: 202,
+		},
+		{
+			name: "Test Case Negative - Malformed body",
+			body: []byte(`not json`),
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+			},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name: "Test Case Negative - Body too large",
+			body: []byte(`{"source_account_id":"123","destination_account_id":"1234","amount":"19","description":"` + strings.Repeat("a", middleware.MaxTransferBodyBytes) + `"}`),
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+			},
+			code:       domain.ErrCodeRequestTooLarge,
+			statusCode: 413,
+		},
+		{
+			name: "Test Case Negative - Insufficient funds",
+			body: []byte(`{"source_account_id":"123","destination_account_id":"1234","amount":"500"}`),
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().Transfer(gomock.Any(), gomock.Any()).Return(
+					domain.Transaction{},
+					domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount),
+				)
+			},
+			code:       domain.ErrCodeInsufficientFunds,
+			statusCode: 422,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+			tc.doMockSvc(mockTransSvc)
+			handler := http.HandlerFunc(NewTransactionHandler(mockTransSvc).PostTransaction)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewReader(tc.body))
+			if tc.apiKey != "" {
+				req.Header.Set(middleware.HeaderAPIKey, tc.apiKey)
+			}
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			}
+		})
+	}
+}
+
+func TestReviewTransfer(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	completed := domain.TransferApproval{
+		ID:            7,
+		SourceID:      "123",
+		DestinationID: "1234",
+		Amount:        "5000",
+		Status:        domain.TransferApprovalStatusCompleted,
+		InitiatedBy:   "key:abc",
+		ReviewedBy:    "ip:192.0.2.1",
+		TransactionID: 11,
+		CreatedAt:     time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		ExpiresAt:     time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name       string
+		approvalID string
+		review     func(h *TransactionHandler) http.HandlerFunc
+		doMockSvc  func(service *mock_ports.MockTransactionService)
+		want       domain.TransferApproval
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name:       "Test Case Positive",
+			approvalID: "7",
+			review:     func(h *TransactionHandler) http.HandlerFunc { return h.ApproveTransfer },
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().ReviewTransfer(gomock.Any(), domain.ReviewTransferCommand{ApprovalID: 7, Approve: true, Actor: "ip:192.0.2.1"}).Return(completed, nil)
+			},
+			want:       completed,
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Negative - Rejected by initiator",
+			approvalID: "7",
+			review:     func(h *TransactionHandler) http.HandlerFunc { return h.RejectTransfer },
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().ReviewTransfer(gomock.Any(), domain.ReviewTransferCommand{ApprovalID: 7, Actor: "ip:192.0.2.1"}).
+					Return(domain.TransferApproval{}, domain.NewError(domain.ErrCodeForbidden, static.ErrTransferSelfApproval))
+			},
+			code:       domain.ErrCodeForbidden,
+			statusCode: 403,
+		},
+		{
+			name:       "Test Case Negative - Expired",
+			approvalID: "7",
+			review:     func(h *TransactionHandler) http.HandlerFunc { return h.ApproveTransfer },
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().ReviewTransfer(gomock.Any(), gomock.Any()).Return(domain.TransferApproval{}, domain.NewError(domain.ErrCodeApprovalExpired, static.ErrTransferApprovalExpired))
+			},
+			code:       domain.ErrCodeApprovalExpired,
+			statusCode: 409,
+		},
+		{
+			name:       "Test Case Negative - Invalid approval ID",
+			approvalID: "abc",
+			review:     func(h *TransactionHandler) http.HandlerFunc { return h.ApproveTransfer },
+			doMockSvc:  func(service *mock_ports.MockTransactionService) {},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+			tc.doMockSvc(mockTransSvc)
+			handler := tc.review(NewTransactionHandler(mockTransSvc))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("approval_id", tc.approvalID)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/transactions/approvals/"+tc.approvalID+"/approve", nil)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			req.RemoteAddr = "192.0.2.1:1234"
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.TransferApproval
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
+
+func TestListTransactions(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name       string
+		url        string
+		doMockSvc  func(service *mock_ports.MockTransactionService)
+		want       domain.TransactionList
+		code       domain.ErrorCode
+		statusCode int
+	}{
+		{
+			name: "Test Case Positive",
+			url:  "/transactions?account_id=123&limit=1&before_id=10",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().ListTransactions(gomock.Any(), domain.ListTransactionsQuery{AccountID: "123", Limit: 1, BeforeID: 10}).Return(
+					domain.TransactionList{Transactions: []domain.Transaction{{ID: 9, SourceID: "123", DestinationID: "1234", Amount: "19", Status: domain.TransactionStatusCompleted}}, NextBeforeID: 9},
+					nil,
+				)
+			},
+			want:       domain.TransactionList{Transactions: []domain.Transaction{{ID: 9, SourceID: "123", DestinationID: "1234", Amount: "19", Status: domain.TransactionStatusCompleted}}, NextBeforeID: 9},
+			statusCode: 200,
+		},
+		{
+			name: "Test Case Negative - Invalid limit",
+			url:  "/transactions?account_id=123&limit=abc",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+			},
+			code:       domain.ErrCodeMalformedRequest,
+			statusCode: 400,
+		},
+		{
+			name: "Test Case Negative - Account does not exist",
+			url:  "/transactions?account_id=123",
+			doMockSvc: func(service *mock_ports.MockTransactionService) {
+				service.EXPECT().ListTransactions(gomock.Any(), gomock.Any()).Return(
+					domain.TransactionList{},
+					domain.NewError(domain.ErrCodeAccountNotFound, static.ErrAccountDoesNotExist),
+				)
+			},
+			code:       domain.ErrCodeAccountNotFound,
+			statusCode: 404,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockTransSvc := mock_ports.NewMockTransactionService(mockCtrl)
+			tc.doMockSvc(mockTransSvc)
+			handler := http.HandlerFunc(NewTransactionHandler(mockTransSvc).ListTransactions)
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", tc.url, nil)
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if len(tc.code) > 0 {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, tc.code, problem.Code)
+			} else {
+				var response domain.TransactionList
+				_ = json.NewDecoder(rec.Body).Decode(&response)
+				assert.Equal(t, tc.want, response)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextHandler(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]any
+	}{
+		{
+			name: "Test Case Positive - Request ID and trace ID",
+			ctx:  trace.ContextWithSpanContext(WithRequestID(context.Background(), "abc"), spanContext),
+			want: map[string]any{"request_id": "abc", "trace_id": "4bf92f3577b34da6a3ce929d0e0e4736", "account_id": "123"},
+		},
+		{
+			name: "Test Case Positive - No request ID",
+			ctx:  context.Background(),
+			want: map[string]any{"account_id": "123"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := slog.New(ContextHandler{Handler: slog.NewJSONHandler(&buf, nil)})
+			log.InfoContext(tc.ctx, "account created", "account_id", "123")
+
+			var record map[string]any
+			assert.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+			for key, value := range tc.want {
+				assert.Equal(t, value, record[key])
+			}
+			if _, ok := tc.want["request_id"]; !ok {
+				assert.NotContains(t, record, "request_id")
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level string
+		want  slog.Level
+		err   bool
+	}{
+		{level: "debug", want: slog.LevelDebug},
+		{level: "", want: slog.LevelInfo},
+		{level: "WARN", want: slog.LevelWarn},
+		{level: "error", want: slog.LevelError},
+		{level: "verbose", want: slog.LevelInfo, err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.level, func(t *testing.T) {
+			level, err := ParseLevel(tc.level)
+			assert.Equal(t, tc.want, level)
+			assert.Equal(t, tc.err, err != nil)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"account-test/internal/core/domain"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	chimiddleware "github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	namespace = "account"
+
+	// unmatchedRoute is the route label for requests that did not match a registered route, so unknown paths do not create new series
+	unmatchedRoute = "unmatched"
+)
+
+// Metrics holds the Prometheus collectors of the app on a registry of its own
+// Collectors are registered on a dedicated registry rather than the global one so that tests can create and inspect isolated instances
+type Metrics struct {
+	registry           *prometheus.Registry
+	httpRequests       *prometheus.CounterVec
+	httpDuration       *prometheus.HistogramVec
+	transfersCompleted prometheus.Counter
+	transfersFailed    *prometheus.CounterVec
+	transferVolume     prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, chi route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of HTTP requests by method and chi route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		transfersCompleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_completed_total",
+			Help:      "Number of transfers completed.",
+		}),
+		transfersFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfers_failed_total",
+			Help:      "Number of transfers rejected or failed by error code.",
+		}, []string{"reason"}),
+		transferVolume: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transfer_volume_total",
+			Help:      "Total amount moved by completed transfers.",
+		}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.transfersCompleted,
+		m.transfersFailed,
+		m.transferVolume,
+	)
+	return m
+}
+
+// Registry returns the registry holding every collector of the app
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns the HTTP handler exposing the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDB will expose the connection pool statistics of db, such as open, in use and idle connections and wait counts
+func (m *Metrics) RegisterDB(db *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Middleware will record the count and latency of every request labelled by the chi route pattern it matched
+// The route pattern is read after the request is served since chi only resolves it while routing
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.httpDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// TransferCompleted will count a completed transfer and add its amount to the volume moved
+func (m *Metrics) TransferCompleted(amount float64) {
+	m.transfersCompleted.Inc()
+	m.transferVolume.Add(amount)
+}
+
+// TransferFailed will count a rejected or failed transfer under the error code that caused it
+func (m *Metrics) TransferFailed(reason domain.ErrorCode) {
+	m.transfersFailed.WithLabelValues(string(reason)).Inc()
+}
+
+// TransfersCompleted returns the counter of completed transfers
+func (m *Metrics) TransfersCompleted() prometheus.Counter {
+	return m.transfersCompleted
+}
+
+// TransfersFailed returns the counter of failed transfers for an error code
+func (m *Metrics) TransfersFailed(reason domain.ErrorCode) prometheus.Counter {
+	return m.transfersFailed.WithLabelValues(string(reason))
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"account-test/internal/core/domain"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	_ "github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/accounts/{account_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		route  string
+		status string
+	}{
+		{name: "Test Case Positive - Labelled by route pattern", path: "/accounts/123", route: "/accounts/{account_id}", status: "404"},
+		{name: "Test Case Positive - Same route pattern", path: "/accounts/456", route: "/accounts/{account_id}", status: "404"},
+		{name: "Test Case Positive - Implicit status OK", path: "/health", route: "/health", status: "200"},
+		{name: "Test Case Positive - Unmatched route", path: "/unknown/123", route: unmatchedRoute, status: "404"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tc.path, nil))
+		})
+	}
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/accounts/{account_id}", "404")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/health", "200")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", unmatchedRoute, "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	db, err := sql.Open("postgres", "host=localhost")
+	assert.NoError(t, err)
+	defer db.Close()
+	m.RegisterDB(db, "postgres")
+	m.TransferCompleted(10.5)
+	m.TransferCompleted(2)
+	m.TransferFailed(domain.ErrCodeInsufficientFunds)
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	assert.Equal(t, 200, rec.Result().StatusCode)
+	assert.Contains(t, string(body), "account_transfers_completed_total 2")
+	assert.Contains(t, string(body), "account_transfer_volume_total 12.5")
+	assert.Contains(t, string(body), `account_transfers_failed_total{reason="INSUFFICIENT_FUNDS"} 1`)
+	assert.Contains(t, string(body), `go_sql_open_connections{db_name="postgres"} 0`)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/utils"
+	"account-test/static"
+	"crypto/subtle"
+	"net/http"
+)
+
+type AdminConfig struct {
+	// APIKeys are the API keys allowed to call the admin endpoints, the admin endpoints reject every request when empty
+	APIKeys []string
+}
+
+// Admin returns a middleware only letting through requests with one of the admin API keys of config in the X-API-Key header
+// Other requests are rejected with HTTP status 403 and code FORBIDDEN
+func Admin(config *AdminConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !IsAdmin(config, r.Header.Get(HeaderAPIKey)) {
+				utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeForbidden, static.ErrAdminAPIKeyRequired))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IsAdmin reports whether apiKey is one of the admin API keys of config, comparing keys in constant time
+func IsAdmin(config *AdminConfig, apiKey string) bool {
+	if apiKey == "" {
+		return false
+	}
+	admin := false
+	for _, key := range config.APIKeys {
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+			admin = true
+		}
+	}
+	return admin
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"account-test/internal/core/domain"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdmin(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiKeys    []string
+		apiKey     string
+		statusCode int
+	}{
+		{
+			name:       "Test Case Positive",
+			apiKeys:    []string{"old-admin-key", "admin-key"},
+			apiKey:     "admin-key",
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Negative - Missing API key",
+			apiKeys:    []string{"admin-key"},
+			statusCode: 403,
+		},
+		{
+			name:       "Test Case Negative - Not an admin API key",
+			apiKeys:    []string{"admin-key"},
+			apiKey:     "client-key",
+			statusCode: 403,
+		},
+		{
+			name:       "Test Case Negative - No admin API keys configured",
+			apiKey:     "admin-key",
+			statusCode: 403,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := Admin(&AdminConfig{APIKeys: tc.apiKeys})(http.HandlerFunc(okHandler))
+			req := httptest.NewRequest("POST", "/admin/reconciliations", nil)
+			if tc.apiKey != "" {
+				req.Header.Set(HeaderAPIKey, tc.apiKey)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Code)
+			if tc.statusCode == http.StatusForbidden {
+				var problem domain.Problem
+				_ = json.NewDecoder(rec.Body).Decode(&problem)
+				assert.Equal(t, domain.ErrCodeForbidden, problem.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/logger"
+	"net/http"
+)
+
+// Principal returns a middleware injecting the domain.Principal of the request into its context, so that the writes made for it are recorded in the audit log with who made them and from where
+// The principal is authenticated when the X-API-Key header holds one of the admin API keys of config
+// It must run after RequestID so that the request ID is recorded too
+func Principal(config *AdminConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := domain.WithPrincipal(r.Context(), domain.Principal{
+				Actor:         ClientID(r),
+				RequestID:     logger.RequestID(r.Context()),
+				ClientIP:      ClientIP(r.RemoteAddr),
+				Authenticated: IsAdmin(config, r.Header.Get(HeaderAPIKey)),
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
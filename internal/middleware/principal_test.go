@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"account-test/internal/core/domain"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrincipal(t *testing.T) {
+	tests := []struct {
+		name          string
+		apiKey        string
+		actor         string
+		authenticated bool
+	}{
+		{name: "Test Case Positive - API key", apiKey: "abc", actor: "key:ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", authenticated: true},
+		{name: "Test Case Positive - Unknown API key", apiKey: "made-up", actor: "key:da53422a4618735f8ae72abdb21c16d340849b1a510ef20768b131171f8f7122"},
+		{name: "Test Case Positive - Client IP", actor: "ip:192.0.2.1"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var principal domain.Principal
+			handler := RequestID(Principal(&AdminConfig{APIKeys: []string{"abc"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = domain.PrincipalFrom(r.Context())
+			})))
+			req := httptest.NewRequest("POST", "/accounts", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set(HeaderRequestID, "req-1")
+			if tc.apiKey != "" {
+				req.Header.Set(HeaderAPIKey, tc.apiKey)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tc.actor, principal.Actor)
+			assert.Equal(t, "req-1", principal.RequestID)
+			assert.Equal(t, "192.0.2.1", principal.ClientIP)
+			assert.Equal(t, tc.authenticated, principal.Authenticated)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/internal/core/ports"
+	"account-test/internal/core/utils"
+	"account-test/static"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+
+	HeaderAPIKey     = "X-API-Key"
+	HeaderRetryAfter = "Retry-After"
+
+	// MaxTransferBodyBytes bounds the body of a transfer request read by TransferSourceKey and by the transfer handler
+	MaxTransferBodyBytes = 64 << 10
+)
+
+type RateLimitConfig struct {
+	Store    string
+	Client   domain.RateLimit
+	Transfer domain.RateLimit
+}
+
+// KeyFunc returns the bucket key a request is limited by
+// An empty key means the request is not subject to the limiter
+type KeyFunc func(r *http.Request) string
+
+// RateLimit returns a middleware taking a token from the bucket named by keyFunc for every request
+// Requests are rejected with HTTP status 429 and a Retry-After header once the bucket is empty
+// Requests are let through when the store returns an error so that an unavailable store does not take the API down
+func RateLimit(store ports.RateLimitRepository, limit domain.RateLimit, keyFunc KeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !limit.Enabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			result, err := store.Take(r.Context(), key, limit)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "rate limit store unavailable, letting request through", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !result.Allowed {
+				slog.InfoContext(r.Context(), "request rate limited", "method", r.Method, "path", r.URL.Path, "retry_after", result.RetryAfter.String())
+				w.Header().Set(HeaderRetryAfter, strconv.Itoa(RetryAfterSeconds(result)))
+				utils.ErrorResponse(w, r, domain.NewError(domain.ErrCodeRateLimited, static.ErrTooManyRequests))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RetryAfterSeconds will return the whole number of seconds, at least 1, a client rejected by the limiter should wait before retrying
+func RetryAfterSeconds(result domain.RateLimitResult) int {
+	retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		return 1
+	}
+	return retryAfter
+}
+
+// ClientKey returns a KeyFunc keying a request by the SHA-256 hash of the API key supplied in the X-API-Key header, as ClientID does, when it is one of the admin API keys of config
+// Requests with any other API key are keyed by client IP, so that a client cannot get a fresh bucket by sending made up keys
+func ClientKey(config *AdminConfig) KeyFunc {
+	return func(r *http.Request) string {
+		return ClientKeyOf(config, r.Header.Get(HeaderAPIKey), r.RemoteAddr)
+	}
+}
+
+// ClientKeyOf will key a client as ClientKey does, by its API key when it is one of the admin API keys of config or by the IP of remoteAddr otherwise, for clients not calling over HTTP
+func ClientKeyOf(config *AdminConfig, apiKey string, remoteAddr string) string {
+	if IsAdmin(config, apiKey) {
+		return ClientIDOf(apiKey, remoteAddr)
+	}
+	return "ip:" + ClientIP(remoteAddr)
+}
+
+// ClientIP will return the IP of a client from the remote address of its connection
+func ClientIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+// ClientID will identify the client making a request by the API key supplied in the X-API-Key header, replaced by its SHA-256 hash so that the identifier can be stored without revealing the key, or by client IP when no API key is supplied
+func ClientID(r *http.Request) string {
+	return ClientIDOf(r.Header.Get(HeaderAPIKey), r.RemoteAddr)
+}
+
+// ClientIDOf will identify a client as ClientID does, by its API key or by the IP of remoteAddr when it has none, for clients not calling over HTTP
+func ClientIDOf(apiKey string, remoteAddr string) string {
+	if apiKey != "" {
+		hash := sha256.Sum256([]byte(apiKey))
+		return "key:" + hex.EncodeToString(hash[:])
+	}
+	return "ip:" + ClientIP(remoteAddr)
+}
+
+// TransferSourceKey will key a transfer request by the source_account_id in its domain.Transaction body
+// At most MaxTransferBodyBytes of the body are read, and the body is restored after reading so the handler can decode it again
+// Requests without a readable source account, or whose body is larger than MaxTransferBodyBytes, are not limited here and are left for the handler to reject
+func TransferSourceKey(r *http.Request) string {
+	// One byte past the limit tells an oversized body apart, unlike http.MaxBytesReader it is kept so the body is restored whole
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxTransferBodyBytes+1))
+	r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	if err != nil || len(body) > MaxTransferBodyBytes {
+		return ""
+	}
+
+	transaction := domain.Transaction{}
+	if err := json.Unmarshal(body, &transaction); err != nil || transaction.SourceID == "" {
+		return ""
+	}
+	return "transfer:" + transaction.SourceID
+}
+
+// readCloser reads a request body restored by TransferSourceKey and closes the original body
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"account-test/internal/core/domain"
+	mock_ports "account-test/internal/mocks/ports"
+	"account-test/internal/repositories"
+	"account-test/static"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+func TestRateLimit(t *testing.T) {
+	abcKey := ClientIDOf("abc", "")
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	tests := []struct {
+		name        string
+		limit       domain.RateLimit
+		doMockStore func(store *mock_ports.MockRateLimitRepository)
+		requests    int
+		want        []int
+		retryAfter  string
+	}{
+		{
+			name:  "Test Case Positive - Within burst",
+			limit: domain.RateLimit{Rate: 1, Burst: 2},
+			doMockStore: func(store *mock_ports.MockRateLimitRepository) {
+				store.EXPECT().Take(gomock.Any(), abcKey, gomock.Any()).Return(domain.RateLimitResult{Allowed: true}, nil).Times(2)
+			},
+			requests: 2,
+			want:     []int{200, 200},
+		},
+		{
+			name:  "Test Case Negative - Bucket empty",
+			limit: domain.RateLimit{Rate: 1, Burst: 1},
+			doMockStore: func(store *mock_ports.MockRateLimitRepository) {
+				store.EXPECT().Take(gomock.Any(), abcKey, gomock.Any()).Return(domain.RateLimitResult{Allowed: true}, nil)
+				store.EXPECT().Take(gomock.Any(), abcKey, gomock.Any()).Return(domain.RateLimitResult{Allowed: false, RetryAfter: 1500 * time.Millisecond}, nil)
+			},
+			requests:   2,
+			want:       []int{200, 429},
+			retryAfter: "2",
+		},
+		{
+			name:  "Test Case Positive - Store error lets request through",
+			limit: domain.RateLimit{Rate: 1, Burst: 1},
+			doMockStore: func(store *mock_ports.MockRateLimitRepository) {
+				store.EXPECT().Take(gomock.Any(), gomock.Any(), gomock.Any()).Return(domain.RateLimitResult{}, errors.New("random error"))
+			},
+			requests: 1,
+			want:     []int{200},
+		},
+		{
+			name:  "Test Case Positive - Disabled limit",
+			limit: domain.RateLimit{Rate: 0, Burst: 0},
+			doMockStore: func(store *mock_ports.MockRateLimitRepository) {
+			},
+			requests: 3,
+			want:     []int{200, 200, 200},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStore := mock_ports.NewMockRateLimitRepository(mockCtrl)
+			tc.doMockStore(mockStore)
+			handler := RateLimit(mockStore, tc.limit, ClientKey(&AdminConfig{APIKeys: []string{"abc"}}))(http.HandlerFunc(okHandler))
+
+			var rec *httptest.ResponseRecorder
+			for i := 0; i < tc.requests; i++ {
+				rec = httptest.NewRecorder()
+				req := httptest.NewRequest("GET", "/accounts/123", nil)
+				req.Header.Set(HeaderAPIKey, "abc")
+				handler.ServeHTTP(rec, req)
+				assert.Equal(t, tc.want[i], rec.Result().StatusCode)
+			}
+			if len(tc.retryAfter) > 0 {
+				bodyBytes, _ := io.ReadAll(rec.Body)
+				assert.Contains(t, string(bodyBytes), static.ErrTooManyRequests)
+				assert.Equal(t, tc.retryAfter, rec.Header().Get(HeaderRetryAfter))
+			}
+		})
+	}
+}
+
+func TestRateLimitTransferPerSourceAccount(t *testing.T) {
+	store := repositories.NewMemoryRateLimitPort()
+	handler := RateLimit(store, domain.RateLimit{Rate: 0.001, Burst: 1}, TransferSourceKey)(http.HandlerFunc(okHandler))
+
+	tests := []struct {
+		name       string
+		body       string
+		statusCode int
+	}{
+		{
+			name:       "Test Case Positive - First transfer from source",
+			body:       `{"source_account_id":"123","destination_account_id":"456","amount":"1"}`,
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Negative - Second transfer from same source",
+			body:       `{"source_account_id":"123","destination_account_id":"789","amount":"1"}`,
+			statusCode: 429,
+		},
+		{
+			name:       "Test Case Positive - Transfer from another source",
+			body:       `{"source_account_id":"456","destination_account_id":"123","amount":"1"}`,
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Positive - Unreadable body is left to the handler",
+			body:       `not json`,
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Positive - Oversized body is left to the handler whole",
+			body:       `{"source_account_id":"123","destination_account_id":"789","amount":"1","description":"` + strings.Repeat("a", MaxTransferBodyBytes) + `"}`,
+			statusCode: 200,
+		},
+		{
+			name:       "Test Case Positive - Body one byte over the limit is left to the handler whole",
+			body:       `{"source_account_id":"123"}` + strings.Repeat(" ", MaxTransferBodyBytes-len(`{"source_account_id":"123"}`)+1),
+			statusCode: 200,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("POST", "/transactions", bytes.NewReader([]byte(tc.body)))
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.statusCode, rec.Result().StatusCode)
+			if tc.statusCode == 200 {
+				bodyBytes, _ := io.ReadAll(rec.Body)
+				assert.Equal(t, tc.body, string(bodyBytes))
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	keyFunc := ClientKey(&AdminConfig{APIKeys: []string{"admin-key"}})
+	tests := []struct {
+		name   string
+		apiKey string
+		want   string
+	}{
+		{
+			name:   "Test Case Positive - Admin API key keyed by its hash",
+			apiKey: "admin-key",
+			want:   "key:69a5265506c94c77b787a7d7377b7685a0eff82e33920a71e7ee22cd6154953e",
+		},
+		{
+			name:   "Test Case Positive - Unknown API key keyed by IP",
+			apiKey: "made-up-key",
+			want:   "ip:192.0.2.1",
+		},
+		{
+			name: "Test Case Positive - No API key keyed by IP",
+			want: "ip:192.0.2.1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts/123", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			if tc.apiKey != "" {
+				req.Header.Set(HeaderAPIKey, tc.apiKey)
+			}
+
+			assert.Equal(t, tc.want, keyFunc(req))
+		})
+	}
+}
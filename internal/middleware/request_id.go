@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"account-test/internal/logger"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// validRequestID limits the request IDs accepted from clients so arbitrary values are not written to logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID returns a middleware injecting a request ID into the request context and the X-Request-ID response header
+// The request ID supplied by the client in the X-Request-ID header is reused when valid, otherwise a new one is generated
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(HeaderRequestID)
+		if !IsValidRequestID(id) {
+			id = NewRequestID()
+		}
+		w.Header().Set(HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(logger.WithRequestID(r.Context(), id)))
+	})
+}
+
+// IsValidRequestID reports whether a request ID supplied by a client can be reused
+func IsValidRequestID(id string) bool {
+	return validRequestID.MatchString(id)
+}
+
+// NewRequestID will generate a random 128 bit request ID encoded as hex
+func NewRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
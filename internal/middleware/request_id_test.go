@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"account-test/internal/logger"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		reused bool
+	}{
+		{name: "Test Case Positive - Client request ID reused", header: "abc-123", reused: true},
+		{name: "Test Case Positive - Request ID generated", header: "", reused: false},
+		{name: "Test Case Negative - Invalid client request ID replaced", header: "abc\nINFO forged log line", reused: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ctxID string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = logger.RequestID(r.Context())
+			}))
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/accounts/123", nil)
+			req.Header.Set(HeaderRequestID, tc.header)
+			handler.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, ctxID)
+			assert.Equal(t, ctxID, rec.Header().Get(HeaderRequestID))
+			if tc.reused {
+				assert.Equal(t, tc.header, ctxID)
+			} else {
+				assert.NotEqual(t, tc.header, ctxID)
+				assert.Len(t, ctxID, 32)
+			}
+		})
+	}
+}
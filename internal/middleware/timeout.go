@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type TimeoutConfig struct {
+	Default   time.Duration
+	Transfer  time.Duration
+	Statement time.Duration
+	Admin     time.Duration
+	Readiness time.Duration
+}
+
+// Timeout returns a middleware setting a deadline of timeout on the request context
+// Service and repository calls made with the request context are cancelled once the deadline passes or the client disconnects
+// A timeout of 0 or less leaves the request context unchanged
+func Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if timeout <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
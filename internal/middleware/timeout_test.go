@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		hasDeadline bool
+	}{
+		{name: "Test Case Positive - Deadline set", timeout: time.Second, hasDeadline: true},
+		{name: "Test Case Positive - Disabled", timeout: 0, hasDeadline: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var deadline time.Time
+			var ok bool
+			handler := Timeout(tc.timeout)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			}))
+			start := time.Now()
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/accounts/123", nil))
+
+			assert.Equal(t, tc.hasDeadline, ok)
+			if tc.hasDeadline {
+				assert.WithinDuration(t, start.Add(tc.timeout), deadline, 100*time.Millisecond)
+			}
+		})
+	}
+}
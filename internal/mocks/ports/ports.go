@@ -6,12 +6,388 @@ package mock_ports
 
 import (
 	domain "account-test/internal/core/domain"
+	ports "account-test/internal/core/ports"
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
 
+// MockAccountService is a mock of AccountService interface.
+type MockAccountService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountServiceMockRecorder
+}
+
+// MockAccountServiceMockRecorder is the mock recorder for MockAccountService.
+type MockAccountServiceMockRecorder struct {
+	mock *MockAccountService
+}
+
+// NewMockAccountService creates a new mock instance.
+func NewMockAccountService(ctrl *gomock.Controller) *MockAccountService {
+	mock := &MockAccountService{ctrl: ctrl}
+	mock.recorder = &MockAccountServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountService) EXPECT() *MockAccountServiceMockRecorder {
+	return m.recorder
+}
+
+// CreateAccount mocks base method.
+func (m *MockAccountService) CreateAccount(ctx context.Context, cmd domain.CreateAccountCommand) (domain.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccount", ctx, cmd)
+	ret0, _ := ret[0].(domain.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccount indicates an expected call of CreateAccount.
+func (mr *MockAccountServiceMockRecorder) CreateAccount(ctx, cmd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockAccountService)(nil).CreateAccount), ctx, cmd)
+}
+
+// GetAccount mocks base method.
+func (m *MockAccountService) GetAccount(ctx context.Context, id string) (domain.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", ctx, id)
+	ret0, _ := ret[0].(domain.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockAccountServiceMockRecorder) GetAccount(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccountService)(nil).GetAccount), ctx, id)
+}
+
+// GetBalance mocks base method.
+func (m *MockAccountService) GetBalance(ctx context.Context, id string, asOf *time.Time) (domain.Balance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", ctx, id, asOf)
+	ret0, _ := ret[0].(domain.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockAccountServiceMockRecorder) GetBalance(ctx, id, asOf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockAccountService)(nil).GetBalance), ctx, id, asOf)
+}
+
+// ListAccounts mocks base method.
+func (m *MockAccountService) ListAccounts(ctx context.Context, query domain.ListAccountsQuery) (domain.AccountList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccounts", ctx, query)
+	ret0, _ := ret[0].(domain.AccountList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccounts indicates an expected call of ListAccounts.
+func (mr *MockAccountServiceMockRecorder) ListAccounts(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockAccountService)(nil).ListAccounts), ctx, query)
+}
+
+// UpdateAccount mocks base method.
+func (m *MockAccountService) UpdateAccount(ctx context.Context, cmd domain.UpdateAccountCommand) (domain.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccount", ctx, cmd)
+	ret0, _ := ret[0].(domain.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccount indicates an expected call of UpdateAccount.
+func (mr *MockAccountServiceMockRecorder) UpdateAccount(ctx, cmd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockAccountService)(nil).UpdateAccount), ctx, cmd)
+}
+
+// MockTransactionService is a mock of TransactionService interface.
+type MockTransactionService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransactionServiceMockRecorder
+}
+
+// MockTransactionServiceMockRecorder is the mock recorder for MockTransactionService.
+type MockTransactionServiceMockRecorder struct {
+	mock *MockTransactionService
+}
+
+// NewMockTransactionService creates a new mock instance.
+func NewMockTransactionService(ctrl *gomock.Controller) *MockTransactionService {
+	mock := &MockTransactionService{ctrl: ctrl}
+	mock.recorder = &MockTransactionServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransactionService) EXPECT() *MockTransactionServiceMockRecorder {
+	return m.recorder
+}
+
+// ListTransactions mocks base method.
+func (m *MockTransactionService) ListTransactions(ctx context.Context, query domain.ListTransactionsQuery) (domain.TransactionList, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransactions", ctx, query)
+	ret0, _ := ret[0].(domain.TransactionList)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransactions indicates an expected call of ListTransactions.
+func (mr *MockTransactionServiceMockRecorder) ListTransactions(ctx, query interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactions", reflect.TypeOf((*MockTransactionService)(nil).ListTransactions), ctx, query)
+}
+
+// ReviewTransfer mocks base method.
+func (m *MockTransactionService) ReviewTransfer(ctx context.Context, cmd domain.ReviewTransferCommand) (domain.TransferApproval, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewTransfer", ctx, cmd)
+	ret0, _ := ret[0].(domain.TransferApproval)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewTransfer indicates an expected call of ReviewTransfer.
+func (mr *MockTransactionServiceMockRecorder) ReviewTransfer(ctx, cmd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewTransfer", reflect.TypeOf((*MockTransactionService)(nil).ReviewTransfer), ctx, cmd)
+}
+
+// Statement mocks base method.
+func (m *MockTransactionService) Statement(ctx context.Context, query domain.StatementQuery, w ports.StatementWriter) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Statement", ctx, query, w)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Statement indicates an expected call of Statement.
+func (mr *MockTransactionServiceMockRecorder) Statement(ctx, query, w interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Statement", reflect.TypeOf((*MockTransactionService)(nil).Statement), ctx, query, w)
+}
+
+// Transfer mocks base method.
+func (m *MockTransactionService) Transfer(ctx context.Context, cmd domain.TransferCommand) (domain.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Transfer", ctx, cmd)
+	ret0, _ := ret[0].(domain.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Transfer indicates an expected call of Transfer.
+func (mr *MockTransactionServiceMockRecorder) Transfer(ctx, cmd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockTransactionService)(nil).Transfer), ctx, cmd)
+}
+
+// MockStatementWriter is a mock of StatementWriter interface.
+type MockStatementWriter struct {
+	ctrl     *gomock.Controller
+	recorder *MockStatementWriterMockRecorder
+}
+
+// MockStatementWriterMockRecorder is the mock recorder for MockStatementWriter.
+type MockStatementWriterMockRecorder struct {
+	mock *MockStatementWriter
+}
+
+// NewMockStatementWriter creates a new mock instance.
+func NewMockStatementWriter(ctrl *gomock.Controller) *MockStatementWriter {
+	mock := &MockStatementWriter{ctrl: ctrl}
+	mock.recorder = &MockStatementWriterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStatementWriter) EXPECT() *MockStatementWriterMockRecorder {
+	return m.recorder
+}
+
+// WriteFooter mocks base method.
+func (m *MockStatementWriter) WriteFooter(statement domain.Statement) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteFooter", statement)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteFooter indicates an expected call of WriteFooter.
+func (mr *MockStatementWriterMockRecorder) WriteFooter(statement interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteFooter", reflect.TypeOf((*MockStatementWriter)(nil).WriteFooter), statement)
+}
+
+// WriteHeader mocks base method.
+func (m *MockStatementWriter) WriteHeader(statement domain.Statement) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteHeader", statement)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteHeader indicates an expected call of WriteHeader.
+func (mr *MockStatementWriterMockRecorder) WriteHeader(statement interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteHeader", reflect.TypeOf((*MockStatementWriter)(nil).WriteHeader), statement)
+}
+
+// WriteLine mocks base method.
+func (m *MockStatementWriter) WriteLine(line domain.StatementLine) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WriteLine", line)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WriteLine indicates an expected call of WriteLine.
+func (mr *MockStatementWriterMockRecorder) WriteLine(line interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WriteLine", reflect.TypeOf((*MockStatementWriter)(nil).WriteLine), line)
+}
+
+// MockReconciliationService is a mock of ReconciliationService interface.
+type MockReconciliationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockReconciliationServiceMockRecorder
+}
+
+// MockReconciliationServiceMockRecorder is the mock recorder for MockReconciliationService.
+type MockReconciliationServiceMockRecorder struct {
+	mock *MockReconciliationService
+}
+
+// NewMockReconciliationService creates a new mock instance.
+func NewMockReconciliationService(ctrl *gomock.Controller) *MockReconciliationService {
+	mock := &MockReconciliationService{ctrl: ctrl}
+	mock.recorder = &MockReconciliationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReconciliationService) EXPECT() *MockReconciliationServiceMockRecorder {
+	return m.recorder
+}
+
+// Reconcile mocks base method.
+func (m *MockReconciliationService) Reconcile(ctx context.Context, cmd domain.ReconcileCommand) (domain.Reconciliation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reconcile", ctx, cmd)
+	ret0, _ := ret[0].(domain.Reconciliation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reconcile indicates an expected call of Reconcile.
+func (mr *MockReconciliationServiceMockRecorder) Reconcile(ctx, cmd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reconcile", reflect.TypeOf((*MockReconciliationService)(nil).Reconcile), ctx, cmd)
+}
+
+// MockAdjustmentService is a mock of AdjustmentService interface.
+type MockAdjustmentService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdjustmentServiceMockRecorder
+}
+
+// MockAdjustmentServiceMockRecorder is the mock recorder for MockAdjustmentService.
+type MockAdjustmentServiceMockRecorder struct {
+	mock *MockAdjustmentService
+}
+
+// NewMockAdjustmentService creates a new mock instance.
+func NewMockAdjustmentService(ctrl *gomock.Controller) *MockAdjustmentService {
+	mock := &MockAdjustmentService{ctrl: ctrl}
+	mock.recorder = &MockAdjustmentServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdjustmentService) EXPECT() *MockAdjustmentServiceMockRecorder {
+	return m.recorder
+}
+
+// Adjust mocks base method.
+func (m *MockAdjustmentService) Adjust(ctx context.Context, cmd domain.AdjustmentCommand) (domain.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Adjust", ctx, cmd)
+	ret0, _ := ret[0].(domain.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Adjust indicates an expected call of Adjust.
+func (mr *MockAdjustmentServiceMockRecorder) Adjust(ctx, cmd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Adjust", reflect.TypeOf((*MockAdjustmentService)(nil).Adjust), ctx, cmd)
+}
+
+// ReviewAdjustment mocks base method.
+func (m *MockAdjustmentService) ReviewAdjustment(ctx context.Context, cmd domain.ReviewAdjustmentCommand) (domain.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewAdjustment", ctx, cmd)
+	ret0, _ := ret[0].(domain.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewAdjustment indicates an expected call of ReviewAdjustment.
+func (mr *MockAdjustmentServiceMockRecorder) ReviewAdjustment(ctx, cmd interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewAdjustment", reflect.TypeOf((*MockAdjustmentService)(nil).ReviewAdjustment), ctx, cmd)
+}
+
+// MockAuditService is a mock of AuditService interface.
+type MockAuditService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditServiceMockRecorder
+}
+
+// MockAuditServiceMockRecorder is the mock recorder for MockAuditService.
+type MockAuditServiceMockRecorder struct {
+	mock *MockAuditService
+}
+
+// NewMockAuditService creates a new mock instance.
+func NewMockAuditService(ctrl *gomock.Controller) *MockAuditService {
+	mock := &MockAuditService{ctrl: ctrl}
+	mock.recorder = &MockAuditServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditService) EXPECT() *MockAuditServiceMockRecorder {
+	return m.recorder
+}
+
+// VerifyAuditLog mocks base method.
+func (m *MockAuditService) VerifyAuditLog(ctx context.Context, anchor string) (domain.AuditVerification, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyAuditLog", ctx, anchor)
+	ret0, _ := ret[0].(domain.AuditVerification)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyAuditLog indicates an expected call of VerifyAuditLog.
+func (mr *MockAuditServiceMockRecorder) VerifyAuditLog(ctx, anchor interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyAuditLog", reflect.TypeOf((*MockAuditService)(nil).VerifyAuditLog), ctx, anchor)
+}
+
 // MockAccountRepository is a mock of AccountRepository interface.
 type MockAccountRepository struct {
 	ctrl     *gomock.Controller
@@ -49,6 +425,36 @@ func (mr *MockAccountRepositoryMockRecorder) CheckAccountExists(ctx, id interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckAccountExists", reflect.TypeOf((*MockAccountRepository)(nil).CheckAccountExists), ctx, id)
 }
 
+// CheckBalances mocks base method.
+func (m *MockAccountRepository) CheckBalances(ctx context.Context, settledBefore time.Time) ([]domain.BalanceCheck, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBalances", ctx, settledBefore)
+	ret0, _ := ret[0].([]domain.BalanceCheck)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckBalances indicates an expected call of CheckBalances.
+func (mr *MockAccountRepositoryMockRecorder) CheckBalances(ctx, settledBefore interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBalances", reflect.TypeOf((*MockAccountRepository)(nil).CheckBalances), ctx, settledBefore)
+}
+
+// CorrectBalance mocks base method.
+func (m *MockAccountRepository) CorrectBalance(ctx context.Context, id, suspenseID string, settledBefore time.Time, actor, reason string) (*domain.BalanceCorrection, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CorrectBalance", ctx, id, suspenseID, settledBefore, actor, reason)
+	ret0, _ := ret[0].(*domain.BalanceCorrection)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CorrectBalance indicates an expected call of CorrectBalance.
+func (mr *MockAccountRepositoryMockRecorder) CorrectBalance(ctx, id, suspenseID, settledBefore, actor, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CorrectBalance", reflect.TypeOf((*MockAccountRepository)(nil).CorrectBalance), ctx, id, suspenseID, settledBefore, actor, reason)
+}
+
 // GetAccount mocks base method.
 func (m *MockAccountRepository) GetAccount(ctx context.Context, id string) (*domain.Account, error) {
 	m.ctrl.T.Helper()
@@ -64,18 +470,78 @@ func (mr *MockAccountRepositoryMockRecorder) GetAccount(ctx, id interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockAccountRepository)(nil).GetAccount), ctx, id)
 }
 
+// GetBalanceAsOf mocks base method.
+func (m *MockAccountRepository) GetBalanceAsOf(ctx context.Context, id string, asOf time.Time) (*domain.HistoricalBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceAsOf", ctx, id, asOf)
+	ret0, _ := ret[0].(*domain.HistoricalBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceAsOf indicates an expected call of GetBalanceAsOf.
+func (mr *MockAccountRepositoryMockRecorder) GetBalanceAsOf(ctx, id, asOf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceAsOf", reflect.TypeOf((*MockAccountRepository)(nil).GetBalanceAsOf), ctx, id, asOf)
+}
+
 // InsertAccount mocks base method.
-func (m *MockAccountRepository) InsertAccount(ctx context.Context, id string, balance float64) error {
+func (m *MockAccountRepository) InsertAccount(ctx context.Context, id string, balance float64, currency string, details domain.AccountDetails) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "InsertAccount", ctx, id, balance)
+	ret := m.ctrl.Call(m, "InsertAccount", ctx, id, balance, currency, details)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // InsertAccount indicates an expected call of InsertAccount.
-func (mr *MockAccountRepositoryMockRecorder) InsertAccount(ctx, id, balance interface{}) *gomock.Call {
+func (mr *MockAccountRepositoryMockRecorder) InsertAccount(ctx, id, balance, currency, details interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAccount", reflect.TypeOf((*MockAccountRepository)(nil).InsertAccount), ctx, id, balance, currency, details)
+}
+
+// ListAccounts mocks base method.
+func (m *MockAccountRepository) ListAccounts(ctx context.Context, query domain.ListAccountsQuery, after *domain.AccountCursor, limit int) ([]domain.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccounts", ctx, query, after, limit)
+	ret0, _ := ret[0].([]domain.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccounts indicates an expected call of ListAccounts.
+func (mr *MockAccountRepositoryMockRecorder) ListAccounts(ctx, query, after, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockAccountRepository)(nil).ListAccounts), ctx, query, after, limit)
+}
+
+// SnapshotBalances mocks base method.
+func (m *MockAccountRepository) SnapshotBalances(ctx context.Context, asOf time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SnapshotBalances", ctx, asOf)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SnapshotBalances indicates an expected call of SnapshotBalances.
+func (mr *MockAccountRepositoryMockRecorder) SnapshotBalances(ctx, asOf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SnapshotBalances", reflect.TypeOf((*MockAccountRepository)(nil).SnapshotBalances), ctx, asOf)
+}
+
+// UpdateAccount mocks base method.
+func (m *MockAccountRepository) UpdateAccount(ctx context.Context, cmd domain.UpdateAccountCommand) (*domain.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccount", ctx, cmd)
+	ret0, _ := ret[0].(*domain.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccount indicates an expected call of UpdateAccount.
+func (mr *MockAccountRepositoryMockRecorder) UpdateAccount(ctx, cmd interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAccount", reflect.TypeOf((*MockAccountRepository)(nil).InsertAccount), ctx, id, balance)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockAccountRepository)(nil).UpdateAccount), ctx, cmd)
 }
 
 // MockTransactionRepository is a mock of TransactionRepository interface.
@@ -101,16 +567,443 @@ func (m *MockTransactionRepository) EXPECT() *MockTransactionRepositoryMockRecor
 	return m.recorder
 }
 
+// ListTransactions mocks base method.
+func (m *MockTransactionRepository) ListTransactions(ctx context.Context, accountID string, beforeID int64, limit int) ([]domain.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransactions", ctx, accountID, beforeID, limit)
+	ret0, _ := ret[0].([]domain.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransactions indicates an expected call of ListTransactions.
+func (mr *MockTransactionRepositoryMockRecorder) ListTransactions(ctx, accountID, beforeID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactions", reflect.TypeOf((*MockTransactionRepository)(nil).ListTransactions), ctx, accountID, beforeID, limit)
+}
+
 // ProcessTransaction mocks base method.
-func (m *MockTransactionRepository) ProcessTransaction(ctx context.Context, transaction domain.Transaction, source_amount, destination_amount float64) error {
+func (m *MockTransactionRepository) ProcessTransaction(ctx context.Context, transaction domain.Transaction, decide domain.TransferDecision) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ProcessTransaction", ctx, transaction, source_amount, destination_amount)
+	ret := m.ctrl.Call(m, "ProcessTransaction", ctx, transaction, decide)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ProcessTransaction indicates an expected call of ProcessTransaction.
+func (mr *MockTransactionRepositoryMockRecorder) ProcessTransaction(ctx, transaction, decide interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTransaction", reflect.TypeOf((*MockTransactionRepository)(nil).ProcessTransaction), ctx, transaction, decide)
+}
+
+// StreamTransactions mocks base method.
+func (m *MockTransactionRepository) StreamTransactions(ctx context.Context, accountID string, from, to time.Time, each func(domain.Transaction) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamTransactions", ctx, accountID, from, to, each)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// ProcessTransaction indicates an expected call of ProcessTransaction.
-func (mr *MockTransactionRepositoryMockRecorder) ProcessTransaction(ctx, transaction, source_amount, destination_amount interface{}) *gomock.Call {
+// StreamTransactions indicates an expected call of StreamTransactions.
+func (mr *MockTransactionRepositoryMockRecorder) StreamTransactions(ctx, accountID, from, to, each interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamTransactions", reflect.TypeOf((*MockTransactionRepository)(nil).StreamTransactions), ctx, accountID, from, to, each)
+}
+
+// MockAdjustmentRepository is a mock of AdjustmentRepository interface.
+type MockAdjustmentRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAdjustmentRepositoryMockRecorder
+}
+
+// MockAdjustmentRepositoryMockRecorder is the mock recorder for MockAdjustmentRepository.
+type MockAdjustmentRepositoryMockRecorder struct {
+	mock *MockAdjustmentRepository
+}
+
+// NewMockAdjustmentRepository creates a new mock instance.
+func NewMockAdjustmentRepository(ctrl *gomock.Controller) *MockAdjustmentRepository {
+	mock := &MockAdjustmentRepository{ctrl: ctrl}
+	mock.recorder = &MockAdjustmentRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAdjustmentRepository) EXPECT() *MockAdjustmentRepositoryMockRecorder {
+	return m.recorder
+}
+
+// InsertAdjustment mocks base method.
+func (m *MockAdjustmentRepository) InsertAdjustment(ctx context.Context, adjustment domain.Adjustment, decide domain.TransferDecision) (domain.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertAdjustment", ctx, adjustment, decide)
+	ret0, _ := ret[0].(domain.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertAdjustment indicates an expected call of InsertAdjustment.
+func (mr *MockAdjustmentRepositoryMockRecorder) InsertAdjustment(ctx, adjustment, decide interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertAdjustment", reflect.TypeOf((*MockAdjustmentRepository)(nil).InsertAdjustment), ctx, adjustment, decide)
+}
+
+// ReviewAdjustment mocks base method.
+func (m *MockAdjustmentRepository) ReviewAdjustment(ctx context.Context, accountID string, id int64, review domain.AdjustmentReview, decide domain.TransferDecision) (domain.Adjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewAdjustment", ctx, accountID, id, review, decide)
+	ret0, _ := ret[0].(domain.Adjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewAdjustment indicates an expected call of ReviewAdjustment.
+func (mr *MockAdjustmentRepositoryMockRecorder) ReviewAdjustment(ctx, accountID, id, review, decide interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewAdjustment", reflect.TypeOf((*MockAdjustmentRepository)(nil).ReviewAdjustment), ctx, accountID, id, review, decide)
+}
+
+// MockTransferApprovalRepository is a mock of TransferApprovalRepository interface.
+type MockTransferApprovalRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransferApprovalRepositoryMockRecorder
+}
+
+// MockTransferApprovalRepositoryMockRecorder is the mock recorder for MockTransferApprovalRepository.
+type MockTransferApprovalRepositoryMockRecorder struct {
+	mock *MockTransferApprovalRepository
+}
+
+// NewMockTransferApprovalRepository creates a new mock instance.
+func NewMockTransferApprovalRepository(ctrl *gomock.Controller) *MockTransferApprovalRepository {
+	mock := &MockTransferApprovalRepository{ctrl: ctrl}
+	mock.recorder = &MockTransferApprovalRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransferApprovalRepository) EXPECT() *MockTransferApprovalRepositoryMockRecorder {
+	return m.recorder
+}
+
+// InsertTransferApproval mocks base method.
+func (m *MockTransferApprovalRepository) InsertTransferApproval(ctx context.Context, approval domain.TransferApproval, expiry time.Duration) (domain.TransferApproval, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertTransferApproval", ctx, approval, expiry)
+	ret0, _ := ret[0].(domain.TransferApproval)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InsertTransferApproval indicates an expected call of InsertTransferApproval.
+func (mr *MockTransferApprovalRepositoryMockRecorder) InsertTransferApproval(ctx, approval, expiry interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertTransferApproval", reflect.TypeOf((*MockTransferApprovalRepository)(nil).InsertTransferApproval), ctx, approval, expiry)
+}
+
+// ReviewTransferApproval mocks base method.
+func (m *MockTransferApprovalRepository) ReviewTransferApproval(ctx context.Context, id int64, review domain.TransferApprovalReview, decide domain.TransferDecision) (domain.TransferApproval, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewTransferApproval", ctx, id, review, decide)
+	ret0, _ := ret[0].(domain.TransferApproval)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewTransferApproval indicates an expected call of ReviewTransferApproval.
+func (mr *MockTransferApprovalRepositoryMockRecorder) ReviewTransferApproval(ctx, id, review, decide interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewTransferApproval", reflect.TypeOf((*MockTransferApprovalRepository)(nil).ReviewTransferApproval), ctx, id, review, decide)
+}
+
+// MockAuditRepository is a mock of AuditRepository interface.
+type MockAuditRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditRepositoryMockRecorder
+}
+
+// MockAuditRepositoryMockRecorder is the mock recorder for MockAuditRepository.
+type MockAuditRepositoryMockRecorder struct {
+	mock *MockAuditRepository
+}
+
+// NewMockAuditRepository creates a new mock instance.
+func NewMockAuditRepository(ctrl *gomock.Controller) *MockAuditRepository {
+	mock := &MockAuditRepository{ctrl: ctrl}
+	mock.recorder = &MockAuditRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuditRepository) EXPECT() *MockAuditRepositoryMockRecorder {
+	return m.recorder
+}
+
+// StreamAuditLog mocks base method.
+func (m *MockAuditRepository) StreamAuditLog(ctx context.Context, each func(domain.AuditEntry) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StreamAuditLog", ctx, each)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// StreamAuditLog indicates an expected call of StreamAuditLog.
+func (mr *MockAuditRepositoryMockRecorder) StreamAuditLog(ctx, each interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StreamAuditLog", reflect.TypeOf((*MockAuditRepository)(nil).StreamAuditLog), ctx, each)
+}
+
+// MockBalanceSnapshotter is a mock of BalanceSnapshotter interface.
+type MockBalanceSnapshotter struct {
+	ctrl     *gomock.Controller
+	recorder *MockBalanceSnapshotterMockRecorder
+}
+
+// MockBalanceSnapshotterMockRecorder is the mock recorder for MockBalanceSnapshotter.
+type MockBalanceSnapshotterMockRecorder struct {
+	mock *MockBalanceSnapshotter
+}
+
+// NewMockBalanceSnapshotter creates a new mock instance.
+func NewMockBalanceSnapshotter(ctrl *gomock.Controller) *MockBalanceSnapshotter {
+	mock := &MockBalanceSnapshotter{ctrl: ctrl}
+	mock.recorder = &MockBalanceSnapshotterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBalanceSnapshotter) EXPECT() *MockBalanceSnapshotterMockRecorder {
+	return m.recorder
+}
+
+// SnapshotBalances mocks base method.
+func (m *MockBalanceSnapshotter) SnapshotBalances(ctx context.Context, asOf time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SnapshotBalances", ctx, asOf)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SnapshotBalances indicates an expected call of SnapshotBalances.
+func (mr *MockBalanceSnapshotterMockRecorder) SnapshotBalances(ctx, asOf interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SnapshotBalances", reflect.TypeOf((*MockBalanceSnapshotter)(nil).SnapshotBalances), ctx, asOf)
+}
+
+// MockRateLimitRepository is a mock of RateLimitRepository interface.
+type MockRateLimitRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockRateLimitRepositoryMockRecorder
+}
+
+// MockRateLimitRepositoryMockRecorder is the mock recorder for MockRateLimitRepository.
+type MockRateLimitRepositoryMockRecorder struct {
+	mock *MockRateLimitRepository
+}
+
+// NewMockRateLimitRepository creates a new mock instance.
+func NewMockRateLimitRepository(ctrl *gomock.Controller) *MockRateLimitRepository {
+	mock := &MockRateLimitRepository{ctrl: ctrl}
+	mock.recorder = &MockRateLimitRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRateLimitRepository) EXPECT() *MockRateLimitRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Take mocks base method.
+func (m *MockRateLimitRepository) Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Take", ctx, key, limit)
+	ret0, _ := ret[0].(domain.RateLimitResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Take indicates an expected call of Take.
+func (mr *MockRateLimitRepositoryMockRecorder) Take(ctx, key, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Take", reflect.TypeOf((*MockRateLimitRepository)(nil).Take), ctx, key, limit)
+}
+
+// MockHealthRepository is a mock of HealthRepository interface.
+type MockHealthRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockHealthRepositoryMockRecorder
+}
+
+// MockHealthRepositoryMockRecorder is the mock recorder for MockHealthRepository.
+type MockHealthRepositoryMockRecorder struct {
+	mock *MockHealthRepository
+}
+
+// NewMockHealthRepository creates a new mock instance.
+func NewMockHealthRepository(ctrl *gomock.Controller) *MockHealthRepository {
+	mock := &MockHealthRepository{ctrl: ctrl}
+	mock.recorder = &MockHealthRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockHealthRepository) EXPECT() *MockHealthRepositoryMockRecorder {
+	return m.recorder
+}
+
+// CheckMigrations mocks base method.
+func (m *MockHealthRepository) CheckMigrations(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckMigrations", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckMigrations indicates an expected call of CheckMigrations.
+func (mr *MockHealthRepositoryMockRecorder) CheckMigrations(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckMigrations", reflect.TypeOf((*MockHealthRepository)(nil).CheckMigrations), ctx)
+}
+
+// Ping mocks base method.
+func (m *MockHealthRepository) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockHealthRepositoryMockRecorder) Ping(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockHealthRepository)(nil).Ping), ctx)
+}
+
+// MockAccountIDPolicy is a mock of AccountIDPolicy interface.
+type MockAccountIDPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountIDPolicyMockRecorder
+}
+
+// MockAccountIDPolicyMockRecorder is the mock recorder for MockAccountIDPolicy.
+type MockAccountIDPolicyMockRecorder struct {
+	mock *MockAccountIDPolicy
+}
+
+// NewMockAccountIDPolicy creates a new mock instance.
+func NewMockAccountIDPolicy(ctrl *gomock.Controller) *MockAccountIDPolicy {
+	mock := &MockAccountIDPolicy{ctrl: ctrl}
+	mock.recorder = &MockAccountIDPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountIDPolicy) EXPECT() *MockAccountIDPolicyMockRecorder {
+	return m.recorder
+}
+
+// Generate mocks base method.
+func (m *MockAccountIDPolicy) Generate() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Generate")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Generate indicates an expected call of Generate.
+func (mr *MockAccountIDPolicyMockRecorder) Generate() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Generate", reflect.TypeOf((*MockAccountIDPolicy)(nil).Generate))
+}
+
+// Generated mocks base method.
+func (m *MockAccountIDPolicy) Generated() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Generated")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Generated indicates an expected call of Generated.
+func (mr *MockAccountIDPolicyMockRecorder) Generated() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Generated", reflect.TypeOf((*MockAccountIDPolicy)(nil).Generated))
+}
+
+// Validate mocks base method.
+func (m *MockAccountIDPolicy) Validate(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockAccountIDPolicyMockRecorder) Validate(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockAccountIDPolicy)(nil).Validate), id)
+}
+
+// ValidateNew mocks base method.
+func (m *MockAccountIDPolicy) ValidateNew(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateNew", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ValidateNew indicates an expected call of ValidateNew.
+func (mr *MockAccountIDPolicyMockRecorder) ValidateNew(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateNew", reflect.TypeOf((*MockAccountIDPolicy)(nil).ValidateNew), id)
+}
+
+// MockTransferMetrics is a mock of TransferMetrics interface.
+type MockTransferMetrics struct {
+	ctrl     *gomock.Controller
+	recorder *MockTransferMetricsMockRecorder
+}
+
+// MockTransferMetricsMockRecorder is the mock recorder for MockTransferMetrics.
+type MockTransferMetricsMockRecorder struct {
+	mock *MockTransferMetrics
+}
+
+// NewMockTransferMetrics creates a new mock instance.
+func NewMockTransferMetrics(ctrl *gomock.Controller) *MockTransferMetrics {
+	mock := &MockTransferMetrics{ctrl: ctrl}
+	mock.recorder = &MockTransferMetricsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTransferMetrics) EXPECT() *MockTransferMetricsMockRecorder {
+	return m.recorder
+}
+
+// TransferCompleted mocks base method.
+func (m *MockTransferMetrics) TransferCompleted(amount float64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TransferCompleted", amount)
+}
+
+// TransferCompleted indicates an expected call of TransferCompleted.
+func (mr *MockTransferMetricsMockRecorder) TransferCompleted(amount interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferCompleted", reflect.TypeOf((*MockTransferMetrics)(nil).TransferCompleted), amount)
+}
+
+// TransferFailed mocks base method.
+func (m *MockTransferMetrics) TransferFailed(reason domain.ErrorCode) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "TransferFailed", reason)
+}
+
+// TransferFailed indicates an expected call of TransferFailed.
+func (mr *MockTransferMetricsMockRecorder) TransferFailed(reason interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ProcessTransaction", reflect.TypeOf((*MockTransactionRepository)(nil).ProcessTransaction), ctx, transaction, source_amount, destination_amount)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferFailed", reflect.TypeOf((*MockTransferMetrics)(nil).TransferFailed), reason)
 }
@@ -2,31 +2,291 @@ package repositories
 
 import (
 	"account-test/internal/core/domain"
+	"account-test/internal/core/utils"
 	"account-test/postgres"
-	"account-test/static"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type AccountPortImpl struct {
-	db       *sqlx.DB
-	dbConfig *postgres.DBConfig
+	db      *sqlx.DB
+	reads   *postgres.ReadPool
+	tables  tables
+	queries accountQueries
+	stmts   *statements
 }
 
-func NewAccountPort(db *sqlx.DB, dbConfig *postgres.DBConfig) *AccountPortImpl {
-	return &AccountPortImpl{
-		db:       db,
-		dbConfig: dbConfig,
+type accountQueries struct {
+	insert        string
+	exists        string
+	get           string
+	update        string
+	balanceAt     string
+	snapshot      string
+	checkBalances string
+	checkBalance  string
+	lock          string
+	correct       string
+	setBalance    string
+	logCorrection string
+	getForUpdate  string
+	audit         auditQueries
+}
+
+// completedTransfer holds a transfer of the transaction table aliased t to those completed, whose balances were moved in the same DB transaction as their status was set
+const completedTransfer = "t.status = '" + domain.TransactionStatusCompleted + "'"
+
+// transferDelta is the change a completed transfer of the transaction table aliased t made to the balance of the account aliased a
+const transferDelta = "CASE WHEN t.destination_account_id = a.id THEN t.amount::float8 ELSE -t.amount::float8 END"
+
+// lastSnapshot joins the latest balance snapshot of the account aliased a taken at or before $%d, aliased s, see SnapshotBalances
+const lastSnapshot = `
+	LEFT JOIN LATERAL (
+		SELECT as_of, balance FROM %s
+		WHERE account_id = a.id AND as_of <= $%d
+		ORDER BY as_of DESC
+		LIMIT 1
+	) s ON TRUE`
+
+// balanceCheck computes the balance of the accounts of the account table from their initial balance and their completed transfers, as the columns of a domain.BalanceCheck
+// Only accounts whose balance differs, rounded to 5 decimal places, or with transfers recorded after $1, which may be in flight, are returned
+// The last %s is a WHERE clause on the accounts checked, aliased a
+const balanceCheck = `
+	SELECT id, currency, balance, expected_balance, in_flight
+	FROM (
+		SELECT
+			a.id, a.currency, a.balance,
+			ROUND((a.initial_balance + COALESCE(SUM(%s), 0))::numeric, 5)::float8 AS expected_balance,
+			COALESCE(MAX(t.created_at) > $1, FALSE) AS in_flight
+		FROM %s a
+		LEFT JOIN %s t ON (t.source_account_id = a.id OR t.destination_account_id = a.id)
+			AND ` + completedTransfer + `
+		%s
+		GROUP BY a.id
+	) c
+	WHERE in_flight OR ROUND(balance::numeric, 5)::float8 <> expected_balance
+	ORDER BY id`
+
+// accountColumns are the columns of the account table read into an accountRow
+const accountColumns = "id, balance, currency, status, display_name, metadata, labels, created_at"
+
+// accountRow is a row of the account table, with the JSONB metadata and TEXT[] labels columns in their database types
+type accountRow struct {
+	domain.Account
+	Metadata []byte         `db:"metadata"`
+	Labels   pq.StringArray `db:"labels"`
+}
+
+// toAccount will return the domain.Account of the row, with nil metadata and labels when the account has none
+func (r accountRow) toAccount() (domain.Account, error) {
+	account := r.Account
+	if len(r.Metadata) > 0 {
+		if err := json.Unmarshal(r.Metadata, &account.Metadata); err != nil {
+			return domain.Account{}, fmt.Errorf("account %s metadata: %w", account.ID, err)
+		}
+	}
+	if len(account.Metadata) == 0 {
+		account.Metadata = nil
+	}
+	if len(r.Labels) > 0 {
+		account.Labels = r.Labels
+	}
+	return account, nil
+}
+
+// metadataArg will return metadata as a JSONB query argument, nil when metadata is nil so that a COALESCE keeps the stored value
+func metadataArg(metadata map[string]string) (any, error) {
+	if metadata == nil {
+		return nil, nil
 	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return string(encoded), nil
+}
+
+// labelsArg will return labels as a TEXT[] query argument, nil when labels is nil so that a COALESCE keeps the stored value
+func labelsArg(labels []string) any {
+	if labels == nil {
+		return nil
+	}
+	return pq.StringArray(labels)
+}
+
+// accountSortColumns maps the sort orders of domain.ListAccountsQuery to the column sorted on and the type its cursor value is cast to
+var accountSortColumns = map[string]struct {
+	column string
+	cast   string
+}{
+	domain.AccountSortID:        {column: "id", cast: "varchar"},
+	domain.AccountSortCreatedAt: {column: "created_at", cast: "timestamptz"},
+	domain.AccountSortBalance:   {column: "balance", cast: "float8"},
+}
+
+// likeEscaper escapes the LIKE wildcards in an ID prefix so that they match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// NewAccountPort returns an AccountRepository writing to db, the primary, and reading balances through reads
+// The function will return an error object if the schema in dbConfig is not a valid identifier
+func NewAccountPort(db *sqlx.DB, reads *postgres.ReadPool, dbConfig *postgres.DBConfig) (*AccountPortImpl, error) {
+	t, err := newTables(dbConfig.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountPortImpl{
+		db:     db,
+		reads:  reads,
+		tables: t,
+		queries: accountQueries{
+			insert: fmt.Sprintf(`
+				INSERT INTO %s(
+					id, balance, initial_balance, currency, status, display_name, metadata, labels
+				)
+				VALUES (
+					$1, $2, $2, $3, $4, $5, $6, $7
+				)`,
+				t.account,
+			),
+			exists: fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1)`, t.account),
+			get: fmt.Sprintf(`
+				SELECT
+					%s
+				FROM %s
+				WHERE id = $1`,
+				accountColumns, t.account,
+			),
+			update: fmt.Sprintf(`
+				UPDATE %s
+				SET
+					display_name = COALESCE($2, display_name),
+					metadata = COALESCE($3::jsonb, metadata),
+					labels = COALESCE($4::text[], labels),
+					updated_at = NOW()
+				WHERE id = $1
+				RETURNING %s`,
+				t.account, accountColumns,
+			),
+			balanceAt: fmt.Sprintf(`
+				SELECT
+					a.currency, a.created_at,
+					COALESCE(s.balance, a.initial_balance) + COALESCE((
+						SELECT SUM(%s)
+						FROM %s t
+						WHERE (t.source_account_id = a.id OR t.destination_account_id = a.id)
+							AND `+completedTransfer+`
+							AND t.completed_at <= $2
+							AND t.completed_at > COALESCE(s.as_of, '-infinity')
+					), 0) AS balance
+				FROM %s a %s
+				WHERE a.id = $1`,
+				transferDelta, t.transaction, t.account, fmt.Sprintf(lastSnapshot, t.balanceSnapshot, 2),
+			),
+			snapshot: fmt.Sprintf(`
+				INSERT INTO %s(account_id, as_of, balance)
+				SELECT a.id, $1, COALESCE(s.balance, a.initial_balance) + SUM(%s)
+				FROM %s a %s
+				JOIN %s t ON (t.source_account_id = a.id OR t.destination_account_id = a.id)
+					AND `+completedTransfer+`
+					AND t.completed_at <= $1
+					AND t.completed_at > COALESCE(s.as_of, '-infinity')
+				GROUP BY a.id, s.balance, a.initial_balance
+				ON CONFLICT (account_id, as_of) DO NOTHING`,
+				t.balanceSnapshot, transferDelta, t.account, fmt.Sprintf(lastSnapshot, t.balanceSnapshot, 1), t.transaction,
+			),
+			checkBalances: fmt.Sprintf(balanceCheck, transferDelta, t.account, t.transaction, ""),
+			checkBalance:  fmt.Sprintf(balanceCheck, transferDelta, t.account, t.transaction, "WHERE a.id = $2"),
+			lock:          fmt.Sprintf(`SELECT id, balance FROM %s WHERE id IN ($1, $2) ORDER BY id FOR UPDATE`, t.account),
+			getForUpdate: fmt.Sprintf(`
+				SELECT
+					%s
+				FROM %s
+				WHERE id = $1
+				FOR UPDATE`,
+				accountColumns, t.account,
+			),
+			correct: fmt.Sprintf(`
+				INSERT INTO %s(
+					source_account_id, destination_account_id, amount, description, metadata, client_id, status, completed_at
+				)
+				VALUES (
+					$1, $2, $3, $4, $5, $6, '%s', clock_timestamp()
+				) RETURNING id`,
+				t.transaction, domain.TransactionStatusCompleted,
+			),
+			setBalance: fmt.Sprintf(`
+				UPDATE %s
+				SET
+					balance = $2,
+					updated_at = NOW()
+				WHERE id = $1`,
+				t.account,
+			),
+			logCorrection: fmt.Sprintf(`
+				INSERT INTO %s(account_id, suspense_account_id, transaction_id, previous_balance, corrected_balance, actor, reason)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id, created_at`,
+				t.balanceCorrection,
+			),
+			audit: newAuditQueries(t),
+		},
+		stmts: newStatements(),
+	}, nil
 }
 
-// InsertAccount will accept a string id and the initial balance of a new account object to be created in a new row in the account table
+// InsertAccount will accept a string id, the initial balance, the currency and the details of a new account object to be created in a new row in the account table
+// The account is created with status domain.AccountStatusActive
+// The creation is recorded in the audit log in the same DB transaction, with the domain.Principal carried by ctx
 // This function will return nil if there is no error and a error object when there is error
-func (i *AccountPortImpl) InsertAccount(ctx context.Context, id string, balance float64) error {
-	tx, err := i.db.BeginTx(ctx, nil)
+func (i *AccountPortImpl) InsertAccount(ctx context.Context, id string, balance float64, currency string, details domain.AccountDetails) (err error) {
+	ctx, span := startSpan(ctx, "AccountRepository.InsertAccount", i.queries.insert)
+	var rows int64 = -1
+	defer func() {
+		endSpan(span, rows, err)
+	}()
+
+	metadata, err := metadataArg(details.Metadata)
+	if err != nil {
+		return err
+	}
+	if metadata == nil {
+		metadata = "{}"
+	}
+	labels := pq.StringArray(details.Labels)
+	if labels == nil {
+		labels = pq.StringArray{}
+	}
+	entry, err := newAuditEntry(ctx, domain.AuditActionAccountCreate, domain.AuditResourceAccount, id, nil, auditAccount{
+		ID:          id,
+		Balance:     strconv.FormatFloat(balance, 'f', -1, 64),
+		Currency:    currency,
+		Status:      domain.AccountStatusActive,
+		DisplayName: details.DisplayName,
+		Metadata:    details.Metadata,
+		Labels:      details.Labels,
+	})
+	if err != nil {
+		return err
+	}
+	stmt, err := i.stmts.prepare(ctx, i.db, i.queries.insert)
+	if err != nil {
+		return err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -35,27 +295,25 @@ func (i *AccountPortImpl) InsertAccount(ctx context.Context, id string, balance
 		_ = tx.Rollback()
 	}()
 
-	query := fmt.Sprintf(`
-			INSERT INTO %s.%s( 
-				id, balance 
-			)
-			VALUES (
-				$1, $2
-			)
-		`,
-		i.dbConfig.Schema, static.TableAccount,
-	)
-
-	_, err = tx.ExecContext(
+	result, err := tx.StmtxContext(ctx, stmt).ExecContext(
 		ctx,
-		query,
 		id,
 		balance,
+		currency,
+		domain.AccountStatusActive,
+		details.DisplayName,
+		metadata,
+		labels,
 	)
 	if err != nil {
 		return err
 	}
+	rows, _ = result.RowsAffected()
 
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return err
+	}
 	err = tx.Commit()
 	if err != nil {
 		return err
@@ -64,38 +322,453 @@ func (i *AccountPortImpl) InsertAccount(ctx context.Context, id string, balance
 }
 
 // CheckAccountExists will accept a account id and check whether the account already exists
+// Accounts are never deleted, so an account found on the read replica exists, while an account not found on the replica is checked again on the primary
 // This function will return a boolean value denoting  if an account exists or not (true for exists and vice versa)
 func (i *AccountPortImpl) CheckAccountExists(ctx context.Context, id string) bool {
 	var isExist bool
-	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s.%s WHERE id = $1)`, i.dbConfig.Schema, static.TableAccount)
-	err := i.db.QueryRowContext(ctx, query, id).Scan(&isExist)
+	ctx, span := startSpan(ctx, "AccountRepository.CheckAccountExists", i.queries.exists)
+	err := i.stmts.getContext(ctx, i.reads.DB(), &isExist, i.queries.exists, id)
+	if (err != nil || !isExist) && i.reads.DB() != i.db {
+		err = i.stmts.getContext(ctx, i.db, &isExist, i.queries.exists, id)
+	}
 	if err != nil {
-		log.Println("CheckAccountExists-Repository-Error : " + err.Error())
+		slog.ErrorContext(ctx, "CheckAccountExists failed", "account_id", id, "error", err)
 	}
+	endSpan(span, 1, err)
 	return isExist
 
 }
 
-// CheckAccountExists will accept a account id and return the account details associated with the id
+// GetAccount will accept a account id and return the account details associated with the id
+// The account is read from the read replica when it is within the configured lag of the primary, and from the primary when the replica does not have it yet
+// The balance read may be stale by up to the configured lag and must not be used to decide a transfer, see TransactionRepository.ProcessTransaction
 // This function will return a account object as domain.Account and an error object if there is an error
 func (i *AccountPortImpl) GetAccount(ctx context.Context, id string) (*domain.Account, error) {
-	query := fmt.Sprintf(`
-	SELECT 
-		id, balance
-	FROM %s.%s 
-	WHERE id = $1`,
-		i.dbConfig.Schema, static.TableAccount,
-	)
+	ctx, span := startSpan(ctx, "AccountRepository.GetAccount", i.queries.get)
+	var row accountRow
+	err := i.stmts.getContext(ctx, i.reads.DB(), &row, i.queries.get, id)
+	if errors.Is(err, sql.ErrNoRows) && i.reads.DB() != i.db {
+		// The account may have been created after the last change replicated to the replica
+		err = i.stmts.getContext(ctx, i.db, &row, i.queries.get, id)
+	}
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	response, err := row.toAccount()
+	endSpan(span, 1, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response, nil
+
+}
+
+// UpdateAccount will accept a domain.UpdateAccountCommand and set the display name, metadata and labels of the account that are not nil in the command
+// The account is locked and read before it is updated, so that the update is recorded in the audit log with the account before and after it, in the same DB transaction
+// This function will return the updated account as domain.Account, sql.ErrNoRows if the account does not exist and an error object if there is an error
+func (i *AccountPortImpl) UpdateAccount(ctx context.Context, cmd domain.UpdateAccountCommand) (response *domain.Account, err error) {
+	ctx, span := startSpan(ctx, "AccountRepository.UpdateAccount", i.queries.update)
+	var rows int64
+	defer func() {
+		endSpan(span, rows, err)
+	}()
+
+	metadata, err := metadataArg(cmd.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	getStmt, err := i.stmts.prepare(ctx, i.db, i.queries.getForUpdate)
+	if err != nil {
+		return nil, err
+	}
+	updateStmt, err := i.stmts.prepare(ctx, i.db, i.queries.update)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
 
-	var response domain.Account
-	err := i.db.QueryRowContext(ctx, query, id).Scan(
-		&response.ID,
-		&response.Balance,
+	var beforeRow, afterRow accountRow
+	err = tx.StmtxContext(ctx, getStmt).GetContext(ctx, &beforeRow, cmd.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.StmtxContext(ctx, updateStmt).GetContext(ctx, &afterRow, cmd.ID, cmd.DisplayName, metadata, labelsArg(cmd.Labels))
+	if err != nil {
+		return nil, err
+	}
+	rows = 1
+	before, err := beforeRow.toAccount()
+	if err != nil {
+		return nil, err
+	}
+	account, err := afterRow.toAccount()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := newAuditEntry(ctx, domain.AuditActionAccountUpdate, domain.AuditResourceAccount, cmd.ID, toAuditAccount(before), toAuditAccount(account))
+	if err != nil {
+		return nil, err
+	}
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return nil, err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListAccounts will accept a domain.ListAccountsQuery, the cursor of the last account of the previous page and a row limit to retrieve the accounts matching the filters of the query
+// Accounts are ordered by the sort of the query, with ties broken by id, and only accounts after the cursor are returned when it is not nil
+// Accounts are read from the read replica when it is within the configured lag of the primary
+// The query is run unprepared, as its SQL depends on the filters, sort and cursor of the request and preparing each combination would keep a statement per combination on every connection
+// The function will return a list of domain.Account objects and an error object if there is error
+func (i *AccountPortImpl) ListAccounts(ctx context.Context, query domain.ListAccountsQuery, after *domain.AccountCursor, limit int) ([]domain.Account, error) {
+	listQuery, args := i.listAccountsQuery(query, after, limit)
+	ctx, span := startSpan(ctx, "AccountRepository.ListAccounts", listQuery)
+	rows := []accountRow{}
+	err := i.reads.DB().SelectContext(ctx, &rows, listQuery, args...)
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	response := make([]domain.Account, 0, len(rows))
+	for _, row := range rows {
+		account, err := row.toAccount()
+		if err != nil {
+			endSpan(span, int64(len(rows)), err)
+			return nil, err
+		}
+		response = append(response, account)
+	}
+	endSpan(span, int64(len(rows)), nil)
+	return response, nil
+}
+
+// listAccountsQuery will build the SQL and arguments of ListAccounts
+// Only the filters set in query are added to the SQL, so that each combination of filters and sort is planned with the account indexes it can use
+func (i *AccountPortImpl) listAccountsQuery(query domain.ListAccountsQuery, after *domain.AccountCursor, limit int) (string, []any) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if query.Status != "" {
+		where("status = $%d", query.Status)
+	}
+	if query.Currency != "" {
+		where("currency = $%d", query.Currency)
+	}
+	if query.IDPrefix != "" {
+		where("id LIKE $%d", likeEscaper.Replace(query.IDPrefix)+"%")
+	}
+	if query.DisplayName != "" {
+		where("display_name ILIKE $%d", "%"+likeEscaper.Replace(query.DisplayName)+"%")
+	}
+	if len(query.Labels) > 0 {
+		where("labels @> $%d::text[]", pq.StringArray(query.Labels))
+	}
+	if len(query.Metadata) > 0 {
+		// Encoding a map[string]string cannot fail
+		metadata, _ := json.Marshal(query.Metadata)
+		where("metadata @> $%d::jsonb", string(metadata))
+	}
+	if query.MinBalance != nil {
+		where("balance >= $%d", *query.MinBalance)
+	}
+	if query.MaxBalance != nil {
+		where("balance <= $%d", *query.MaxBalance)
+	}
+	if query.CreatedFrom != nil {
+		where("created_at >= $%d", *query.CreatedFrom)
+	}
+	if query.CreatedTo != nil {
+		where("created_at < $%d", *query.CreatedTo)
+	}
+
+	descending := strings.HasPrefix(query.Sort, "-")
+	sort := accountSortColumns[strings.TrimPrefix(query.Sort, "-")]
+	if sort.column == "" {
+		sort = accountSortColumns[domain.AccountSortID]
+	}
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+	if after != nil {
+		if sort.column == "id" {
+			where("id "+comparison+" $%d", after.ID)
+		} else {
+			args = append(args, after.Value, after.ID)
+			conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d::%s, $%d)", sort.column, comparison, len(args)-1, sort.cast, len(args)))
+		}
+	}
+
+	listQuery := fmt.Sprintf("SELECT %s FROM %s", accountColumns, i.tables.account)
+	if len(conditions) > 0 {
+		listQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if sort.column != "id" {
+		listQuery += fmt.Sprintf(" ORDER BY %s %s, id %s", sort.column, direction, direction)
+	} else {
+		listQuery += " ORDER BY id " + direction
+	}
+	args = append(args, limit)
+	listQuery += fmt.Sprintf(" LIMIT $%d", len(args))
+	return listQuery, args
+}
+
+// GetBalanceAsOf will accept an account id and a point in time to compute the balance of the account at that time
+// The balance is the latest snapshot taken at or before asOf, or the initial balance of the account when there is none, plus the transfers completed after the snapshot and up to asOf
+// A transfer counts from the time it completed, in the DB transaction that moved the balances, rather than the time it was requested
+// The account is read from the read replica when it is within the configured lag of the primary, and from the primary when the replica does not have it yet
+// The function will return the balance as a domain.HistoricalBalance, sql.ErrNoRows if the account does not exist and an error object if there is an error
+func (i *AccountPortImpl) GetBalanceAsOf(ctx context.Context, id string, asOf time.Time) (*domain.HistoricalBalance, error) {
+	ctx, span := startSpan(ctx, "AccountRepository.GetBalanceAsOf", i.queries.balanceAt)
+	var balance domain.HistoricalBalance
+	err := i.stmts.getContext(ctx, i.reads.DB(), &balance, i.queries.balanceAt, id, asOf)
+	if errors.Is(err, sql.ErrNoRows) && i.reads.DB() != i.db {
+		err = i.stmts.getContext(ctx, i.db, &balance, i.queries.balanceAt, id, asOf)
+	}
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	endSpan(span, 1, nil)
+	return &balance, nil
+}
+
+// SnapshotBalances will accept a point in time to store the balance at that time of every account with transfers completed since its latest snapshot in the balance_snapshot table
+// Accounts without such transfers are skipped, as their latest snapshot or initial balance is still their balance
+// Snapshots already taken at asOf are kept, so that instances snapshotting at the same time do not conflict
+// Transfers count from the time they completed, so a transfer requested before asOf but completed after it is left to the next snapshot
+// asOf must be older than any DB transaction still running, as a transfer completed before asOf but committed after its snapshot would be missed
+// The function will return the number of snapshots taken and an error object if there is an error
+func (i *AccountPortImpl) SnapshotBalances(ctx context.Context, asOf time.Time) (rows int64, err error) {
+	ctx, span := startSpan(ctx, "AccountRepository.SnapshotBalances", i.queries.snapshot)
+	defer func() {
+		endSpan(span, rows, err)
+	}()
+
+	stmt, err := i.stmts.prepare(ctx, i.db, i.queries.snapshot)
+	if err != nil {
+		return 0, err
+	}
+	result, err := stmt.ExecContext(ctx, asOf)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// CheckBalances will accept a point in time to compare the balance of every account with its initial balance plus its completed transfers
+// Transfers recorded after settledBefore may still be in flight, with the transaction recorded but the balances not yet updated, so an account with such transfers is returned with InFlight set whatever its balance
+// Balances are compared rounded to 5 decimal places, and are read from the primary as a reconciliation must not report the lag of a replica
+// The function will return the accounts whose balance differs or cannot be checked, in order of id, and an error object if there is an error
+func (i *AccountPortImpl) CheckBalances(ctx context.Context, settledBefore time.Time) ([]domain.BalanceCheck, error) {
+	ctx, span := startSpan(ctx, "AccountRepository.CheckBalances", i.queries.checkBalances)
+	checks := []domain.BalanceCheck{}
+	err := i.stmts.selectContext(ctx, i.db, &checks, i.queries.checkBalances, settledBefore)
+	endSpan(span, int64(len(checks)), err)
+	if err != nil {
+		return nil, err
+	}
+	return checks, nil
+}
+
+// CorrectBalance will accept an account id, the id of the suspense account of its currency, a point in time, the actor correcting the balance and the reason for the correction to book the difference between the balance of the account and its initial balance plus its completed transfers
+// The difference is booked as a completed transaction between the suspense account and the account, so that the history of the account matches its balance, which is left as it is
+// The balance of the suspense account is moved by the amount of the transaction, so that its own history still matches its balance
+// Both accounts are locked in order of id for the rest of the DB transaction before the balance is computed, so that no concurrent transfer can change them before the difference is booked
+// The correction is recorded with the balances before and after it, the transaction, the actor and the reason in the balance_correction table and in the audit log in the same DB transaction
+// The function will return the correction, nil if the balance is already correct, domain.ErrTransfersInFlight if the account has transfers recorded after settledBefore, sql.ErrNoRows if either account does not exist and an error object if there is an error
+func (i *AccountPortImpl) CorrectBalance(ctx context.Context, id string, suspenseID string, settledBefore time.Time, actor string, reason string) (_ *domain.BalanceCorrection, err error) {
+	ctx, span := startSpan(ctx, "AccountRepository.CorrectBalance", i.queries.correct)
+	defer func() {
+		endSpan(span, -1, err)
+	}()
+
+	lockStmt, err := i.stmts.prepare(ctx, i.db, i.queries.lock)
+	if err != nil {
+		return nil, err
+	}
+	checkStmt, err := i.stmts.prepare(ctx, i.db, i.queries.checkBalance)
+	if err != nil {
+		return nil, err
+	}
+	correctStmt, err := i.stmts.prepare(ctx, i.db, i.queries.correct)
+	if err != nil {
+		return nil, err
+	}
+	setBalanceStmt, err := i.stmts.prepare(ctx, i.db, i.queries.setBalance)
+	if err != nil {
+		return nil, err
+	}
+	logStmt, err := i.stmts.prepare(ctx, i.db, i.queries.logCorrection)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var locked []domain.Account
+	err = tx.StmtxContext(ctx, lockStmt).SelectContext(ctx, &locked, id, suspenseID)
+	if err != nil {
+		return nil, err
+	}
+	balances := map[string]string{}
+	for _, account := range locked {
+		balances[account.ID] = account.Balance
+	}
+	if len(balances) != 2 {
+		return nil, sql.ErrNoRows
+	}
+	var check domain.BalanceCheck
+	err = tx.StmtxContext(ctx, checkStmt).GetContext(ctx, &check, settledBefore, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The account is locked, so it exists and its balance is correct
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if check.InFlight {
+		return nil, domain.ErrTransfersInFlight
+	}
+
+	// A balance above its history is booked as a transfer from the suspense account, and one below it as a transfer to it
+	difference := utils.ToFixed(check.Balance-check.ExpectedBalance, 5)
+	transaction := domain.Transaction{
+		SourceID:      suspenseID,
+		DestinationID: id,
+		Amount:        utils.FormatAmount(difference),
+		Description:   "Balance correction",
+		ClientID:      domain.ReconciliationClientID,
+	}
+	if difference < 0 {
+		transaction.SourceID, transaction.DestinationID = id, suspenseID
+		transaction.Amount = utils.FormatAmount(-difference)
+	}
+	transaction.Metadata, err = json.Marshal(map[string]string{"reason": reason})
+	if err != nil {
+		return nil, err
+	}
+	suspenseBalance, err := strconv.ParseFloat(balances[suspenseID], 64)
+	if err != nil {
+		return nil, err
+	}
+	correctedSuspenseBalance := utils.ToFixed(suspenseBalance-difference, 5)
+
+	var transactionID int64
+	err = tx.StmtxContext(ctx, correctStmt).QueryRowxContext(
+		ctx,
+		transaction.SourceID,
+		transaction.DestinationID,
+		transaction.Amount,
+		transaction.Description,
+		transaction.Metadata,
+		transaction.ClientID,
+	).Scan(&transactionID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tracedExec(
+		ctx,
+		tx.StmtxContext(ctx, setBalanceStmt),
+		"AccountRepository.CorrectBalance.UpdateSuspense",
+		i.queries.setBalance,
+		suspenseID,
+		correctedSuspenseBalance,
 	)
 	if err != nil {
 		return nil, err
 	}
+	correction := domain.BalanceCorrection{
+		AccountID:         id,
+		SuspenseAccountID: suspenseID,
+		TransactionID:     transactionID,
+		PreviousBalance:   check.ExpectedBalance,
+		CorrectedBalance:  check.Balance,
+		Actor:             actor,
+		Reason:            reason,
+	}
+	err = tx.StmtxContext(ctx, logStmt).QueryRowxContext(
+		ctx,
+		id,
+		suspenseID,
+		transactionID,
+		correction.PreviousBalance,
+		correction.CorrectedBalance,
+		actor,
+		reason,
+	).Scan(&correction.ID, &correction.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
 
-	return &response, nil
+	after := auditTransfer{
+		SourceID:           transaction.SourceID,
+		SourceBalance:      balances[transaction.SourceID],
+		DestinationID:      transaction.DestinationID,
+		DestinationBalance: balances[transaction.DestinationID],
+		Amount:             transaction.Amount,
+		Description:        transaction.Description,
+		Metadata:           transaction.Metadata,
+	}
+	if transaction.SourceID == suspenseID {
+		after.SourceBalance = strconv.FormatFloat(correctedSuspenseBalance, 'f', -1, 64)
+	} else {
+		after.DestinationBalance = strconv.FormatFloat(correctedSuspenseBalance, 'f', -1, 64)
+	}
+	entry, err := newAuditEntry(ctx, domain.AuditActionBalanceCorrect, domain.AuditResourceTransaction, strconv.FormatInt(transactionID, 10),
+		auditTransfer{
+			SourceID:           transaction.SourceID,
+			SourceBalance:      balances[transaction.SourceID],
+			DestinationID:      transaction.DestinationID,
+			DestinationBalance: balances[transaction.DestinationID],
+		},
+		after,
+	)
+	if err != nil {
+		return nil, err
+	}
+	// The actor of the correction may not be the client of a request, such as a user of cmd/reconcile
+	entry.Actor = actor
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return nil, err
+	}
 
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return &correction, nil
 }
@@ -0,0 +1,400 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAccountReadsReplica(t *testing.T) {
+	tests := []struct {
+		name         string
+		replicaRows  *sqlmock.Rows
+		readsPrimary bool
+	}{
+		{
+			name:        "Test Case Positive - Read from replica",
+			replicaRows: sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100"),
+		},
+		{
+			name:         "Test Case Positive - Not replicated yet, read from primary",
+			replicaRows:  sqlmock.NewRows([]string{"id", "balance"}),
+			readsPrimary: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			primary, primaryMock := newMockDB(t)
+			replica, replicaMock := newMockDB(t)
+			replicaMock.ExpectPrepare(`SELECT (.+) FROM "public"."account"`).ExpectQuery().WithArgs("123").WillReturnRows(tc.replicaRows)
+			if tc.readsPrimary {
+				primaryMock.ExpectPrepare(`SELECT (.+) FROM "public"."account"`).ExpectQuery().WithArgs("123").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100"))
+			}
+
+			account, err := newTestAccountPort(t, primary, replica).GetAccount(context.Background(), "123")
+
+			assert.NoError(t, err)
+			assert.Equal(t, &domain.Account{ID: "123", Balance: "100"}, account)
+			assert.NoError(t, primaryMock.ExpectationsWereMet())
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestCheckAccountExistsReadsReplica(t *testing.T) {
+	tests := []struct {
+		name          string
+		replicaExists bool
+		primaryExists bool
+		want          bool
+	}{
+		{name: "Test Case Positive - Found on replica", replicaExists: true, want: true},
+		{name: "Test Case Positive - Not replicated yet, found on primary", replicaExists: false, primaryExists: true, want: true},
+		{name: "Test Case Negative - Not found", replicaExists: false, primaryExists: false, want: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			primary, primaryMock := newMockDB(t)
+			replica, replicaMock := newMockDB(t)
+			replicaMock.ExpectPrepare("SELECT EXISTS").ExpectQuery().WithArgs("123").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.replicaExists))
+			if !tc.replicaExists {
+				primaryMock.ExpectPrepare("SELECT EXISTS").ExpectQuery().WithArgs("123").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.primaryExists))
+			}
+
+			exists := newTestAccountPort(t, primary, replica).CheckAccountExists(context.Background(), "123")
+
+			assert.Equal(t, tc.want, exists)
+			assert.NoError(t, primaryMock.ExpectationsWereMet())
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestListAccountsQuery(t *testing.T) {
+	minBalance := 10.0
+	createdTo := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		query    domain.ListAccountsQuery
+		after    *domain.AccountCursor
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "Test Case Positive - No filters",
+			query:    domain.ListAccountsQuery{Sort: domain.AccountSortID},
+			wantSQL:  `SELECT id, balance, currency, status, display_name, metadata, labels, created_at FROM "public"."account" ORDER BY id ASC LIMIT $1`,
+			wantArgs: []any{51},
+		},
+		{
+			name:     "Test Case Positive - Filters and id cursor",
+			query:    domain.ListAccountsQuery{Status: "active", Currency: "USD", IDPrefix: "a_b%", MinBalance: &minBalance, CreatedTo: &createdTo, Sort: domain.AccountSortIDDesc},
+			after:    &domain.AccountCursor{Value: "a_b%9", ID: "a_b%9"},
+			wantSQL:  `SELECT id, balance, currency, status, display_name, metadata, labels, created_at FROM "public"."account" WHERE status = $1 AND currency = $2 AND id LIKE $3 AND balance >= $4 AND created_at < $5 AND id < $6 ORDER BY id DESC LIMIT $7`,
+			wantArgs: []any{"active", "USD", `a\_b\%%`, 10.0, createdTo, "a_b%9", 51},
+		},
+		{
+			name:     "Test Case Positive - Balance cursor",
+			query:    domain.ListAccountsQuery{Sort: domain.AccountSortBalanceDesc},
+			after:    &domain.AccountCursor{Value: "20.5", ID: "acc"},
+			wantSQL:  `SELECT id, balance, currency, status, display_name, metadata, labels, created_at FROM "public"."account" WHERE (balance, id) < ($1::float8, $2) ORDER BY balance DESC, id DESC LIMIT $3`,
+			wantArgs: []any{"20.5", "acc", 51},
+		},
+		{
+			name:     "Test Case Positive - Created at cursor",
+			query:    domain.ListAccountsQuery{Currency: "EUR", Sort: domain.AccountSortCreatedAt},
+			after:    &domain.AccountCursor{Value: "2024-03-31T12:00:00Z", ID: "acc"},
+			wantSQL:  `SELECT id, balance, currency, status, display_name, metadata, labels, created_at FROM "public"."account" WHERE currency = $1 AND (created_at, id) > ($2::timestamptz, $3) ORDER BY created_at ASC, id ASC LIMIT $4`,
+			wantArgs: []any{"EUR", "2024-03-31T12:00:00Z", "acc", 51},
+		},
+		{
+			name:     "Test Case Positive - Details filters",
+			query:    domain.ListAccountsQuery{DisplayName: "50%", Labels: []string{"eu", "vip"}, Metadata: map[string]string{"team": "payments"}, Sort: domain.AccountSortID},
+			wantSQL:  `SELECT id, balance, currency, status, display_name, metadata, labels, created_at FROM "public"."account" WHERE display_name ILIKE $1 AND labels @> $2::text[] AND metadata @> $3::jsonb ORDER BY id ASC LIMIT $4`,
+			wantArgs: []any{`%50\%%`, pq.StringArray{"eu", "vip"}, `{"team":"payments"}`, 51},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, _ := newMockDB(t)
+
+			query, args := newTestAccountPort(t, db, nil).listAccountsQuery(tc.query, tc.after, 51)
+
+			assert.Equal(t, tc.wantSQL, query)
+			assert.Equal(t, tc.wantArgs, args)
+		})
+	}
+}
+
+func TestListAccountsReadsReplica(t *testing.T) {
+	primary, primaryMock := newMockDB(t)
+	replica, replicaMock := newMockDB(t)
+	createdAt := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	replicaMock.ExpectQuery(`SELECT (.+) FROM "public"."account" WHERE currency = \$1 ORDER BY id ASC LIMIT \$2`).WithArgs("USD", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "balance", "currency", "status", "display_name", "metadata", "labels", "created_at"}).
+			AddRow("123", "100", "USD", "active", "Payroll", []byte(`{"team":"payments"}`), []byte("{eu,vip}"), createdAt).
+			AddRow("456", "0", "USD", "active", "", []byte("{}"), []byte("{}"), createdAt))
+
+	port := newTestAccountPort(t, primary, replica)
+	accounts, err := port.ListAccounts(context.Background(), domain.ListAccountsQuery{Currency: "USD", Sort: domain.AccountSortID}, nil, 3)
+
+	assert.NoError(t, err)
+	// The SQL depends on the request, so it is never kept as a prepared statement
+	assert.Empty(t, port.stmts.prepared)
+	assert.Equal(t, []domain.Account{
+		{ID: "123", Balance: "100", Currency: "USD", Status: "active", DisplayName: "Payroll", Metadata: map[string]string{"team": "payments"}, Labels: []string{"eu", "vip"}, CreatedAt: createdAt},
+		{ID: "456", Balance: "0", Currency: "USD", Status: "active", CreatedAt: createdAt},
+	}, accounts)
+	assert.NoError(t, primaryMock.ExpectationsWereMet())
+	assert.NoError(t, replicaMock.ExpectationsWereMet())
+}
+
+func TestInsertAccountDetails(t *testing.T) {
+	db, mock := newMockDB(t)
+	insert := mock.ExpectPrepare(`INSERT INTO "public"."account"`)
+	audit := expectAuditPrepare(mock)
+	mock.ExpectBegin()
+	insert.ExpectExec().WithArgs("123", 100.0, "USD", domain.AccountStatusActive, "", "{}", pq.StringArray{}).WillReturnResult(sqlmock.NewResult(0, 1))
+	audit.expectAppend("", domain.AuditActionAccountCreate, domain.AuditResourceAccount, "123", nil)
+	mock.ExpectCommit()
+
+	err := newTestAccountPort(t, db, nil).InsertAccount(context.Background(), "123", 100, "USD", domain.AccountDetails{})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAccount(t *testing.T) {
+	name := "Payroll"
+	tests := []struct {
+		name     string
+		cmd      domain.UpdateAccountCommand
+		wantArgs []driver.Value
+	}{
+		{
+			name:     "Test Case Positive - Every field",
+			cmd:      domain.UpdateAccountCommand{ID: "123", DisplayName: &name, Metadata: map[string]string{"team": "payments"}, Labels: []string{}},
+			wantArgs: []driver.Value{"123", "Payroll", `{"team":"payments"}`, "{}"},
+		},
+		{
+			name:     "Test Case Positive - Unset fields are kept",
+			cmd:      domain.UpdateAccountCommand{ID: "123"},
+			wantArgs: []driver.Value{"123", nil, nil, nil},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			get := mock.ExpectPrepare(`SELECT (.+) FROM "public"."account" WHERE id = \$1 FOR UPDATE`)
+			update := mock.ExpectPrepare(`UPDATE "public"."account" SET (.+) COALESCE\(\$4::text\[\], labels\)`)
+			audit := expectAuditPrepare(mock)
+			mock.ExpectBegin()
+			get.ExpectQuery().WithArgs("123").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "balance", "display_name", "metadata", "labels"}).AddRow("123", "100", "", []byte(`{}`), []byte("{}")))
+			update.ExpectQuery().WithArgs(tc.wantArgs...).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "balance", "display_name", "metadata", "labels"}).AddRow("123", "100", "Payroll", []byte(`{"team":"payments"}`), []byte("{}")))
+			var args []driver.Value
+			audit.expectAppend("", domain.AuditActionAccountUpdate, domain.AuditResourceAccount, "123", &args)
+			mock.ExpectCommit()
+
+			account, err := newTestAccountPort(t, db, nil).UpdateAccount(context.Background(), tc.cmd)
+
+			assert.NoError(t, err)
+			assert.Equal(t, &domain.Account{ID: "123", Balance: "100", DisplayName: "Payroll", Metadata: map[string]string{"team": "payments"}}, account)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			entry := auditEntryOf(args)
+			assert.JSONEq(t, `{"account_id":"123","balance":"100"}`, string(entry.Before))
+			assert.JSONEq(t, `{"account_id":"123","balance":"100","display_name":"Payroll","metadata":{"team":"payments"}}`, string(entry.After))
+		})
+	}
+}
+
+func TestGetBalanceAsOf(t *testing.T) {
+	asOf := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	createdAt := asOf.Add(-24 * time.Hour)
+	tests := []struct {
+		name         string
+		replicaRows  *sqlmock.Rows
+		readsPrimary bool
+	}{
+		{
+			name:        "Test Case Positive - Read from replica",
+			replicaRows: sqlmock.NewRows([]string{"currency", "created_at", "balance"}).AddRow("USD", createdAt, 150.5),
+		},
+		{
+			name:         "Test Case Positive - Not replicated yet, read from primary",
+			replicaRows:  sqlmock.NewRows([]string{"currency", "created_at", "balance"}),
+			readsPrimary: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			primary, primaryMock := newMockDB(t)
+			replica, replicaMock := newMockDB(t)
+			query := `FROM "public"."transaction" t (.+) FROM "public"."account" a\s+LEFT JOIN LATERAL \(\s+SELECT as_of, balance FROM "public"."balance_snapshot"`
+			replicaMock.ExpectPrepare(query).ExpectQuery().WithArgs("123", asOf).WillReturnRows(tc.replicaRows)
+			if tc.readsPrimary {
+				primaryMock.ExpectPrepare(query).ExpectQuery().WithArgs("123", asOf).
+					WillReturnRows(sqlmock.NewRows([]string{"currency", "created_at", "balance"}).AddRow("USD", createdAt, 150.5))
+			}
+
+			balance, err := newTestAccountPort(t, primary, replica).GetBalanceAsOf(context.Background(), "123", asOf)
+
+			assert.NoError(t, err)
+			assert.Equal(t, &domain.HistoricalBalance{Balance: 150.5, Currency: "USD", CreatedAt: createdAt}, balance)
+			assert.NoError(t, primaryMock.ExpectationsWereMet())
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSnapshotBalances(t *testing.T) {
+	asOf := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, mock := newMockDB(t)
+	mock.ExpectPrepare(`INSERT INTO "public"."balance_snapshot"(.+)AND t.status = 'completed' AND t.completed_at <= \$1 (.+)ON CONFLICT \(account_id, as_of\) DO NOTHING`).ExpectExec().WithArgs(asOf).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	taken, err := newTestAccountPort(t, db, nil).SnapshotBalances(context.Background(), asOf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), taken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCheckBalances(t *testing.T) {
+	settledBefore := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	db, mock := newMockDB(t)
+	mock.ExpectPrepare(`SELECT id, currency, balance, expected_balance, in_flight(.+)FROM "public"."account" a(.+)LEFT JOIN "public"."transaction" t`).ExpectQuery().WithArgs(settledBefore).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "balance", "expected_balance", "in_flight"}).
+			AddRow("123", "USD", 100.5, 100, false).
+			AddRow("456", "EUR", 20, 20, true))
+
+	checks, err := newTestAccountPort(t, db, nil).CheckBalances(context.Background(), settledBefore)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.BalanceCheck{
+		{AccountID: "123", Currency: "USD", Balance: 100.5, ExpectedBalance: 100},
+		{AccountID: "456", Currency: "EUR", Balance: 20, ExpectedBalance: 20, InFlight: true},
+	}, checks)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCorrectBalance(t *testing.T) {
+	settledBefore := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC)
+	checkColumns := []string{"id", "currency", "balance", "expected_balance", "in_flight"}
+	lockRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100.5").AddRow("SUSPENSE", "-2")
+	}
+	tests := []struct {
+		name      string
+		lockRows  *sqlmock.Rows
+		checkRows *sqlmock.Rows
+		doMockDB  func(mock sqlmock.Sqlmock, correct *sqlmock.ExpectedPrepare, setBalance *sqlmock.ExpectedPrepare, log *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value)
+		want      *domain.BalanceCorrection
+		wantAfter string
+		wantErr   error
+	}{
+		{
+			name:      "Test Case Positive - Balance above its history",
+			lockRows:  lockRows(),
+			checkRows: sqlmock.NewRows(checkColumns).AddRow("123", "USD", 100.5, 100, false),
+			doMockDB: func(mock sqlmock.Sqlmock, correct *sqlmock.ExpectedPrepare, setBalance *sqlmock.ExpectedPrepare, log *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				correct.ExpectQuery().WithArgs("SUSPENSE", "123", "0.5", "Balance correction", []byte(`{"reason":"drift"}`), domain.ReconciliationClientID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+				// The balance of the account is left as it is
+				setBalance.ExpectExec().WithArgs("SUSPENSE", -2.5).WillReturnResult(sqlmock.NewResult(0, 1))
+				log.ExpectQuery().WithArgs("123", "SUSPENSE", int64(42), 100.0, 100.5, "key:abc", "drift").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(9, createdAt))
+				audit.expectAppend("", domain.AuditActionBalanceCorrect, domain.AuditResourceTransaction, "42", args)
+				mock.ExpectCommit()
+			},
+			want: &domain.BalanceCorrection{
+				ID: 9, AccountID: "123", SuspenseAccountID: "SUSPENSE", TransactionID: 42,
+				PreviousBalance: 100, CorrectedBalance: 100.5, Actor: "key:abc", Reason: "drift", CreatedAt: createdAt,
+			},
+			wantAfter: `{"source_account_id":"SUSPENSE","source_balance":"-2.5","destination_account_id":"123","destination_balance":"100.5",
+				"amount":"0.5","description":"Balance correction","metadata":{"reason":"drift"}}`,
+		},
+		{
+			name:      "Test Case Positive - Balance below its history",
+			lockRows:  lockRows(),
+			checkRows: sqlmock.NewRows(checkColumns).AddRow("123", "USD", 100.5, 101, false),
+			doMockDB: func(mock sqlmock.Sqlmock, correct *sqlmock.ExpectedPrepare, setBalance *sqlmock.ExpectedPrepare, log *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				correct.ExpectQuery().WithArgs("123", "SUSPENSE", "0.5", "Balance correction", []byte(`{"reason":"drift"}`), domain.ReconciliationClientID).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+				setBalance.ExpectExec().WithArgs("SUSPENSE", -1.5).WillReturnResult(sqlmock.NewResult(0, 1))
+				log.ExpectQuery().WithArgs("123", "SUSPENSE", int64(42), 101.0, 100.5, "key:abc", "drift").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(9, createdAt))
+				audit.expectAppend("", domain.AuditActionBalanceCorrect, domain.AuditResourceTransaction, "42", args)
+				mock.ExpectCommit()
+			},
+			want: &domain.BalanceCorrection{
+				ID: 9, AccountID: "123", SuspenseAccountID: "SUSPENSE", TransactionID: 42,
+				PreviousBalance: 101, CorrectedBalance: 100.5, Actor: "key:abc", Reason: "drift", CreatedAt: createdAt,
+			},
+			wantAfter: `{"source_account_id":"123","source_balance":"100.5","destination_account_id":"SUSPENSE","destination_balance":"-1.5",
+				"amount":"0.5","description":"Balance correction","metadata":{"reason":"drift"}}`,
+		},
+		{
+			name:      "Test Case Positive - Balance already correct",
+			lockRows:  lockRows(),
+			checkRows: sqlmock.NewRows(checkColumns),
+			doMockDB: func(mock sqlmock.Sqlmock, correct *sqlmock.ExpectedPrepare, setBalance *sqlmock.ExpectedPrepare, log *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:      "Test Case Negative - Transfers in flight",
+			lockRows:  lockRows(),
+			checkRows: sqlmock.NewRows(checkColumns).AddRow("123", "USD", 100.5, 100, true),
+			doMockDB: func(mock sqlmock.Sqlmock, correct *sqlmock.ExpectedPrepare, setBalance *sqlmock.ExpectedPrepare, log *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				mock.ExpectRollback()
+			},
+			wantErr: domain.ErrTransfersInFlight,
+		},
+		{
+			name:     "Test Case Negative - Suspense account does not exist",
+			lockRows: sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100.5"),
+			doMockDB: func(mock sqlmock.Sqlmock, correct *sqlmock.ExpectedPrepare, setBalance *sqlmock.ExpectedPrepare, log *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				mock.ExpectRollback()
+			},
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			lock := mock.ExpectPrepare(`SELECT id, balance FROM "public"."account" WHERE id IN \(\$1, \$2\) ORDER BY id FOR UPDATE`)
+			check := mock.ExpectPrepare(`SELECT id, currency, balance, expected_balance, in_flight(.+)WHERE a.id = \$2`)
+			correct := mock.ExpectPrepare(`INSERT INTO "public"."transaction"(.+)'completed', clock_timestamp\(\)`)
+			setBalance := mock.ExpectPrepare(`UPDATE "public"."account"`)
+			log := mock.ExpectPrepare(`INSERT INTO "public"."balance_correction"`)
+			audit := expectAuditPrepare(mock)
+			mock.ExpectBegin()
+			lock.ExpectQuery().WithArgs("123", "SUSPENSE").WillReturnRows(tc.lockRows)
+			if tc.checkRows != nil {
+				check.ExpectQuery().WithArgs(settledBefore, "123").WillReturnRows(tc.checkRows)
+			}
+			var args []driver.Value
+			tc.doMockDB(mock, correct, setBalance, log, audit, &args)
+
+			correction, err := newTestAccountPort(t, db, nil).CorrectBalance(context.Background(), "123", "SUSPENSE", settledBefore, "key:abc", "drift")
+
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, correction)
+			if tc.wantAfter != "" {
+				assert.JSONEq(t, tc.wantAfter, string(auditEntryOf(args).After))
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
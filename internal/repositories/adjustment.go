@@ -0,0 +1,365 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AdjustmentPortImpl struct {
+	db        *sqlx.DB
+	transfers *TransactionPortImpl
+	queries   adjustmentQueries
+	stmts     *statements
+}
+
+type adjustmentQueries struct {
+	next   string
+	insert string
+	get    string
+	lock   string
+	review string
+	audit  auditQueries
+}
+
+// adjustmentColumns are the columns of the adjustment table read into a domain.Adjustment
+const adjustmentColumns = `
+	id, account_id, suspense_account_id, direction, amount, reason_code, note, status,
+	requested_by, reviewed_by, COALESCE(transaction_id, 0) AS transaction_id, created_at, reviewed_at`
+
+// NewAdjustmentPort returns an AdjustmentRepository writing adjustments to db, the primary, and booking approved adjustments through transfers
+// The function will return an error object if the schema in dbConfig is not a valid identifier
+func NewAdjustmentPort(db *sqlx.DB, transfers *TransactionPortImpl, dbConfig *postgres.DBConfig) (*AdjustmentPortImpl, error) {
+	t, err := newTables(dbConfig.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &AdjustmentPortImpl{
+		db:        db,
+		transfers: transfers,
+		queries: adjustmentQueries{
+			next: fmt.Sprintf(`SELECT nextval(pg_get_serial_sequence('%s', 'id'))`, t.adjustment),
+			insert: fmt.Sprintf(`
+				INSERT INTO %s(
+					id, account_id, suspense_account_id, direction, amount, reason_code, note, status, requested_by, transaction_id
+				)
+				VALUES (
+					$1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, 0)
+				) RETURNING created_at`,
+				t.adjustment,
+			),
+			get: fmt.Sprintf(`
+				SELECT
+					%s
+				FROM %s
+				WHERE id = $1 AND account_id = $2`,
+				adjustmentColumns, t.adjustment,
+			),
+			lock: fmt.Sprintf(`
+				SELECT
+					%s
+				FROM %s
+				WHERE id = $1 AND account_id = $2
+				FOR UPDATE`,
+				adjustmentColumns, t.adjustment,
+			),
+			review: fmt.Sprintf(`
+				UPDATE %s SET
+					status = $2,
+					reviewed_by = $3,
+					transaction_id = NULLIF($4, 0),
+					reviewed_at = NOW(),
+					updated_at = NOW()
+				WHERE id = $1
+				RETURNING reviewed_at`,
+				t.adjustment,
+			),
+			audit: newAuditQueries(t),
+		},
+		stmts: newStatements(),
+	}, nil
+}
+
+// InsertAdjustment will accept a domain.Adjustment, pending approval or approved, and the domain.TransferDecision of the transfer booking an approved adjustment
+// The id of the adjustment is allocated first, so that the transfer booking it is referenced by it, see domain.Adjustment.Transaction
+// An adjustment pending approval is created in the adjustment table with its request in the audit log in a single DB transaction
+// An approved adjustment is booked through TransactionPortImpl, creating it as applied with the id of the transfer and its request in the audit log in the DB transaction of the transfer, so that an adjustment is never left approved without its transfer
+// When the transfer fails, the adjustment is created as failed in a DB transaction of its own, detached from the cancellation of ctx as for a failed transaction
+// The request is recorded in the audit log with the domain.Principal carried by ctx
+// The function will return the adjustment with its id, creation time and final status, the error the transfer failed with and an error object if there is error
+func (i *AdjustmentPortImpl) InsertAdjustment(ctx context.Context, adjustment domain.Adjustment, decide domain.TransferDecision) (_ domain.Adjustment, err error) {
+	ctx, span := startSpan(ctx, "AdjustmentRepository.InsertAdjustment", i.queries.insert)
+	defer func() {
+		endSpan(span, -1, err)
+	}()
+
+	err = i.stmts.getContext(ctx, i.db, &adjustment.ID, i.queries.next)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	if adjustment.Status != domain.AdjustmentStatusApproved {
+		return i.create(ctx, adjustment)
+	}
+
+	transaction, err := adjustment.Transaction()
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	insertStmt, err := i.stmts.prepare(ctx, i.db, i.queries.insert)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	var applied domain.Adjustment
+	_, err = i.transfers.process(ctx, transaction, decide, settlement{
+		complete: func(ctx context.Context, tx *sqlx.Tx, transactionID int64) error {
+			var insertErr error
+			applied = adjustment
+			applied.Status = domain.AdjustmentStatusApplied
+			applied.TransactionID = transactionID
+			applied, insertErr = i.insert(ctx, tx, insertStmt, audit, applied)
+			return insertErr
+		},
+	})
+	if err != nil {
+		failed := adjustment
+		failed.Status = domain.AdjustmentStatusFailed
+		if _, failErr := i.create(ctx, failed); failErr != nil {
+			slog.ErrorContext(ctx, "failed adjustment not recorded", "adjustment_id", adjustment.ID, "error", failErr)
+		}
+		return domain.Adjustment{}, err
+	}
+	return applied, nil
+}
+
+// create will create the adjustment in the adjustment table with its request in the audit log in a single DB transaction
+// A failed adjustment is created detached from the cancellation of ctx, as for a failed transaction, so that its outcome is recorded even when the request is cancelled
+// The function will return the adjustment with its creation time and an error object if there is error
+func (i *AdjustmentPortImpl) create(ctx context.Context, adjustment domain.Adjustment) (domain.Adjustment, error) {
+	if adjustment.Status == domain.AdjustmentStatusFailed {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), errorMessageUpdateTimeout)
+		defer cancel()
+	}
+	insertStmt, err := i.stmts.prepare(ctx, i.db, i.queries.insert)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	adjustment, err = i.insert(ctx, tx, insertStmt, audit, adjustment)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	return adjustment, nil
+}
+
+// insert will accept the insert statement, the audit log and a DB transaction to create a row for the adjustment and record its request in the audit log
+// The function will return the adjustment with its creation time and an error object if there is error
+func (i *AdjustmentPortImpl) insert(ctx context.Context, tx *sqlx.Tx, insertStmt *sqlx.Stmt, audit *auditAppend, adjustment domain.Adjustment) (domain.Adjustment, error) {
+	err := tx.StmtxContext(ctx, insertStmt).QueryRowxContext(
+		ctx,
+		adjustment.ID,
+		adjustment.AccountID,
+		adjustment.SuspenseAccountID,
+		adjustment.Direction,
+		adjustment.Amount,
+		adjustment.ReasonCode,
+		adjustment.Note,
+		adjustment.Status,
+		adjustment.RequestedBy,
+		adjustment.TransactionID,
+	).Scan(&adjustment.CreatedAt)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	entry, err := newAuditEntry(ctx, domain.AuditActionAdjustment, domain.AuditResourceAdjustment, strconv.FormatInt(adjustment.ID, 10), nil, adjustment)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	return adjustment, nil
+}
+
+// ReviewAdjustment will accept the id of an adjustment of the account accountID, a domain.AdjustmentReview deciding its outcome and the domain.TransferDecision of the transfer booking it once approved
+// review is first passed the adjustment as read, so that a review failing its checks has no effect, and then passed the adjustment again once it is locked, so that an adjustment is never reviewed twice
+// A rejected adjustment is recorded as rejected with its review in the audit log in a single DB transaction
+// An approved adjustment is booked through TransactionPortImpl, recording it as applied with the id of the transfer and its review in the audit log in the DB transaction of the transfer, so that an adjustment is never left approved without its transfer
+// When the transfer fails, the adjustment is recorded as failed with its review in a DB transaction of its own, detached from the cancellation of ctx as for a failed transaction, and is left pending approval if that fails too
+// The function will return the reviewed adjustment, sql.ErrNoRows if the account has no such adjustment, the error returned by review, the error the transfer failed with and an error object if there is error
+func (i *AdjustmentPortImpl) ReviewAdjustment(ctx context.Context, accountID string, id int64, review domain.AdjustmentReview, decide domain.TransferDecision) (_ domain.Adjustment, err error) {
+	ctx, span := startSpan(ctx, "AdjustmentRepository.ReviewAdjustment", i.queries.review)
+	defer func() {
+		endSpan(span, -1, err)
+	}()
+
+	var adjustment domain.Adjustment
+	err = i.stmts.getContext(ctx, i.db, &adjustment, i.queries.get, id, accountID)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	reviewed, err := review(adjustment)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	if reviewed.Status != domain.AdjustmentStatusApproved {
+		return i.record(ctx, accountID, id, review, "")
+	}
+
+	transaction, err := reviewed.Transaction()
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	lockStmt, err := i.stmts.prepare(ctx, i.db, i.queries.lock)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	reviewStmt, err := i.stmts.prepare(ctx, i.db, i.queries.review)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	// approved is the adjustment once locked and approved in the DB transaction of the transfer, and applied the adjustment recorded as applied
+	var locked, approved, applied domain.Adjustment
+	_, err = i.transfers.process(ctx, transaction, decide, settlement{
+		lock: func(ctx context.Context, tx *sqlx.Tx) error {
+			var lockErr error
+			locked, approved, lockErr = i.lock(ctx, tx, lockStmt, accountID, id, review)
+			if lockErr == nil && approved.Status != domain.AdjustmentStatusApproved {
+				lockErr = fmt.Errorf("adjustment %d no longer approved: %s", id, approved.Status)
+			}
+			return lockErr
+		},
+		complete: func(ctx context.Context, tx *sqlx.Tx, transactionID int64) error {
+			var completeErr error
+			applied = approved
+			applied.Status = domain.AdjustmentStatusApplied
+			applied.TransactionID = transactionID
+			applied, completeErr = i.update(ctx, tx, reviewStmt, audit, locked, applied)
+			return completeErr
+		},
+	})
+	// An adjustment failing once approved, or whose reference is already used, can never be applied, so it is failed rather than left pending
+	if err != nil && (approved.Status == domain.AdjustmentStatusApproved || errors.Is(err, domain.ErrReferenceExists)) {
+		if _, failErr := i.record(ctx, accountID, id, review, domain.AdjustmentStatusFailed); failErr != nil {
+			slog.ErrorContext(ctx, "adjustment failure not recorded, left pending approval", "adjustment_id", id, "error", failErr)
+		}
+	}
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	return applied, nil
+}
+
+// record will lock the adjustment id, pass it to review and record it as reviewed, with status rather than the status returned by review when set, and its review in the audit log in a single DB transaction
+// A failed adjustment is recorded detached from the cancellation of ctx, as for a failed transaction, so that its outcome is recorded even when the request is cancelled
+// The function will return the reviewed adjustment, the error returned by review and an error object if there is error
+func (i *AdjustmentPortImpl) record(ctx context.Context, accountID string, id int64, review domain.AdjustmentReview, status string) (domain.Adjustment, error) {
+	if status == domain.AdjustmentStatusFailed {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), errorMessageUpdateTimeout)
+		defer cancel()
+	}
+	lockStmt, err := i.stmts.prepare(ctx, i.db, i.queries.lock)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	reviewStmt, err := i.stmts.prepare(ctx, i.db, i.queries.review)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	locked, reviewed, err := i.lock(ctx, tx, lockStmt, accountID, id, review)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	if status != "" {
+		reviewed.Status = status
+	}
+	reviewed, err = i.update(ctx, tx, reviewStmt, audit, locked, reviewed)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	return reviewed, nil
+}
+
+// lock will accept the lock statement and a DB transaction to lock the adjustment id of the account accountID for the rest of the DB transaction and pass it to review
+// The function will return the adjustment as locked and as reviewed, sql.ErrNoRows if the account has no such adjustment, the error returned by review and an error object if there is error
+func (i *AdjustmentPortImpl) lock(ctx context.Context, tx *sqlx.Tx, lockStmt *sqlx.Stmt, accountID string, id int64, review domain.AdjustmentReview) (domain.Adjustment, domain.Adjustment, error) {
+	var locked domain.Adjustment
+	err := tx.StmtxContext(ctx, lockStmt).GetContext(ctx, &locked, id, accountID)
+	if err != nil {
+		return domain.Adjustment{}, domain.Adjustment{}, err
+	}
+	reviewed, err := review(locked)
+	if err != nil {
+		return domain.Adjustment{}, domain.Adjustment{}, err
+	}
+	return locked, reviewed, nil
+}
+
+// update will accept the review statement, the audit log and a DB transaction to update a locked adjustment with the status, reviewer and transaction of reviewed and record the review in the audit log
+// The function will return the reviewed adjustment with its review time and an error object if there is error
+func (i *AdjustmentPortImpl) update(ctx context.Context, tx *sqlx.Tx, reviewStmt *sqlx.Stmt, audit *auditAppend, locked domain.Adjustment, reviewed domain.Adjustment) (domain.Adjustment, error) {
+	err := tx.StmtxContext(ctx, reviewStmt).QueryRowxContext(ctx, locked.ID, reviewed.Status, reviewed.ReviewedBy, reviewed.TransactionID).Scan(&reviewed.ReviewedAt)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	entry, err := newAuditEntry(ctx, domain.AuditActionAdjustmentReview, domain.AuditResourceAdjustment, strconv.FormatInt(locked.ID, 10), locked, reviewed)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return domain.Adjustment{}, err
+	}
+	return reviewed, nil
+}
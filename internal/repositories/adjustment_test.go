@@ -0,0 +1,272 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/static"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var adjustmentRowColumns = []string{
+	"id", "account_id", "suspense_account_id", "direction", "amount", "reason_code", "note", "status",
+	"requested_by", "reviewed_by", "transaction_id", "created_at", "reviewed_at",
+}
+
+func newTestAdjustmentPort(t *testing.T, db *sqlx.DB) *AdjustmentPortImpl {
+	port, err := NewAdjustmentPort(db, newTestTransactionPort(t, db), testDBConfig)
+	assert.NoError(t, err)
+	return port
+}
+
+// expectFailedTransfer expects transaction 7 to be rolled back and marked failed with message
+func expectFailedTransfer(mock sqlmock.Sqlmock, message string) {
+	mock.ExpectRollback()
+	mock.ExpectPrepare(`UPDATE "public"."transaction" SET status = 'failed'`).ExpectExec().
+		WithArgs(message, int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestInsertAdjustment(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	insufficientFunds := domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount)
+	requested := func(status string) domain.Adjustment {
+		return domain.Adjustment{
+			AccountID:         "123",
+			SuspenseAccountID: "SUSPENSE",
+			Direction:         domain.AdjustmentCredit,
+			Amount:            "19.5",
+			ReasonCode:        "chargeback",
+			Note:              "INC-42",
+			Status:            status,
+			RequestedBy:       "key:abc",
+		}
+	}
+	// expectInsert expects adjustment 7 to be created with status and transactionID and its request to be appended to the audit log, capturing the entry in args
+	expectInsert := func(insert *sqlmock.ExpectedPrepare, audit expectedAudit, status string, transactionID int64, args *[]driver.Value) {
+		insert.ExpectQuery().WithArgs(int64(7), "123", "SUSPENSE", domain.AdjustmentCredit, "19.5", "chargeback", "INC-42", status, "key:abc", transactionID).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(createdAt))
+		audit.expectAppend("", domain.AuditActionAdjustment, domain.AuditResourceAdjustment, "7", args)
+	}
+	tests := []struct {
+		name      string
+		status    string
+		decide    domain.TransferDecision
+		doMockDB  func(mock sqlmock.Sqlmock, insert *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value)
+		want      domain.Adjustment
+		wantAfter string
+		wantErr   error
+	}{
+		{
+			name:   "Test Case Positive - Pending approval",
+			status: domain.AdjustmentStatusPendingApproval,
+			doMockDB: func(mock sqlmock.Sqlmock, insert *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				mock.ExpectBegin()
+				expectInsert(insert, audit, domain.AdjustmentStatusPendingApproval, 0, args)
+				mock.ExpectCommit()
+			},
+			want: func() domain.Adjustment {
+				adjustment := requested(domain.AdjustmentStatusPendingApproval)
+				adjustment.ID = 7
+				adjustment.CreatedAt = createdAt
+				return adjustment
+			}(),
+			wantAfter: `{"adjustment_id":7,"account_id":"123","suspense_account_id":"SUSPENSE","direction":"credit","amount":"19.5","reason_code":"chargeback","note":"INC-42","status":"pending_approval","requested_by":"key:abc","created_at":"2024-03-01T12:00:00Z"}`,
+		},
+		{
+			name:   "Test Case Positive - Approved adjustment applied in the DB transaction of its transfer",
+			status: domain.AdjustmentStatusApproved,
+			decide: fixedDecision(80.5, 119.5),
+			doMockDB: func(mock sqlmock.Sqlmock, insert *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				lock, update, transferAudit := expectTransferBegins(mock)
+				expectLockAccounts(lock, "SUSPENSE", "123")
+				update.ExpectExec().WithArgs(80.5, "SUSPENSE").WillReturnResult(sqlmock.NewResult(0, 1))
+				update.ExpectExec().WithArgs(119.5, "123").WillReturnResult(sqlmock.NewResult(0, 1))
+				expectTransferCompletes(mock)
+				expectInsert(insert, audit, domain.AdjustmentStatusApplied, 7, args)
+				transferAudit.expectAppend("", domain.AuditActionTransfer, domain.AuditResourceTransaction, "7", nil)
+				mock.ExpectCommit()
+			},
+			want: func() domain.Adjustment {
+				adjustment := requested(domain.AdjustmentStatusApplied)
+				adjustment.ID = 7
+				adjustment.TransactionID = 7
+				adjustment.CreatedAt = createdAt
+				return adjustment
+			}(),
+			wantAfter: `{"adjustment_id":7,"account_id":"123","suspense_account_id":"SUSPENSE","direction":"credit","amount":"19.5","reason_code":"chargeback","note":"INC-42","status":"applied","requested_by":"key:abc","transaction_id":7,"created_at":"2024-03-01T12:00:00Z"}`,
+		},
+		{
+			name:   "Test Case Negative - Approved adjustment fails and is created failed",
+			status: domain.AdjustmentStatusApproved,
+			decide: func(source domain.Account, destination domain.Account) (float64, float64, error) {
+				return 0, 0, insufficientFunds
+			},
+			doMockDB: func(mock sqlmock.Sqlmock, insert *sqlmock.ExpectedPrepare, audit expectedAudit, args *[]driver.Value) {
+				lock, _, _ := expectTransferBegins(mock)
+				expectLockAccounts(lock, "SUSPENSE", "123")
+				expectFailedTransfer(mock, static.ErrTransferAmountLargerThanAccount)
+				mock.ExpectBegin()
+				expectInsert(insert, audit, domain.AdjustmentStatusFailed, 0, args)
+				mock.ExpectCommit()
+			},
+			wantAfter: `{"adjustment_id":7,"account_id":"123","suspense_account_id":"SUSPENSE","direction":"credit","amount":"19.5","reason_code":"chargeback","note":"INC-42","status":"failed","requested_by":"key:abc","created_at":"2024-03-01T12:00:00Z"}`,
+			wantErr:   insufficientFunds,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectPrepare(`SELECT nextval\(pg_get_serial_sequence\('"public"."adjustment"', 'id'\)\)`).ExpectQuery().
+				WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(7))
+			insert := mock.ExpectPrepare(`INSERT INTO "public"."adjustment"`)
+			audit := expectAuditPrepare(mock)
+			var args []driver.Value
+			tc.doMockDB(mock, insert, audit, &args)
+
+			adjustment, err := newTestAdjustmentPort(t, db).InsertAdjustment(context.Background(), requested(tc.status), tc.decide)
+
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, adjustment)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			entry := auditEntryOf(args)
+			assert.Nil(t, entry.Before)
+			assert.JSONEq(t, tc.wantAfter, string(entry.After))
+		})
+	}
+}
+
+func TestReviewAdjustment(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	reviewedAt := time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC)
+	errReview := errors.New("review error")
+	insufficientFunds := domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount)
+	pendingRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(adjustmentRowColumns).
+			AddRow(7, "123", "SUSPENSE", "debit", "19.5", "correction", "", "pending_approval", "key:abc", "", 0, createdAt, nil)
+	}
+	reviewWith := func(status string) domain.AdjustmentReview {
+		return func(adjustment domain.Adjustment) (domain.Adjustment, error) {
+			adjustment.Status = status
+			adjustment.ReviewedBy = "key:def"
+			return adjustment, nil
+		}
+	}
+	reviewed := func(status string, transactionID int64) domain.Adjustment {
+		return domain.Adjustment{
+			ID: 7, AccountID: "123", SuspenseAccountID: "SUSPENSE", Direction: "debit", Amount: "19.5", ReasonCode: "correction",
+			Status: status, RequestedBy: "key:abc", ReviewedBy: "key:def", TransactionID: transactionID, CreatedAt: createdAt, ReviewedAt: &reviewedAt,
+		}
+	}
+	// expectAdjustmentPrepare expects the statements reviewing an adjustment to be prepared
+	expectAdjustmentPrepare := func(mock sqlmock.Sqlmock) (*sqlmock.ExpectedPrepare, *sqlmock.ExpectedPrepare, expectedAudit) {
+		lock := mock.ExpectPrepare(`SELECT (.+) FROM "public"."adjustment" WHERE id = \$1 AND account_id = \$2 FOR UPDATE`)
+		review := mock.ExpectPrepare(`UPDATE "public"."adjustment" SET status = \$2, reviewed_by = \$3, transaction_id = NULLIF\(\$4, 0\)`)
+		return lock, review, expectAuditPrepare(mock)
+	}
+	// expectRecord expects the adjustment to be locked and recorded with status and its review in a DB transaction of its own
+	expectRecord := func(mock sqlmock.Sqlmock, lock *sqlmock.ExpectedPrepare, review *sqlmock.ExpectedPrepare, audit expectedAudit, status string) {
+		mock.ExpectBegin()
+		lock.ExpectQuery().WithArgs(int64(7), "123").WillReturnRows(pendingRows())
+		review.ExpectQuery().WithArgs(int64(7), status, "key:def", int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"reviewed_at"}).AddRow(reviewedAt))
+		audit.expectAppend("", domain.AuditActionAdjustmentReview, domain.AuditResourceAdjustment, "7", nil)
+		mock.ExpectCommit()
+	}
+	tests := []struct {
+		name     string
+		getRows  *sqlmock.Rows
+		review   domain.AdjustmentReview
+		decide   domain.TransferDecision
+		doMockDB func(mock sqlmock.Sqlmock)
+		want     domain.Adjustment
+		wantErr  error
+	}{
+		{
+			name:    "Test Case Positive - Rejected",
+			getRows: pendingRows(),
+			review:  reviewWith(domain.AdjustmentStatusRejected),
+			doMockDB: func(mock sqlmock.Sqlmock) {
+				lock, review, audit := expectAdjustmentPrepare(mock)
+				expectRecord(mock, lock, review, audit, domain.AdjustmentStatusRejected)
+			},
+			want: reviewed(domain.AdjustmentStatusRejected, 0),
+		},
+		{
+			name:    "Test Case Positive - Approved adjustment applied in the DB transaction of its transfer",
+			getRows: pendingRows(),
+			review:  reviewWith(domain.AdjustmentStatusApproved),
+			decide:  fixedDecision(80.5, 19.5),
+			doMockDB: func(mock sqlmock.Sqlmock) {
+				adjustmentLock, review, adjustmentAudit := expectAdjustmentPrepare(mock)
+				lock, update, transferAudit := expectTransferBegins(mock)
+				adjustmentLock.ExpectQuery().WithArgs(int64(7), "123").WillReturnRows(pendingRows())
+				expectLockAccounts(lock, "123", "SUSPENSE")
+				update.ExpectExec().WithArgs(80.5, "123").WillReturnResult(sqlmock.NewResult(0, 1))
+				update.ExpectExec().WithArgs(19.5, "SUSPENSE").WillReturnResult(sqlmock.NewResult(0, 1))
+				expectTransferCompletes(mock)
+				review.ExpectQuery().WithArgs(int64(7), domain.AdjustmentStatusApplied, "key:def", int64(7)).
+					WillReturnRows(sqlmock.NewRows([]string{"reviewed_at"}).AddRow(reviewedAt))
+				adjustmentAudit.expectAppend("", domain.AuditActionAdjustmentReview, domain.AuditResourceAdjustment, "7", nil)
+				transferAudit.expectAppend("", domain.AuditActionTransfer, domain.AuditResourceTransaction, "7", nil)
+				mock.ExpectCommit()
+			},
+			want: reviewed(domain.AdjustmentStatusApplied, 7),
+		},
+		{
+			name:    "Test Case Negative - Approved adjustment fails and is failed",
+			getRows: pendingRows(),
+			review:  reviewWith(domain.AdjustmentStatusApproved),
+			decide: func(source domain.Account, destination domain.Account) (float64, float64, error) {
+				return 0, 0, insufficientFunds
+			},
+			doMockDB: func(mock sqlmock.Sqlmock) {
+				adjustmentLock, review, audit := expectAdjustmentPrepare(mock)
+				lock, _, _ := expectTransferBegins(mock)
+				adjustmentLock.ExpectQuery().WithArgs(int64(7), "123").WillReturnRows(pendingRows())
+				expectLockAccounts(lock, "123", "SUSPENSE")
+				expectFailedTransfer(mock, static.ErrTransferAmountLargerThanAccount)
+				expectRecord(mock, adjustmentLock, review, audit, domain.AdjustmentStatusFailed)
+			},
+			wantErr: insufficientFunds,
+		},
+		{
+			name:    "Test Case Negative - Adjustment does not exist",
+			getRows: sqlmock.NewRows(adjustmentRowColumns),
+			review:  reviewWith(domain.AdjustmentStatusApproved),
+			doMockDB: func(mock sqlmock.Sqlmock) {
+			},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name: "Test Case Negative - Review error has no effect",
+			getRows: sqlmock.NewRows(adjustmentRowColumns).
+				AddRow(7, "123", "SUSPENSE", "debit", "19.5", "correction", "", "applied", "key:abc", "key:def", 11, createdAt, reviewedAt),
+			review: func(adjustment domain.Adjustment) (domain.Adjustment, error) {
+				return domain.Adjustment{}, errReview
+			},
+			doMockDB: func(mock sqlmock.Sqlmock) {
+			},
+			wantErr: errReview,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectPrepare(`SELECT (.+) FROM "public"."adjustment" WHERE id = \$1 AND account_id = \$2$`).ExpectQuery().WithArgs(int64(7), "123").WillReturnRows(tc.getRows)
+			tc.doMockDB(mock)
+
+			adjustment, err := newTestAdjustmentPort(t, db).ReviewAdjustment(context.Background(), "123", 7, tc.review, tc.decide)
+
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, adjustment)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/postgres"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// auditQueries are the queries appending an entry to the audit log
+// lockKey names the audit log the transaction level advisory lock taken by lock serialises appends to, so that each entry is chained to the last one committed
+// The key is hashed from the schema qualified name of the table, so that the audit logs of different schemas in the same database are appended to independently
+type auditQueries struct {
+	lock    string
+	lockKey string
+	last    string
+	insert  string
+}
+
+func newAuditQueries(t tables) auditQueries {
+	return auditQueries{
+		lock:    `SELECT pg_advisory_xact_lock(hashtext($1))`,
+		lockKey: t.auditLog,
+		last:    fmt.Sprintf(`SELECT hash FROM %s ORDER BY id DESC LIMIT 1`, t.auditLog),
+		insert: fmt.Sprintf(`
+			INSERT INTO %s(
+				created_at, actor, action, resource_type, resource_id, before, after, request_id, client_ip, prev_hash, hash
+			)
+			VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+			)`,
+			t.auditLog,
+		),
+	}
+}
+
+// auditAppend appends an entry to the audit log in the DB transaction of the write it records, so that the write is never committed without its entry
+// Its statements are prepared by prepareAudit before the DB transaction begins
+type auditAppend struct {
+	queries auditQueries
+	lock    *sqlx.Stmt
+	last    *sqlx.Stmt
+	insert  *sqlx.Stmt
+}
+
+// prepareAudit will prepare the statements appending an entry to the audit log on db
+// The function will return the auditAppend and an error object if a statement cannot be prepared
+func (s *statements) prepareAudit(ctx context.Context, db *sqlx.DB, queries auditQueries) (*auditAppend, error) {
+	a := &auditAppend{queries: queries}
+	var err error
+	if a.lock, err = s.prepare(ctx, db, queries.lock); err != nil {
+		return nil, err
+	}
+	if a.last, err = s.prepare(ctx, db, queries.last); err != nil {
+		return nil, err
+	}
+	if a.insert, err = s.prepare(ctx, db, queries.insert); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// append will accept a DB transaction and an entry to chain to the last entry of the audit log and insert in the DB transaction
+// The audit log is locked until the DB transaction ends, so append should be the last statement before the commit to keep concurrent writes waiting as little as possible
+// The lock is held from the append until the commit, so audited writes to the same schema of every instance of the app commit one at a time, about one per commit round trip
+// The last entry is read after the lock is taken, so that it includes every entry committed while waiting for it
+// The function will return nil if there is no error and an error object if there is error
+func (a *auditAppend) append(ctx context.Context, tx *sqlx.Tx, entry domain.AuditEntry) (err error) {
+	ctx, span := startSpan(ctx, "AuditLog.Append", a.queries.insert)
+	var rows int64 = -1
+	defer func() {
+		endSpan(span, rows, err)
+	}()
+
+	if _, err = tx.StmtxContext(ctx, a.lock).ExecContext(ctx, a.queries.lockKey); err != nil {
+		return err
+	}
+	err = tx.StmtxContext(ctx, a.last).GetContext(ctx, &entry.PrevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	// The timestamp is hashed, so it is kept to the microsecond precision Postgres stores
+	entry.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+
+	result, err := tx.StmtxContext(ctx, a.insert).ExecContext(
+		ctx,
+		entry.CreatedAt,
+		entry.Actor,
+		entry.Action,
+		entry.ResourceType,
+		entry.ResourceID,
+		jsonArg(entry.Before),
+		jsonArg(entry.After),
+		entry.RequestID,
+		entry.ClientIP,
+		entry.PrevHash,
+		entry.Hash,
+	)
+	if err != nil {
+		return err
+	}
+	rows, _ = result.RowsAffected()
+	return nil
+}
+
+// newAuditEntry will return the audit log entry of action on a resource made for the domain.Principal carried by ctx, with the values of the resource before and after it encoded as JSON
+// before is nil when the action creates the resource
+// The function will return the entry and an error object if a value cannot be encoded
+func newAuditEntry(ctx context.Context, action string, resourceType string, resourceID string, before any, after any) (domain.AuditEntry, error) {
+	principal := domain.PrincipalFrom(ctx)
+	entry := domain.AuditEntry{
+		Actor:        principal.Actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		RequestID:    principal.RequestID,
+		ClientIP:     principal.ClientIP,
+	}
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return domain.AuditEntry{}, err
+		}
+	}
+	if entry.After, err = json.Marshal(after); err != nil {
+		return domain.AuditEntry{}, err
+	}
+	return entry, nil
+}
+
+// jsonArg will return value as a JSON query argument, nil when there is no value
+func jsonArg(value json.RawMessage) any {
+	if value == nil {
+		return nil
+	}
+	return string(value)
+}
+
+// auditAccount is the value of an account recorded in the audit log
+type auditAccount struct {
+	ID          string            `json:"account_id"`
+	Balance     string            `json:"balance"`
+	Currency    string            `json:"currency,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	DisplayName string            `json:"display_name,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Labels      []string          `json:"labels,omitempty"`
+}
+
+func toAuditAccount(account domain.Account) auditAccount {
+	return auditAccount{
+		ID:          account.ID,
+		Balance:     account.Balance,
+		Currency:    account.Currency,
+		Status:      account.Status,
+		DisplayName: account.DisplayName,
+		Metadata:    account.Metadata,
+		Labels:      account.Labels,
+	}
+}
+
+// auditTransfer is the value of a transfer recorded in the audit log, with the balances of its accounts
+// Before a transfer only the accounts and their balances are set
+type auditTransfer struct {
+	SourceID           string          `json:"source_account_id"`
+	SourceBalance      string          `json:"source_balance"`
+	DestinationID      string          `json:"destination_account_id"`
+	DestinationBalance string          `json:"destination_balance"`
+	Amount             string          `json:"amount,omitempty"`
+	Description        string          `json:"description,omitempty"`
+	Reference          string          `json:"reference,omitempty"`
+	Metadata           json.RawMessage `json:"metadata,omitempty"`
+}
+
+// auditLogRow is a row of the audit_log table, with the nullable JSON before and after columns in their database type
+type auditLogRow struct {
+	domain.AuditEntry
+	Before []byte `db:"before"`
+	After  []byte `db:"after"`
+}
+
+// toAuditEntry will return the domain.AuditEntry of the row, with nil Before or After when the column is null
+func (r auditLogRow) toAuditEntry() domain.AuditEntry {
+	entry := r.AuditEntry
+	if r.Before != nil {
+		entry.Before = r.Before
+	}
+	if r.After != nil {
+		entry.After = r.After
+	}
+	return entry
+}
+
+type AuditPortImpl struct {
+	db      *sqlx.DB
+	queries auditLogQueries
+	stmts   *statements
+}
+
+type auditLogQueries struct {
+	stream string
+}
+
+// NewAuditPort returns an AuditRepository reading the audit log from db, the primary
+// The function will return an error object if the schema in dbConfig is not a valid identifier
+func NewAuditPort(db *sqlx.DB, dbConfig *postgres.DBConfig) (*AuditPortImpl, error) {
+	t, err := newTables(dbConfig.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditPortImpl{
+		db: db,
+		queries: auditLogQueries{
+			stream: fmt.Sprintf(`
+				SELECT
+					id, created_at, actor, action, resource_type, resource_id, before, after, request_id, client_ip, prev_hash, hash
+				FROM %s
+				ORDER BY id`,
+				t.auditLog,
+			),
+		},
+		stmts: newStatements(),
+	}, nil
+}
+
+// StreamAuditLog will call each with every entry of the audit log in order of id, reading the entries as each is called so that the log is never held in memory
+// The log is read from the primary, as a replica may not have the latest entries
+// The function will return the error returned by each, which stops the stream, and an error object if the log cannot be read
+func (i *AuditPortImpl) StreamAuditLog(ctx context.Context, each func(domain.AuditEntry) error) (err error) {
+	ctx, span := startSpan(ctx, "AuditRepository.StreamAuditLog", i.queries.stream)
+	var count int64
+	defer func() {
+		endSpan(span, count, err)
+	}()
+
+	stmt, err := i.stmts.prepare(ctx, i.db, i.queries.stream)
+	if err != nil {
+		return err
+	}
+	rows, err := stmt.QueryxContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row auditLogRow
+		if err := rows.StructScan(&row); err != nil {
+			return err
+		}
+		count++
+		if err := each(row.toAuditEntry()); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
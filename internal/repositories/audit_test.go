@@ -0,0 +1,384 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/postgres"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+// testAuditLockKey is the key of the advisory lock on the audit log of testDBConfig
+const testAuditLockKey = `"public"."audit_log"`
+
+// expectedAudit are the statements appending to the audit log, as prepared by prepareAudit
+type expectedAudit struct {
+	lock   *sqlmock.ExpectedPrepare
+	last   *sqlmock.ExpectedPrepare
+	insert *sqlmock.ExpectedPrepare
+}
+
+// expectAuditPrepare expects the statements appending to the audit log to be prepared
+func expectAuditPrepare(mock sqlmock.Sqlmock) expectedAudit {
+	return expectedAudit{
+		lock:   mock.ExpectPrepare(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`),
+		last:   mock.ExpectPrepare(`SELECT hash FROM "public"."audit_log" ORDER BY id DESC LIMIT 1`),
+		insert: mock.ExpectPrepare(`INSERT INTO "public"."audit_log"`),
+	}
+}
+
+// expectAppend expects an entry of action on the resource to be appended after the entry with hash prevHash, capturing the arguments of the insert in args
+func (a expectedAudit) expectAppend(prevHash string, action string, resourceType string, resourceID string, args *[]driver.Value) {
+	a.lock.ExpectExec().WithArgs(testAuditLockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"hash"})
+	if prevHash != "" {
+		rows.AddRow(prevHash)
+	}
+	a.last.ExpectQuery().WillReturnRows(rows)
+	capture := make([]driver.Value, 11)
+	matchers := make([]driver.Value, 11)
+	for i := range capture {
+		matchers[i] = captureArg{value: &capture[i]}
+	}
+	for i, want := range map[int]string{2: action, 3: resourceType, 4: resourceID, 9: prevHash} {
+		matchers[i] = captureArg{value: &capture[i], want: want}
+	}
+	a.insert.ExpectExec().WithArgs(matchers...).WillReturnResult(sqlmock.NewResult(0, 1))
+	if args != nil {
+		*args = capture
+	}
+}
+
+// captureArg matches an argument equal to want, or any argument when want is nil, and keeps its value
+type captureArg struct {
+	value *driver.Value
+	want  driver.Value
+}
+
+func (c captureArg) Match(value driver.Value) bool {
+	*c.value = value
+	return c.want == nil || c.want == value
+}
+
+// auditEntryOf will return the domain.AuditEntry of the arguments of an audit log insert
+func auditEntryOf(args []driver.Value) domain.AuditEntry {
+	jsonValue := func(value driver.Value) json.RawMessage {
+		if value == nil {
+			return nil
+		}
+		return json.RawMessage(value.(string))
+	}
+	return domain.AuditEntry{
+		CreatedAt:    args[0].(time.Time),
+		Actor:        args[1].(string),
+		Action:       args[2].(string),
+		ResourceType: args[3].(string),
+		ResourceID:   args[4].(string),
+		Before:       jsonValue(args[5]),
+		After:        jsonValue(args[6]),
+		RequestID:    args[7].(string),
+		ClientIP:     args[8].(string),
+		PrevHash:     args[9].(string),
+		Hash:         args[10].(string),
+	}
+}
+
+func TestInsertAccountAudit(t *testing.T) {
+	db, mock := newMockDB(t)
+	insert := mock.ExpectPrepare(`INSERT INTO "public"."account"`)
+	audit := expectAuditPrepare(mock)
+	mock.ExpectBegin()
+	insert.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	var args []driver.Value
+	audit.expectAppend("", domain.AuditActionAccountCreate, domain.AuditResourceAccount, "123", &args)
+	mock.ExpectCommit()
+
+	ctx := domain.WithPrincipal(context.Background(), domain.Principal{Actor: "key:abc", RequestID: "req-1", ClientIP: "192.0.2.1"})
+	err := newTestAccountPort(t, db, nil).InsertAccount(ctx, "123", 100.5, "USD", domain.AccountDetails{DisplayName: "Payroll"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	entry := auditEntryOf(args)
+	assert.Equal(t, "key:abc", entry.Actor)
+	assert.Equal(t, "req-1", entry.RequestID)
+	assert.Equal(t, "192.0.2.1", entry.ClientIP)
+	assert.Nil(t, entry.Before)
+	assert.JSONEq(t, `{"account_id":"123","balance":"100.5","currency":"USD","status":"active","display_name":"Payroll"}`, string(entry.After))
+	assert.Equal(t, entry.ComputeHash(), entry.Hash)
+	assert.WithinDuration(t, time.Now(), entry.CreatedAt, time.Minute)
+}
+
+func TestProcessTransactionAudit(t *testing.T) {
+	db, mock := newMockDB(t)
+	lock, update, audit := expectTransferBegins(mock)
+	expectLockAccounts(lock, "123", "456")
+	update.ExpectExec().WithArgs(90.0, "123").WillReturnResult(sqlmock.NewResult(0, 1))
+	update.ExpectExec().WithArgs(110.0, "456").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTransferCompletes(mock)
+	var args []driver.Value
+	audit.expectAppend("", domain.AuditActionTransfer, domain.AuditResourceTransaction, "7", &args)
+	mock.ExpectCommit()
+
+	ctx := domain.WithPrincipal(context.Background(), domain.Principal{Actor: "key:abc", RequestID: "req-1", ClientIP: "192.0.2.1"})
+	_, err := newTestTransactionPort(t, db).ProcessTransaction(
+		ctx,
+		domain.Transaction{SourceID: "123", DestinationID: "456", Amount: "10", Reference: "payroll-2024-03"},
+		fixedDecision(90, 110),
+	)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	entry := auditEntryOf(args)
+	assert.Equal(t, "key:abc", entry.Actor)
+	assert.JSONEq(t, `{"source_account_id":"123","source_balance":"100","destination_account_id":"456","destination_balance":"100"}`, string(entry.Before))
+	assert.JSONEq(t, `{"source_account_id":"123","source_balance":"90","destination_account_id":"456","destination_balance":"110","amount":"10","reference":"payroll-2024-03"}`, string(entry.After))
+}
+
+func TestAuditAppendChainsEntries(t *testing.T) {
+	db, mock := newMockDB(t)
+	insert := mock.ExpectPrepare(`INSERT INTO "public"."account"`)
+	audit := expectAuditPrepare(mock)
+	mock.ExpectBegin()
+	insert.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	var args []driver.Value
+	audit.expectAppend("f00d", domain.AuditActionAccountCreate, domain.AuditResourceAccount, "123", &args)
+	mock.ExpectCommit()
+
+	err := newTestAccountPort(t, db, nil).InsertAccount(context.Background(), "123", 0, "USD", domain.AccountDetails{})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	entry := auditEntryOf(args)
+	assert.Equal(t, domain.AuditActorSystem, entry.Actor)
+	assert.Equal(t, "f00d", entry.PrevHash)
+	assert.Equal(t, entry.ComputeHash(), entry.Hash)
+}
+
+func TestAuditAppendFailureRollsBackWrite(t *testing.T) {
+	db, mock := newMockDB(t)
+	insert := mock.ExpectPrepare(`INSERT INTO "public"."account"`)
+	audit := expectAuditPrepare(mock)
+	mock.ExpectBegin()
+	insert.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	audit.lock.ExpectExec().WithArgs(testAuditLockKey).WillReturnError(errors.New("random error"))
+	mock.ExpectRollback()
+
+	err := newTestAccountPort(t, db, nil).InsertAccount(context.Background(), "123", 0, "USD", domain.AccountDetails{})
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditLockPerSchema(t *testing.T) {
+	db, mock := newMockDB(t)
+	insert := mock.ExpectPrepare(`INSERT INTO "tenant_a"."account"`)
+	audit := expectedAudit{
+		lock:   mock.ExpectPrepare(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`),
+		last:   mock.ExpectPrepare(`SELECT hash FROM "tenant_a"."audit_log"`),
+		insert: mock.ExpectPrepare(`INSERT INTO "tenant_a"."audit_log"`),
+	}
+	mock.ExpectBegin()
+	insert.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	// Appends to the audit log of another schema take a lock of their own
+	audit.lock.ExpectExec().WithArgs(`"tenant_a"."audit_log"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	audit.last.ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+	audit.insert.ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	port, err := NewAccountPort(db, postgres.NewReadPool(db, nil, 0), &postgres.DBConfig{Schema: "tenant_a"})
+	assert.NoError(t, err)
+
+	err = port.InsertAccount(context.Background(), "123", 0, "USD", domain.AccountDetails{})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// BenchmarkAuditAppend measures audited writes per second against the Postgres database at AUDIT_BENCH_DATABASE_URL, and is skipped without it
+// Each write is a DB transaction appending one entry, run from as many goroutines as GOMAXPROCS with -cpu, spread over 1 and 4 schemas
+// As appends to the audit log of a schema are serialised until the commit, writes to one schema are capped at about one per commit round trip, while writes to several schemas are not
+//
+//	AUDIT_BENCH_DATABASE_URL=postgres://... go test ./internal/repositories -run '^$' -bench BenchmarkAuditAppend -cpu 16
+func BenchmarkAuditAppend(b *testing.B) {
+	url := os.Getenv("AUDIT_BENCH_DATABASE_URL")
+	if url == "" {
+		b.Skip("AUDIT_BENCH_DATABASE_URL is not set")
+	}
+	db, err := sqlx.Open("postgres", url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(64)
+
+	for _, schemas := range []int{1, 4} {
+		schemas := schemas
+		b.Run(fmt.Sprintf("schemas=%d", schemas), func(b *testing.B) {
+			audits := make([]*auditAppend, schemas)
+			for i := range audits {
+				schema := fmt.Sprintf("audit_bench_%d", i)
+				if _, err := db.Exec(`CREATE SCHEMA IF NOT EXISTS ` + schema); err != nil {
+					b.Fatal(err)
+				}
+				if err := postgres.Migrate(context.Background(), db, schema); err != nil {
+					b.Fatal(err)
+				}
+				tbl, err := newTables(schema)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if audits[i], err = newStatements().prepareAudit(context.Background(), db, newAuditQueries(tbl)); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				audit := audits[int(next.Add(1))%schemas]
+				for pb.Next() {
+					if err := appendInTransaction(db, audit); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "appends/s")
+		})
+	}
+}
+
+// appendInTransaction will append an entry with audit in a DB transaction of its own and commit it
+func appendInTransaction(db *sqlx.DB, audit *auditAppend) error {
+	tx, err := db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	entry, err := newAuditEntry(context.Background(), domain.AuditActionAccountCreate, domain.AuditResourceAccount, "bench", nil, "bench")
+	if err != nil {
+		return err
+	}
+	if err := audit.append(context.Background(), tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TestAuditAppendConcurrentTransactions appends entries from concurrent DB transactions, each taking the audit log lock before it reads the last entry and inserts its own
+// The lock itself is taken by Postgres and is not simulated, so the test checks that appends share nothing but the prepared statements, and is meant to run with -race too
+// The DB transactions are begun first, so that each but the first, which reuses the connection the statements were prepared on, prepares them again on a connection of its own
+func TestAuditAppendConcurrentTransactions(t *testing.T) {
+	const writers = 8
+	db, mock := newMockDB(t)
+	tbl, err := newTables(testDBConfig.Schema)
+	assert.NoError(t, err)
+	expectAuditPrepare(mock)
+	audit, err := newStatements().prepareAudit(context.Background(), db, newAuditQueries(tbl))
+	assert.NoError(t, err)
+
+	for i := 0; i < writers; i++ {
+		mock.ExpectBegin()
+	}
+	txs := make([]*sqlx.Tx, writers)
+	for i := range txs {
+		txs[i], err = db.BeginTxx(context.Background(), nil)
+		assert.NoError(t, err)
+	}
+	mock.MatchExpectationsInOrder(false)
+	for i := 0; i < writers; i++ {
+		if i > 0 {
+			expectAuditPrepare(mock)
+		}
+		// Waiting for the lock lets the other writers start their DB transactions meanwhile
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock\(hashtext\(\$1\)\)`).WithArgs(testAuditLockKey).WillDelayFor(5 * time.Millisecond).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT hash FROM "public"."audit_log"`).WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow("f00d"))
+		mock.ExpectExec(`INSERT INTO "public"."audit_log"`).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	errs := make(chan error, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int, tx *sqlx.Tx) {
+			defer wg.Done()
+			entry, err := newAuditEntry(context.Background(), domain.AuditActionAccountCreate, domain.AuditResourceAccount, strconv.Itoa(i), nil, i)
+			if err == nil {
+				err = audit.append(context.Background(), tx, entry)
+			}
+			if err != nil {
+				_ = tx.Rollback()
+				errs <- err
+				return
+			}
+			errs <- tx.Commit()
+		}(i, txs[i])
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamAuditLog(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"id", "created_at", "actor", "action", "resource_type", "resource_id", "before", "after", "request_id", "client_ip", "prev_hash", "hash"}
+	tests := []struct {
+		name    string
+		eachErr error
+		want    []int64
+	}{
+		{
+			name: "Test Case Positive",
+			want: []int64{1, 2},
+		},
+		{
+			name:    "Test Case Negative - each stops the stream",
+			eachErr: errors.New("stop"),
+			want:    []int64{1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectPrepare(`SELECT (.+) FROM "public"."audit_log" ORDER BY id`).ExpectQuery().WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, createdAt, "key:abc", domain.AuditActionAccountCreate, domain.AuditResourceAccount, "123", nil, []byte(`{"account_id":"123"}`), "req-1", "192.0.2.1", "", "aa").
+				AddRow(2, createdAt, "key:abc", domain.AuditActionAccountUpdate, domain.AuditResourceAccount, "123", []byte(`{"account_id":"123"}`), []byte(`{"account_id":"123"}`), "req-2", "192.0.2.1", "aa", "bb"))
+			port, err := NewAuditPort(db, testDBConfig)
+			assert.NoError(t, err)
+
+			var ids []int64
+			var first domain.AuditEntry
+			err = port.StreamAuditLog(context.Background(), func(entry domain.AuditEntry) error {
+				if len(ids) == 0 {
+					first = entry
+				}
+				ids = append(ids, entry.ID)
+				return tc.eachErr
+			})
+
+			assert.ErrorIs(t, err, tc.eachErr)
+			assert.Equal(t, tc.want, ids)
+			assert.Nil(t, first.Before)
+			assert.Equal(t, json.RawMessage(`{"account_id":"123"}`), first.After)
+			assert.Equal(t, "aa", first.Hash)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"account-test/postgres"
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type HealthPortImpl struct {
+	db       *sqlx.DB
+	dbConfig *postgres.DBConfig
+}
+
+func NewHealthPort(db *sqlx.DB, dbConfig *postgres.DBConfig) *HealthPortImpl {
+	return &HealthPortImpl{
+		db:       db,
+		dbConfig: dbConfig,
+	}
+}
+
+// Ping will check that a connection to the database can be made within the deadline of ctx
+// This function will return nil if the database is reachable and a error object when it is not
+func (i *HealthPortImpl) Ping(ctx context.Context) error {
+	return i.db.PingContext(ctx)
+}
+
+// CheckMigrations will check that every migration known to this build has been applied to the schema
+// This function will return nil if the schema is current and a error object when it is behind or cannot be read
+func (i *HealthPortImpl) CheckMigrations(ctx context.Context) error {
+	current, err := postgres.CurrentVersion(ctx, i.db, i.dbConfig.Schema)
+	if err != nil {
+		return err
+	}
+	if latest := postgres.LatestVersion(); current < latest {
+		return fmt.Errorf("schema is at migration %d, expected %d", current, latest)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"account-test/postgres"
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		version int
+		wantErr bool
+	}{
+		{name: "Test Case Positive", version: postgres.LatestVersion(), wantErr: false},
+		{name: "Test Case Negative - Migrations behind", version: postgres.LatestVersion() - 1, wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			mock.ExpectQuery("SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM \"public\".schema_migrations").
+				WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(tc.version))
+
+			err := NewHealthPort(db, &postgres.DBConfig{Schema: "public"}).CheckMigrations(context.Background())
+
+			assert.Equal(t, tc.wantErr, err != nil)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
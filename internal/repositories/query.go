@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"account-test/postgres"
+	"account-test/static"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// tables holds the quoted, schema qualified names of the tables queried by the repositories
+// Repository queries are built from these names once when the repository is created, never from configuration at call time
+type tables struct {
+	account           string
+	transaction       string
+	rateLimitBucket   string
+	balanceSnapshot   string
+	balanceCorrection string
+	auditLog          string
+	adjustment        string
+	transferApproval  string
+}
+
+// newTables will validate and quote schema and the table names in static
+// The function will return the quoted names and an error object if the schema is not a valid identifier, see postgres.ValidateIdentifier
+func newTables(schema string) (tables, error) {
+	var t tables
+	for table, name := range map[string]*string{
+		static.TableAccount:           &t.account,
+		static.TableTransaction:       &t.transaction,
+		static.TableRateLimitBucket:   &t.rateLimitBucket,
+		static.TableBalanceSnapshot:   &t.balanceSnapshot,
+		static.TableBalanceCorrection: &t.balanceCorrection,
+		static.TableAuditLog:          &t.auditLog,
+		static.TableAdjustment:        &t.adjustment,
+		static.TableTransferApproval:  &t.transferApproval,
+	} {
+		qualified, err := postgres.QualifiedName(schema, table)
+		if err != nil {
+			return tables{}, fmt.Errorf("schema %q: %w", schema, err)
+		}
+		*name = qualified
+	}
+	return t, nil
+}
+
+// statementKey identifies a query prepared on a connection pool
+type statementKey struct {
+	db    *sqlx.DB
+	query string
+}
+
+// statements prepares the queries of a repository once per connection pool and reuses the prepared statements across calls
+// Only queries with a fixed SQL are prepared, as every statement is kept for the life of the pool, a query built from a request is run unprepared
+// database/sql prepares a statement again on each connection of the pool the first time it is used there
+// Statements run in a DB transaction are fetched before the transaction begins and bound to it with sqlx.Tx.StmtxContext, so preparing them never waits for a second connection
+type statements struct {
+	mu       sync.Mutex
+	prepared map[statementKey]*sqlx.Stmt
+}
+
+func newStatements() *statements {
+	return &statements{prepared: map[statementKey]*sqlx.Stmt{}}
+}
+
+// prepare will return query prepared on db, preparing it the first time it is requested
+// A statement that fails to prepare is not cached, so it is prepared again on the next call
+// The function will return the prepared statement and an error object if the statement cannot be prepared
+func (s *statements) prepare(ctx context.Context, db *sqlx.DB, query string) (*sqlx.Stmt, error) {
+	key := statementKey{db: db, query: query}
+	s.mu.Lock()
+	stmt, ok := s.prepared[key]
+	s.mu.Unlock()
+	if ok {
+		return stmt, nil
+	}
+
+	stmt, err := db.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if existing, ok := s.prepared[key]; ok {
+		// Another call prepared the same query first
+		_ = stmt.Close()
+		return existing, nil
+	}
+	s.prepared[key] = stmt
+	return stmt, nil
+}
+
+// getContext will run the prepared statement of query on db with args and scan the single row returned into dest, see sqlx.GetContext
+// The function will return sql.ErrNoRows if no row is returned and an error object if the statement cannot be prepared or run
+func (s *statements) getContext(ctx context.Context, db *sqlx.DB, dest any, query string, args ...any) error {
+	stmt, err := s.prepare(ctx, db, query)
+	if err != nil {
+		return err
+	}
+	return stmt.GetContext(ctx, dest, args...)
+}
+
+// selectContext will run the prepared statement of query on db with args and scan every row returned into dest, see sqlx.SelectContext
+// The function will return an error object if the statement cannot be prepared or run
+func (s *statements) selectContext(ctx context.Context, db *sqlx.DB, dest any, query string, args ...any) error {
+	stmt, err := s.prepare(ctx, db, query)
+	if err != nil {
+		return err
+	}
+	return stmt.SelectContext(ctx, dest, args...)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/postgres"
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDBConfig = &postgres.DBConfig{Schema: "public"}
+
+func newTestAccountPort(t *testing.T, db *sqlx.DB, replica *sqlx.DB) *AccountPortImpl {
+	port, err := NewAccountPort(db, postgres.NewReadPool(db, replica, 0), testDBConfig)
+	assert.NoError(t, err)
+	return port
+}
+
+func newTestTransactionPort(t *testing.T, db *sqlx.DB) *TransactionPortImpl {
+	port, err := NewTransactionPort(db, postgres.NewReadPool(db, nil, 0), testDBConfig)
+	assert.NoError(t, err)
+	return port
+}
+
+func TestNewTables(t *testing.T) {
+	tables, err := newTables("ledger")
+
+	assert.NoError(t, err)
+	assert.Equal(t, `"ledger"."account"`, tables.account)
+	assert.Equal(t, `"ledger"."transaction"`, tables.transaction)
+	assert.Equal(t, `"ledger"."rate_limit_bucket"`, tables.rateLimitBucket)
+}
+
+func TestNewPortsRejectHostileSchema(t *testing.T) {
+	schemas := []string{
+		"",
+		"public; DROP TABLE account; --",
+		`public"."account" CASCADE; --`,
+		"public.account",
+		"public /* comment */",
+		"Public",
+	}
+	for _, schema := range schemas {
+		t.Run(schema, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			dbConfig := &postgres.DBConfig{Schema: schema}
+
+			_, err := NewAccountPort(db, postgres.NewReadPool(db, nil, 0), dbConfig)
+			assert.Error(t, err)
+			_, err = NewTransactionPort(db, postgres.NewReadPool(db, nil, 0), dbConfig)
+			assert.Error(t, err)
+			_, err = NewRateLimitPort(db, dbConfig)
+			assert.Error(t, err)
+			// Nothing is sent to the database
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestStatementsReusedAcrossCalls(t *testing.T) {
+	db, mock := newMockDB(t)
+	prepared := mock.ExpectPrepare(`SELECT (.+) FROM "public"."account"`)
+	prepared.ExpectQuery().WithArgs("123").WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100"))
+	prepared.ExpectQuery().WithArgs("456").WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow("456", "50"))
+	port := newTestAccountPort(t, db, nil)
+
+	first, err := port.GetAccount(context.Background(), "123")
+	assert.NoError(t, err)
+	second, err := port.GetAccount(context.Background(), "456")
+	assert.NoError(t, err)
+
+	assert.Equal(t, &domain.Account{ID: "123", Balance: "100"}, first)
+	assert.Equal(t, &domain.Account{ID: "456", Balance: "50"}, second)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatementsNotCachedWhenPrepareFails(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectPrepare(`SELECT EXISTS`).WillReturnError(assert.AnError)
+	mock.ExpectPrepare(`SELECT EXISTS`).ExpectQuery().WithArgs("123").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	port := newTestAccountPort(t, db, nil)
+
+	assert.False(t, port.CheckAccountExists(context.Background(), "123"))
+	assert.True(t, port.CheckAccountExists(context.Background(), "123"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
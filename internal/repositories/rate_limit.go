@@ -0,0 +1,199 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/postgres"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+)
+
+type RateLimitPortImpl struct {
+	db      *sqlx.DB
+	queries rateLimitQueries
+	stmts   *statements
+
+	mu        sync.Mutex
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type rateLimitQueries struct {
+	insert string
+	lock   string
+	update string
+	sweep  string
+}
+
+// sweepBatch is the most expired buckets deleted from the rate_limit_bucket table by a sweep, so that a sweep never holds many row locks
+const sweepBatch = 1000
+
+// NewRateLimitPort returns a RateLimitRepository keeping token buckets in the rate_limit_bucket table of db
+// The function will return an error object if the schema in dbConfig is not a valid identifier
+func NewRateLimitPort(db *sqlx.DB, dbConfig *postgres.DBConfig) (*RateLimitPortImpl, error) {
+	t, err := newTables(dbConfig.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &RateLimitPortImpl{
+		db: db,
+		queries: rateLimitQueries{
+			insert: fmt.Sprintf(`
+				INSERT INTO %s(
+					key, tokens, updated_at
+				)
+				VALUES (
+					$1, $2, NOW()
+				) ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key`,
+				t.rateLimitBucket,
+			),
+			lock: fmt.Sprintf(`
+				SELECT
+					tokens, updated_at, NOW()
+				FROM %s
+				WHERE key = $1
+				FOR UPDATE`,
+				t.rateLimitBucket,
+			),
+			update: fmt.Sprintf(`
+				UPDATE %s SET
+					tokens = $1,
+					updated_at = $2,
+					expires_at = $3
+				WHERE key = $4`,
+				t.rateLimitBucket,
+			),
+			sweep: fmt.Sprintf(`
+				DELETE FROM %[1]s
+				WHERE key IN (
+					SELECT key FROM %[1]s
+					WHERE expires_at < NOW()
+					LIMIT %[2]d
+					FOR UPDATE SKIP LOCKED
+				)`,
+				t.rateLimitBucket, sweepBatch,
+			),
+		},
+		stmts: newStatements(),
+		now:   time.Now,
+	}, nil
+}
+
+// Take will accept a bucket key and a domain.RateLimit and attempt to take a single token from the bucket stored in the rate_limit_bucket table
+// The bucket row is locked for the duration of the update so that multiple app instances sharing the database see a consistent token count
+// The database clock is used for refills to avoid skew between app instances
+// Each bucket expires once it would be full again, as it is then equivalent to a missing bucket, and expired buckets are deleted every sweepInterval, see sweep
+// The bucket is inserted or locked in a single statement, so that a bucket deleted by a concurrent sweep is inserted again
+// This function will return a domain.RateLimitResult and an error object if there is an error
+func (i *RateLimitPortImpl) Take(ctx context.Context, key string, limit domain.RateLimit) (_ domain.RateLimitResult, err error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "RateLimitRepository.Take")
+	defer func() {
+		endSpan(span, -1, err)
+	}()
+
+	insertStmt, err := i.stmts.prepare(ctx, i.db, i.queries.insert)
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+	lockStmt, err := i.stmts.prepare(ctx, i.db, i.queries.lock)
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+	updateStmt, err := i.stmts.prepare(ctx, i.db, i.queries.update)
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	_, err = tracedExec(ctx, tx.StmtxContext(ctx, insertStmt), "RateLimitRepository.Take.Insert", i.queries.insert, key, float64(limit.Burst))
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+
+	var tokens float64
+	var updatedAt, now time.Time
+	selectCtx, selectSpan := startSpan(ctx, "RateLimitRepository.Take.Select", i.queries.lock)
+	err = tx.StmtxContext(ctx, lockStmt).QueryRowContext(selectCtx, key).Scan(&tokens, &updatedAt, &now)
+	endSpan(selectSpan, 1, err)
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+
+	tokens, result := takeToken(tokens, updatedAt, now, limit)
+
+	_, err = tracedExec(ctx, tx.StmtxContext(ctx, updateStmt), "RateLimitRepository.Take.Update", i.queries.update, tokens, now, fullAt(tokens, now, limit), key)
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.RateLimitResult{}, err
+	}
+	i.sweep(ctx)
+	return result, nil
+}
+
+// sweep will delete up to sweepBatch expired buckets from the rate_limit_bucket table, at most once every sweepInterval per app instance
+// A failed sweep is only logged, as the expired buckets are deleted by a later sweep
+func (i *RateLimitPortImpl) sweep(ctx context.Context) {
+	i.mu.Lock()
+	now := i.now()
+	if now.Sub(i.lastSweep) < sweepInterval {
+		i.mu.Unlock()
+		return
+	}
+	i.lastSweep = now
+	i.mu.Unlock()
+
+	stmt, err := i.stmts.prepare(ctx, i.db, i.queries.sweep)
+	if err == nil {
+		_, err = tracedExec(ctx, stmt, "RateLimitRepository.Sweep", i.queries.sweep)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "rate limit bucket sweep failed", "error", err)
+	}
+}
+
+// takeToken will refill a bucket holding tokens since last according to limit and then attempt to take a single token from it
+// The function will return the tokens remaining in the bucket and whether the token was taken
+// When the token cannot be taken, the result carries how long the caller should wait for the next token
+func takeToken(tokens float64, last time.Time, now time.Time, limit domain.RateLimit) (float64, domain.RateLimitResult) {
+	tokens = refillTokens(tokens, last, now, limit)
+	if tokens >= 1 {
+		return tokens - 1, domain.RateLimitResult{Allowed: true}
+	}
+	wait := time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+	return tokens, domain.RateLimitResult{Allowed: false, RetryAfter: wait}
+}
+
+// fullAt will return the time a bucket holding tokens at now is full again according to limit
+func fullAt(tokens float64, now time.Time, limit domain.RateLimit) time.Time {
+	missing := float64(limit.Burst) - tokens
+	if missing <= 0 {
+		return now
+	}
+	return now.Add(time.Duration(missing / limit.Rate * float64(time.Second)))
+}
+
+// refillTokens will add the tokens accrued between last and now to a bucket, capped at the bucket capacity
+func refillTokens(tokens float64, last time.Time, now time.Time, limit domain.RateLimit) float64 {
+	elapsed := now.Sub(last).Seconds()
+	if elapsed <= 0 {
+		return tokens
+	}
+	return math.Min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often idle buckets are dropped from memory
+const sweepInterval = time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	limit     domain.RateLimit
+}
+
+type MemoryRateLimitPortImpl struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryRateLimitPort returns a rate limit store holding buckets in process memory
+// It is suitable for a single app instance, multi-instance deployments should use NewRateLimitPort to share buckets through Postgres
+func NewMemoryRateLimitPort() *MemoryRateLimitPortImpl {
+	return &MemoryRateLimitPortImpl{
+		buckets: map[string]*memoryBucket{},
+		now:     time.Now,
+	}
+}
+
+// Take will accept a bucket key and a domain.RateLimit and attempt to take a single token from the in-memory bucket
+// This function will return a domain.RateLimitResult and never returns an error
+func (i *MemoryRateLimitPortImpl) Take(ctx context.Context, key string, limit domain.RateLimit) (domain.RateLimitResult, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	i.sweep(now)
+
+	bucket, ok := i.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(limit.Burst), updatedAt: now}
+		i.buckets[key] = bucket
+	}
+	var result domain.RateLimitResult
+	bucket.tokens, result = takeToken(bucket.tokens, bucket.updatedAt, now, limit)
+	bucket.updatedAt = now
+	bucket.limit = limit
+	return result, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again, as they are equivalent to a missing bucket
+func (i *MemoryRateLimitPortImpl) sweep(now time.Time) {
+	if now.Sub(i.lastSweep) < sweepInterval {
+		return
+	}
+	i.lastSweep = now
+	for key, bucket := range i.buckets {
+		if refillTokens(bucket.tokens, bucket.updatedAt, now, bucket.limit) >= float64(bucket.limit.Burst) {
+			delete(i.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryRateLimitTake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := domain.RateLimit{Rate: 2, Burst: 2}
+
+	tests := []struct {
+		name       string
+		elapsed    time.Duration
+		key        string
+		allowed    bool
+		retryAfter time.Duration
+	}{
+		{name: "Test Case Positive - Full bucket", elapsed: 0, key: "a", allowed: true},
+		{name: "Test Case Positive - Burst", elapsed: 0, key: "a", allowed: true},
+		{name: "Test Case Negative - Bucket empty", elapsed: 0, key: "a", allowed: false, retryAfter: 500 * time.Millisecond},
+		{name: "Test Case Positive - Separate key", elapsed: 0, key: "b", allowed: true},
+		{name: "Test Case Negative - Partially refilled", elapsed: 250 * time.Millisecond, key: "a", allowed: false, retryAfter: 250 * time.Millisecond},
+		{name: "Test Case Positive - Refilled", elapsed: 500 * time.Millisecond, key: "a", allowed: true},
+	}
+
+	store := NewMemoryRateLimitPort()
+	store.now = func() time.Time { return start }
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := start.Add(tc.elapsed)
+			store.now = func() time.Time { return now }
+			result, err := store.Take(context.Background(), tc.key, limit)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.allowed, result.Allowed)
+			assert.Equal(t, tc.retryAfter, result.RetryAfter)
+		})
+	}
+}
+
+func TestMemoryRateLimitSweep(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := domain.RateLimit{Rate: 1, Burst: 1}
+	store := NewMemoryRateLimitPort()
+	store.now = func() time.Time { return start }
+	_, _ = store.Take(context.Background(), "a", limit)
+	_, _ = store.Take(context.Background(), "b", limit)
+
+	store.now = func() time.Time { return start.Add(2 * sweepInterval) }
+	_, _ = store.Take(context.Background(), "c", limit)
+
+	assert.Len(t, store.buckets, 1)
+}
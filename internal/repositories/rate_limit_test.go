@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectTake expects a token to be taken from bucket "a" holding tokens at now, updated to remaining expiring at expiresAt
+// The statements of Take are only prepared by the first take of a store
+func expectTake(mock sqlmock.Sqlmock, first bool, tokens float64, now time.Time, remaining float64, expiresAt time.Time) {
+	if first {
+		mock.ExpectPrepare(`INSERT INTO "public"."rate_limit_bucket"(.+)ON CONFLICT \(key\) DO UPDATE`)
+		mock.ExpectPrepare(`SELECT (.+) FROM "public"."rate_limit_bucket" (.+) FOR UPDATE`)
+		mock.ExpectPrepare(`UPDATE "public"."rate_limit_bucket"`)
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "public"."rate_limit_bucket"`).WithArgs("a", 2.0).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT (.+) FROM "public"."rate_limit_bucket"`).WithArgs("a").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "updated_at", "now"}).AddRow(tokens, now, now))
+	mock.ExpectExec(`UPDATE "public"."rate_limit_bucket"`).WithArgs(remaining, now, expiresAt, "a").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestRateLimitTakeSweepsExpiredBuckets(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := domain.RateLimit{Rate: 2, Burst: 2}
+	tests := []struct {
+		name      string
+		elapsed   time.Duration
+		sweepErr  error
+		wantSweep bool
+	}{
+		{name: "Test Case Positive - First take sweeps", elapsed: 0, wantSweep: true},
+		{name: "Test Case Positive - No sweep within interval", elapsed: sweepInterval / 2, wantSweep: false},
+		{name: "Test Case Negative - Failed sweep lets token be taken", elapsed: sweepInterval, sweepErr: errors.New("random error"), wantSweep: true},
+	}
+
+	db, mock := newMockDB(t)
+	store, err := NewRateLimitPort(db, testDBConfig)
+	assert.NoError(t, err)
+	for i, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := start.Add(tc.elapsed)
+			store.now = func() time.Time { return now }
+			// A bucket with a token left is full again after half a second at 2 tokens per second
+			expectTake(mock, i == 0, 2, now, 1, now.Add(500*time.Millisecond))
+			if tc.wantSweep {
+				if i == 0 {
+					mock.ExpectPrepare(`DELETE FROM "public"."rate_limit_bucket"`)
+				}
+				sweep := mock.ExpectExec(`DELETE FROM "public"."rate_limit_bucket" WHERE key IN \( SELECT key FROM "public"."rate_limit_bucket" WHERE expires_at < NOW\(\) LIMIT 1000 FOR UPDATE SKIP LOCKED \)`)
+				if tc.sweepErr != nil {
+					sweep.WillReturnError(tc.sweepErr)
+				} else {
+					sweep.WillReturnResult(sqlmock.NewResult(0, 3))
+				}
+			}
+
+			result, err := store.Take(context.Background(), "a", limit)
+
+			assert.NoError(t, err)
+			assert.True(t, result.Allowed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "account-test/internal/repositories"
+
+// rowsAttribute is the span attribute holding the number of rows returned or affected by a statement
+var rowsAttribute = attribute.Key("db.rows")
+
+// startSpan will start a client span named name for a repository call executing query
+func startSpan(ctx context.Context, name string, query string) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+// endSpan will record the row count and error of a repository call on span and end it
+// rows is not recorded when it is negative, for calls where the row count is not known
+func endSpan(span trace.Span, rows int64, err error) {
+	if rows >= 0 {
+		span.SetAttributes(rowsAttribute.Int64(rows))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedExec will execute stmt, the prepared statement of query, inside a span named name, recording the affected row count
+func tracedExec(ctx context.Context, stmt *sqlx.Stmt, name string, query string, args ...any) (sql.Result, error) {
+	ctx, span := startSpan(ctx, name, query)
+	var rows int64 = -1
+	result, err := stmt.ExecContext(ctx, args...)
+	if err == nil {
+		rows, _ = result.RowsAffected()
+	}
+	endSpan(span, rows, err)
+	return result, err
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/postgres"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+func newSpanRecorder() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func newMockDB(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return sqlx.NewDb(db, postgres.DriverName), mock
+}
+
+func TestGetAccountSpan(t *testing.T) {
+	recorder := newSpanRecorder()
+	db, mock := newMockDB(t)
+	mock.ExpectPrepare(`SELECT (.+) FROM "public"."account"`).ExpectQuery().WithArgs("123").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100"))
+
+	account, err := newTestAccountPort(t, db, nil).GetAccount(context.Background(), "123")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.Account{ID: "123", Balance: "100"}, account)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "AccountRepository.GetAccount", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), semconv.DBSystemPostgreSQL)
+	assert.Contains(t, spans[0].Attributes(), rowsAttribute.Int64(1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessTransactionSpans(t *testing.T) {
+	recorder := newSpanRecorder()
+	db, mock := newMockDB(t)
+	lock, update, _ := expectTransferBegins(mock)
+	expectLockAccounts(lock, "123", "456")
+	update.ExpectExec().WithArgs(90.0, "123").WillReturnResult(sqlmock.NewResult(0, 1))
+	update.ExpectExec().WithArgs(110.0, "456").WillReturnError(errors.New("random error"))
+	mock.ExpectRollback()
+	mock.ExpectPrepare(`UPDATE "public"."transaction"`).ExpectExec().WithArgs("random error", int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := newTestTransactionPort(t, db).ProcessTransaction(
+		context.Background(),
+		domain.Transaction{SourceID: "123", DestinationID: "456", Amount: "10"},
+		fixedDecision(90, 110),
+	)
+
+	assert.Error(t, err)
+	names := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		names[span.Name()] = span
+	}
+	assert.Contains(t, names, "TransactionRepository.insertTransaction")
+	assert.Contains(t, names["TransactionRepository.ProcessTransaction.LockAccounts"].Attributes(), rowsAttribute.Int64(2))
+	assert.Contains(t, names["TransactionRepository.ProcessTransaction.UpdateSource"].Attributes(), rowsAttribute.Int64(1))
+	assert.Equal(t, codes.Error, names["TransactionRepository.ProcessTransaction.UpdateDestination"].Status().Code)
+	assert.Contains(t, names, "TransactionRepository.updateTransactionWithErrorMessage")
+	parent := names["TransactionRepository.ProcessTransaction"]
+	assert.Equal(t, codes.Error, parent.Status().Code)
+	assert.Equal(t, parent.SpanContext().SpanID(), names["TransactionRepository.ProcessTransaction.UpdateSource"].Parent().SpanID())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,132 +3,431 @@ package repositories
 import (
 	"account-test/internal/core/domain"
 	"account-test/postgres"
-	"account-test/static"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel"
 )
 
+const (
+	// uniqueViolation is the Postgres error code of a unique constraint violation
+	uniqueViolation = "23505"
+	// clientReferenceIndex holds the references of the transfers of each client unique, see postgres/migrations.go
+	clientReferenceIndex = "transaction_client_reference_idx"
+)
+
+// errorMessageUpdateTimeout bounds the update recording why a transaction failed, which is not cancelled with the request
+const errorMessageUpdateTimeout = 5 * time.Second
+
 type TransactionPortImpl struct {
-	db       *sqlx.DB
-	dbConfig *postgres.DBConfig
+	db      *sqlx.DB
+	reads   *postgres.ReadPool
+	queries transactionQueries
+	stmts   *statements
 }
 
-func NewTransactionPort(db *sqlx.DB, dbConfig *postgres.DBConfig) *TransactionPortImpl {
-	return &TransactionPortImpl{
-		db:       db,
-		dbConfig: dbConfig,
+type transactionQueries struct {
+	insert        string
+	setError      string
+	complete      string
+	lockAccounts  string
+	updateBalance string
+	list          string
+	stream        string
+	audit         auditQueries
+}
+
+// settlement settles the request a transfer executes, such as a transfer approval, in the DB transaction of the transfer, see process
+// Either callback may be nil
+type settlement struct {
+	// lock is called first in the DB transaction, before the accounts are locked, to lock and check the request
+	lock func(ctx context.Context, tx *sqlx.Tx) error
+	// complete is called once the balances are updated and the transaction is completed, with the id of the transaction
+	complete func(ctx context.Context, tx *sqlx.Tx, transactionID int64) error
+}
+
+// transactionRow is a row of the transaction table, with the nullable JSONB metadata column in its database type
+type transactionRow struct {
+	domain.Transaction
+	Metadata []byte `db:"metadata"`
+}
+
+// toTransaction will return the domain.Transaction of the row, with nil metadata when the transaction has none
+func (r transactionRow) toTransaction() domain.Transaction {
+	transaction := r.Transaction
+	if len(r.Metadata) > 0 {
+		transaction.Metadata = r.Metadata
 	}
+	return transaction
 }
 
-// ProcessTransaction accepts a Transaction object, source_amount and destination_amount to update the amount for the account with the transaction.sourceID with source_amount and account with transaction.destinationID with destination_amount
-// The function will also call insertTransaction to create a new transaction in the DB for logging of the transactions details
-// The function will also call updateTransactionWithErrorMessage to update the created transaction with error message in the event of error happening
-// The function will return nil if there is no error and an error object of there is error
-func (i *TransactionPortImpl) ProcessTransaction(ctx context.Context, transaction domain.Transaction, source_amount float64, destination_amount float64) error {
+// NewTransactionPort returns a TransactionRepository writing to db, the primary, and reading transaction history through reads
+// The function will return an error object if the schema in dbConfig is not a valid identifier
+func NewTransactionPort(db *sqlx.DB, reads *postgres.ReadPool, dbConfig *postgres.DBConfig) (*TransactionPortImpl, error) {
+	t, err := newTables(dbConfig.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &TransactionPortImpl{
+		db:    db,
+		reads: reads,
+		queries: transactionQueries{
+			insert: fmt.Sprintf(`
+				INSERT INTO %s(
+					source_account_id, destination_account_id, amount, description, reference, metadata, client_id
+				)
+				VALUES (
+					$1, $2, $3, $4, NULLIF($5, ''), $6, $7
+				) RETURNING id`,
+				t.transaction,
+			),
+			setError: fmt.Sprintf(`
+				UPDATE %s SET
+					status = '%s',
+					error_message = $1,
+					updated_at = NOW()
+				WHERE id = $2`,
+				t.transaction, domain.TransactionStatusFailed,
+			),
+			complete: fmt.Sprintf(`
+				UPDATE %s SET
+					status = '%s',
+					completed_at = clock_timestamp(),
+					updated_at = NOW()
+				WHERE id = $1`,
+				t.transaction, domain.TransactionStatusCompleted,
+			),
+			lockAccounts: fmt.Sprintf(`
+				SELECT
+					id, balance, currency
+				FROM %s
+				WHERE id IN ($1, $2)
+				ORDER BY id
+				FOR UPDATE`,
+				t.account,
+			),
+			updateBalance: fmt.Sprintf(`
+				UPDATE %s SET
+					balance = $1,
+					updated_at = NOW()
+				WHERE id = $2`,
+				t.account,
+			),
+			list: fmt.Sprintf(`
+				SELECT
+					id, source_account_id, destination_account_id, amount,
+					description, COALESCE(reference, '') AS reference, metadata, created_at, status
+				FROM %s
+				WHERE (source_account_id = $1 OR destination_account_id = $1)
+					AND ($2 = 0 OR id < $2)
+				ORDER BY id DESC
+				LIMIT $3`,
+				t.transaction,
+			),
+			stream: fmt.Sprintf(`
+				SELECT
+					id, source_account_id, destination_account_id, amount,
+					description, COALESCE(reference, '') AS reference, created_at, completed_at
+				FROM %s
+				WHERE (source_account_id = $1 OR destination_account_id = $1)
+					AND status = '%s'
+					AND completed_at > $2 AND completed_at <= $3
+				ORDER BY completed_at, id`,
+				t.transaction, domain.TransactionStatusCompleted,
+			),
+			audit: newAuditQueries(t),
+		},
+		stmts: newStatements(),
+	}, nil
+}
+
+// ProcessTransaction accepts a Transaction object and a domain.TransferDecision computing the new balances of the source and destination accounts of the transaction
+// The function will call insertTransaction to create a new transaction in the DB for logging of the transactions details
+// The function will lock the source and destination accounts on the primary for the rest of the DB transaction and pass their current balances to decide, so that no concurrent transfer can change them before they are updated
+// The function will update the source and destination accounts with the balances returned by decide, and set the status of the transaction to completed in the same DB transaction
+// Until then the transaction is pending, so a transfer still running or whose process died before recording its outcome is never counted as completed
+// The transfer is recorded in the audit log in the same DB transaction, with the balances of both accounts before and after it and the domain.Principal carried by ctx
+// The function will call updateTransactionWithErrorMessage to update the created transaction with error message in the event of error happening, including an error returned by decide
+// The function will return the id of the created transaction and an error object of there is error
+func (i *TransactionPortImpl) ProcessTransaction(ctx context.Context, transaction domain.Transaction, decide domain.TransferDecision) (int64, error) {
+	return i.process(ctx, transaction, decide, settlement{})
+}
+
+// process will process a transaction as ProcessTransaction does, calling the callbacks of settle in its DB transaction so that the request it settles is settled if and only if the transfer is
+// An error returned by either callback fails the transaction like an error returned by decide
+func (i *TransactionPortImpl) process(ctx context.Context, transaction domain.Transaction, decide domain.TransferDecision, settle settlement) (_ int64, err error) {
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, "TransactionRepository.ProcessTransaction")
+	defer func() {
+		endSpan(span, -1, err)
+	}()
+
 	transactionId, err := i.insertTransaction(ctx, transaction) //Insert transaction for logging purpose
 	if err != nil {
-		return err
+		return 0, err
 	}
-	tx, err := i.db.BeginTx(ctx, nil)
+	defer func() {
+		if err != nil {
+			i.updateTransactionWithErrorMessage(ctx, err.Error(), transactionId) // Update transaction with error message
+		}
+	}()
+
+	lockStmt, err := i.stmts.prepare(ctx, i.db, i.queries.lockAccounts)
 	if err != nil {
-		i.updateTransactionWithErrorMessage(ctx, err.Error(), transactionId) // Update transaction with error message
-		return err
+		return 0, err
+	}
+	updateStmt, err := i.stmts.prepare(ctx, i.db, i.queries.updateBalance)
+	if err != nil {
+		return 0, err
+	}
+	completeStmt, err := i.stmts.prepare(ctx, i.db, i.queries.complete)
+	if err != nil {
+		return 0, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return 0, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
 	}
 
 	defer func() {
 		_ = tx.Rollback()
 	}()
 
-	query := fmt.Sprintf(`
-		UPDATE %s.%s SET 
-			balance = $1,
-			updated_at = NOW()
-		WHERE id = $2`,
-		i.dbConfig.Schema, static.TableAccount,
-	)
+	if settle.lock != nil {
+		err = settle.lock(ctx, tx)
+		if err != nil {
+			return 0, err
+		}
+	}
+	source, destination, err := i.lockAccounts(ctx, tx.StmtxContext(ctx, lockStmt), transaction.SourceID, transaction.DestinationID)
+	if err != nil {
+		return 0, err
+	}
+	sourceBalance, destinationBalance, err := decide(source, destination)
+	if err != nil {
+		return 0, err
+	}
 
-	_, err = tx.ExecContext( //Update source account with new amount
+	updateStmt = tx.StmtxContext(ctx, updateStmt)
+	_, err = tracedExec( //Update source account with new amount
 		ctx,
-		query,
-		source_amount,
+		updateStmt,
+		"TransactionRepository.ProcessTransaction.UpdateSource",
+		i.queries.updateBalance,
+		sourceBalance,
 		transaction.SourceID,
 	)
 	if err != nil {
-		i.updateTransactionWithErrorMessage(ctx, err.Error(), transactionId) // Update transaction with error message
-		return err
+		return 0, err
 	}
 
-	_, err = tx.ExecContext( //Update destination account with new amount
+	_, err = tracedExec( //Update destination account with new amount
 		ctx,
-		query,
-		destination_amount,
+		updateStmt,
+		"TransactionRepository.ProcessTransaction.UpdateDestination",
+		i.queries.updateBalance,
+		destinationBalance,
 		transaction.DestinationID,
 	)
 	if err != nil {
-		i.updateTransactionWithErrorMessage(ctx, err.Error(), transactionId) // Update transaction with error message
-		return err
+		return 0, err
+	}
+
+	_, err = tracedExec(
+		ctx,
+		tx.StmtxContext(ctx, completeStmt),
+		"TransactionRepository.ProcessTransaction.Complete",
+		i.queries.complete,
+		transactionId,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if settle.complete != nil {
+		err = settle.complete(ctx, tx, transactionId)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	entry, err := newAuditEntry(ctx, domain.AuditActionTransfer, domain.AuditResourceTransaction, strconv.FormatInt(transactionId, 10),
+		auditTransfer{
+			SourceID:           transaction.SourceID,
+			SourceBalance:      source.Balance,
+			DestinationID:      transaction.DestinationID,
+			DestinationBalance: destination.Balance,
+		},
+		auditTransfer{
+			SourceID:           transaction.SourceID,
+			SourceBalance:      strconv.FormatFloat(sourceBalance, 'f', -1, 64),
+			DestinationID:      transaction.DestinationID,
+			DestinationBalance: strconv.FormatFloat(destinationBalance, 'f', -1, 64),
+			Amount:             transaction.Amount,
+			Description:        transaction.Description,
+			Reference:          transaction.Reference,
+			Metadata:           transaction.Metadata,
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return 0, err
 	}
 
 	err = tx.Commit()
 	if err != nil {
-		i.updateTransactionWithErrorMessage(ctx, err.Error(), transactionId) // Update transaction with error message
-		return err
+		return 0, err
 	}
-	return nil
+	return transactionId, nil
+}
+
+// lockAccounts will accept the lockAccounts statement bound to a DB transaction and the ids of the source and destination accounts of a transfer to read and lock both accounts until the DB transaction ends
+// Accounts are locked in order of id so that concurrent transfers between the same accounts cannot deadlock
+// The function will return the source and destination accounts and an error object if there is error or either account does not exist
+func (i *TransactionPortImpl) lockAccounts(ctx context.Context, stmt *sqlx.Stmt, sourceID string, destinationID string) (domain.Account, domain.Account, error) {
+	ctx, span := startSpan(ctx, "TransactionRepository.ProcessTransaction.LockAccounts", i.queries.lockAccounts)
+	accounts := []domain.Account{}
+	err := stmt.SelectContext(ctx, &accounts, sourceID, destinationID)
+	endSpan(span, int64(len(accounts)), err)
+	if err != nil {
+		return domain.Account{}, domain.Account{}, err
+	}
+
+	locked := map[string]domain.Account{}
+	for _, account := range accounts {
+		locked[account.ID] = account
+	}
+	for _, id := range []string{sourceID, destinationID} {
+		if _, ok := locked[id]; !ok {
+			return domain.Account{}, domain.Account{}, fmt.Errorf("account %s: %w", id, sql.ErrNoRows)
+		}
+	}
+	return locked[sourceID], locked[destinationID], nil
 }
 
 // updateTransactionWithErrorMessage will accept a error message and the ID of a transaction to update the transaction row in DB with the error message for logging purpose
+// The update is detached from the cancellation of ctx so that a transaction failed by a cancelled or timed out request is still recorded as failed
 // The function will return nil if there is no error and an error object of there is error
-func (i *TransactionPortImpl) updateTransactionWithErrorMessage(ctx context.Context, message string, id int) error {
-
-	query := fmt.Sprintf(`
-		UPDATE %s.%s SET 
-			error_message = $1,
-		WHERE id = $2`,
-		i.dbConfig.Schema, static.TableTransaction,
-	)
+func (i *TransactionPortImpl) updateTransactionWithErrorMessage(ctx context.Context, message string, id int64) error {
+	slog.WarnContext(ctx, "transaction failed", "transaction_id", id, "error", message)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), errorMessageUpdateTimeout)
+	defer cancel()
 
-	_, err := i.db.ExecContext(
-		ctx,
-		query,
-		message,
-		id,
-	)
+	stmt, err := i.stmts.prepare(ctx, i.db, i.queries.setError)
+	if err == nil {
+		_, err = tracedExec(
+			ctx,
+			stmt,
+			"TransactionRepository.updateTransactionWithErrorMessage",
+			i.queries.setError,
+			message,
+			id,
+		)
+	}
 
 	if err != nil {
+		slog.ErrorContext(ctx, "updateTransactionWithErrorMessage failed", "transaction_id", id, "error", err)
 		return err
 	}
 	return nil
 }
 
 // insertTransaction will accept a domain.Transaction object to create a new row in the transaction table to log the transaction details
-// The function will return the id of the created transaction object and an error object of there is error
-func (i *TransactionPortImpl) insertTransaction(ctx context.Context, transaction domain.Transaction) (int, error) {
-
-	query := fmt.Sprintf(`
-	INSERT INTO %s.%s( 
-		source_account_id, destination_account_id, amount 
-	)
-	VALUES (
-		$1, $2, $3
-	) RETURNING id
-	`,
-		i.dbConfig.Schema, static.TableTransaction,
-	)
-
-	row := i.db.QueryRowContext(
+// An empty reference is stored as NULL, so that only transfers with a reference are held unique per client by transaction_client_reference_idx
+// The function will return the id of the created transaction object, domain.ErrReferenceExists if the reference is already used by the client and an error object of there is error
+func (i *TransactionPortImpl) insertTransaction(ctx context.Context, transaction domain.Transaction) (int64, error) {
+	ctx, span := startSpan(ctx, "TransactionRepository.insertTransaction", i.queries.insert)
+	var metadata any
+	if transaction.Metadata != nil {
+		metadata = string(transaction.Metadata)
+	}
+	var id int64
+	err := i.stmts.getContext(
 		ctx,
-		query,
+		i.db,
+		&id,
+		i.queries.insert,
 		transaction.SourceID,
 		transaction.DestinationID,
 		transaction.Amount,
+		transaction.Description,
+		transaction.Reference,
+		metadata,
+		transaction.ClientID,
 	)
-	var id int
-	err := row.Scan(&id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == clientReferenceIndex {
+		err = fmt.Errorf("%w: %s", domain.ErrReferenceExists, transaction.Reference)
+	}
 	if err != nil {
+		endSpan(span, 0, err)
 		return 0, err
 	}
+	endSpan(span, 1, nil)
 	return id, nil
 }
+
+// ListTransactions will accept an account id, a transaction id to page from and a row limit to retrieve the transactions where the account is either the source or destination
+// Transactions are ordered from newest to oldest and only transactions with an id lower than beforeID are returned when beforeID is not 0
+// Transactions are read from the read replica when it is within the configured lag of the primary
+// The function will return a list of domain.Transaction objects and an error object if there is error
+func (i *TransactionPortImpl) ListTransactions(ctx context.Context, accountID string, beforeID int64, limit int) ([]domain.Transaction, error) {
+	ctx, span := startSpan(ctx, "TransactionRepository.ListTransactions", i.queries.list)
+	rows := []transactionRow{}
+	err := i.stmts.selectContext(ctx, i.reads.DB(), &rows, i.queries.list, accountID, beforeID, limit)
+	if err != nil {
+		endSpan(span, 0, err)
+		return nil, err
+	}
+	endSpan(span, int64(len(rows)), nil)
+	response := make([]domain.Transaction, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, row.toTransaction())
+	}
+	return response, nil
+}
+
+// StreamTransactions will accept an account id, a time range and a function called with each completed transaction where the account is either the source or destination
+// Transactions completed after from up to and including to are passed to each in the order they completed as they are read, so that they are never all held in memory
+// Transactions are read from the read replica when it is within the configured lag of the primary
+// The function will stop and return the error returned by each, and an error object if the transactions cannot be read
+func (i *TransactionPortImpl) StreamTransactions(ctx context.Context, accountID string, from time.Time, to time.Time, each func(domain.Transaction) error) (err error) {
+	ctx, span := startSpan(ctx, "TransactionRepository.StreamTransactions", i.queries.stream)
+	var count int64
+	defer func() {
+		endSpan(span, count, err)
+	}()
+
+	stmt, err := i.stmts.prepare(ctx, i.reads.DB(), i.queries.stream)
+	if err != nil {
+		return err
+	}
+	rows, err := stmt.QueryxContext(ctx, accountID, from, to)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var transaction domain.Transaction
+		if err := rows.StructScan(&transaction); err != nil {
+			return err
+		}
+		count++
+		if err := each(transaction); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,205 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/static"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+// expectTransferBegins expects ProcessTransaction to insert transaction 7, prepare the statements of the transfer and begin a DB transaction
+// The function will return the prepared statements locking the accounts, updating their balances and appending to the audit log
+func expectTransferBegins(mock sqlmock.Sqlmock) (*sqlmock.ExpectedPrepare, *sqlmock.ExpectedPrepare, expectedAudit) {
+	mock.ExpectPrepare(`INSERT INTO "public"."transaction"`).ExpectQuery().WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	lock := mock.ExpectPrepare(`SELECT (.+) FROM "public"."account" (.+) FOR UPDATE`)
+	update := mock.ExpectPrepare(`UPDATE "public"."account"`)
+	mock.ExpectPrepare(`UPDATE "public"."transaction" SET status = 'completed'`)
+	audit := expectAuditPrepare(mock)
+	mock.ExpectBegin()
+	return lock, update, audit
+}
+
+// expectTransferCompletes expects transaction 7 to be marked completed in the DB transaction of the transfer
+func expectTransferCompletes(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(`UPDATE "public"."transaction" SET status = 'completed'`).WithArgs(int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectLockAccounts expects the accounts of a transfer to be locked, both with a balance of 100
+func expectLockAccounts(lock *sqlmock.ExpectedPrepare, sourceID string, destinationID string) {
+	lock.ExpectQuery().WithArgs(sourceID, destinationID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "balance"}).AddRow(sourceID, "100").AddRow(destinationID, "100"))
+}
+
+// fixedDecision returns a domain.TransferDecision setting the source and destination accounts to the given balances
+func fixedDecision(sourceBalance float64, destinationBalance float64) domain.TransferDecision {
+	return func(source domain.Account, destination domain.Account) (float64, float64, error) {
+		return sourceBalance, destinationBalance, nil
+	}
+}
+
+func TestProcessTransaction(t *testing.T) {
+	insufficientFunds := domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount)
+	tests := []struct {
+		name       string
+		lockedRows *sqlmock.Rows
+		decideErr  error
+		doMockDB   func(mock sqlmock.Sqlmock, update *sqlmock.ExpectedPrepare, audit expectedAudit)
+		wantID     int64
+		wantErr    error
+	}{
+		{
+			name:       "Test Case Positive",
+			lockedRows: sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100").AddRow("456", "50"),
+			doMockDB: func(mock sqlmock.Sqlmock, update *sqlmock.ExpectedPrepare, audit expectedAudit) {
+				update.ExpectExec().WithArgs(90.0, "123").WillReturnResult(sqlmock.NewResult(0, 1))
+				update.ExpectExec().WithArgs(60.0, "456").WillReturnResult(sqlmock.NewResult(0, 1))
+				expectTransferCompletes(mock)
+				audit.expectAppend("", domain.AuditActionTransfer, domain.AuditResourceTransaction, "7", nil)
+				mock.ExpectCommit()
+			},
+			wantID: 7,
+		},
+		{
+			name:       "Test Case Negative - Decision rejects transfer",
+			lockedRows: sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100").AddRow("456", "50"),
+			decideErr:  insufficientFunds,
+			doMockDB: func(mock sqlmock.Sqlmock, update *sqlmock.ExpectedPrepare, audit expectedAudit) {
+				mock.ExpectRollback()
+				mock.ExpectPrepare(`UPDATE "public"."transaction"`).ExpectExec().WithArgs(static.ErrTransferAmountLargerThanAccount, int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: insufficientFunds,
+		},
+		{
+			name:       "Test Case Negative - Destination account missing",
+			lockedRows: sqlmock.NewRows([]string{"id", "balance"}).AddRow("123", "100"),
+			doMockDB: func(mock sqlmock.Sqlmock, update *sqlmock.ExpectedPrepare, audit expectedAudit) {
+				mock.ExpectRollback()
+				mock.ExpectPrepare(`UPDATE "public"."transaction"`).ExpectExec().WithArgs(sqlmock.AnyArg(), int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantErr: sql.ErrNoRows,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			lock, update, audit := expectTransferBegins(mock)
+			lock.ExpectQuery().WithArgs("123", "456").WillReturnRows(tc.lockedRows)
+			tc.doMockDB(mock, update, audit)
+
+			var decided []domain.Account
+			id, err := newTestTransactionPort(t, db).ProcessTransaction(
+				context.Background(),
+				domain.Transaction{SourceID: "123", DestinationID: "456", Amount: "10"},
+				func(source domain.Account, destination domain.Account) (float64, float64, error) {
+					decided = []domain.Account{source, destination}
+					return 90, 60, tc.decideErr
+				},
+			)
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantID, id)
+				assert.Equal(t, []domain.Account{{ID: "123", Balance: "100"}, {ID: "456", Balance: "50"}}, decided)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestProcessTransactionCancelled(t *testing.T) {
+	db, mock := newMockDB(t)
+	// The rollback triggered by the cancelled context happens in the background, so it is not ordered with the error message update
+	mock.MatchExpectationsInOrder(false)
+	lock, update, _ := expectTransferBegins(mock)
+	expectLockAccounts(lock, "123", "456")
+	update.ExpectExec().WithArgs(90.0, "123").WillDelayFor(time.Second).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectRollback()
+	mock.ExpectPrepare(`UPDATE "public"."transaction"`).ExpectExec().WithArgs(sqlmock.AnyArg(), int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err := newTestTransactionPort(t, db).ProcessTransaction(
+		ctx,
+		domain.Transaction{SourceID: "123", DestinationID: "456", Amount: "10"},
+		fixedDecision(90, 110),
+	)
+
+	assert.Error(t, err)
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, 10*time.Millisecond)
+}
+
+func TestProcessTransactionDuplicateReference(t *testing.T) {
+	db, mock := newMockDB(t)
+	mock.ExpectPrepare(`INSERT INTO "public"."transaction"`).ExpectQuery().
+		WithArgs("123", "456", "10", "March salary", "payroll-2024-03", `{"employee":42}`, "ip:192.0.2.1").
+		WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: clientReferenceIndex})
+
+	_, err := newTestTransactionPort(t, db).ProcessTransaction(
+		context.Background(),
+		domain.Transaction{SourceID: "123", DestinationID: "456", Amount: "10", Description: "March salary", Reference: "payroll-2024-03", Metadata: json.RawMessage(`{"employee":42}`), ClientID: "ip:192.0.2.1"},
+		fixedDecision(90, 60),
+	)
+
+	assert.ErrorIs(t, err, domain.ErrReferenceExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListTransactionsDetails(t *testing.T) {
+	db, mock := newMockDB(t)
+	createdAt := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	mock.ExpectPrepare(`SELECT (.+) description, COALESCE\(reference, ''\) AS reference, metadata, (.+) FROM "public"."transaction"`).ExpectQuery().WithArgs("123", int64(0), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_account_id", "destination_account_id", "amount", "description", "reference", "metadata", "created_at", "status"}).
+			AddRow(2, "123", "456", "10", "March salary", "payroll-2024-03", []byte(`{"employee": 42}`), createdAt, domain.TransactionStatusCompleted).
+			AddRow(1, "456", "123", "5", "", "", nil, createdAt, domain.TransactionStatusFailed))
+
+	transactions, err := newTestTransactionPort(t, db).ListTransactions(context.Background(), "123", 0, 3)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Transaction{
+		{ID: 2, SourceID: "123", DestinationID: "456", Amount: "10", Description: "March salary", Reference: "payroll-2024-03", Metadata: json.RawMessage(`{"employee": 42}`), Status: domain.TransactionStatusCompleted, CreatedAt: createdAt},
+		{ID: 1, SourceID: "456", DestinationID: "123", Amount: "5", Status: domain.TransactionStatusFailed, CreatedAt: createdAt},
+	}, transactions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStreamTransactions(t *testing.T) {
+	db, mock := newMockDB(t)
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	completedAt := createdAt.Add(time.Second)
+	completedAfterFrom := from.Add(time.Second)
+	// The first transfer was requested before from and completed after it
+	mock.ExpectPrepare(`SELECT (.+) created_at, completed_at FROM "public"."transaction" (.+) AND status = 'completed' AND completed_at > (.+) ORDER BY completed_at, id`).ExpectQuery().WithArgs("123", from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_account_id", "destination_account_id", "amount", "description", "reference", "created_at", "completed_at"}).
+			AddRow(1, "456", "123", "5", "", "", from.Add(-time.Second), from.Add(time.Second)).
+			AddRow(2, "123", "456", "10", "March salary", "payroll-2024-03", createdAt, completedAt).
+			AddRow(3, "123", "789", "1", "", "", createdAt, completedAt))
+
+	var streamed []domain.Transaction
+	stop := errors.New("stop")
+	err := newTestTransactionPort(t, db).StreamTransactions(context.Background(), "123", from, to, func(transaction domain.Transaction) error {
+		streamed = append(streamed, transaction)
+		if len(streamed) == 2 {
+			return stop
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []domain.Transaction{
+		{ID: 1, SourceID: "456", DestinationID: "123", Amount: "5", CreatedAt: from.Add(-time.Second), CompletedAt: &completedAfterFrom},
+		{ID: 2, SourceID: "123", DestinationID: "456", Amount: "10", Description: "March salary", Reference: "payroll-2024-03", CreatedAt: createdAt, CompletedAt: &completedAt},
+	}, streamed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
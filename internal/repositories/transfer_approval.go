@@ -0,0 +1,365 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/postgres"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// pendingReferenceIndex holds the references of the transfers of each client pending approval unique, see postgres/migrations.go
+const pendingReferenceIndex = "transfer_approval_pending_reference_idx"
+
+type TransferApprovalPortImpl struct {
+	db        *sqlx.DB
+	transfers *TransactionPortImpl
+	queries   transferApprovalQueries
+	stmts     *statements
+}
+
+type transferApprovalQueries struct {
+	insert string
+	expire string
+	get    string
+	lock   string
+	review string
+	audit  auditQueries
+}
+
+// transferApprovalColumns are the columns of the transfer_approval table read into a transferApprovalRow
+// A pending approval past its expiry is read as expired, so that expiry is decided by the clock of the database alone, see expire
+var transferApprovalColumns = fmt.Sprintf(`
+	id, source_account_id, destination_account_id, amount, description, COALESCE(reference, '') AS reference, metadata,
+	client_id, CASE WHEN status = '%s' AND expires_at <= NOW() THEN '%s' ELSE status END AS status,
+	initiated_by, reviewed_by, COALESCE(transaction_id, 0) AS transaction_id, created_at, expires_at, reviewed_at`,
+	domain.TransferApprovalStatusPending, domain.TransferApprovalStatusExpired,
+)
+
+// transferApprovalRow is a row of the transfer_approval table, with the nullable JSONB metadata column in its database type
+type transferApprovalRow struct {
+	domain.TransferApproval
+	Metadata []byte `db:"metadata"`
+}
+
+// toTransferApproval will return the domain.TransferApproval of the row, with nil metadata when the transfer has none
+func (r transferApprovalRow) toTransferApproval() domain.TransferApproval {
+	approval := r.TransferApproval
+	if len(r.Metadata) > 0 {
+		approval.Metadata = r.Metadata
+	}
+	return approval
+}
+
+// NewTransferApprovalPort returns a TransferApprovalRepository writing transfers held for approval to db, the primary, and executing approved transfers through transfers
+// The function will return an error object if the schema in dbConfig is not a valid identifier
+func NewTransferApprovalPort(db *sqlx.DB, transfers *TransactionPortImpl, dbConfig *postgres.DBConfig) (*TransferApprovalPortImpl, error) {
+	t, err := newTables(dbConfig.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &TransferApprovalPortImpl{
+		db:        db,
+		transfers: transfers,
+		queries: transferApprovalQueries{
+			insert: fmt.Sprintf(`
+				INSERT INTO %s(
+					source_account_id, destination_account_id, amount, description, reference, metadata, client_id, status, initiated_by, expires_at
+				)
+				VALUES (
+					$1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, NOW() + make_interval(secs => $10)
+				) RETURNING id, created_at, expires_at`,
+				t.transferApproval,
+			),
+			expire: fmt.Sprintf(`
+				UPDATE %s SET
+					status = '%s',
+					updated_at = NOW()
+				WHERE status = '%s' AND expires_at <= NOW()`,
+				t.transferApproval,
+				domain.TransferApprovalStatusExpired, domain.TransferApprovalStatusPending,
+			),
+			get: fmt.Sprintf(`
+				SELECT
+					%s
+				FROM %s
+				WHERE id = $1`,
+				transferApprovalColumns, t.transferApproval,
+			),
+			lock: fmt.Sprintf(`
+				SELECT
+					%s
+				FROM %s
+				WHERE id = $1
+				FOR UPDATE`,
+				transferApprovalColumns, t.transferApproval,
+			),
+			review: fmt.Sprintf(`
+				UPDATE %s SET
+					status = $2,
+					reviewed_by = $3,
+					transaction_id = NULLIF($4, 0),
+					reviewed_at = NOW(),
+					updated_at = NOW()
+				WHERE id = $1
+				RETURNING reviewed_at`,
+				t.transferApproval,
+			),
+			audit: newAuditQueries(t),
+		},
+		stmts: newStatements(),
+	}, nil
+}
+
+// InsertTransferApproval will accept a domain.TransferApproval to create a new row in the transfer_approval table, expiring after expiry by the clock of the database
+// Transfers whose approval has expired are marked expired first, so that their references can be used again, see expire
+// An empty reference is stored as NULL, so that only transfers with a reference are held unique per client by transfer_approval_pending_reference_idx while they are pending approval
+// The request is recorded in the audit log in the same DB transaction, with the domain.Principal carried by ctx
+// The function will return the approval with its id, creation time and expiry, domain.ErrReferenceExists if the reference is already used by a transfer of the client pending approval and an error object if there is error
+func (i *TransferApprovalPortImpl) InsertTransferApproval(ctx context.Context, approval domain.TransferApproval, expiry time.Duration) (_ domain.TransferApproval, err error) {
+	ctx, span := startSpan(ctx, "TransferApprovalRepository.InsertTransferApproval", i.queries.insert)
+	defer func() {
+		endSpan(span, -1, err)
+	}()
+
+	err = i.expire(ctx)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	insertStmt, err := i.stmts.prepare(ctx, i.db, i.queries.insert)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	var metadata any
+	if approval.Metadata != nil {
+		metadata = string(approval.Metadata)
+	}
+	err = tx.StmtxContext(ctx, insertStmt).QueryRowxContext(
+		ctx,
+		approval.SourceID,
+		approval.DestinationID,
+		approval.Amount,
+		approval.Description,
+		approval.Reference,
+		metadata,
+		approval.ClientID,
+		approval.Status,
+		approval.InitiatedBy,
+		expiry.Seconds(),
+	).Scan(&approval.ID, &approval.CreatedAt, &approval.ExpiresAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == pendingReferenceIndex {
+		return domain.TransferApproval{}, fmt.Errorf("%w: %s", domain.ErrReferenceExists, approval.Reference)
+	}
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+
+	entry, err := newAuditEntry(ctx, domain.AuditActionTransferRequest, domain.AuditResourceApproval, strconv.FormatInt(approval.ID, 10), nil, approval)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	return approval, nil
+}
+
+// ReviewTransferApproval will accept the id of a transfer approval, a domain.TransferApprovalReview deciding its outcome and the domain.TransferDecision of the transfer once approved
+// Transfers whose approval has expired are marked expired first, so that review is passed an expired approval as such, see expire
+// review is first passed the approval as read, so that a review failing its checks has no effect, and then passed the approval again once it is locked, so that a transfer is never reviewed twice
+// A rejected transfer is recorded as rejected with its review in the audit log in a single DB transaction
+// An approved transfer is executed through TransactionPortImpl, recording the approval as completed with the id of the transaction and its review in the audit log in the DB transaction of the transfer, so that an approval is never left approved without its transfer
+// When the transfer fails, the approval is recorded as failed with its review in a DB transaction of its own, detached from the cancellation of ctx as for a failed transaction, and is left pending approval if that fails too
+// The function will return the reviewed approval, sql.ErrNoRows if there is no such approval, the error returned by review, the error the transfer failed with and an error object if there is error
+func (i *TransferApprovalPortImpl) ReviewTransferApproval(ctx context.Context, id int64, review domain.TransferApprovalReview, decide domain.TransferDecision) (_ domain.TransferApproval, err error) {
+	ctx, span := startSpan(ctx, "TransferApprovalRepository.ReviewTransferApproval", i.queries.review)
+	defer func() {
+		endSpan(span, -1, err)
+	}()
+
+	err = i.expire(ctx)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	var row transferApprovalRow
+	err = i.stmts.getContext(ctx, i.db, &row, i.queries.get, id)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	reviewed, err := review(row.toTransferApproval())
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	if reviewed.Status != domain.TransferApprovalStatusApproved {
+		return i.record(ctx, id, review, "")
+	}
+
+	lockStmt, err := i.stmts.prepare(ctx, i.db, i.queries.lock)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	reviewStmt, err := i.stmts.prepare(ctx, i.db, i.queries.review)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	// approved is the approval once locked and approved in the DB transaction of the transfer, and completed the approval recorded as completed
+	var locked, approved, completed domain.TransferApproval
+	_, err = i.transfers.process(ctx, row.toTransferApproval().Transaction(), decide, settlement{
+		lock: func(ctx context.Context, tx *sqlx.Tx) error {
+			var lockErr error
+			locked, approved, lockErr = i.lock(ctx, tx, lockStmt, id, review)
+			if lockErr == nil && approved.Status != domain.TransferApprovalStatusApproved {
+				lockErr = fmt.Errorf("transfer approval %d no longer approved: %s", id, approved.Status)
+			}
+			return lockErr
+		},
+		complete: func(ctx context.Context, tx *sqlx.Tx, transactionID int64) error {
+			var completeErr error
+			completed = approved
+			completed.Status = domain.TransferApprovalStatusCompleted
+			completed.TransactionID = transactionID
+			completed, completeErr = i.update(ctx, tx, reviewStmt, audit, locked, completed)
+			return completeErr
+		},
+	})
+	// A transfer failing once approved, or whose reference is already used, can never complete, so its approval is failed rather than left pending
+	if err != nil && (approved.Status == domain.TransferApprovalStatusApproved || errors.Is(err, domain.ErrReferenceExists)) {
+		if _, failErr := i.record(ctx, id, review, domain.TransferApprovalStatusFailed); failErr != nil {
+			slog.ErrorContext(ctx, "transfer approval failure not recorded, left pending approval", "approval_id", id, "error", failErr)
+		}
+	}
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	return completed, nil
+}
+
+// record will lock the approval id, pass it to review and record it as reviewed, with status rather than the status returned by review when set, and its review in the audit log in a single DB transaction
+// A failed transfer is recorded detached from the cancellation of ctx, as for a failed transaction, so that its outcome is recorded even when the request is cancelled
+// The function will return the reviewed approval, the error returned by review and an error object if there is error
+func (i *TransferApprovalPortImpl) record(ctx context.Context, id int64, review domain.TransferApprovalReview, status string) (domain.TransferApproval, error) {
+	if status == domain.TransferApprovalStatusFailed {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), errorMessageUpdateTimeout)
+		defer cancel()
+	}
+	lockStmt, err := i.stmts.prepare(ctx, i.db, i.queries.lock)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	reviewStmt, err := i.stmts.prepare(ctx, i.db, i.queries.review)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	audit, err := i.stmts.prepareAudit(ctx, i.db, i.queries.audit)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	tx, err := i.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	locked, reviewed, err := i.lock(ctx, tx, lockStmt, id, review)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	if status != "" {
+		reviewed.Status = status
+	}
+	reviewed, err = i.update(ctx, tx, reviewStmt, audit, locked, reviewed)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	return reviewed, nil
+}
+
+// lock will accept the lock statement and a DB transaction to lock the approval id for the rest of the DB transaction and pass it to review
+// The function will return the approval as locked and as reviewed, sql.ErrNoRows if there is no such approval, the error returned by review and an error object if there is error
+func (i *TransferApprovalPortImpl) lock(ctx context.Context, tx *sqlx.Tx, lockStmt *sqlx.Stmt, id int64, review domain.TransferApprovalReview) (domain.TransferApproval, domain.TransferApproval, error) {
+	var row transferApprovalRow
+	err := tx.StmtxContext(ctx, lockStmt).GetContext(ctx, &row, id)
+	if err != nil {
+		return domain.TransferApproval{}, domain.TransferApproval{}, err
+	}
+	locked := row.toTransferApproval()
+	reviewed, err := review(locked)
+	if err != nil {
+		return domain.TransferApproval{}, domain.TransferApproval{}, err
+	}
+	return locked, reviewed, nil
+}
+
+// update will accept the review statement, the audit log and a DB transaction to update a locked approval with the status, reviewer and transaction of reviewed and record the review in the audit log
+// The function will return the reviewed approval with its review time and an error object if there is error
+func (i *TransferApprovalPortImpl) update(ctx context.Context, tx *sqlx.Tx, reviewStmt *sqlx.Stmt, audit *auditAppend, locked domain.TransferApproval, reviewed domain.TransferApproval) (domain.TransferApproval, error) {
+	err := tx.StmtxContext(ctx, reviewStmt).QueryRowxContext(ctx, locked.ID, reviewed.Status, reviewed.ReviewedBy, reviewed.TransactionID).Scan(&reviewed.ReviewedAt)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	entry, err := newAuditEntry(ctx, domain.AuditActionTransferReview, domain.AuditResourceApproval, strconv.FormatInt(locked.ID, 10), locked, reviewed)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	err = audit.append(ctx, tx, entry)
+	if err != nil {
+		return domain.TransferApproval{}, err
+	}
+	return reviewed, nil
+}
+
+// expire will mark every transfer pending approval past its expiry as expired
+// Expiry is not recorded in the audit log, it follows from the expiry recorded with the request
+// The function will return nil if there is no error and an error object if there is error
+func (i *TransferApprovalPortImpl) expire(ctx context.Context) error {
+	stmt, err := i.stmts.prepare(ctx, i.db, i.queries.expire)
+	if err != nil {
+		return err
+	}
+	_, err = tracedExec(
+		ctx,
+		stmt,
+		"TransferApprovalRepository.expire",
+		i.queries.expire,
+	)
+	return err
+}
@@ -0,0 +1,220 @@
+package repositories
+
+import (
+	"account-test/internal/core/domain"
+	"account-test/static"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+var transferApprovalRowColumns = []string{
+	"id", "source_account_id", "destination_account_id", "amount", "description", "reference", "metadata",
+	"client_id", "status", "initiated_by", "reviewed_by", "transaction_id", "created_at", "expires_at", "reviewed_at",
+}
+
+func newTestTransferApprovalPort(t *testing.T, db *sqlx.DB) *TransferApprovalPortImpl {
+	port, err := NewTransferApprovalPort(db, newTestTransactionPort(t, db), testDBConfig)
+	assert.NoError(t, err)
+	return port
+}
+
+// expectExpire expects the transfers pending approval past their expiry to be marked expired
+func expectExpire(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare(`UPDATE "public"."transfer_approval" SET status = 'expired', updated_at = NOW\(\) WHERE status = 'pending_approval' AND expires_at <= NOW\(\)`).
+		ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestInsertTransferApproval(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(24 * time.Hour)
+	approval := domain.TransferApproval{
+		SourceID:      "123",
+		DestinationID: "1234",
+		Amount:        "5000",
+		Reference:     "payroll-2024-03",
+		Metadata:      []byte(`{"batch":"march"}`),
+		ClientID:      "key:abc",
+		Status:        domain.TransferApprovalStatusPending,
+		InitiatedBy:   "key:abc",
+	}
+
+	t.Run("Test Case Positive", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectExpire(mock)
+		insert := mock.ExpectPrepare(`INSERT INTO "public"."transfer_approval"(.+)NOW\(\) \+ make_interval\(secs => \$10\)`)
+		audit := expectAuditPrepare(mock)
+		mock.ExpectBegin()
+		insert.ExpectQuery().WithArgs("123", "1234", "5000", "", "payroll-2024-03", `{"batch":"march"}`, "key:abc", domain.TransferApprovalStatusPending, "key:abc", float64(86400)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "expires_at"}).AddRow(7, createdAt, expiresAt))
+		var args []driver.Value
+		audit.expectAppend("", domain.AuditActionTransferRequest, domain.AuditResourceApproval, "7", &args)
+		mock.ExpectCommit()
+
+		inserted, err := newTestTransferApprovalPort(t, db).InsertTransferApproval(context.Background(), approval, 24*time.Hour)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, int64(7), inserted.ID)
+		assert.Equal(t, createdAt, inserted.CreatedAt)
+		assert.Equal(t, expiresAt, inserted.ExpiresAt)
+		entry := auditEntryOf(args)
+		assert.Nil(t, entry.Before)
+		assert.JSONEq(t, `{"approval_id":7,"source_account_id":"123","destination_account_id":"1234","amount":"5000","reference":"payroll-2024-03","metadata":{"batch":"march"},"status":"pending_approval","initiated_by":"key:abc","created_at":"2024-03-01T12:00:00Z","expires_at":"2024-03-02T12:00:00Z"}`, string(entry.After))
+	})
+
+	t.Run("Test Case Negative - Duplicate pending reference", func(t *testing.T) {
+		db, mock := newMockDB(t)
+		expectExpire(mock)
+		insert := mock.ExpectPrepare(`INSERT INTO "public"."transfer_approval"`)
+		expectAuditPrepare(mock)
+		mock.ExpectBegin()
+		insert.ExpectQuery().WillReturnError(&pq.Error{Code: uniqueViolation, Constraint: pendingReferenceIndex})
+		mock.ExpectRollback()
+
+		_, err := newTestTransferApprovalPort(t, db).InsertTransferApproval(context.Background(), approval, 24*time.Hour)
+
+		assert.ErrorIs(t, err, domain.ErrReferenceExists)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReviewTransferApproval(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := createdAt.Add(24 * time.Hour)
+	reviewedAt := time.Date(2024, 3, 1, 13, 0, 0, 0, time.UTC)
+	errReview := errors.New("review error")
+	insufficientFunds := domain.NewError(domain.ErrCodeInsufficientFunds, static.ErrTransferAmountLargerThanAccount)
+	pendingRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(transferApprovalRowColumns).
+			AddRow(7, "123", "1234", "5000", "", "payroll-2024-03", []byte(`{"batch":"march"}`), "key:abc", "pending_approval", "key:abc", "", 0, createdAt, expiresAt, nil)
+	}
+	reviewWith := func(status string) domain.TransferApprovalReview {
+		return func(approval domain.TransferApproval) (domain.TransferApproval, error) {
+			approval.Status = status
+			approval.ReviewedBy = "key:def"
+			return approval, nil
+		}
+	}
+	reviewed := func(status string, transactionID int64) domain.TransferApproval {
+		return domain.TransferApproval{
+			ID: 7, SourceID: "123", DestinationID: "1234", Amount: "5000", Reference: "payroll-2024-03", Metadata: []byte(`{"batch":"march"}`), ClientID: "key:abc",
+			Status: status, InitiatedBy: "key:abc", ReviewedBy: "key:def", TransactionID: transactionID, CreatedAt: createdAt, ExpiresAt: expiresAt, ReviewedAt: &reviewedAt,
+		}
+	}
+	// expectApprovalPrepare expects the statements reviewing an approval to be prepared
+	expectApprovalPrepare := func(mock sqlmock.Sqlmock) (*sqlmock.ExpectedPrepare, *sqlmock.ExpectedPrepare, expectedAudit) {
+		lock := mock.ExpectPrepare(`SELECT (.+) FROM "public"."transfer_approval" WHERE id = \$1 FOR UPDATE`)
+		review := mock.ExpectPrepare(`UPDATE "public"."transfer_approval" SET status = \$2, reviewed_by = \$3, transaction_id = NULLIF\(\$4, 0\)`)
+		return lock, review, expectAuditPrepare(mock)
+	}
+	// expectRecord expects the approval to be locked and recorded with status and its review in a DB transaction of its own
+	expectRecord := func(mock sqlmock.Sqlmock, lock *sqlmock.ExpectedPrepare, review *sqlmock.ExpectedPrepare, audit expectedAudit, status string) {
+		mock.ExpectBegin()
+		lock.ExpectQuery().WithArgs(int64(7)).WillReturnRows(pendingRows())
+		review.ExpectQuery().WithArgs(int64(7), status, "key:def", int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"reviewed_at"}).AddRow(reviewedAt))
+		audit.expectAppend("", domain.AuditActionTransferReview, domain.AuditResourceApproval, "7", nil)
+		mock.ExpectCommit()
+	}
+	tests := []struct {
+		name     string
+		getRows  *sqlmock.Rows
+		review   domain.TransferApprovalReview
+		decide   domain.TransferDecision
+		doMockDB func(mock sqlmock.Sqlmock)
+		want     domain.TransferApproval
+		wantErr  error
+	}{
+		{
+			name:    "Test Case Positive - Rejected",
+			getRows: pendingRows(),
+			review:  reviewWith(domain.TransferApprovalStatusRejected),
+			doMockDB: func(mock sqlmock.Sqlmock) {
+				lock, review, audit := expectApprovalPrepare(mock)
+				expectRecord(mock, lock, review, audit, domain.TransferApprovalStatusRejected)
+			},
+			want: reviewed(domain.TransferApprovalStatusRejected, 0),
+		},
+		{
+			name:    "Test Case Positive - Approved transfer completed in its DB transaction",
+			getRows: pendingRows(),
+			review:  reviewWith(domain.TransferApprovalStatusApproved),
+			decide:  fixedDecision(90, 110),
+			doMockDB: func(mock sqlmock.Sqlmock) {
+				approvalLock, review, approvalAudit := expectApprovalPrepare(mock)
+				lock, update, transferAudit := expectTransferBegins(mock)
+				approvalLock.ExpectQuery().WithArgs(int64(7)).WillReturnRows(pendingRows())
+				expectLockAccounts(lock, "123", "1234")
+				update.ExpectExec().WithArgs(90.0, "123").WillReturnResult(sqlmock.NewResult(0, 1))
+				update.ExpectExec().WithArgs(110.0, "1234").WillReturnResult(sqlmock.NewResult(0, 1))
+				expectTransferCompletes(mock)
+				review.ExpectQuery().WithArgs(int64(7), domain.TransferApprovalStatusCompleted, "key:def", int64(7)).
+					WillReturnRows(sqlmock.NewRows([]string{"reviewed_at"}).AddRow(reviewedAt))
+				approvalAudit.expectAppend("", domain.AuditActionTransferReview, domain.AuditResourceApproval, "7", nil)
+				transferAudit.expectAppend("", domain.AuditActionTransfer, domain.AuditResourceTransaction, "7", nil)
+				mock.ExpectCommit()
+			},
+			want: reviewed(domain.TransferApprovalStatusCompleted, 7),
+		},
+		{
+			name:    "Test Case Negative - Approved transfer fails and approval is failed",
+			getRows: pendingRows(),
+			review:  reviewWith(domain.TransferApprovalStatusApproved),
+			decide: func(source domain.Account, destination domain.Account) (float64, float64, error) {
+				return 0, 0, insufficientFunds
+			},
+			doMockDB: func(mock sqlmock.Sqlmock) {
+				approvalLock, review, audit := expectApprovalPrepare(mock)
+				lock, _, _ := expectTransferBegins(mock)
+				approvalLock.ExpectQuery().WithArgs(int64(7)).WillReturnRows(pendingRows())
+				expectLockAccounts(lock, "123", "1234")
+				mock.ExpectRollback()
+				mock.ExpectPrepare(`UPDATE "public"."transaction" SET status = 'failed'`).ExpectExec().
+					WithArgs(static.ErrTransferAmountLargerThanAccount, int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectRecord(mock, approvalLock, review, audit, domain.TransferApprovalStatusFailed)
+			},
+			wantErr: insufficientFunds,
+		},
+		{
+			name:    "Test Case Negative - Approval does not exist",
+			getRows: sqlmock.NewRows(transferApprovalRowColumns),
+			review:  reviewWith(domain.TransferApprovalStatusApproved),
+			doMockDB: func(mock sqlmock.Sqlmock) {
+			},
+			wantErr: sql.ErrNoRows,
+		},
+		{
+			name:    "Test Case Negative - Review error has no effect",
+			getRows: pendingRows(),
+			review: func(approval domain.TransferApproval) (domain.TransferApproval, error) {
+				return domain.TransferApproval{}, errReview
+			},
+			doMockDB: func(mock sqlmock.Sqlmock) {
+			},
+			wantErr: errReview,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectExpire(mock)
+			mock.ExpectPrepare(`SELECT (.+) CASE WHEN status = 'pending_approval' AND expires_at <= NOW\(\) THEN 'expired' ELSE status END AS status(.+) FROM "public"."transfer_approval" WHERE id = \$1$`).ExpectQuery().WithArgs(int64(7)).WillReturnRows(tc.getRows)
+			tc.doMockDB(mock)
+
+			approval, err := newTestTransferApprovalPort(t, db).ReviewTransferApproval(context.Background(), 7, tc.review, tc.decide)
+
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.Equal(t, tc.want, approval)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
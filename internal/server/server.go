@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+type ServerConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration
+	DrainDelay        time.Duration
+}
+
+// ShutdownFunc stops a dependency of the server, such as a background worker or a connection pool, within the deadline of ctx
+type ShutdownFunc func(ctx context.Context) error
+
+type shutdownHook struct {
+	name string
+	fn   ShutdownFunc
+}
+
+type Server struct {
+	http            *http.Server
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	drainHooks      []func()
+	hooks           []shutdownHook
+}
+
+// New returns a Server serving handler on addr with the timeouts and header size limit of config
+func New(addr string, handler http.Handler, config *ServerConfig) *Server {
+	return &Server{
+		http: &http.Server{
+			Addr:              addr,
+			Handler:           handler,
+			ReadTimeout:       config.ReadTimeout,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+			MaxHeaderBytes:    config.MaxHeaderBytes,
+		},
+		shutdownTimeout: config.ShutdownTimeout,
+		drainDelay:      config.DrainDelay,
+	}
+}
+
+// OnDrain registers fn to be called as soon as shutdown starts, before the server stops accepting connections
+// It is used to report the app as not ready so that load balancers stop routing new requests during the DrainDelay
+func (s *Server) OnDrain(fn func()) {
+	s.drainHooks = append(s.drainHooks, fn)
+}
+
+// OnShutdown registers fn to be called once in-flight requests have drained during shutdown
+// Hooks are called in the order they are registered and share the remaining shutdown deadline
+func (s *Server) OnShutdown(name string, fn ShutdownFunc) {
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+}
+
+// ListenAndServe will listen on the address of the server and call Serve
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.http.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve will accept connections on listener until ctx is done, then shut down the server
+// Shutting down calls the drain hooks, waits for DrainDelay, stops accepting new connections, waits up to ShutdownTimeout for in-flight requests to complete and then calls the shutdown hooks
+// The function will return once shutdown has completed, with an error if the server failed or did not shut down cleanly
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.http.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info("shutting down, draining in-flight requests", "timeout", s.shutdownTimeout.String(), "drain_delay", s.drainDelay.String())
+	for _, fn := range s.drainHooks {
+		fn()
+	}
+	time.Sleep(s.drainDelay)
+
+	shutdownCtx := context.Background()
+	if s.shutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		shutdownCtx, cancel = context.WithTimeout(shutdownCtx, s.shutdownTimeout)
+		defer cancel()
+	}
+	var errs []error
+	if err := s.http.Shutdown(shutdownCtx); err != nil {
+		slog.Error("HTTP server did not drain in time", "error", err)
+		errs = append(errs, err)
+	}
+	for _, hook := range s.hooks {
+		if err := hook.fn(shutdownCtx); err != nil {
+			slog.Error("shutdown hook failed", "hook", hook.name, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+	slog.Info("shutdown complete")
+	return errors.Join(errs...)
+}
+
+// StopGRPC returns a ShutdownFunc gracefully stopping server, which stops accepting new RPCs and waits for in-flight RPCs to complete
+// The server is stopped forcefully if in-flight RPCs have not completed by the deadline of ctx
+func StopGRPC(server *grpc.Server) ShutdownFunc {
+	return func(ctx context.Context) error {
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			return ctx.Err()
+		}
+	}
+}
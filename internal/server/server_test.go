@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	url := "http://" + listener.Addr().String()
+
+	srv := New(listener.Addr().String(), handler, &ServerConfig{ShutdownTimeout: 5 * time.Second})
+	var hooks []string
+	srv.OnDrain(func() {
+		hooks = append(hooks, "drain")
+	})
+	srv.OnShutdown("first", func(ctx context.Context) error {
+		hooks = append(hooks, "first")
+		return nil
+	})
+	srv.OnShutdown("second", func(ctx context.Context) error {
+		hooks = append(hooks, "second")
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ctx, listener)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{body: string(body), err: err}
+	}()
+	<-started
+
+	cancel()
+	// New connections are refused once shutdown has started
+	assert.Eventually(t, func() bool {
+		_, err := net.Dial("tcp", listener.Addr().String())
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case <-served:
+		t.Fatal("Serve returned before the in-flight request completed")
+	default:
+	}
+	assert.Equal(t, []string{"drain"}, hooks)
+
+	close(release)
+	response := <-inFlight
+	assert.NoError(t, response.err)
+	assert.Equal(t, "done", response.body)
+	assert.NoError(t, <-served)
+	assert.Equal(t, []string{"drain", "first", "second"}, hooks)
+}
+
+func TestServeShutdownTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	srv := New(listener.Addr().String(), handler, &ServerConfig{ShutdownTimeout: 50 * time.Millisecond})
+	hookCalled := false
+	srv.OnShutdown("db", func(ctx context.Context) error {
+		hookCalled = true
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- srv.Serve(ctx, listener)
+	}()
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	cancel()
+	assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+	assert.True(t, hookCalled)
+}
@@ -0,0 +1,98 @@
+package snapshot
+
+import (
+	"account-test/internal/core/ports"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type SnapshotConfig struct {
+	// Interval is how often balances are snapshotted, snapshots are taken at multiples of Interval, 0 disables snapshots
+	Interval time.Duration
+	// Delay is how old a snapshot time must be before it is taken, longer than a transfer can be in flight
+	Delay time.Duration
+}
+
+// Scheduler snapshots the balances of the accounts in the background so that historical balances are computed from a recent snapshot, see AccountRepository.GetBalanceAsOf
+type Scheduler struct {
+	snapshotter ports.BalanceSnapshotter
+	config      *SnapshotConfig
+	now         func() time.Time
+
+	last     time.Time
+	started  bool
+	stop     context.CancelFunc
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+// New returns a Scheduler taking snapshots through snapshotter as configured in config
+func New(snapshotter ports.BalanceSnapshotter, config *SnapshotConfig) *Scheduler {
+	return &Scheduler{
+		snapshotter: snapshotter,
+		config:      config,
+		now:         time.Now,
+		stop:        func() {},
+		stopped:     make(chan struct{}),
+	}
+}
+
+// Start will take a snapshot every Interval in the background until Close is called
+// The function does nothing when Interval is 0
+func (s *Scheduler) Start() {
+	s.started = true
+	if s.config.Interval == 0 {
+		close(s.stopped)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	go func() {
+		defer close(s.stopped)
+		s.Run(ctx)
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Run(ctx)
+			}
+		}
+	}()
+}
+
+// Run will snapshot the balances at the latest multiple of Interval that is at least Delay old, unless it was already taken by this Scheduler
+// Instances snapshotting the same time store each snapshot once, see AccountRepository.SnapshotBalances
+// The function will return the time snapshotted, the zero time when there was nothing new to snapshot
+func (s *Scheduler) Run(ctx context.Context) time.Time {
+	asOf := s.now().Add(-s.config.Delay).Truncate(s.config.Interval)
+	if !asOf.After(s.last) {
+		return time.Time{}
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.config.Interval)
+	defer cancel()
+	if _, err := s.snapshotter.SnapshotBalances(ctx, asOf); err != nil {
+		slog.ErrorContext(ctx, "balance snapshot failed", "as_of", asOf, "error", err)
+		return time.Time{}
+	}
+	s.last = asOf
+	return asOf
+}
+
+// Close will stop taking snapshots, cancelling a snapshot being taken
+func (s *Scheduler) Close(ctx context.Context) error {
+	s.stopOnce.Do(s.stop)
+	if !s.started {
+		return nil
+	}
+	select {
+	case <-s.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
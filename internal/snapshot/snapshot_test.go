@@ -0,0 +1,44 @@
+package snapshot
+
+import (
+	mock_ports "account-test/internal/mocks/ports"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	snapshotter := mock_ports.NewMockBalanceSnapshotter(mockCtrl)
+	scheduler := New(snapshotter, &SnapshotConfig{Interval: time.Hour, Delay: 5 * time.Minute})
+	now := time.Date(2024, 3, 1, 12, 3, 0, 0, time.UTC)
+	scheduler.now = func() time.Time { return now }
+
+	// 12:03 less the delay is 11:58, snapshotted at the previous hour
+	snapshotter.EXPECT().SnapshotBalances(gomock.Any(), time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)).Return(int64(0), errors.New("random error"))
+	assert.True(t, scheduler.Run(context.Background()).IsZero())
+
+	// A failed snapshot is taken again on the next run
+	snapshotter.EXPECT().SnapshotBalances(gomock.Any(), time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC)).Return(int64(2), nil)
+	assert.Equal(t, time.Date(2024, 3, 1, 11, 0, 0, 0, time.UTC), scheduler.Run(context.Background()))
+
+	// 12:04 less the delay is still before 12:00, nothing new to snapshot
+	now = now.Add(time.Minute)
+	assert.True(t, scheduler.Run(context.Background()).IsZero())
+
+	now = time.Date(2024, 3, 1, 12, 5, 0, 0, time.UTC)
+	snapshotter.EXPECT().SnapshotBalances(gomock.Any(), time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)).Return(int64(1), nil)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), scheduler.Run(context.Background()))
+}
+
+func TestCloseWithoutStart(t *testing.T) {
+	scheduler := New(nil, &SnapshotConfig{})
+
+	assert.NoError(t, scheduler.Close(context.Background()))
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	_, err := Init(context.Background(), &TracingConfig{Exporter: ExporterNone})
+	assert.NoError(t, err)
+
+	r := chi.NewRouter()
+	r.Use(Middleware)
+	r.Get("/accounts/{account_id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest("GET", "/accounts/123", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, "GET /accounts/{account_id}", span.Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	assert.Contains(t, span.Attributes(), semconv.HTTPRoute("/accounts/{account_id}"))
+	assert.Contains(t, span.Attributes(), semconv.HTTPResponseStatusCode(500))
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
+
+func TestInit(t *testing.T) {
+	tests := []struct {
+		name     string
+		exporter string
+		err      bool
+	}{
+		{name: "Test Case Positive - Disabled", exporter: ExporterNone},
+		{name: "Test Case Positive - Stdout", exporter: ExporterStdout},
+		{name: "Test Case Negative - Unknown exporter", exporter: "zipkin", err: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := Init(context.Background(), &TracingConfig{Exporter: tc.exporter, ServiceName: "account-test"})
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
@@ -1,62 +1,230 @@
 package postgres
 
 import (
-	"fmt"
-	"log"
+	"context"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
 )
 
-// This is added here for ease of setup. In a normal dev setup, table creation should be done outside of code either through a pipeline or DB team
-var schema = `
-	CREATE TABLE IF NOT EXISTS %s.account(
-		id VARCHAR PRIMARY KEY NOT NULL,
-		balance float NOT NULL,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-	);
-
-	CREATE TABLE IF NOT EXISTS %s.transaction(
-		id SERIAL PRIMARY KEY NOT NULL,
-		source_account_id VARCHAR NOT NULL,
-		destination_account_id VARCHAR NOT NULL,
-		amount VARCHAR NOT NULL,
-		error_message VARCHAR,
-		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
-		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-	);
-`
-
 const DriverName = "postgres"
 
+const (
+	SSLModeDisable    = "disable"
+	SSLModeRequire    = "require"
+	SSLModeVerifyCA   = "verify-ca"
+	SSLModeVerifyFull = "verify-full"
+)
+
+// SSLModes are the sslmode values supported by the driver
+var SSLModes = []string{SSLModeDisable, SSLModeRequire, SSLModeVerifyCA, SSLModeVerifyFull}
+
 type DBConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
-	Name     string
-	Schema   string
+	URL             string
+	ReplicaURL      string
+	ReplicaMaxLag   time.Duration
+	Host            string
+	Port            int
+	Username        string
+	Password        string
+	Name            string
+	Schema          string
+	SSLMode         string
+	SSLRootCert     string
+	SSLCert         string
+	SSLKey          string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	ConnectTimeout  time.Duration
+}
+
+// Backoff between attempts to reach the database at startup, doubled after every failed attempt up to maxConnectBackoff
+var (
+	initialConnectBackoff = 250 * time.Millisecond
+	maxConnectBackoff     = 5 * time.Second
+)
+
+// DataSourceName returns the connection URL for dbConfig
+// URL is used when set, otherwise the URL is built from Host, Port, Username, Password and Name
+// The sslmode, sslrootcert, sslcert and sslkey parameters are added from dbConfig unless the URL already sets them
+func (dbConfig *DBConfig) DataSourceName() (string, error) {
+	if dbConfig.URL != "" {
+		return dbConfig.withSSLParams(dbConfig.URL)
+	}
+	dataSource := &url.URL{
+		Scheme: "postgres",
+		User:   url.User(dbConfig.Username),
+		Host:   net.JoinHostPort(dbConfig.Host, strconv.Itoa(dbConfig.Port)),
+		Path:   "/" + dbConfig.Name,
+	}
+	if dbConfig.Password != "" {
+		dataSource.User = url.UserPassword(dbConfig.Username, dbConfig.Password)
+	}
+	return dbConfig.withSSLParams(dataSource.String())
+}
+
+// withSSLParams will add the sslmode, sslrootcert, sslcert and sslkey parameters of dbConfig to rawURL unless it already sets them
+func (dbConfig *DBConfig) withSSLParams(rawURL string) (string, error) {
+	dataSource, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	query := dataSource.Query()
+	for param, value := range map[string]string{
+		"sslmode":     dbConfig.SSLMode,
+		"sslrootcert": dbConfig.SSLRootCert,
+		"sslcert":     dbConfig.SSLCert,
+		"sslkey":      dbConfig.SSLKey,
+	} {
+		if value != "" && !query.Has(param) {
+			query.Set(param, value)
+		}
+	}
+	dataSource.RawQuery = query.Encode()
+	return dataSource.String(), nil
+}
+
+// FillFromURL will set Host, Port, Username and Name from URL, when URL is set, so that they can be used in logs and metrics
+func (dbConfig *DBConfig) FillFromURL() {
+	if dbConfig.URL == "" {
+		return
+	}
+	dataSource, err := url.Parse(dbConfig.URL)
+	if err != nil {
+		return
+	}
+	dbConfig.Host = dataSource.Hostname()
+	if port, err := strconv.Atoi(dataSource.Port()); err == nil {
+		dbConfig.Port = port
+	}
+	dbConfig.Username = dataSource.User.Username()
+	dbConfig.Name = strings.TrimPrefix(dataSource.Path, "/")
 }
 
 // Init function takes in DB config object and returns a wrapper to sql/DB object.
+// The function will retry reaching the database with backoff for up to ConnectTimeout, for databases still starting up
 func Init(dbConfig *DBConfig) (*sqlx.DB, error) {
-	dataSource := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbConfig.Host, dbConfig.Port, dbConfig.Username, dbConfig.Password, dbConfig.Name)
+	dataSource, err := dbConfig.DataSourceName()
+	if err != nil {
+		slog.Error("invalid database URL", "error", err)
+		return nil, err
+	}
+	client, err := dbConfig.open(dataSource)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Migrate(context.Background(), client, dbConfig.Schema); err != nil {
+		slog.Error("failed to migrate database", "schema", dbConfig.Schema, "error", err)
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// InitReadOnly function takes in DB config object and returns a wrapper to sql/DB object for commands that only read the database, such as verifying the audit log
+// Unlike Init, the schema is not migrated and every session is read-only, so that the command never changes the database and can run as a role that cannot
+func InitReadOnly(dbConfig *DBConfig) (*sqlx.DB, error) {
+	dataSource, err := dbConfig.DataSourceName()
+	if err == nil {
+		dataSource, err = readOnly(dataSource)
+	}
+	if err != nil {
+		slog.Error("invalid database URL", "error", err)
+		return nil, err
+	}
+	return dbConfig.open(dataSource)
+}
+
+// readOnly will add the default_transaction_read_only run-time parameter to the dataSource URL, which the driver passes on to every session
+func readOnly(dataSource string) (string, error) {
+	dataSourceURL, err := url.Parse(dataSource)
+	if err != nil {
+		return "", err
+	}
+	query := dataSourceURL.Query()
+	query.Set("default_transaction_read_only", "on")
+	dataSourceURL.RawQuery = query.Encode()
+	return dataSourceURL.String(), nil
+}
+
+// open will open a pool to dataSource configured by dbConfig and wait for the database to be reachable, see connect
+func (dbConfig *DBConfig) open(dataSource string) (*sqlx.DB, error) {
 	client, err := sqlx.Open(DriverName, dataSource)
 	if err != nil {
-		log.Println("err - ", err.Error())
+		slog.Error("failed to open database", "host", dbConfig.Host, "name", dbConfig.Name, "error", err)
 		return nil, err
 	}
+	dbConfig.configurePool(client)
 
 	// verifies connection is db is working
-	if err := client.Ping(); err != nil {
-		log.Println("err 2 - ", err.Error())
+	if err := connect(context.Background(), client, dbConfig.ConnectTimeout); err != nil {
+		slog.Error("failed to ping database", "host", dbConfig.Host, "name", dbConfig.Name, "error", err)
+		client.Close()
 		return nil, err
 	}
+	return client, nil
+}
 
-	schema = fmt.Sprintf(schema, dbConfig.Schema, dbConfig.Schema)
-	client.MustExec(schema)
+// configurePool will apply the connection pool settings of dbConfig to client
+func (dbConfig *DBConfig) configurePool(client *sqlx.DB) {
+	client.SetMaxOpenConns(dbConfig.MaxOpenConns)
+	client.SetMaxIdleConns(dbConfig.MaxIdleConns)
+	client.SetConnMaxLifetime(dbConfig.ConnMaxLifetime)
+	client.SetConnMaxIdleTime(dbConfig.ConnMaxIdleTime)
+}
 
+// InitReplica function takes in DB config object and returns a wrapper to sql/DB object connected to the read replica at ReplicaURL, nil when ReplicaURL is not set
+// The replica is not required to be reachable at startup, reads fall back to the primary until it is, see ReadPool
+func InitReplica(dbConfig *DBConfig) (*sqlx.DB, error) {
+	if dbConfig.ReplicaURL == "" {
+		return nil, nil
+	}
+	dataSource, err := dbConfig.withSSLParams(dbConfig.ReplicaURL)
+	if err != nil {
+		slog.Error("invalid replica URL", "error", err)
+		return nil, err
+	}
+	client, err := sqlx.Open(DriverName, dataSource)
+	if err != nil {
+		slog.Error("failed to open replica", "error", err)
+		return nil, err
+	}
+	dbConfig.configurePool(client)
 	return client, nil
 }
+
+// connect will ping the database until it is reachable or timeout has passed, waiting with exponential backoff and jitter between attempts
+// The function will return the error of the last attempt when the database could not be reached in time
+func connect(ctx context.Context, client *sqlx.DB, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := initialConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := client.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		// Half of the backoff is randomised so that instances restarting together do not retry in step
+		wait := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		if time.Now().Add(wait).After(deadline) {
+			return err
+		}
+		slog.Warn("database not reachable, retrying", "attempt", attempt, "retry_in", wait.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
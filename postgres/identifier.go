@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// maxIdentifierLength is the length at which Postgres truncates identifiers
+const maxIdentifierLength = 63
+
+// identifierPattern matches lower case unquoted identifiers, which Postgres reads the same whether quoted or not
+// Upper case letters are rejected because quoting them would change the schema used by an existing DB_SCHEMA value, which Postgres folds to lower case when unquoted
+var identifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// ValidateIdentifier will check that name can be used as a schema or table name
+// This function will return nil if name is valid and an error object describing why it is not otherwise
+func ValidateIdentifier(name string) error {
+	if name == "" {
+		return errors.New("must be set")
+	}
+	if len(name) > maxIdentifierLength {
+		return errors.New("must be at most 63 characters")
+	}
+	if !identifierPattern.MatchString(name) {
+		return errors.New("must start with a lower case letter or underscore and contain only lower case letters, digits, underscores and dollar signs")
+	}
+	return nil
+}
+
+// QuoteIdentifier will validate name with ValidateIdentifier and quote it for use in SQL
+// This function will return the quoted identifier and an error object if name is not valid
+func QuoteIdentifier(name string) (string, error) {
+	if err := ValidateIdentifier(name); err != nil {
+		return "", err
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`, nil
+}
+
+// QualifiedName will return table in schema as a quoted, schema qualified name for use in SQL
+// This function will return an error object if either name is not valid
+func QualifiedName(schema string, table string) (string, error) {
+	quotedSchema, err := QuoteIdentifier(schema)
+	if err != nil {
+		return "", err
+	}
+	quotedTable, err := QuoteIdentifier(table)
+	if err != nil {
+		return "", err
+	}
+	return quotedSchema + "." + quotedTable, nil
+}
@@ -0,0 +1,55 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Test Case Positive", input: "public", want: `"public"`},
+		{name: "Test Case Positive - Underscore, digits and dollar sign", input: "_ledger_2$", want: `"_ledger_2$"`},
+		{name: "Test Case Positive - Maximum length", input: strings.Repeat("a", 63), want: `"` + strings.Repeat("a", 63) + `"`},
+		{name: "Test Case Negative - Empty", input: "", wantErr: true},
+		{name: "Test Case Negative - Too long", input: strings.Repeat("a", 64), wantErr: true},
+		{name: "Test Case Negative - Statement injection", input: "public; DROP TABLE account; --", wantErr: true},
+		{name: "Test Case Negative - Embedded quote", input: `public"."account`, wantErr: true},
+		{name: "Test Case Negative - Already quoted", input: `"public"`, wantErr: true},
+		{name: "Test Case Negative - Qualified name", input: "public.account", wantErr: true},
+		{name: "Test Case Negative - Comment", input: "public--", wantErr: true},
+		{name: "Test Case Negative - Whitespace", input: "public schema", wantErr: true},
+		{name: "Test Case Negative - Upper case", input: "Public", wantErr: true},
+		{name: "Test Case Negative - Leading digit", input: "1public", wantErr: true},
+		{name: "Test Case Negative - Null byte", input: "public\x00", wantErr: true},
+		{name: "Test Case Negative - Non ASCII", input: "publіc", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := QuoteIdentifier(tc.input)
+
+			if tc.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestQualifiedName(t *testing.T) {
+	got, err := QualifiedName("ledger", "account")
+	assert.NoError(t, err)
+	assert.Equal(t, `"ledger"."account"`, got)
+
+	_, err = QualifiedName("ledger", `account" CASCADE`)
+	assert.Error(t, err)
+}
@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// This is added here for ease of setup. In a normal dev setup, table creation should be done outside of code either through a pipeline or DB team
+// Migrations are applied in order of Version and a migration must never be changed once released, add a new one instead
+// %[1]s in a migration is replaced with the quoted schema name, see QuoteIdentifier
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create_account_and_transaction",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS %[1]s.account(
+		id VARCHAR PRIMARY KEY NOT NULL,
+		balance float NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS %[1]s.transaction(
+		id SERIAL PRIMARY KEY NOT NULL,
+		source_account_id VARCHAR NOT NULL,
+		destination_account_id VARCHAR NOT NULL,
+		amount VARCHAR NOT NULL,
+		error_message VARCHAR,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	},
+	{
+		Version: 2,
+		Name:    "create_transaction_account_indexes",
+		SQL: `
+	CREATE INDEX IF NOT EXISTS transaction_source_account_id_idx ON %[1]s.transaction(source_account_id, id);
+	CREATE INDEX IF NOT EXISTS transaction_destination_account_id_idx ON %[1]s.transaction(destination_account_id, id);`,
+	},
+	{
+		Version: 3,
+		Name:    "create_rate_limit_bucket",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS %[1]s.rate_limit_bucket(
+		key VARCHAR PRIMARY KEY NOT NULL,
+		tokens float NOT NULL,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	},
+	{
+		Version: 4,
+		Name:    "add_account_status_currency_and_list_indexes",
+		SQL: `
+	ALTER TABLE %[1]s.account ADD COLUMN IF NOT EXISTS status VARCHAR NOT NULL DEFAULT 'active';
+	ALTER TABLE %[1]s.account ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+	CREATE INDEX IF NOT EXISTS account_id_pattern_idx ON %[1]s.account(id varchar_pattern_ops);
+	CREATE INDEX IF NOT EXISTS account_created_at_idx ON %[1]s.account(created_at, id);
+	CREATE INDEX IF NOT EXISTS account_balance_idx ON %[1]s.account(balance, id);
+	CREATE INDEX IF NOT EXISTS account_status_currency_idx ON %[1]s.account(status, currency, id);`,
+	},
+	{
+		Version: 5,
+		Name:    "add_account_display_name_metadata_and_labels",
+		SQL: `
+	ALTER TABLE %[1]s.account ADD COLUMN IF NOT EXISTS display_name VARCHAR NOT NULL DEFAULT '';
+	ALTER TABLE %[1]s.account ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+	ALTER TABLE %[1]s.account ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
+
+	CREATE INDEX IF NOT EXISTS account_metadata_idx ON %[1]s.account USING GIN (metadata jsonb_path_ops);
+	CREATE INDEX IF NOT EXISTS account_labels_idx ON %[1]s.account USING GIN (labels);`,
+	},
+	{
+		Version: 6,
+		Name:    "add_transaction_description_reference_and_metadata",
+		SQL: `
+	ALTER TABLE %[1]s.transaction ADD COLUMN IF NOT EXISTS description VARCHAR NOT NULL DEFAULT '';
+	ALTER TABLE %[1]s.transaction ADD COLUMN IF NOT EXISTS reference VARCHAR;
+	ALTER TABLE %[1]s.transaction ADD COLUMN IF NOT EXISTS metadata JSONB;
+	ALTER TABLE %[1]s.transaction ADD COLUMN IF NOT EXISTS client_id VARCHAR NOT NULL DEFAULT '';
+
+	CREATE UNIQUE INDEX IF NOT EXISTS transaction_client_reference_idx ON %[1]s.transaction(client_id, reference)
+		WHERE reference IS NOT NULL AND error_message IS NULL;`,
+	},
+	{
+		Version: 7,
+		Name:    "add_account_initial_balance_and_balance_snapshot",
+		SQL: `
+	ALTER TABLE %[1]s.account ADD COLUMN IF NOT EXISTS initial_balance float;
+	UPDATE %[1]s.account a SET initial_balance = a.balance - COALESCE((
+		SELECT SUM(CASE WHEN t.destination_account_id = a.id THEN t.amount::float8 ELSE -t.amount::float8 END)
+		FROM %[1]s.transaction t
+		WHERE (t.source_account_id = a.id OR t.destination_account_id = a.id) AND t.error_message IS NULL
+	), 0)
+	WHERE initial_balance IS NULL;
+	ALTER TABLE %[1]s.account ALTER COLUMN initial_balance SET NOT NULL;
+
+	CREATE TABLE IF NOT EXISTS %[1]s.balance_snapshot(
+		account_id VARCHAR NOT NULL,
+		as_of TIMESTAMPTZ NOT NULL,
+		balance float NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (account_id, as_of)
+	);
+
+	CREATE INDEX IF NOT EXISTS transaction_source_account_created_at_idx ON %[1]s.transaction(source_account_id, created_at);
+	CREATE INDEX IF NOT EXISTS transaction_destination_account_created_at_idx ON %[1]s.transaction(destination_account_id, created_at);`,
+	},
+	{
+		Version: 8,
+		Name:    "create_balance_correction",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS %[1]s.balance_correction(
+		id BIGSERIAL PRIMARY KEY NOT NULL,
+		account_id VARCHAR NOT NULL,
+		previous_balance float NOT NULL,
+		corrected_balance float NOT NULL,
+		actor VARCHAR NOT NULL,
+		reason VARCHAR NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS balance_correction_account_id_idx ON %[1]s.balance_correction(account_id, id);`,
+	},
+	{
+		Version: 9,
+		Name:    "create_audit_log",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS %[1]s.audit_log(
+		id BIGSERIAL PRIMARY KEY NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL,
+		actor VARCHAR NOT NULL,
+		action VARCHAR NOT NULL,
+		resource_type VARCHAR NOT NULL,
+		resource_id VARCHAR NOT NULL,
+		before JSON,
+		after JSON,
+		request_id VARCHAR NOT NULL,
+		client_ip VARCHAR NOT NULL,
+		prev_hash VARCHAR NOT NULL,
+		hash VARCHAR NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS audit_log_resource_idx ON %[1]s.audit_log(resource_type, resource_id, id);
+
+	CREATE OR REPLACE FUNCTION %[1]s.audit_log_append_only() RETURNS trigger LANGUAGE plpgsql AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_log is append-only';
+	END;
+	$$;
+	DROP TRIGGER IF EXISTS audit_log_append_only ON %[1]s.audit_log;
+	CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON %[1]s.audit_log
+		FOR EACH ROW EXECUTE FUNCTION %[1]s.audit_log_append_only();
+	DROP TRIGGER IF EXISTS audit_log_no_truncate ON %[1]s.audit_log;
+	CREATE TRIGGER audit_log_no_truncate BEFORE TRUNCATE ON %[1]s.audit_log
+		FOR EACH STATEMENT EXECUTE FUNCTION %[1]s.audit_log_append_only();`,
+	},
+	{
+		Version: 10,
+		Name:    "create_adjustment",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS %[1]s.adjustment(
+		id BIGSERIAL PRIMARY KEY NOT NULL,
+		account_id VARCHAR NOT NULL,
+		suspense_account_id VARCHAR NOT NULL,
+		direction VARCHAR NOT NULL,
+		amount VARCHAR NOT NULL,
+		reason_code VARCHAR NOT NULL,
+		note VARCHAR NOT NULL DEFAULT '',
+		status VARCHAR NOT NULL,
+		requested_by VARCHAR NOT NULL,
+		reviewed_by VARCHAR NOT NULL DEFAULT '',
+		transaction_id BIGINT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		reviewed_at TIMESTAMPTZ,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE INDEX IF NOT EXISTS adjustment_account_id_idx ON %[1]s.adjustment(account_id, id);`,
+	},
+	{
+		Version: 11,
+		Name:    "create_transfer_approval",
+		SQL: `
+	CREATE TABLE IF NOT EXISTS %[1]s.transfer_approval(
+		id BIGSERIAL PRIMARY KEY NOT NULL,
+		source_account_id VARCHAR NOT NULL,
+		destination_account_id VARCHAR NOT NULL,
+		amount VARCHAR NOT NULL,
+		description VARCHAR NOT NULL DEFAULT '',
+		reference VARCHAR,
+		metadata JSONB,
+		client_id VARCHAR NOT NULL DEFAULT '',
+		status VARCHAR NOT NULL,
+		initiated_by VARCHAR NOT NULL,
+		reviewed_by VARCHAR NOT NULL DEFAULT '',
+		transaction_id BIGINT,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		reviewed_at TIMESTAMPTZ,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS transfer_approval_pending_reference_idx ON %[1]s.transfer_approval(client_id, reference)
+		WHERE reference IS NOT NULL AND status = 'pending_approval';
+	CREATE INDEX IF NOT EXISTS transfer_approval_pending_expires_at_idx ON %[1]s.transfer_approval(expires_at)
+		WHERE status = 'pending_approval';`,
+	},
+	{
+		Version: 12,
+		Name:    "add_transaction_status",
+		SQL: `
+	ALTER TABLE %[1]s.transaction ADD COLUMN IF NOT EXISTS status VARCHAR;
+	UPDATE %[1]s.transaction SET status = CASE WHEN error_message IS NULL THEN 'completed' ELSE 'failed' END
+	WHERE status IS NULL;
+	ALTER TABLE %[1]s.transaction ALTER COLUMN status SET DEFAULT 'pending';
+	ALTER TABLE %[1]s.transaction ALTER COLUMN status SET NOT NULL;`,
+	},
+	{
+		Version: 13,
+		Name:    "add_transaction_completed_at",
+		SQL: `
+	ALTER TABLE %[1]s.transaction ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ;
+	UPDATE %[1]s.transaction SET completed_at = created_at WHERE status = 'completed' AND completed_at IS NULL;
+
+	CREATE INDEX IF NOT EXISTS transaction_source_account_completed_at_idx ON %[1]s.transaction(source_account_id, completed_at);
+	CREATE INDEX IF NOT EXISTS transaction_destination_account_completed_at_idx ON %[1]s.transaction(destination_account_id, completed_at);`,
+	},
+	{
+		Version: 14,
+		Name:    "add_rate_limit_bucket_expires_at",
+		SQL: `
+	ALTER TABLE %[1]s.rate_limit_bucket ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+	CREATE INDEX IF NOT EXISTS rate_limit_bucket_expires_at_idx ON %[1]s.rate_limit_bucket(expires_at);`,
+	},
+	{
+		Version: 15,
+		Name:    "add_balance_correction_transaction",
+		SQL: `
+	ALTER TABLE %[1]s.balance_correction ADD COLUMN IF NOT EXISTS suspense_account_id VARCHAR;
+	ALTER TABLE %[1]s.balance_correction ADD COLUMN IF NOT EXISTS transaction_id BIGINT;`,
+	},
+}
+
+var migrationsTable = `
+	CREATE TABLE IF NOT EXISTS %[1]s.schema_migrations(
+		version INT PRIMARY KEY NOT NULL,
+		name VARCHAR NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+`
+
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// LatestVersion returns the version of the last migration known to this build
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// CurrentVersion will return the version of the last migration applied to the schema, 0 when none has been applied
+func CurrentVersion(ctx context.Context, db sqlx.QueryerContext, schema string) (int, error) {
+	quotedSchema, err := QuoteIdentifier(schema)
+	if err != nil {
+		return 0, fmt.Errorf("schema %q: %w", schema, err)
+	}
+	var version int
+	query := fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) FROM %s.schema_migrations`, quotedSchema)
+	if err := sqlx.GetContext(ctx, db, &version, query); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Migrate will apply every migration newer than the current version of the schema, each in its own transaction
+// The schema_migrations table is locked while a migration is applied so that instances starting together apply it once
+func Migrate(ctx context.Context, db *sqlx.DB, schema string) error {
+	quotedSchema, err := QuoteIdentifier(schema)
+	if err != nil {
+		return fmt.Errorf("schema %q: %w", schema, err)
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(migrationsTable, quotedSchema)); err != nil {
+		return err
+	}
+	for _, migration := range migrations {
+		if err := applyMigration(ctx, db, schema, quotedSchema, migration); err != nil {
+			return fmt.Errorf("migration %d %s: %w", migration.Version, migration.Name, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration will apply migration to schema, quoted as quotedSchema, unless it has already been applied
+func applyMigration(ctx context.Context, db *sqlx.DB, schema string, quotedSchema string, migration Migration) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`LOCK TABLE %s.schema_migrations IN EXCLUSIVE MODE`, quotedSchema)); err != nil {
+		return err
+	}
+	current, err := CurrentVersion(ctx, tx, schema)
+	if err != nil {
+		return err
+	}
+	if migration.Version <= current {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(migration.SQL, quotedSchema)); err != nil {
+		return err
+	}
+	insert := fmt.Sprintf(`INSERT INTO %s.schema_migrations(version, name) VALUES ($1, $2)`, quotedSchema)
+	if _, err := tx.ExecContext(ctx, insert, migration.Version, migration.Name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Info("applied migration", "version", migration.Version, "name", migration.Name)
+	return nil
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrateAppliesPendingMigrations(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+	db := sqlx.NewDb(sqlDB, DriverName)
+
+	// Every migration but the last one has been applied
+	applied := LatestVersion() - 1
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS \"public\".schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, migration := range migrations {
+		mock.ExpectBegin()
+		mock.ExpectExec("LOCK TABLE \"public\".schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(applied))
+		if migration.Version <= applied {
+			mock.ExpectRollback()
+			continue
+		}
+		mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(migration.SQL, `"public"`))).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO \"public\".schema_migrations").WithArgs(migration.Version, migration.Name).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	assert.NoError(t, Migrate(context.Background(), db, "public"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrateRejectsInvalidSchema(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer sqlDB.Close()
+	db := sqlx.NewDb(sqlDB, DriverName)
+
+	err = Migrate(context.Background(), db, "public; DROP TABLE account")
+
+	assert.Error(t, err)
+	// Nothing is sent to the database
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,136 @@
+package postgres
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// replicaLagQuery returns whether the replica is receiving WAL from the primary and how far behind the primary it is in seconds
+// The replica is not lagging when it has replayed everything it received, as the last replay time only tells how long ago the primary last wrote
+// That only holds while the WAL receiver is running, as a replica cut off from the primary has replayed everything it received too
+// pg_stat_wal_receiver has a row while the receiver is running, its status is only visible to roles with pg_read_all_stats
+var replicaLagQuery = `
+	SELECT
+		EXISTS (SELECT 1 FROM pg_stat_wal_receiver) AS receiving,
+		CASE
+			WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+			ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)
+		END AS lag`
+
+// replicaLag is the result of replicaLagQuery
+type replicaLag struct {
+	Receiving bool    `db:"receiving"`
+	Seconds   float64 `db:"lag"`
+}
+
+// replicaLagCheckInterval is how often the lag of the replica is checked
+var replicaLagCheckInterval = time.Second
+
+// ReadPool routes read-only queries that tolerate stale data to a read replica
+// Queries are sent to the primary when no replica is configured, or while the replica is unreachable or lagging behind the primary by more than maxLag
+// Queries feeding a write decision, such as the balances of a transfer, must not use a ReadPool
+type ReadPool struct {
+	primary *sqlx.DB
+	replica *sqlx.DB
+	maxLag  time.Duration
+
+	useReplica atomic.Bool
+	started    bool
+	stop       chan struct{}
+	stopOnce   sync.Once
+	stopped    chan struct{}
+}
+
+// NewReadPool returns a ReadPool over primary and replica, replica may be nil when no replica is configured
+// The replica is used once Start has confirmed it is within maxLag of the primary, a maxLag of 0 disables the lag check
+func NewReadPool(primary *sqlx.DB, replica *sqlx.DB, maxLag time.Duration) *ReadPool {
+	pool := &ReadPool{
+		primary: primary,
+		replica: replica,
+		maxLag:  maxLag,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	if replica != nil && maxLag == 0 {
+		pool.useReplica.Store(true)
+	}
+	return pool
+}
+
+// DB returns the pool a read-only query should be sent to
+func (p *ReadPool) DB() *sqlx.DB {
+	if p.useReplica.Load() {
+		return p.replica
+	}
+	return p.primary
+}
+
+// Primary returns the primary pool, for reads that must see the latest writes
+func (p *ReadPool) Primary() *sqlx.DB {
+	return p.primary
+}
+
+// Start will check the lag of the replica every replicaLagCheckInterval in the background until Close is called
+// The function does nothing when there is no replica or the lag check is disabled
+func (p *ReadPool) Start() {
+	p.started = true
+	if p.replica == nil || p.maxLag == 0 {
+		close(p.stopped)
+		return
+	}
+	p.checkLag()
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(replicaLagCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.checkLag()
+			}
+		}
+	}()
+}
+
+// checkLag will route reads to the replica when it is reachable, receiving WAL from the primary and within maxLag of it, and to the primary otherwise
+func (p *ReadPool) checkLag() {
+	ctx, cancel := context.WithTimeout(context.Background(), replicaLagCheckInterval)
+	defer cancel()
+	var result replicaLag
+	err := p.replica.GetContext(ctx, &result, replicaLagQuery)
+	lag := time.Duration(result.Seconds * float64(time.Second))
+	healthy := err == nil && result.Receiving && lag <= p.maxLag
+
+	if healthy != p.useReplica.Swap(healthy) {
+		if healthy {
+			slog.Info("reading from replica", "lag", lag.String())
+		} else {
+			slog.Warn("replica unavailable, not receiving WAL or lagging, reading from primary", "receiving", result.Receiving, "lag", lag.String(), "max_lag", p.maxLag.String(), "error", err)
+		}
+	}
+}
+
+// Close will stop the lag check and close the replica pool, the primary pool is left open
+func (p *ReadPool) Close(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+	if p.started {
+		select {
+		case <-p.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if p.replica == nil {
+		return nil
+	}
+	return p.replica.Close()
+}
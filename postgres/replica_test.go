@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadPool(t *testing.T) {
+	tests := []struct {
+		name        string
+		receiving   bool
+		lag         float64
+		lagErr      error
+		wantReplica bool
+	}{
+		{name: "Test Case Positive - Replica within lag", receiving: true, lag: 1.5, wantReplica: true},
+		{name: "Test Case Negative - Replica lagging", receiving: true, lag: 10, wantReplica: false},
+		// A replica cut off from the primary has replayed everything it received, so reports no lag
+		{name: "Test Case Negative - WAL receiver down", receiving: false, lag: 0, wantReplica: false},
+		{name: "Test Case Negative - Replica unreachable", lagErr: errors.New("connection refused"), wantReplica: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			primaryDB, _, err := sqlmock.New()
+			assert.NoError(t, err)
+			defer primaryDB.Close()
+			replicaDB, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			query := mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM pg_stat_wal_receiver\\) AS receiving, CASE WHEN pg_last_wal_receive_lsn\\(\\)")
+			if tc.lagErr != nil {
+				query.WillReturnError(tc.lagErr)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows([]string{"receiving", "lag"}).AddRow(tc.receiving, tc.lag))
+			}
+			mock.ExpectClose()
+			primary, replica := sqlx.NewDb(primaryDB, DriverName), sqlx.NewDb(replicaDB, DriverName)
+
+			pool := NewReadPool(primary, replica, 5*time.Second)
+			assert.Same(t, primary, pool.DB())
+			pool.Start()
+
+			if tc.wantReplica {
+				assert.Same(t, replica, pool.DB())
+			} else {
+				assert.Same(t, primary, pool.DB())
+			}
+			assert.Same(t, primary, pool.Primary())
+			assert.NoError(t, pool.Close(context.Background()))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestReadPoolWithoutReplica(t *testing.T) {
+	primaryDB, _, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer primaryDB.Close()
+	primary := sqlx.NewDb(primaryDB, DriverName)
+
+	pool := NewReadPool(primary, nil, 5*time.Second)
+	pool.Start()
+
+	assert.Same(t, primary, pool.DB())
+	assert.NoError(t, pool.Close(context.Background()))
+}
@@ -1,57 +1,172 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"account-test/internal/accountid"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"syscall"
 
-	"github.com/go-chi/chi"
+	"google.golang.org/grpc"
 
+	accountv1 "account-test/api/proto/account/v1"
 	"account-test/config"
+	"account-test/internal/core/ports"
 	"account-test/internal/core/services"
+	grpchandlers "account-test/internal/handlers/grpc"
+	httphandlers "account-test/internal/handlers/http"
+	"account-test/internal/logger"
+	"account-test/internal/metrics"
+	"account-test/internal/middleware"
 	"account-test/internal/repositories"
+	"account-test/internal/server"
+	"account-test/internal/snapshot"
+	"account-test/internal/tracing"
 	db "account-test/postgres"
-	"account-test/static"
 )
 
 func main() {
-	config.InitReader()
-	port := os.Getenv("PORT")
-	if port == "" {
-		panic(static.EmptyPort)
+	appConfig, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(2)
 	}
-
-	r := chi.NewRouter()
 
 	// Start of Dependency Injection
-	appConfig := config.Init()
+	if err := logger.Init(os.Stdout, appConfig.Log); err != nil {
+		panic(err)
+	}
+	slog.Info("configuration loaded", "config", appConfig)
+	shutdownTracing, err := tracing.Init(context.Background(), appConfig.Tracing)
+	if err != nil {
+		panic(err)
+	}
+
 	dbClient, err := db.Init(appConfig.DB)
 	if err != nil {
 		panic(err)
 	}
-	accountPort := repositories.NewAccountPort(dbClient, appConfig.DB)
-	transactionPort := repositories.NewTransactionPort(dbClient, appConfig.DB)
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB(dbClient.DB, appConfig.DB.Name)
+
+	replicaClient, err := db.InitReplica(appConfig.DB)
+	if err != nil {
+		panic(err)
+	}
+	readPool := db.NewReadPool(dbClient, replicaClient, appConfig.DB.ReplicaMaxLag)
+	readPool.Start()
+
+	accountPort, err := repositories.NewAccountPort(dbClient, readPool, appConfig.DB)
+	if err != nil {
+		panic(err)
+	}
+	transactionPort, err := repositories.NewTransactionPort(dbClient, readPool, appConfig.DB)
+	if err != nil {
+		panic(err)
+	}
+	adjustmentPort, err := repositories.NewAdjustmentPort(dbClient, transactionPort, appConfig.DB)
+	if err != nil {
+		panic(err)
+	}
+	transferApprovalPort, err := repositories.NewTransferApprovalPort(dbClient, transactionPort, appConfig.DB)
+	if err != nil {
+		panic(err)
+	}
+
+	accountIDs, err := accountid.New(appConfig.AccountID)
+	if err != nil {
+		panic(err)
+	}
 
-	accountSvc := services.NewAccountSvc(accountPort)
-	transactionSvc := services.NewTransactionSvc(accountPort, transactionPort)
+	suspenseAccounts, err := services.LoadSuspenseAccounts(context.Background(), accountPort, *appConfig.Adjustment)
+	if err != nil {
+		panic(err)
+	}
+
+	accountSvc := services.NewAccountSvc(accountPort, accountIDs)
+	transactionSvc := services.NewTransactionSvc(accountPort, transactionPort, transferApprovalPort, appMetrics, accountIDs, *appConfig.Approval, suspenseAccounts)
+	balanceSnapshots := snapshot.New(accountSvc, appConfig.Snapshot)
+	balanceSnapshots.Start()
+	// Transfers younger than the snapshot delay may still be in flight, so reconciliation leaves them alone too
+	reconciliationSvc := services.NewReconciliationSvc(accountPort, appConfig.Snapshot.Delay, suspenseAccounts)
+	adjustmentSvc := services.NewAdjustmentSvc(accountPort, adjustmentPort, accountIDs, *appConfig.Adjustment, suspenseAccounts)
+
+	accountHandler := httphandlers.NewAccountHandler(accountSvc)
+	transactionHandler := httphandlers.NewTransactionHandler(transactionSvc)
+	adminHandler := httphandlers.NewAdminHandler(reconciliationSvc)
+	adjustmentHandler := httphandlers.NewAdjustmentHandler(adjustmentSvc)
+	healthHandler := httphandlers.NewHealthHandler(repositories.NewHealthPort(dbClient, appConfig.DB))
+
+	var rateLimitPort ports.RateLimitRepository = repositories.NewMemoryRateLimitPort()
+	if appConfig.RateLimit.Store == middleware.RateLimitStorePostgres {
+		rateLimitPort, err = repositories.NewRateLimitPort(dbClient, appConfig.DB)
+		if err != nil {
+			panic(err)
+		}
+	}
 	// End of Dependency Injection
 
-	r.Group(func(r chi.Router) {
-		r.Route("/accounts", func(route chi.Router) {
-			route.Get("/{account_id}", accountSvc.GetAccount)
-			route.Post("/", accountSvc.PostAccount)
-		})
-		r.Route("/transactions", func(route chi.Router) {
-			route.Post("/", transactionSvc.PostTransaction)
-		})
+	r := httphandlers.NewRouter(httphandlers.RouterDeps{
+		AccountHandler:     accountHandler,
+		TransactionHandler: transactionHandler,
+		HealthHandler:      healthHandler,
+		AdminHandler:       adminHandler,
+		AdjustmentHandler:  adjustmentHandler,
+		RateLimitPort:      rateLimitPort,
+		RateLimit:          appConfig.RateLimit,
+		Timeout:            appConfig.Timeout,
+		Admin:              appConfig.Admin,
+		Metrics:            appMetrics,
 	})
 
-	// Health Endpoint for Liveness Probe
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+	httpServer := server.New(fmt.Sprintf(":%d", appConfig.Port), r, appConfig.Server)
+	httpServer.OnDrain(healthHandler.SetDraining)
+
+	// gRPC API is only started when GRPC_PORT is set
+	if appConfig.GRPCPort != 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", appConfig.GRPCPort))
+		if err != nil {
+			panic(err)
+		}
+		// The same limits and deadlines as the REST routes, so that the gRPC API is not a way around them
+		grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(
+			grpchandlers.RequestIDInterceptor,
+			grpchandlers.PrincipalInterceptor(appConfig.Admin),
+			grpchandlers.RateLimitInterceptor(rateLimitPort, appConfig.RateLimit.Client, grpchandlers.ClientKey(appConfig.Admin)),
+			grpchandlers.TimeoutInterceptor(appConfig.Timeout),
+			grpchandlers.RateLimitInterceptor(rateLimitPort, appConfig.RateLimit.Transfer, grpchandlers.TransferSourceKey),
+		))
+		accountv1.RegisterAccountServiceServer(grpcServer, grpchandlers.NewAccountServer(accountSvc, transactionSvc))
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				slog.Error("gRPC server stopped", "error", err)
+				os.Exit(1)
+			}
+		}()
+		slog.Info("gRPC running", "port", appConfig.GRPCPort)
+		httpServer.OnShutdown("grpc", server.StopGRPC(grpcServer))
+	}
+	// Shutdown hooks run after in-flight HTTP requests have drained, the DB pool is closed last as the others may still use it
+	httpServer.OnShutdown("tracing", shutdownTracing)
+	httpServer.OnShutdown("snapshot", balanceSnapshots.Close)
+	httpServer.OnShutdown("replica", readPool.Close)
+	httpServer.OnShutdown("database", func(context.Context) error {
+		return dbClient.Close()
 	})
 
-	log.Printf("app running on http://localhost:%s/", port)
-	log.Fatal(http.ListenAndServe(":"+port, r))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	slog.Info("app running", "url", fmt.Sprintf("http://localhost:%d/", appConfig.Port))
+	if err := httpServer.ListenAndServe(ctx); err != nil {
+		slog.Error("HTTP server stopped", "error", err)
+		os.Exit(1)
+	}
 }
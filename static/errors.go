@@ -1,19 +1,56 @@
 package static
 
 const (
-	EmptyPort           = "PORT cannot be empty"
-	ErrUnableToReadBody = "Failed to read request body"
+	EmptyPort               = "PORT cannot be empty"
+	ErrUnableToReadBody     = "Failed to read request body"
+	ErrTransferBodyTooLarge = "Request body must not be larger than 64 KiB"
+	ErrTooManyRequests      = "Too many requests, please retry later"
+	ErrInternal             = "Internal server error"
+	ErrRequestTimeout       = "Request timed out, please retry later"
+	ErrAdminAPIKeyRequired  = "An admin API key is required in X-API-Key"
 
 	// Business Logic Specific Error - Account
-	ErrAccountAlreadyExist     = "Account already exist"
-	ErrAccountDoesNotExist     = "Account does not exist"
-	ErrBalanceNotValidNumber   = "initial_balance is not a valid number"
-	ErrBalanceCannotBeNegative = "initial_balance cannot be a negative number"
-	ErrBalanceTooLarge         = "initial_balance value is too large"
-	ErrCreatingAccount         = "Error creating new account"
-	ErrIDLengthCannotBeZero    = "ID must be at least one character long"
-	ErrIDLengthTooLong         = "ID length must be not be longer than 32 characters"
-	ErrUnableToRetrieveAccount = "Error retrieving account balance"
+	ErrAccountAlreadyExist        = "Account already exist"
+	ErrAccountDoesNotExist        = "Account does not exist"
+	ErrBalanceNotValidNumber      = "initial_balance is not a valid number"
+	ErrBalanceCannotBeNegative    = "initial_balance cannot be a negative number"
+	ErrBalanceTooLarge            = "initial_balance value is too large"
+	ErrCreatingAccount            = "Error creating new account"
+	ErrIDLengthCannotBeZero       = "ID must be at least one character long"
+	ErrIDLengthTooLong            = "ID length must be not be longer than 32 characters"
+	ErrIDNotULID                  = "ID must be a ULID of 26 upper case characters"
+	ErrIDNotUUIDv7                = "ID must be a lower case version 7 UUID"
+	ErrIDNotMod97                 = "ID is not a valid account number, check for a mistyped digit"
+	ErrIDGeneratedByServer        = "account_id must not be set, account IDs are generated by the server"
+	ErrGeneratingAccountID        = "Error generating account ID"
+	ErrUnableToRetrieveAccount    = "Error retrieving account balance"
+	ErrInvalidCurrency            = "currency must be a 3 letter ISO 4217 code such as USD"
+	ErrInvalidAccountStatus       = "status is not a valid account status"
+	ErrInvalidAccountSort         = "sort must be one of id, -id, created_at, -created_at, balance, -balance"
+	ErrInvalidBalanceRange        = "min_balance cannot be larger than max_balance"
+	ErrInvalidCreatedRange        = "created_from must be before created_to"
+	ErrIDPrefixTooLong            = "id_prefix must not be longer than 64 characters"
+	ErrInvalidAccountListParam    = "limit must be an integer, min_balance and max_balance numbers and created_from and created_to RFC 3339 timestamps"
+	ErrInvalidCursor              = "cursor is not valid for this sort"
+	ErrUnableToListAccounts       = "Error retrieving accounts"
+	ErrDisplayNameTooLong         = "display_name must not be longer than 100 characters"
+	ErrTooManyMetadataKeys        = "metadata must not have more than 32 keys"
+	ErrInvalidMetadataKey         = "metadata keys must be 1 to 64 characters"
+	ErrMetadataValueTooLong       = "metadata values must not be longer than 512 characters"
+	ErrInvalidMetadataFilter      = "metadata filters must be given as key:value"
+	ErrTooManyLabels              = "labels must not have more than 16 labels"
+	ErrInvalidLabel               = "labels must be 1 to 63 lower case letters, digits, '_', '.', ':' or '-', starting with a letter or digit"
+	ErrUnableToUpdateAccount      = "Unable to update account"
+	ErrInvalidAsOf                = "as_of must be an RFC 3339 timestamp"
+	ErrAsOfInFuture               = "as_of cannot be in the future"
+	ErrAccountNotCreatedAsOf      = "Account did not exist at as_of"
+	ErrUnableToRetrieveBalance    = "Error retrieving historical balance"
+	ErrSnapshottingBalances       = "Error taking balance snapshots"
+	ErrReasonRequired             = "reason is required to correct balances"
+	ErrReasonTooLong              = "reason must not be longer than 255 characters"
+	ErrUnableToReconcile          = "Error reconciling account balances"
+	ErrUnableToCorrectBalance     = "Error correcting account balance"
+	ErrNoSuspenseAccountToCorrect = "No suspense account holds %s, the currency of account %s, its balance cannot be corrected"
 
 	//Business Logic Specific Error - Transaction
 	ErrSourceAccountDoesNotExist       = "Source account does not exist"
@@ -25,7 +62,54 @@ const (
 	ErrGetSourceAccount                = "Error retrieving source account"
 	ErrGetDestinationAccount           = "Error retrieving destination account"
 	ErrTransferAmountLargerThanAccount = "amount cannot be larger than source account's balance"
+	ErrDescriptionTooLong              = "description must not be longer than 255 characters"
+	ErrReferenceTooLong                = "reference must not be longer than 64 characters"
+	ErrDuplicateReference              = "reference has already been used by another transfer"
+	ErrInvalidTransferMetadata         = "metadata must be a JSON object of at most 4096 bytes"
+	ErrCurrencyMismatch                = "Source account and destination account must have the same currency"
 	ErrUnableToCompleteTransaction     = "Error - unable to complete transaction"
+	ErrUnableToListTransactions        = "Error retrieving transactions"
+	ErrInvalidPaginationParameter      = "limit and before_id must be integers"
+	ErrInvalidStatementParam           = "from and to must be RFC 3339 timestamps"
+	ErrInvalidStatementFormat          = "format must be json, csv or text"
+	ErrInvalidStatementPeriod          = "from must be before to"
+	ErrStatementToInFuture             = "to cannot be in the future"
+	ErrAccountNotCreatedInPeriod       = "Account did not exist before to"
+	ErrUnableToProduceStatement        = "Error producing statement"
+
+	//Business Logic Specific Error - Adjustment
+	ErrAdjustmentsDisabled         = "Adjustments are disabled, no suspense account is configured"
+	ErrAdjustSuspenseAccount       = "A suspense account cannot be adjusted"
+	ErrInvalidAdjustmentDirection  = "direction must be credit or debit"
+	ErrInvalidAdjustmentReasonCode = "reason_code must be one of chargeback, correction, fee_refund, goodwill, write_off"
+	ErrNoteTooLong                 = "note must not be longer than 255 characters"
+	ErrInvalidAdjustmentID         = "adjustment_id must be an integer"
+	ErrAdjustmentDoesNotExist      = "Adjustment does not exist"
+	ErrAdjustmentNotPending        = "Adjustment is not pending approval"
+	ErrSelfApproval                = "An adjustment must be reviewed by another actor than the one who requested it"
+	ErrUnableToAdjust              = "Error adjusting account balance"
+	ErrUnableToReviewAdjustment    = "Error reviewing adjustment"
+	ErrSuspenseAccountDoesNotExist = "Suspense account %s configured by ADJUSTMENT_SUSPENSE_ACCOUNT_ID does not exist"
+	ErrSuspenseAccountCurrency     = "Suspense accounts %s and %s configured by ADJUSTMENT_SUSPENSE_ACCOUNT_ID both hold %s, there must be at most one per currency"
+	ErrNoSuspenseAccount           = "No suspense account holds the currency of the account"
+	ErrTransferSuspenseAccount     = "A suspense account can only be booked against by adjustments"
+	ErrGetSuspenseAccount          = "Error getting suspense account"
+
+	//Business Logic Specific Error - Transfer Approval
+	ErrInvalidApprovalID              = "approval_id must be an integer"
+	ErrApprovalDoesNotExist           = "Transfer approval does not exist"
+	ErrTransferNotPending             = "Transfer is not pending approval"
+	ErrTransferApprovalExpired        = "Transfer approval has expired, the transfer must be requested again"
+	ErrTransferSelfApproval           = "A transfer must be reviewed by another actor than the one who requested it"
+	ErrTransferApprovalAPIKeyRequired = "A transfer above the approval threshold must be requested with an admin API key"
+	ErrUnableToRequestApproval        = "Error requesting transfer approval"
+	ErrUnableToReviewTransfer         = "Error reviewing transfer"
+
+	//Business Logic Specific Error - Audit
+	ErrUnableToVerifyAuditLog = "Error verifying audit log"
+	ErrAuditPrevHashMismatch  = "prev_hash does not match the hash of the entry before it, an entry was removed, inserted or reordered"
+	ErrAuditHashMismatch      = "hash does not match the content of the entry, the entry was changed"
+	ErrAuditAnchorMissing     = "no entry has the expected hash, entries were removed from the end of the log"
 )
 
 var ()
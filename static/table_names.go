@@ -1,6 +1,12 @@
 package static
 
 const (
-	TableAccount     = "account"
-	TableTransaction = "transaction"
+	TableAccount           = "account"
+	TableTransaction       = "transaction"
+	TableRateLimitBucket   = "rate_limit_bucket"
+	TableBalanceSnapshot   = "balance_snapshot"
+	TableBalanceCorrection = "balance_correction"
+	TableAuditLog          = "audit_log"
+	TableAdjustment        = "adjustment"
+	TableTransferApproval  = "transfer_approval"
 )